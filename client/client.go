@@ -0,0 +1,213 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package client is a typed Go client for the node status API.
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nymtech/node-status-api/models"
+)
+
+// DefaultTimeout is used for requests unless configured otherwise
+const DefaultTimeout = 30 * time.Second
+
+// Config for the client
+type Config struct {
+	BaseURL string        // address of the API, e.g. http://localhost:8081
+	Timeout time.Duration // timeout of a single request, 0 means DefaultTimeout
+}
+
+// Client talks to the node status API
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// StatusError is returned whenever the API responds with an unexpected status code
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (err *StatusError) Error() string {
+	if err.Message == "" {
+		return fmt.Sprintf("node status api responded with %d", err.StatusCode)
+	}
+	return fmt.Sprintf("node status api responded with %d: %s", err.StatusCode, err.Message)
+}
+
+// IsNotFound checks whether the error was caused by the requested resource not existing
+func IsNotFound(err error) bool {
+	statusErr, ok := err.(*StatusError)
+	return ok && statusErr.StatusCode == http.StatusNotFound
+}
+
+// New returns a new client
+func New(cfg Config) *Client {
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	return &Client{
+		baseURL:    strings.TrimRight(cfg.BaseURL, "/"),
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// CreateMixStatus submits a single mixnode status
+func (client *Client) CreateMixStatus(status models.MixStatus) error {
+	return client.post("/api/status/mixnode", status)
+}
+
+// BatchCreateMixStatus submits multiple mixnode statuses at once
+func (client *Client) BatchCreateMixStatus(batch models.BatchMixStatus) error {
+	return client.post("/api/status/mixnode/batch", batch)
+}
+
+// ListMixStatus lists the stored statuses of the given mixnode
+func (client *Client) ListMixStatus(pubkey string) ([]models.PersistedMixStatus, error) {
+	var statuses []models.PersistedMixStatus
+	err := client.get("/api/status/mixnode/"+url.PathEscape(pubkey)+"/history", &statuses)
+	return statuses, err
+}
+
+// GetMixStatusReport retrieves the uptime report of the given mixnode
+func (client *Client) GetMixStatusReport(pubkey string) (models.MixStatusReport, error) {
+	var report models.MixStatusReport
+	err := client.get("/api/status/mixnode/"+url.PathEscape(pubkey)+"/report", &report)
+	return report, err
+}
+
+// GetMixNodeSummary retrieves the report of the given mixnode along with its uptime trends
+func (client *Client) GetMixNodeSummary(pubkey string) (models.MixNodeSummary, error) {
+	var summary models.MixNodeSummary
+	err := client.get("/api/status/mixnode/"+url.PathEscape(pubkey)+"/summary", &summary)
+	return summary, err
+}
+
+// GetFullMixReport retrieves the uptime reports of all active mixnodes
+func (client *Client) GetFullMixReport() (models.BatchMixStatusReport, error) {
+	var report models.BatchMixStatusReport
+	err := client.get("/api/status/fullmixreport", &report)
+	return report, err
+}
+
+// GetMixUptimeAggregate retrieves uptime statistics across all active mixnodes over the given number of hours
+func (client *Client) GetMixUptimeAggregate(hours int) (models.MixUptimeAggregate, error) {
+	var aggregate models.MixUptimeAggregate
+	err := client.get("/api/status/mixnodes/aggregate?hours="+strconv.Itoa(hours), &aggregate)
+	return aggregate, err
+}
+
+// CreateGatewayStatus submits a single gateway status
+func (client *Client) CreateGatewayStatus(status models.GatewayStatus) error {
+	return client.post("/api/status/gateway", status)
+}
+
+// BatchCreateGatewayStatus submits multiple gateway statuses at once
+func (client *Client) BatchCreateGatewayStatus(batch models.BatchGatewayStatus) error {
+	return client.post("/api/status/gateway/batch", batch)
+}
+
+// ListGatewayStatus lists the stored statuses of the given gateway
+func (client *Client) ListGatewayStatus(pubkey string) ([]models.PersistedGatewayStatus, error) {
+	var statuses []models.PersistedGatewayStatus
+	err := client.get("/api/status/gateway/"+url.PathEscape(pubkey)+"/history", &statuses)
+	return statuses, err
+}
+
+// GetGatewayStatusReport retrieves the uptime report of the given gateway
+func (client *Client) GetGatewayStatusReport(pubkey string) (models.GatewayStatusReport, error) {
+	var report models.GatewayStatusReport
+	err := client.get("/api/status/gateway/"+url.PathEscape(pubkey)+"/report", &report)
+	return report, err
+}
+
+// GetFullGatewayReport retrieves the uptime reports of all active gateways
+func (client *Client) GetFullGatewayReport() (models.BatchGatewayStatusReport, error) {
+	var report models.BatchGatewayStatusReport
+	err := client.get("/api/status/fullgatewayreport", &report)
+	return report, err
+}
+
+// GetStats retrieves the number of stored statuses along with the active node counts
+func (client *Client) GetStats() (models.StatusStats, error) {
+	var stats models.StatusStats
+	err := client.get("/api/status/stats", &stats)
+	return stats, err
+}
+
+func (client *Client) get(path string, out interface{}) error {
+	return client.do(http.MethodGet, path, nil, http.StatusOK, out)
+}
+
+func (client *Client) post(path string, in interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return client.do(http.MethodPost, path, bytes.NewReader(body), http.StatusCreated, nil)
+}
+
+func (client *Client) do(method, path string, body io.Reader, expectedStatus int, out interface{}) error {
+	req, err := http.NewRequest(method, client.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != expectedStatus {
+		return newStatusError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// newStatusError builds a StatusError, using the message of the models.Error body if there is one
+func newStatusError(resp *http.Response) *StatusError {
+	statusErr := &StatusError{StatusCode: resp.StatusCode}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return statusErr
+	}
+	var apiErr models.Error
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.Message != "" {
+		statusErr.Message = apiErr.Message
+	} else {
+		statusErr.Message = strings.TrimSpace(string(body))
+	}
+	return statusErr
+}
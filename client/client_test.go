@@ -0,0 +1,150 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"net/http/httptest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nymtech/node-status-api/mixmining"
+	"github.com/nymtech/node-status-api/mixmining/fixtures"
+	"github.com/nymtech/node-status-api/mixmining/mocks"
+	"github.com/nymtech/node-status-api/models"
+	. "github.com/onsi/ginkgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var _ = Describe("Client", func() {
+	var server *httptest.Server
+	var client *Client
+	var mockService *mocks.IService
+	var mockSanitizer *mocks.Sanitizer
+	var mockBatchSanitizer *mocks.BatchSanitizer
+
+	BeforeEach(func() {
+		var router *gin.Engine
+		router, mockService, mockSanitizer, mockBatchSanitizer = SetupRouter()
+		server = httptest.NewServer(router)
+		client = New(Config{BaseURL: server.URL + "/"})
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("creating a mix status", func() {
+		It("should submit the status", func() {
+			mockSanitizer.On("Sanitize", fixtures.GoodMixStatus()).Return(fixtures.GoodMixStatus())
+			mockService.On("CreateMixStatus", fixtures.GoodMixStatus()).Return(fixtures.GoodPersistedMixStatus(), nil)
+			mockService.On("SaveMixStatusReport", mock.Anything, fixtures.GoodPersistedMixStatus()).Return(models.MixStatusReport{})
+
+			err := client.CreateMixStatus(fixtures.GoodMixStatus())
+
+			assert.Nil(GinkgoT(), err)
+			mockService.AssertCalled(GinkgoT(), "CreateMixStatus", fixtures.GoodMixStatus())
+		})
+	})
+
+	Describe("creating a batch mix status", func() {
+		Context("when the batch is rejected", func() {
+			It("should return the status code along with the api error", func() {
+				router, _, _, _ := SetupRouterWithConfig(mixmining.Config{MaxBatchSize: 1})
+				server.Config.Handler = router
+
+				err := client.BatchCreateMixStatus(fixtures.GoodBatchMixStatus())
+
+				statusErr, ok := err.(*StatusError)
+				assert.True(GinkgoT(), ok)
+				assert.Equal(GinkgoT(), 413, statusErr.StatusCode)
+				assert.Contains(GinkgoT(), statusErr.Message, "at most 1")
+				mockBatchSanitizer.AssertNotCalled(GinkgoT(), "Sanitize", mock.Anything)
+			})
+		})
+	})
+
+	Describe("getting a mix status report", func() {
+		Context("when the report exists", func() {
+			It("should decode it", func() {
+				mockService.On("GetMixStatusReport", mock.Anything, "key1").Return(fixtures.MixStatusReport(), nil)
+
+				report, err := client.GetMixStatusReport("key1")
+
+				expected := fixtures.MixStatusReport()
+				expected.Status = models.NodeUp
+				assert.Nil(GinkgoT(), err)
+				assert.Equal(GinkgoT(), expected, report)
+			})
+		})
+
+		Context("when the report doesn't exist", func() {
+			It("should return a not found error", func() {
+				mockService.On("GetMixStatusReport", mock.Anything, "foo").Return(models.MixStatusReport{}, nil)
+
+				_, err := client.GetMixStatusReport("foo")
+
+				assert.True(GinkgoT(), IsNotFound(err))
+			})
+		})
+	})
+
+	Describe("getting the full mix report", func() {
+		It("should decode the gzipped report", func() {
+			expected := models.BatchMixStatusReport{Report: []models.MixStatusReport{fixtures.MixStatusReport()}}
+			mockService.On("BatchGetMixStatusReport", mock.Anything).Return(expected)
+
+			report, err := client.GetFullMixReport()
+
+			assert.Nil(GinkgoT(), err)
+			assert.Equal(GinkgoT(), expected, report)
+		})
+	})
+
+	Describe("getting the mix uptime aggregate", func() {
+		It("should pass the window along", func() {
+			expected := models.MixUptimeAggregate{Hours: 12, Nodes: 3}
+			mockService.On("AggregateMixUptime", mock.Anything, 12).Return(expected)
+
+			aggregate, err := client.GetMixUptimeAggregate(12)
+
+			assert.Nil(GinkgoT(), err)
+			assert.Equal(GinkgoT(), expected, aggregate)
+		})
+	})
+})
+
+func SetupRouter() (*gin.Engine, *mocks.IService, *mocks.Sanitizer, *mocks.BatchSanitizer) {
+	return SetupRouterWithConfig(mixmining.Config{})
+}
+
+// SetupRouterWithConfig sets up the real router backed by mocks, keeping the non-mock settings of the provided config
+func SetupRouterWithConfig(cfg mixmining.Config) (*gin.Engine, *mocks.IService, *mocks.Sanitizer, *mocks.BatchSanitizer) {
+	mockSanitizer := new(mocks.Sanitizer)
+	mockBatchSanitizer := new(mocks.BatchSanitizer)
+	mockService := new(mocks.IService)
+
+	mockService.On("MixCount", mock.Anything).Return(0)
+	mockService.On("GatewayCount", mock.Anything).Return(0)
+	mockService.On("StartupPurge")
+
+	cfg.BatchMixSanitizer = mockBatchSanitizer
+	cfg.GenericSanitizer = new(mocks.GenericSanitizer)
+	cfg.Sanitizer = mockSanitizer
+	cfg.Service = mockService
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	mixmining.New(cfg).RegisterRoutes(router)
+	return router, mockService, mockSanitizer, mockBatchSanitizer
+}
@@ -29,9 +29,9 @@ var doc = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/status/fullgatewayreport": {
-            "get": {
-                "description": "Provides summary uptime statistics for last 5 minutes, day, week, and month",
+        "/api/admin/backfill": {
+            "post": {
+                "description": "Recomputes the report of every mixnode that has retained statuses but no stored report, e.g. after the reports were wiped. The same happens on startup. Only available to connections from the local machine, forwarding headers are ignored.",
                 "consumes": [
                     "application/json"
                 ],
@@ -39,22 +39,19 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "status"
+                    "admin"
                 ],
-                "summary": "Retrieves a summary report of historical gateway status",
-                "operationId": "batchGetGatewayStatusReport",
+                "summary": "Rebuilds missing mixnode reports",
+                "operationId": "backfillMixReports",
                 "responses": {
                     "200": {
-                        "description": ""
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.Backfill"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/models.Error"
                         }
@@ -68,38 +65,40 @@ var doc = `{
                 }
             }
         },
-        "/api/status/fullmixreport": {
+        "/api/admin/export": {
             "get": {
-                "description": "Provides summary uptime statistics for last 5 minutes, day, week, and month",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Streams every mix and gateway report, and every retained status if asked for, as newline-delimited models.ExportRecord, so that the service can be backed up while it keeps running. Only available to connections from the local machine, forwarding headers are ignored.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "status"
+                    "admin"
+                ],
+                "summary": "Exports the whole dataset",
+                "operationId": "exportData",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Include the statuses, not just the reports",
+                        "name": "statuses",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Retrieves a summary report of historical mix status",
-                "operationId": "batchGetMixStatusReport",
                 "responses": {
                     "200": {
-                        "description": ""
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.ExportRecord"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/models.Error"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/models.Error"
                         }
@@ -107,9 +106,9 @@ var doc = `{
                 }
             }
         },
-        "/api/status/gateway": {
-            "post": {
-                "description": "Nym network monitor sends packets through the system and checks if they make it. The network monitor then hits this method to report whether the node was up at a given time.",
+        "/api/admin/gateway/{pubkey}/statuses/{ipversion}/{timestamp}": {
+            "delete": {
+                "description": "Same as retracting a mixnode status, but for gateways. Only available to connections from the local machine, forwarding headers are ignored.",
                 "consumes": [
                     "application/json"
                 ],
@@ -117,24 +116,39 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "status"
+                    "admin"
                 ],
-                "summary": "Lets the network monitor create a new uptime status for a gateway",
-                "operationId": "addGatewayStatus",
+                "summary": "Retracts a gateway status",
+                "operationId": "retractGatewayStatus",
                 "parameters": [
                     {
-                        "description": "object",
-                        "name": "object",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.GatewayStatus"
-                        }
+                        "type": "string",
+                        "description": "Gateway Pubkey",
+                        "name": "pubkey",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "IP version of the status",
+                        "name": "ipversion",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Timestamp of the status",
+                        "name": "timestamp",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": ""
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.OK"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
@@ -163,9 +177,9 @@ var doc = `{
                 }
             }
         },
-        "/api/status/gateway/batch": {
+        "/api/admin/import": {
             "post": {
-                "description": "Nym network monitor sends packets through the system and checks if they make it. The network monitor then hits this method to report whether nodes were up at a given time.",
+                "description": "Stores the newline-delimited models.ExportRecord of an export, e.g. to restore a backup or to move to another database. The database mustn't hold any statuses yet. Records stored before a failure stay stored. Only available to connections from the local machine, forwarding headers are ignored.",
                 "consumes": [
                     "application/json"
                 ],
@@ -173,24 +187,16 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "status"
+                    "admin"
                 ],
-                "summary": "Lets the network monitor create a new uptime status for multiple gateways",
-                "operationId": "batchCreateGatewayStatus",
-                "parameters": [
-                    {
-                        "description": "object",
-                        "name": "object",
-                        "in": "body",
-                        "required": true,
+                "summary": "Imports an export into a fresh database",
+                "operationId": "importData",
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.BatchGatewayStatus"
+                            "$ref": "#/definitions/models.Import"
                         }
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": ""
                     },
                     "400": {
                         "description": "Bad Request",
@@ -204,14 +210,14 @@ var doc = `{
                             "$ref": "#/definitions/models.Error"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/models.Error"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "503": {
+                        "description": "Service Unavailable",
                         "schema": {
                             "$ref": "#/definitions/models.Error"
                         }
@@ -219,9 +225,9 @@ var doc = `{
                 }
             }
         },
-        "/api/status/gateway/{pubkey}/history": {
-            "get": {
-                "description": "Lists all gateway statuses for a given node pubkey",
+        "/api/admin/mixnode/{pubkey}/recompute": {
+            "post": {
+                "description": "Recomputes every uptime window of the mixnode report from all of its retained statuses and replaces the stored report, in case it drifted from the underlying data. Only available to connections from the local machine, forwarding headers are ignored.",
                 "consumes": [
                     "application/json"
                 ],
@@ -229,14 +235,14 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "status"
+                    "admin"
                 ],
-                "summary": "Lists mixnode activity",
-                "operationId": "listGatewayStatuses",
+                "summary": "Rebuilds the report of a mixnode from its statuses",
+                "operationId": "recomputeMixReport",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Gateway Pubkey",
+                        "description": "Mixnode Pubkey",
                         "name": "pubkey",
                         "in": "path",
                         "required": true
@@ -246,14 +252,11 @@ var doc = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.GatewayStatus"
-                            }
+                            "$ref": "#/definitions/models.MixStatusReport"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/models.Error"
                         }
@@ -273,9 +276,9 @@ var doc = `{
                 }
             }
         },
-        "/api/status/gateway/{pubkey}/report": {
-            "get": {
-                "description": "Provides summary uptime statistics for last 5 minutes, day, week, and month",
+        "/api/admin/mixnode/{pubkey}/statuses/{ipversion}/{timestamp}": {
+            "delete": {
+                "description": "Lets the network monitor retract a status it reported, e.g. because it realised it mis-measured. The status is identified by the ip version and the timestamp it was stored with. It stops counting towards uptime and the report of the node gets recomputed without it. Only available to connections from the local machine, forwarding headers are ignored.",
                 "consumes": [
                     "application/json"
                 ],
@@ -283,22 +286,39 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "status"
+                    "admin"
                 ],
-                "summary": "Retrieves a summary report of historical gateway status",
-                "operationId": "getGatewayStatusReport",
+                "summary": "Retracts a mixnode status",
+                "operationId": "retractMixStatus",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Gateway Pubkey",
+                        "description": "Mixnode Pubkey",
                         "name": "pubkey",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "IP version of the status",
+                        "name": "ipversion",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Timestamp of the status",
+                        "name": "timestamp",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": ""
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.OK"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
@@ -306,6 +326,12 @@ var doc = `{
                             "$ref": "#/definitions/models.Error"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -321,9 +347,9 @@ var doc = `{
                 }
             }
         },
-        "/api/status/mixnode": {
+        "/api/admin/recompute-all": {
             "post": {
-                "description": "Nym network monitor sends packets through the system and checks if they make it. The network monitor then hits this method to report whether the node was up at a given time.",
+                "description": "Recomputes the report of every mixnode and gateway with retained statuses from those statuses, e.g. after the way uptime gets calculated changed. Only available to connections from the local machine, forwarding headers are ignored.",
                 "consumes": [
                     "application/json"
                 ],
@@ -331,29 +357,15 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "status"
-                ],
-                "summary": "Lets the network monitor create a new uptime status for a mix",
-                "operationId": "addMixStatus",
-                "parameters": [
-                    {
-                        "description": "object",
-                        "name": "object",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.MixStatus"
-                        }
-                    }
+                    "admin"
                 ],
+                "summary": "Rebuilds the reports of all nodes",
+                "operationId": "recomputeAllReports",
                 "responses": {
-                    "201": {
-                        "description": ""
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.Recomputation"
                         }
                     },
                     "403": {
@@ -362,12 +374,6 @@ var doc = `{
                             "$ref": "#/definitions/models.Error"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/models.Error"
-                        }
-                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -377,9 +383,9 @@ var doc = `{
                 }
             }
         },
-        "/api/status/mixnode/batch": {
-            "post": {
-                "description": "Nym network monitor sends packets through the system and checks if they make it. The network monitor then hits this method to report whether nodes were up at a given time.",
+        "/api/healthcheck": {
+            "get": {
+                "description": "Always returns 200 while the HTTP server is running. It does not check any of the dependencies, use /api/healthcheck/ready for that.",
                 "consumes": [
                     "application/json"
                 ],
@@ -387,45 +393,43 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "status"
+                    "healthcheck"
                 ],
-                "summary": "Lets the network monitor create a new uptime status for multiple mixes",
-                "operationId": "batchCreateMixStatus",
-                "parameters": [
-                    {
-                        "description": "object",
-                        "name": "object",
-                        "in": "body",
-                        "required": true,
+                "summary": "Lets you know whether the server process is alive",
+                "operationId": "healthCheck",
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.BatchMixStatus"
+                            "$ref": "#/definitions/models.OK"
                         }
                     }
+                }
+            }
+        },
+        "/api/healthcheck/ready": {
+            "get": {
+                "description": "Pings the database and returns the number of nodes that reported in the last day. Returns 503 if the database can't be reached.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "healthcheck"
                 ],
+                "summary": "Lets you know whether the server is able to serve requests",
+                "operationId": "readinessCheck",
                 "responses": {
-                    "201": {
-                        "description": ""
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/models.Error"
-                        }
-                    },
-                    "403": {
-                        "description": "Forbidden",
-                        "schema": {
-                            "$ref": "#/definitions/models.Error"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Error"
+                            "$ref": "#/definitions/models.Readiness"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "503": {
+                        "description": "Service Unavailable",
                         "schema": {
                             "$ref": "#/definitions/models.Error"
                         }
@@ -433,9 +437,9 @@ var doc = `{
                 }
             }
         },
-        "/api/status/mixnode/{pubkey}/history": {
+        "/api/status/fullgatewayreport": {
             "get": {
-                "description": "Lists all mixnode statuses for a given node pubkey",
+                "description": "Provides summary uptime statistics for last 5 minutes, day, week, and month. An uptime of -1 means there weren't enough statuses during the window to tell, which includes windows without any status. Earlier versions reported 0 for those.",
                 "consumes": [
                     "application/json"
                 ],
@@ -445,27 +449,32 @@ var doc = `{
                 "tags": [
                     "status"
                 ],
-                "summary": "Lists mixnode activity",
-                "operationId": "listMixStatuses",
+                "summary": "Retrieves a summary report of historical gateway status",
+                "operationId": "batchGetGatewayStatusReport",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Mixnode Pubkey",
-                        "name": "pubkey",
-                        "in": "path",
-                        "required": true
+                        "description": "ETag of a previously retrieved report",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated json names of the only report fields to include, e.g. pubKey,lastHourIPV4",
+                        "name": "fields",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.MixStatus"
-                            }
+                            "$ref": "#/definitions/models.BatchGatewayStatusReport"
                         }
                     },
+                    "304": {
+                        "description": ""
+                    },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
@@ -483,13 +492,19 @@ var doc = `{
                         "schema": {
                             "$ref": "#/definitions/models.Error"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
                     }
                 }
             }
         },
-        "/api/status/mixnode/{pubkey}/report": {
+        "/api/status/fullmixreport": {
             "get": {
-                "description": "Provides summary uptime statistics for last 5 minutes, day, week, and month",
+                "description": "Provides summary uptime statistics for last 5 minutes, day, week, and month. An uptime of -1 means there weren't enough statuses during the window to tell, which includes windows without any status. Earlier versions reported 0 for those.",
                 "consumes": [
                     "application/json"
                 ],
@@ -500,19 +515,250 @@ var doc = `{
                     "status"
                 ],
                 "summary": "Retrieves a summary report of historical mix status",
-                "operationId": "getMixStatusReport",
+                "operationId": "batchGetMixStatusReport",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Mixnode Pubkey",
-                        "name": "pubkey",
-                        "in": "path",
-                        "required": true
+                        "description": "ETag of a previously retrieved report",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated json names of the only report fields to include, e.g. pubKey,lastHourIPV4",
+                        "name": "fields",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": ""
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.BatchMixStatusReport"
+                        }
+                    },
+                    "304": {
+                        "description": ""
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/status/gateway": {
+            "post": {
+                "description": "Nym network monitor sends packets through the system and checks if they make it. The network monitor then hits this method to report whether the node was up at a given time.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "status"
+                ],
+                "summary": "Lets the network monitor create a new uptime status for a gateway",
+                "operationId": "addGatewayStatus",
+                "parameters": [
+                    {
+                        "description": "object",
+                        "name": "object",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.GatewayStatus"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.OK"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ValidationError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/status/gateway/batch": {
+            "post": {
+                "description": "Nym network monitor sends packets through the system and checks if they make it. The network monitor then hits this method to report whether nodes were up at a given time.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "status"
+                ],
+                "summary": "Lets the network monitor create a new uptime status for multiple gateways",
+                "operationId": "batchCreateGatewayStatus",
+                "parameters": [
+                    {
+                        "description": "object",
+                        "name": "object",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.BatchGatewayStatus"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "gzip to send a compressed body",
+                        "name": "Content-Encoding",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unique key of the batch, retrying it with the same key doesn't store it twice",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.OK"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ValidationError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/status/gateway/{pubkey}/history": {
+            "get": {
+                "description": "Lists all gateway statuses for a given node pubkey",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "status"
+                ],
+                "summary": "Lists mixnode activity",
+                "operationId": "listGatewayStatuses",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Gateway Pubkey",
+                        "name": "pubkey",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Wrap the statuses in a models.Envelope",
+                        "name": "envelope",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.GatewayStatus"
+                            }
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
@@ -534,54 +780,1995 @@ var doc = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.BatchGatewayStatus": {
+        },
+        "/api/status/gateway/{pubkey}/lifetime": {
+            "get": {
+                "description": "Provides the timestamps of the first and the most recent retained statuses of the gateway, along with the number of statuses it reported in between",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "status"
+                ],
+                "summary": "Tells when a gateway was first and last seen",
+                "operationId": "getGatewayLifetime",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Gateway Pubkey",
+                        "name": "pubkey",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.NodeLifetime"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/status/gateway/{pubkey}/report": {
+            "get": {
+                "description": "Provides summary uptime statistics for last 5 minutes, day, week, and month. An uptime of -1 means there weren't enough statuses during the window to tell, which includes windows without any status. Earlier versions reported 0 for those.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "status"
+                ],
+                "summary": "Retrieves a summary report of historical gateway status",
+                "operationId": "getGatewayStatusReport",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Gateway Pubkey",
+                        "name": "pubkey",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a previously retrieved report",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.GatewayStatusReport"
+                        }
+                    },
+                    "304": {
+                        "description": ""
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/status/mixnode": {
+            "post": {
+                "description": "Nym network monitor sends packets through the system and checks if they make it. The network monitor then hits this method to report whether the node was up at a given time.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "status"
+                ],
+                "summary": "Lets the network monitor create a new uptime status for a mix",
+                "operationId": "addMixStatus",
+                "parameters": [
+                    {
+                        "description": "object",
+                        "name": "object",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.MixStatus"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.OK"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ValidationError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/status/mixnode/batch": {
+            "post": {
+                "description": "Nym network monitor sends packets through the system and checks if they make it. The network monitor then hits this method to report whether nodes were up at a given time.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "status"
+                ],
+                "summary": "Lets the network monitor create a new uptime status for multiple mixes",
+                "operationId": "batchCreateMixStatus",
+                "parameters": [
+                    {
+                        "description": "object",
+                        "name": "object",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.BatchMixStatus"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "gzip to send a compressed body",
+                        "name": "Content-Encoding",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unique key of the batch, retrying it with the same key doesn't store it twice",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Store the valid statuses even if some are invalid, and tell which ones were stored",
+                        "name": "partial",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "With partial, models.OK otherwise",
+                        "schema": {
+                            "$ref": "#/definitions/models.BatchResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ValidationError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/status/mixnode/batch/validate": {
+            "post": {
+                "description": "Binds and sanitizes the batch exactly like the batch creation endpoint does and returns the sanitized statuses, each flagged with whether it's valid. Nothing gets stored.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "status"
+                ],
+                "summary": "Lets monitor authors check what would be stored for a batch of mix statuses",
+                "operationId": "validateBatchMixStatus",
+                "parameters": [
+                    {
+                        "description": "object",
+                        "name": "object",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.BatchMixStatus"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "gzip to send a compressed body",
+                        "name": "Content-Encoding",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.BatchMixStatusValidation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ValidationError"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/status/mixnode/history/bulk": {
+            "post": {
+                "description": "Lists the most recent statuses of each of the given mixnodes, at most ` + "`" + `limit` + "`" + ` (1000 by default and at most) of them per node, keyed by the node pubkey. At most 100 nodes can be asked for at once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "status"
+                ],
+                "summary": "Lists the activity of multiple mixnodes",
+                "operationId": "listMixStatusesBulk",
+                "parameters": [
+                    {
+                        "description": "object",
+                        "name": "object",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.BulkHistoryRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/models.PersistedMixStatus"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ValidationError"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/status/mixnode/{pubkey}/history": {
+            "get": {
+                "description": "Lists all mixnode statuses for a given node pubkey. With ` + "`" + `up` + "`" + `, only lists the statuses of the ` + "`" + `ipVersion` + "`" + ` (4 by default) during the last ` + "`" + `hours` + "`" + ` hours (24 by default, capped at the status retention period) that were up or down, e.g. to look at the failed measurements of the node.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "status"
+                ],
+                "summary": "Lists mixnode activity",
+                "operationId": "listMixStatuses",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Mixnode Pubkey",
+                        "name": "pubkey",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only list the statuses in that state",
+                        "name": "up",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IP version, 4 by default, only used along with up",
+                        "name": "ipVersion",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Size of the time window in hours, only used along with up",
+                        "name": "hours",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Wrap the statuses in a models.Envelope",
+                        "name": "envelope",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.MixStatus"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/status/mixnode/{pubkey}/lifetime": {
+            "get": {
+                "description": "Provides the timestamps of the first and the most recent retained statuses of the mixnode, along with the number of statuses it reported in between",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "status"
+                ],
+                "summary": "Tells when a mixnode was first and last seen",
+                "operationId": "getMixLifetime",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Mixnode Pubkey",
+                        "name": "pubkey",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.NodeLifetime"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/status/mixnode/{pubkey}/ownership": {
+            "get": {
+                "description": "Lists every time the mixnode reported a status under another owner than the one of its report, oldest first. The report took the new owner each time.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "status"
+                ],
+                "summary": "Lists the ownership changes of a mixnode",
+                "operationId": "listOwnershipChanges",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Mixnode Pubkey",
+                        "name": "pubkey",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.OwnershipChange"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/status/mixnode/{pubkey}/report": {
+            "get": {
+                "description": "Provides summary uptime statistics for last 5 minutes, day, week, and month. An uptime of -1 means there weren't enough statuses during the window to tell, which includes windows without any status. Earlier versions reported 0 for those.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "status"
+                ],
+                "summary": "Retrieves a summary report of historical mix status",
+                "operationId": "getMixStatusReport",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Mixnode Pubkey",
+                        "name": "pubkey",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a previously retrieved report",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.MixStatusReport"
+                        }
+                    },
+                    "304": {
+                        "description": ""
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/status/mixnode/{pubkey}/summary": {
+            "get": {
+                "description": "Provides the summary report of a mixnode along with its uptime trends, telling whether the last hour uptime is higher or lower than the last day one, and the time of its most recent status",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "status"
+                ],
+                "summary": "Retrieves everything needed to show the current state of a mixnode",
+                "operationId": "getMixNodeSummary",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Mixnode Pubkey",
+                        "name": "pubkey",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.MixNodeSummary"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/status/mixnode/{pubkey}/uptime-at": {
+            "get": {
+                "description": "Calculates the uptime of the mixnode over the ` + "`" + `window` + "`" + ` minutes (60 by default) up to ` + "`" + `timestamp` + "`" + `, out of the retained statuses. The window is capped at the status retention period. An uptime of -1 means there weren't enough statuses during the window to tell, which includes windows without any status. Earlier versions reported 0 for those.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "status"
+                ],
+                "summary": "Retrieves the uptime of a mixnode at a past instant",
+                "operationId": "getMixUptimeAt",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Mixnode Pubkey",
+                        "name": "pubkey",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "End of the window as unix nanoseconds",
+                        "name": "timestamp",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Size of the window in minutes",
+                        "name": "window",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IP version, 4 by default",
+                        "name": "ipVersion",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.MixUptimeAt"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/status/mixnode/{pubkey}/uptime/raw": {
+            "get": {
+                "description": "Provides, for every configured uptime window and ip version, the number of statuses of the mixnode during the window and how many of them were up, so that clients can work out the uptime with whatever precision they need. The counts are there even for the windows with too few statuses for an uptime.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "status"
+                ],
+                "summary": "Retrieves the counts behind the uptimes of a mixnode",
+                "operationId": "getMixRawUptime",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Mixnode Pubkey",
+                        "name": "pubkey",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.MixRawUptime"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/status/mixnodes/aggregate": {
+            "get": {
+                "description": "Provides mean, median, min and max uptime of all non-stale mixnodes over the last ` + "`" + `hours` + "`" + ` hours (24 by default). The window is capped at the status retention period. It's under mixnodes rather than mixnode, as that path can't be routed next to mixnode/{pubkey}.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "status"
+                ],
+                "summary": "Retrieves aggregated uptime of all active mixnodes",
+                "operationId": "aggregateMixUptime",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Size of the time window in hours",
+                        "name": "hours",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.MixUptimeAggregate"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/status/mixnodes/alerts": {
+            "get": {
+                "description": "Lists the non-stale mixnodes whose uptime during the last 5 minutes is more than ` + "`" + `drop` + "`" + ` percentage points (40 by default) below their uptime during the last hour, once per affected ip version and biggest drops first. Windows without enough data are never flagged.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "status"
+                ],
+                "summary": "Lists the mixnodes whose uptime dropped sharply",
+                "operationId": "detectMixUptimeDrops",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Drop in percentage points, between 0 and 100",
+                        "name": "drop",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Wrap the drops in a models.Envelope",
+                        "name": "envelope",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.MixUptimeDrop"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/status/mixnodes/compare": {
+            "get": {
+                "description": "Provides the reports of mixnodes ` + "`" + `a` + "`" + ` and ` + "`" + `b` + "`" + ` side by side, along with the differences between their uptimes during every window, ` + "`" + `b` + "`" + ` minus ` + "`" + `a` + "`" + `. Windows either of them doesn't have enough statuses during are left out of the differences, and so are the ipv6 ones unless both of them ever reported ipv6 statuses.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "status"
+                ],
+                "summary": "Compares the reports of two mixnodes",
+                "operationId": "compareMixReports",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Pubkey of the first mixnode",
+                        "name": "a",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Pubkey of the second mixnode",
+                        "name": "b",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.MixComparison"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/status/mixnodes/epochs/{epoch}": {
+            "get": {
+                "description": "Provides every mixnode status the monitors attributed to the epoch, in the order they came in, along with the uptime of each node and ip version over them. Statuses that didn't tell their epoch aren't attributed to any. An uptime of -1 means there weren't enough statuses to tell.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "status"
+                ],
+                "summary": "Retrieves what was measured during an incentive epoch",
+                "operationId": "getMixEpochReport",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Epoch",
+                        "name": "epoch",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.MixEpochReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/status/mixnodes/stream": {
+            "get": {
+                "description": "Upgrades the connection to a websocket and pushes every newly created mix status to it as JSON. Statuses are dropped for clients that can't keep up.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "status"
+                ],
+                "summary": "Streams newly created mix statuses",
+                "operationId": "streamMixStatus",
+                "responses": {
+                    "101": {
+                        "description": "Switching Protocols",
+                        "schema": {
+                            "$ref": "#/definitions/models.PersistedMixStatus"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/status/mixnodes/top": {
+            "get": {
+                "description": "Provides the reports of the ` + "`" + `n` + "`" + ` mixnodes (20 by default, at most 1000) with the highest value of the uptime ` + "`" + `field` + "`" + ` (lastDayIPV4 by default). The field must be one of last5MinutesIPV4, lastHourIPV4, lastDayIPV4 or their IPV6 counterparts.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "status"
+                ],
+                "summary": "Retrieves the reports of the best mixnodes",
+                "operationId": "topMixReports",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Number of reports",
+                        "name": "n",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Uptime field to rank by",
+                        "name": "field",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.BatchMixStatusReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/status/network/history": {
+            "get": {
+                "description": "Lists the samples of the mean last hour uptime of the active mixnodes taken on each reports update during the last ` + "`" + `hours` + "`" + ` hours (24 by default), oldest first. Samples older than the configured horizon are gone.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "status"
+                ],
+                "summary": "Lists the uptime history of the whole network",
+                "operationId": "listNetworkUptimeHistory",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Size of the time window in hours",
+                        "name": "hours",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Wrap the samples in a models.Envelope",
+                        "name": "envelope",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.NetworkUptimeSample"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/status/owners": {
+            "get": {
+                "description": "Provides the sorted list of every distinct owner that submitted a mix or a gateway status still retained. Empty owners are left out.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "status"
+                ],
+                "summary": "Lists all known node owners",
+                "operationId": "listOwners",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Wrap the owners in a models.Envelope",
+                        "name": "envelope",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/status/schema": {
+            "get": {
+                "description": "Provides a JSON Schema of every mix and gateway status payload, generated from the same struct tags the handlers bind with. Meant for monitors written in other languages.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "status"
+                ],
+                "summary": "Describes the accepted status payloads",
+                "operationId": "getPayloadSchema",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.JSONSchema"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/status/stats": {
+            "get": {
+                "description": "Provides the number of stored mix and gateway statuses, the number of nodes active during the last day and the timestamp of the oldest status still retained. lastReportUpdate tells when the reports were last updated, if it falls behind by more than a few 10 minute intervals, the reports are going stale. ingestionLag summarises how long the mix statuses received during the last hour took to get from the monitor to the server, going by the measuredAt the monitor set on them",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "status"
+                ],
+                "summary": "Tells how many statuses are stored",
+                "operationId": "getStats",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.StatusStats"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Error"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/version": {
+            "get": {
+                "description": "Returns the version of the application along with the git commit and the time it was built from. The latter two are unknown unless they were set at build time.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "version"
+                ],
+                "summary": "Lets you know which build is deployed",
+                "operationId": "getVersion",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Version"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "models.Backfill": {
+            "type": "object",
+            "properties": {
+                "pubKeys": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.BatchGatewayStatus": {
+            "type": "object",
+            "required": [
+                "status"
+            ],
+            "properties": {
+                "status": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.GatewayStatus"
+                    }
+                }
+            }
+        },
+        "models.BatchGatewayStatusReport": {
+            "type": "object",
+            "required": [
+                "report"
+            ],
+            "properties": {
+                "report": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.GatewayStatusReport"
+                    }
+                }
+            }
+        },
+        "models.BatchMixStatus": {
+            "type": "object",
+            "required": [
+                "status"
+            ],
+            "properties": {
+                "epoch": {
+                    "description": "Epoch is the incentive epoch of the statuses of the batch that don't tell their own",
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.MixStatus"
+                    }
+                }
+            }
+        },
+        "models.BatchMixStatusReport": {
+            "type": "object",
+            "required": [
+                "report"
+            ],
+            "properties": {
+                "report": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.MixStatusReport"
+                    }
+                }
+            }
+        },
+        "models.BatchMixStatusValidation": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ValidatedMixStatus"
+                    }
+                }
+            }
+        },
+        "models.BatchResult": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FailedStatus"
+                    }
+                },
+                "stored": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "models.BulkHistoryRequest": {
+            "type": "object",
+            "required": [
+                "pubKeys"
+            ],
+            "properties": {
+                "limit": {
+                    "type": "integer"
+                },
+                "pubKeys": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.Error": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ExportRecord": {
+            "type": "object",
+            "properties": {
+                "gatewayReport": {
+                    "$ref": "#/definitions/models.GatewayStatusReport"
+                },
+                "gatewayStatus": {
+                    "$ref": "#/definitions/models.PersistedGatewayStatus"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "mixReport": {
+                    "$ref": "#/definitions/models.MixStatusReport"
+                },
+                "mixStatus": {
+                    "$ref": "#/definitions/models.PersistedMixStatus"
+                }
+            }
+        },
+        "models.FailedStatus": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FieldError"
+                    }
+                },
+                "index": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.GatewayStatus": {
+            "type": "object",
+            "required": [
+                "ipVersion",
+                "pubKey",
+                "up"
+            ],
+            "properties": {
+                "clientsHostUp": {
+                    "description": "ClientsHostUp tells whether clients could connect to the gateway, which can be down even if the gateway is up\nfor mixnet traffic. It's optional, as not all monitors check it.",
+                    "type": "boolean"
+                },
+                "ipVersion": {
+                    "type": "string"
+                },
+                "owner": {
+                    "description": "Owner is required unless owners are configured to be optional, same as for mixnodes",
+                    "type": "string"
+                },
+                "pubKey": {
+                    "type": "string"
+                },
+                "rttMillis": {
+                    "type": "integer"
+                },
+                "up": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.GatewayStatusReport": {
+            "type": "object",
+            "required": [
+                "last5MinutesIPV4",
+                "last5MinutesIPV6",
+                "lastDayIPV4",
+                "lastDayIPV6",
+                "lastHourIPV4",
+                "lastHourIPV6",
+                "mostRecentIPV4",
+                "mostRecentIPV6",
+                "owner",
+                "pubKey"
+            ],
+            "properties": {
+                "clientsLast5MinutesIPV4": {
+                    "description": "Clients* hold the uptime of the host clients connect to during the default windows. They're -1 if no\nmonitor checked it during the window.",
+                    "type": "integer"
+                },
+                "clientsLast5MinutesIPV6": {
+                    "type": "integer"
+                },
+                "clientsLastDayIPV4": {
+                    "type": "integer"
+                },
+                "clientsLastDayIPV6": {
+                    "type": "integer"
+                },
+                "clientsLastHourIPV4": {
+                    "type": "integer"
+                },
+                "clientsLastHourIPV6": {
+                    "type": "integer"
+                },
+                "last5MinutesIPV4": {
+                    "type": "integer"
+                },
+                "last5MinutesIPV6": {
+                    "type": "integer"
+                },
+                "last5MinutesRTTIPV4": {
+                    "type": "integer"
+                },
+                "last5MinutesRTTIPV6": {
+                    "type": "integer"
+                },
+                "lastDayIPV4": {
+                    "type": "integer"
+                },
+                "lastDayIPV6": {
+                    "type": "integer"
+                },
+                "lastDayRTTIPV4": {
+                    "type": "integer"
+                },
+                "lastDayRTTIPV6": {
+                    "type": "integer"
+                },
+                "lastHourIPV4": {
+                    "type": "integer"
+                },
+                "lastHourIPV6": {
+                    "type": "integer"
+                },
+                "lastHourRTTIPV4": {
+                    "type": "integer"
+                },
+                "lastHourRTTIPV6": {
+                    "type": "integer"
+                },
+                "mostRecentIPV4": {
+                    "type": "boolean"
+                },
+                "mostRecentIPV6": {
+                    "type": "boolean"
+                },
+                "owner": {
+                    "type": "string"
+                },
+                "pubKey": {
+                    "type": "string"
+                },
+                "uptimesIPV4": {
+                    "description": "Uptimes* hold the uptime during every configured window, same as for mixnodes",
+                    "$ref": "#/definitions/models.Uptimes"
+                },
+                "uptimesIPV6": {
+                    "$ref": "#/definitions/models.Uptimes"
+                }
+            }
+        },
+        "models.Import": {
+            "type": "object",
+            "properties": {
+                "gatewayReports": {
+                    "type": "integer"
+                },
+                "gatewayStatuses": {
+                    "type": "integer"
+                },
+                "mixReports": {
+                    "type": "integer"
+                },
+                "mixStatuses": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.IngestionLag": {
+            "type": "object",
+            "properties": {
+                "averageMillis": {
+                    "type": "integer"
+                },
+                "maxMillis": {
+                    "type": "integer"
+                },
+                "statuses": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.JSONSchema": {
+            "type": "object",
+            "properties": {
+                "$schema": {
+                    "type": "string"
+                },
+                "definitions": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.JSONSchema"
+                    }
+                },
+                "items": {
+                    "$ref": "#/definitions/models.JSONSchema"
+                },
+                "minimum": {
+                    "type": "integer"
+                },
+                "properties": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.JSONSchema"
+                    }
+                },
+                "required": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.MixComparison": {
+            "type": "object",
+            "properties": {
+                "a": {
+                    "$ref": "#/definitions/models.MixStatusReport"
+                },
+                "b": {
+                    "$ref": "#/definitions/models.MixStatusReport"
+                },
+                "deltasIPV4": {
+                    "$ref": "#/definitions/models.Uptimes"
+                },
+                "deltasIPV6": {
+                    "$ref": "#/definitions/models.Uptimes"
+                }
+            }
+        },
+        "models.MixEpochReport": {
+            "type": "object",
+            "properties": {
+                "epoch": {
+                    "type": "integer"
+                },
+                "statuses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PersistedMixStatus"
+                    }
+                },
+                "uptimes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.MixEpochUptime"
+                    }
+                }
+            }
+        },
+        "models.MixEpochUptime": {
+            "type": "object",
+            "properties": {
+                "ipVersion": {
+                    "type": "string"
+                },
+                "measurements": {
+                    "type": "integer"
+                },
+                "owner": {
+                    "type": "string"
+                },
+                "pubKey": {
+                    "type": "string"
+                },
+                "uptime": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.MixNodeSummary": {
+            "type": "object",
+            "properties": {
+                "mostRecentStatusTime": {
+                    "type": "integer"
+                },
+                "report": {
+                    "$ref": "#/definitions/models.MixStatusReport"
+                },
+                "trendIPV4": {
+                    "type": "string",
+                    "enum": [
+                        "improving",
+                        "steady",
+                        "declining"
+                    ]
+                },
+                "trendIPV6": {
+                    "type": "string",
+                    "enum": [
+                        "improving",
+                        "steady",
+                        "declining"
+                    ]
+                }
+            }
+        },
+        "models.MixRawUptime": {
+            "type": "object",
+            "properties": {
+                "ipv4": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.UptimeCount"
+                    }
+                },
+                "ipv6": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.UptimeCount"
+                    }
+                },
+                "pubKey": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.MixStatus": {
             "type": "object",
             "required": [
-                "status"
+                "ipVersion",
+                "pubKey",
+                "up"
             ],
             "properties": {
-                "status": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/models.GatewayStatus"
-                    }
+                "epoch": {
+                    "description": "Epoch is the incentive epoch the monitor took the measurement in. It's optional, statuses without it aren't\nattributed to any epoch.",
+                    "type": "integer"
+                },
+                "ipVersion": {
+                    "type": "string"
+                },
+                "measuredAt": {
+                    "description": "MeasuredAt is when the monitor took the measurement, as unix nanoseconds. It's optional, the time the status\nwas received at is used for statuses without it.",
+                    "type": "integer"
+                },
+                "owner": {
+                    "description": "Owner is required unless the API is configured to accept statuses of nodes nobody claimed yet, so it's\nchecked by the controller rather than by the binding",
+                    "type": "string"
+                },
+                "pubKey": {
+                    "type": "string"
+                },
+                "rttMillis": {
+                    "type": "integer"
+                },
+                "up": {
+                    "type": "boolean"
                 }
             }
         },
-        "models.BatchMixStatus": {
+        "models.MixStatusReport": {
             "type": "object",
             "required": [
-                "status"
+                "last5MinutesIPV4",
+                "last5MinutesIPV6",
+                "lastDayIPV4",
+                "lastDayIPV6",
+                "lastHourIPV4",
+                "lastHourIPV6",
+                "mostRecentIPV4",
+                "mostRecentIPV6",
+                "owner",
+                "pubKey"
             ],
             "properties": {
+                "hasIPV6Data": {
+                    "description": "HasIPV6Data tells whether the node ever reported an ipv6 status. Some monitors only probe ipv4, without it\nthe zero ipv6 uptimes of their nodes would be indistinguishable from a 0% ipv6 uptime",
+                    "type": "boolean"
+                },
+                "last5MinutesIPV4": {
+                    "type": "integer"
+                },
+                "last5MinutesIPV4Count": {
+                    "description": "*Count are the numbers of statuses the uptimes of the windows were calculated from, so that clients can tell\nhow confident they can be in them",
+                    "type": "integer"
+                },
+                "last5MinutesIPV6": {
+                    "type": "integer"
+                },
+                "last5MinutesIPV6Count": {
+                    "type": "integer"
+                },
+                "last5MinutesRTTIPV4": {
+                    "type": "integer"
+                },
+                "last5MinutesRTTIPV6": {
+                    "type": "integer"
+                },
+                "lastDayIPV4": {
+                    "type": "integer"
+                },
+                "lastDayIPV6": {
+                    "type": "integer"
+                },
+                "lastDayRTTIPV4": {
+                    "type": "integer"
+                },
+                "lastDayRTTIPV6": {
+                    "type": "integer"
+                },
+                "lastHourIPV4": {
+                    "type": "integer"
+                },
+                "lastHourIPV4Count": {
+                    "type": "integer"
+                },
+                "lastHourIPV6": {
+                    "type": "integer"
+                },
+                "lastHourIPV6Count": {
+                    "type": "integer"
+                },
+                "lastHourRTTIPV4": {
+                    "type": "integer"
+                },
+                "lastHourRTTIPV6": {
+                    "type": "integer"
+                },
+                "mostRecentIPV4": {
+                    "type": "boolean"
+                },
+                "mostRecentIPV4Timestamp": {
+                    "description": "MostRecent*Timestamp are the timestamps of the most recent statuses, 0 for reports saved before they were added",
+                    "type": "integer"
+                },
+                "mostRecentIPV6": {
+                    "type": "boolean"
+                },
+                "mostRecentIPV6Timestamp": {
+                    "type": "integer"
+                },
+                "owner": {
+                    "type": "string"
+                },
+                "pubKey": {
+                    "type": "string"
+                },
                 "status": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/models.MixStatus"
-                    }
+                    "description": "Status is the overall state of the node, derived from the fields above whenever the report is served. It's\nnot stored, as a node goes stale without any of them changing.",
+                    "type": "string",
+                    "enum": [
+                        "up",
+                        "degraded",
+                        "down",
+                        "stale",
+                        "no-data"
+                    ]
+                },
+                "uptimesIPV4": {
+                    "description": "Uptimes* hold the uptime during every configured window, the named fields above are filled in from the\ndefault windows",
+                    "$ref": "#/definitions/models.Uptimes"
+                },
+                "uptimesIPV6": {
+                    "$ref": "#/definitions/models.Uptimes"
                 }
             }
         },
-        "models.Error": {
+        "models.MixUptimeAggregate": {
             "type": "object",
             "properties": {
-                "error": {
+                "hours": {
+                    "type": "integer"
+                },
+                "ipv4": {
+                    "$ref": "#/definitions/models.UptimeStatistics"
+                },
+                "ipv6": {
+                    "$ref": "#/definitions/models.UptimeStatistics"
+                },
+                "nodes": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.MixUptimeAt": {
+            "type": "object",
+            "properties": {
+                "ipVersion": {
+                    "type": "string"
+                },
+                "pubKey": {
                     "type": "string"
+                },
+                "timestamp": {
+                    "type": "integer"
+                },
+                "uptime": {
+                    "type": "integer"
+                },
+                "windowMinutes": {
+                    "type": "integer"
                 }
             }
         },
-        "models.GatewayStatus": {
+        "models.MixUptimeDrop": {
+            "type": "object",
+            "properties": {
+                "drop": {
+                    "type": "integer"
+                },
+                "ipVersion": {
+                    "type": "string"
+                },
+                "last5Minutes": {
+                    "type": "integer"
+                },
+                "lastHour": {
+                    "type": "integer"
+                },
+                "owner": {
+                    "type": "string"
+                },
+                "pubKey": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.NetworkUptimeSample": {
+            "type": "object",
+            "properties": {
+                "activeCount": {
+                    "type": "integer"
+                },
+                "meanUptime": {
+                    "type": "number"
+                },
+                "timestamp": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.NodeLifetime": {
+            "type": "object",
+            "properties": {
+                "firstSeen": {
+                    "type": "integer"
+                },
+                "lastSeen": {
+                    "type": "integer"
+                },
+                "totalMeasurements": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.OK": {
+            "type": "object",
+            "properties": {
+                "ok": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.OwnershipChange": {
+            "type": "object",
+            "properties": {
+                "owner": {
+                    "type": "string"
+                },
+                "previousOwner": {
+                    "type": "string"
+                },
+                "pubKey": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.PersistedGatewayStatus": {
             "type": "object",
             "required": [
                 "ipVersion",
                 "owner",
                 "pubKey",
-                "up"
+                "timestamp"
             ],
             "properties": {
+                "clientsHostUp": {
+                    "description": "ClientsHostUp stays a pointer, as unlike Up it's nil whenever the monitor didn't check it",
+                    "type": "boolean"
+                },
                 "ipVersion": {
                     "type": "string"
                 },
@@ -591,33 +2778,199 @@ var doc = `{
                 "pubKey": {
                     "type": "string"
                 },
+                "rttMillis": {
+                    "type": "integer"
+                },
+                "timestamp": {
+                    "type": "integer"
+                },
                 "up": {
                     "type": "boolean"
                 }
             }
         },
-        "models.MixStatus": {
+        "models.PersistedMixStatus": {
             "type": "object",
             "required": [
                 "ipVersion",
                 "owner",
                 "pubKey",
-                "up"
+                "timestamp"
             ],
             "properties": {
+                "epoch": {
+                    "description": "Epoch is the incentive epoch the status was measured in, as told by the monitor, nil if it didn't tell",
+                    "type": "integer"
+                },
                 "ipVersion": {
                     "type": "string"
                 },
+                "measuredAt": {
+                    "description": "MeasuredAt is when the monitor took the measurement, Timestamp is when the status was received. The gap\nbetween the two is the ingestion lag.",
+                    "type": "integer"
+                },
                 "owner": {
                     "type": "string"
                 },
                 "pubKey": {
                     "type": "string"
                 },
+                "rttMillis": {
+                    "type": "integer"
+                },
+                "timestamp": {
+                    "type": "integer"
+                },
+                "up": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.Readiness": {
+            "type": "object",
+            "properties": {
+                "activeGateways": {
+                    "type": "integer"
+                },
+                "activeMixnodes": {
+                    "type": "integer"
+                },
+                "ok": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.Recomputation": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "gateways": {
+                    "type": "integer"
+                },
+                "mixnodes": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.StatusStats": {
+            "type": "object",
+            "properties": {
+                "activeGateways": {
+                    "type": "integer"
+                },
+                "activeMixnodes": {
+                    "type": "integer"
+                },
+                "gatewayStatuses": {
+                    "type": "integer"
+                },
+                "ingestionLag": {
+                    "description": "IngestionLag tells how long the recent mix statuses took to get from the monitor to the server",
+                    "$ref": "#/definitions/models.IngestionLag"
+                },
+                "lastReportUpdate": {
+                    "description": "LastReportUpdate is when the reports were last updated, 0 if they haven't been since startup. They get\nupdated every 10 minutes, so anything much older means the updater is stuck.",
+                    "type": "integer"
+                },
+                "mixStatuses": {
+                    "type": "integer"
+                },
+                "oldestStatusTimestamp": {
+                    "type": "integer"
+                },
+                "queryTimeouts": {
+                    "description": "QueryTimeouts counts the database queries that ran out of time since startup",
+                    "type": "integer"
+                },
+                "reportCacheHits": {
+                    "description": "ReportCacheHits counts the full mix reports served from memory since startup",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.UptimeCount": {
+            "type": "object",
+            "properties": {
+                "total": {
+                    "type": "integer"
+                },
                 "up": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.UptimeStatistics": {
+            "type": "object",
+            "properties": {
+                "max": {
+                    "type": "integer"
+                },
+                "mean": {
+                    "type": "number"
+                },
+                "median": {
+                    "type": "number"
+                },
+                "min": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Uptimes": {
+            "type": "object",
+            "additionalProperties": {
+                "type": "integer"
+            }
+        },
+        "models.ValidatedMixStatus": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/models.MixStatus"
+                },
+                "valid": {
                     "type": "boolean"
                 }
             }
+        },
+        "models.ValidationError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FieldError"
+                    }
+                }
+            }
+        },
+        "models.Version": {
+            "type": "object",
+            "properties": {
+                "buildTime": {
+                    "type": "string"
+                },
+                "gitCommit": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
         }
     }
 }`
@@ -0,0 +1,86 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nymtech/node-status-api/mixmining"
+	"github.com/nymtech/node-status-api/models"
+)
+
+// Config for this controller
+type Config struct {
+	Service mixmining.IService
+}
+
+// controller is the healthcheck controller
+type controller struct {
+	service mixmining.IService
+}
+
+// Controller ...
+type Controller interface {
+	HealthCheck(c *gin.Context)
+	ReadinessCheck(c *gin.Context)
+	RegisterRoutes(router *gin.Engine)
+}
+
+// New returns a new healthcheck.Controller
+func New(cfg Config) Controller {
+	return &controller{cfg.Service}
+}
+
+func (controller *controller) RegisterRoutes(router *gin.Engine) {
+	router.GET("/api/healthcheck", controller.HealthCheck)
+	router.GET("/api/healthcheck/ready", controller.ReadinessCheck)
+}
+
+// HealthCheck ...
+// @Summary Lets you know whether the server process is alive
+// @Description Always returns 200 while the HTTP server is running. It does not check any of the dependencies, use /api/healthcheck/ready for that.
+// @ID healthCheck
+// @Accept  json
+// @Produce  json
+// @Tags healthcheck
+// @Success 200 {object} models.OK
+// @Router /api/healthcheck [get]
+func (controller *controller) HealthCheck(c *gin.Context) {
+	c.JSON(http.StatusOK, models.OK{OK: true})
+}
+
+// ReadinessCheck ...
+// @Summary Lets you know whether the server is able to serve requests
+// @Description Pings the database and returns the number of nodes that reported in the last day. Returns 503 if the database can't be reached.
+// @ID readinessCheck
+// @Accept  json
+// @Produce  json
+// @Tags healthcheck
+// @Success 200 {object} models.Readiness
+// @Failure 503 {object} models.Error
+// @Router /api/healthcheck/ready [get]
+func (controller *controller) ReadinessCheck(c *gin.Context) {
+	ctx := c.Request.Context()
+	if err := controller.service.Ping(ctx); err != nil {
+		c.JSON(http.StatusServiceUnavailable, models.Error{Code: http.StatusServiceUnavailable, Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, models.Readiness{
+		OK:             true,
+		ActiveMixnodes: controller.service.MixCount(ctx),
+		ActiveGateways: controller.service.GatewayCount(ctx),
+	})
+}
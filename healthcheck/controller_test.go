@@ -0,0 +1,88 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nymtech/node-status-api/mixmining/mocks"
+	"github.com/nymtech/node-status-api/models"
+	. "github.com/onsi/ginkgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var _ = Describe("Controller", func() {
+	Describe("the liveness healthcheck", func() {
+		It("should always return ok", func() {
+			router, _ := SetupRouter()
+			resp := performRequest(router, "GET", "/api/healthcheck")
+			assert.Equal(GinkgoT(), 200, resp.Code)
+		})
+	})
+
+	Describe("the readiness healthcheck", func() {
+		Context("when the database is reachable", func() {
+			It("should return ok along with active node counts", func() {
+				router, mockService := SetupRouter()
+				mockService.On("Ping", mock.Anything).Return(nil)
+				mockService.On("MixCount", mock.Anything).Return(42)
+				mockService.On("GatewayCount", mock.Anything).Return(7)
+
+				resp := performRequest(router, "GET", "/api/healthcheck/ready")
+				var response models.Readiness
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.Equal(GinkgoT(), models.Readiness{OK: true, ActiveMixnodes: 42, ActiveGateways: 7}, response)
+			})
+		})
+
+		Context("when the database can't be reached", func() {
+			It("should return 503", func() {
+				router, mockService := SetupRouter()
+				mockService.On("Ping", mock.Anything).Return(errors.New("database is gone"))
+
+				resp := performRequest(router, "GET", "/api/healthcheck/ready")
+				var response models.Error
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 503, resp.Code)
+				assert.Equal(GinkgoT(), models.Error{Code: 503, Message: "database is gone"}, response)
+				mockService.AssertNotCalled(GinkgoT(), "MixCount", mock.Anything)
+			})
+		})
+	})
+})
+
+func SetupRouter() (*gin.Engine, *mocks.IService) {
+	mockService := new(mocks.IService)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	New(Config{Service: mockService}).RegisterRoutes(router)
+	return router, mockService
+}
+
+func performRequest(r http.Handler, method, path string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
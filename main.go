@@ -15,21 +15,111 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
 	"github.com/gin-contrib/cors"
+	"github.com/gin-contrib/gzip"
 	"github.com/gin-gonic/gin"
 	"github.com/microcosm-cc/bluemonday"
 	_ "github.com/nymtech/node-status-api/docs" // docs is generated by Swag CLI, you have to import it.
+	"github.com/nymtech/node-status-api/healthcheck"
+	"github.com/nymtech/node-status-api/middleware"
 	"github.com/nymtech/node-status-api/mixmining"
+	"github.com/nymtech/node-status-api/version"
+	"github.com/sirupsen/logrus"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
+// shutdownTimeout is how long in-flight requests get to finish once the server is asked to stop
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	directory := New()
-	address := ":8081"
-	fmt.Printf("Starting the process on %v\n", address)
-	directory.Run(address)
+	address := listenAddress()
+	certFile, keyFile := tlsFiles()
+
+	server := &http.Server{
+		Addr:    address,
+		Handler: middleware.WithTimeouts(directory, handlerTimeouts()),
+	}
+
+	go func() {
+		var err error
+		if certFile != "" {
+			fmt.Printf("Starting the process on %v (TLS)\n", address)
+			err = server.ListenAndServeTLS(certFile, keyFile)
+		} else {
+			fmt.Printf("Starting the process on %v\n", address)
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	fmt.Println("Shutting down the server")
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Fatalf("server forced to shut down: %v", err)
+	}
+}
+
+// handlerTimeouts reads how long the read and the write handlers get to respond from the READ_HANDLER_TIMEOUT and
+// WRITE_HANDLER_TIMEOUT env vars. Both are unbounded unless set. The status stream and the dataset exports and imports
+// always are, as they take as long as the client listens or the dataset takes to go through. So are the status
+// submissions, as their 503 tells the monitors nothing got stored and to send the statuses again, which a timed out
+// handler that goes on storing them would break.
+func handlerTimeouts() middleware.HandlerTimeouts {
+	return middleware.HandlerTimeouts{
+		Read:   duration("READ_HANDLER_TIMEOUT", 0),
+		Write:  duration("WRITE_HANDLER_TIMEOUT", 0),
+		Exempt: []string{"/api/status/mixnodes/stream", "/api/admin/"},
+		UnboundedWrites: []string{
+			"/api/status/mixnode", "/api/status/mixnode/batch", "/api/status/gateway", "/api/status/gateway/batch",
+		},
+	}
+}
+
+// tlsFiles reads the certificate and private key paths from the TLS_CERT_FILE and TLS_KEY_FILE env vars.
+// If neither is set the server falls back to plain HTTP, setting only one of them is an error.
+func tlsFiles() (string, string) {
+	certFile := os.Getenv("TLS_CERT_FILE")
+	keyFile := os.Getenv("TLS_KEY_FILE")
+	if certFile == "" && keyFile != "" {
+		log.Fatal("TLS_KEY_FILE is set but TLS_CERT_FILE is not, both are required to serve HTTPS")
+	}
+	if certFile != "" && keyFile == "" {
+		log.Fatal("TLS_CERT_FILE is set but TLS_KEY_FILE is not, both are required to serve HTTPS")
+	}
+	return certFile, keyFile
+}
+
+// listenAddress reads the address the server binds to from the LISTEN_ADDR env var, e.g. ":8081"
+// to listen on all interfaces or "127.0.0.1:8081" to only accept local connections.
+func listenAddress() string {
+	address, ok := os.LookupEnv("LISTEN_ADDR")
+	if !ok {
+		return ":8081"
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		log.Fatalf("invalid LISTEN_ADDR %q: %v", address, err)
+	}
+	return address
 }
 
 // New returns a new node status REST API server
@@ -40,10 +130,15 @@ func main() {
 // @license.name Apache 2.0
 // @license.url https://github.com/nymtech/node-status-api/license
 func New() *gin.Engine {
-	gin.SetMode(gin.ReleaseMode)
+	// gin already applies GIN_MODE on its own, release mode is only the default when it's not set
+	if _, ok := os.LookupEnv(gin.EnvGinMode); !ok {
+		gin.SetMode(gin.ReleaseMode)
+	}
 
-	// Set the router as the default one shipped with Gin
-	router := gin.Default()
+	// Set the router up with gin's panic recovery and our own structured request logging
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.Use(middleware.RequestLogger(logrus.StandardLogger()))
 
 	// Add cors middleware
 	router.Use(cors.Default())
@@ -53,31 +148,227 @@ func New() *gin.Engine {
 	// Serve Swagger frontend static files using gin-swagger middleware
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	// Sanitize controller input against XSS attacks using bluemonday.Policy
+	// Sanitize controller input against XSS attacks using bluemonday.Policy. Identifiers such as pubkeys are never
+	// markup, so all of the HTML gets stripped from them.
 	policy := bluemonday.UGCPolicy()
+	identifierPolicy := bluemonday.StrictPolicy()
 
 	// Measurements: wire up dependency injection
-	measurementsCfg := injectMeasurements(policy)
+	measurementsCfg := injectMeasurements(policy, identifierPolicy)
 
 	// Register HTTP controller routes
 	mixmining.New(measurementsCfg).RegisterRoutes(router)
+	healthcheck.New(healthcheck.Config{Service: measurementsCfg.Service}).RegisterRoutes(router)
+	version.New().RegisterRoutes(router)
 
 	return router
 }
 
-func injectMeasurements(policy *bluemonday.Policy) mixmining.Config {
-	sanitizer := mixmining.NewMixStatusSanitizer(policy)
-	batchMixSanitizer := mixmining.NewBatchMixSanitizer(policy)
-	batchGatewaySanitizer := mixmining.NewBatchGatewaySanitizer(policy)
+func injectMeasurements(policy *bluemonday.Policy, identifierPolicy *bluemonday.Policy) mixmining.Config {
+	// the same setting applies to the identifiers written and looked up, so that they agree
+	normalize := normalizeIdentifiers()
+	sanitizer := mixmining.NewMixStatusSanitizer(policy, identifierPolicy, normalize)
+	gatewaySanitizer := mixmining.NewGatewayStatusSanitizer(policy, identifierPolicy, normalize)
+	batchMixSanitizer := mixmining.NewBatchMixSanitizer(policy, identifierPolicy, normalize)
+	batchGatewaySanitizer := mixmining.NewBatchGatewaySanitizer(policy, identifierPolicy, normalize)
 	genericSanitizer := mixmining.NewGenericSanitizer(policy)
 	db := mixmining.NewDb(false)
-	mixminingService := *mixmining.NewService(db, false)
+	// the service and the controller tell the time the same way, so that they agree on how old the reports are
+	clock := mixmining.SystemClock{}
+	// reports are gone past the staleness window by default, same as from the full report
+	staleAfter := duration("STALE_AFTER", mixmining.DefaultStaleAfter)
+	mixminingService := *mixmining.NewService(db, mixmining.ServiceConfig{
+		StaleAfter:            staleAfter,
+		UptimeWindows:         mixmining.AlignUptimeWindows(uptimeWindows(), duration("UPTIME_WINDOW_ALIGNMENT", 0)),
+		MinMeasurements:       minMeasurements(),
+		NetworkHistoryHorizon: duration("NETWORK_HISTORY_HORIZON", mixmining.DefaultNetworkHistoryHorizon),
+		VacuumInterval:        duration("VACUUM_INTERVAL", 0),
+		ReportCacheTTL:        duration("REPORT_CACHE_TTL", mixmining.DefaultReportCacheTTL),
+		Clock:                 clock,
+	})
 
 	return mixmining.Config{
-		Service:           &mixminingService,
-		Sanitizer:         sanitizer,
-		GenericSanitizer:  genericSanitizer,
-		BatchMixSanitizer: batchMixSanitizer,
+		Service:               &mixminingService,
+		Sanitizer:             sanitizer,
+		GatewaySanitizer:      gatewaySanitizer,
+		GenericSanitizer:      genericSanitizer,
+		BatchMixSanitizer:     batchMixSanitizer,
 		BatchGatewaySanitizer: batchGatewaySanitizer,
+		CompressionLevel:      compressionLevel(),
+		MaxBatchSize:          maxBatchSize(),
+		WriteRateLimit:        rateLimit("WRITE_RATE_LIMIT", mixmining.DefaultWriteRateLimit),
+		ReadRateLimit:         rateLimit("READ_RATE_LIMIT", mixmining.DefaultReadRateLimit),
+		MaxServedReportAge:    duration("MAX_SERVED_REPORT_AGE", staleAfter),
+		IdempotencyKeyTTL:     duration("IDEMPOTENCY_KEY_TTL", mixmining.DefaultIdempotencyKeyTTL),
+		OwnerOptional:         ownerOptional(),
+		QueryTimeout:          duration("QUERY_TIMEOUT", mixmining.DefaultQueryTimeout),
+		MaxConcurrentRequests: maxConcurrentRequests(),
+		StatusStaleAfter:      duration("STATUS_STALE_AFTER", mixmining.DefaultStatusStaleAfter),
+		DegradedUptime:        degradedUptime(),
+		NormalizeIdentifiers:  normalize,
+		UnknownIPVersions:     unknownIPVersions(),
+		RejectOwnerChanges:    rejectOwnerChanges(),
+		Clock:                 clock,
+	}
+}
+
+// rateLimit reads the number of requests per second allowed from a single client from the given env var.
+func rateLimit(envVar string, fallback float64) float64 {
+	limit, ok := os.LookupEnv(envVar)
+	if !ok {
+		return fallback
+	}
+	parsed, err := strconv.ParseFloat(limit, 64)
+	if err != nil || parsed <= 0 {
+		log.Fatalf("invalid %s %q, expected a positive number", envVar, limit)
+	}
+	return parsed
+}
+
+// duration reads a positive duration, such as 24h, from the given env var.
+func duration(envVar string, fallback time.Duration) time.Duration {
+	value, ok := os.LookupEnv(envVar)
+	if !ok {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		log.Fatalf("invalid %s %q, expected a positive duration such as 24h", envVar, value)
+	}
+	return parsed
+}
+
+// uptimeWindows reads the windows uptime is calculated over from the UPTIME_WINDOWS env var.
+func uptimeWindows() []mixmining.UptimeWindow {
+	value, ok := os.LookupEnv("UPTIME_WINDOWS")
+	if !ok {
+		return mixmining.DefaultUptimeWindows
+	}
+	windows, err := mixmining.ParseUptimeWindows(value)
+	if err != nil {
+		log.Fatalf("invalid UPTIME_WINDOWS %q: %v", value, err)
+	}
+	return windows
+}
+
+// minMeasurements reads the number of statuses needed for an uptime to be calculated from the MIN_MEASUREMENTS env var.
+func minMeasurements() int {
+	value, ok := os.LookupEnv("MIN_MEASUREMENTS")
+	if !ok {
+		return 0
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		log.Fatalf("invalid MIN_MEASUREMENTS %q, expected a non-negative integer", value)
+	}
+	return parsed
+}
+
+// degradedUptime reads the last hour uptime under which a mixnode that's up is degraded from the DEGRADED_UPTIME env var.
+func degradedUptime() int {
+	value, ok := os.LookupEnv("DEGRADED_UPTIME")
+	if !ok {
+		return mixmining.DefaultDegradedUptime
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed <= 0 || parsed > 100 {
+		log.Fatalf("invalid DEGRADED_UPTIME %q, expected an integer between 1 and 100", value)
+	}
+	return parsed
+}
+
+// ownerOptional reads whether statuses may leave the owner empty from the OWNER_OPTIONAL env var.
+func ownerOptional() bool {
+	value, ok := os.LookupEnv("OWNER_OPTIONAL")
+	if !ok {
+		return false
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("invalid OWNER_OPTIONAL %q, expected true or false", value)
+	}
+	return parsed
+}
+
+// rejectOwnerChanges reads whether mix statuses under another owner than the one of their node are rejected from the
+// REJECT_OWNER_CHANGES env var.
+func rejectOwnerChanges() bool {
+	value, ok := os.LookupEnv("REJECT_OWNER_CHANGES")
+	if !ok {
+		return false
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("invalid REJECT_OWNER_CHANGES %q, expected true or false", value)
+	}
+	return parsed
+}
+
+// normalizeIdentifiers reads whether to trim the whitespace around pubkeys and owners from the NORMALIZE_IDENTIFIERS
+// env var.
+func normalizeIdentifiers() bool {
+	value, ok := os.LookupEnv("NORMALIZE_IDENTIFIERS")
+	if !ok {
+		return false
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("invalid NORMALIZE_IDENTIFIERS %q, expected true or false", value)
+	}
+	return parsed
+}
+
+// unknownIPVersions reads how statuses with an ip version other than 4 or 6 are handled from the UNKNOWN_IP_VERSIONS
+// env var.
+func unknownIPVersions() string {
+	value, ok := os.LookupEnv("UNKNOWN_IP_VERSIONS")
+	if !ok {
+		return mixmining.UnknownIPVersionsStore
+	}
+	switch value {
+	case mixmining.UnknownIPVersionsStore, mixmining.UnknownIPVersionsLog, mixmining.UnknownIPVersionsReject:
+		return value
+	}
+	log.Fatalf("invalid UNKNOWN_IP_VERSIONS %q, expected store, log or reject", value)
+	return ""
+}
+
+// maxBatchSize reads the maximum number of statuses accepted in a single batch from the MAX_BATCH_SIZE env var.
+func maxBatchSize() int {
+	size, ok := os.LookupEnv("MAX_BATCH_SIZE")
+	if !ok {
+		return mixmining.DefaultMaxBatchSize
+	}
+	parsed, err := strconv.Atoi(size)
+	if err != nil || parsed <= 0 {
+		log.Fatalf("invalid MAX_BATCH_SIZE %q, expected a positive integer", size)
+	}
+	return parsed
+}
+
+// maxConcurrentRequests reads the number of requests handled at the same time from the MAX_CONCURRENT_REQUESTS env var.
+func maxConcurrentRequests() int {
+	value, ok := os.LookupEnv("MAX_CONCURRENT_REQUESTS")
+	if !ok {
+		return mixmining.DefaultMaxConcurrentRequests
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed <= 0 {
+		log.Fatalf("invalid MAX_CONCURRENT_REQUESTS %q, expected a positive integer", value)
+	}
+	return parsed
+}
+
+// compressionLevel reads the gzip level used for report responses from the GZIP_COMPRESSION_LEVEL env var.
+// 0 turns the compression off, nil leaves the default level.
+func compressionLevel() *int {
+	level, ok := os.LookupEnv("GZIP_COMPRESSION_LEVEL")
+	if !ok {
+		return nil
+	}
+	parsed, err := strconv.Atoi(level)
+	if err != nil || parsed < gzip.DefaultCompression || parsed > gzip.BestCompression {
+		log.Fatalf("invalid GZIP_COMPRESSION_LEVEL %q, expected a value between %d and %d", level, gzip.DefaultCompression, gzip.BestCompression)
 	}
+	return &parsed
 }
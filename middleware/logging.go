@@ -0,0 +1,52 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// RequestIDHeader is the response header carrying the ID assigned to each request
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDKey is the gin context key under which the request ID is stored for the handlers
+const RequestIDKey = "requestId"
+
+// RequestLogger tags every request with a freshly generated ID, echoes it back in the X-Request-ID header
+// and, once the request got handled, logs it as a single structured line alongside the handler latency.
+func RequestLogger(logger logrus.FieldLogger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		requestID := uuid.NewV4().String()
+		c.Set(RequestIDKey, requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		c.Next()
+
+		logger.WithFields(logrus.Fields{
+			"requestId": requestID,
+			"method":    c.Request.Method,
+			"path":      c.Request.URL.Path,
+			"route":     c.FullPath(),
+			"status":    c.Writer.Status(),
+			"clientIp":  c.ClientIP(),
+			"latency":   time.Since(start).String(),
+		}).Info("handled request")
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ = Describe("RequestLogger", func() {
+	It("should log the handled request and echo its ID back to the client", func() {
+		logger, hook := test.NewNullLogger()
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.Use(RequestLogger(logger))
+		router.GET("/api/status/mixnode/:pubkey/report", func(c *gin.Context) {
+			c.JSON(http.StatusTeapot, gin.H{"requestId": c.GetString(RequestIDKey)})
+		})
+
+		req, _ := http.NewRequest("GET", "/api/status/mixnode/key1/report", nil)
+		req.RemoteAddr = "1.1.1.1:12345"
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		requestID := resp.Header().Get(RequestIDHeader)
+		assert.NotEmpty(GinkgoT(), requestID)
+		assert.Contains(GinkgoT(), resp.Body.String(), requestID)
+
+		assert.Len(GinkgoT(), hook.Entries, 1)
+		fields := hook.LastEntry().Data
+		assert.Equal(GinkgoT(), requestID, fields["requestId"])
+		assert.Equal(GinkgoT(), "GET", fields["method"])
+		assert.Equal(GinkgoT(), "/api/status/mixnode/key1/report", fields["path"])
+		assert.Equal(GinkgoT(), "/api/status/mixnode/:pubkey/report", fields["route"])
+		assert.Equal(GinkgoT(), http.StatusTeapot, fields["status"])
+		assert.Equal(GinkgoT(), "1.1.1.1", fields["clientIp"])
+		assert.NotEmpty(GinkgoT(), fields["latency"])
+	})
+
+	It("should assign a different ID to every request", func() {
+		logger, _ := test.NewNullLogger()
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.Use(RequestLogger(logger))
+		router.GET("/", func(c *gin.Context) {})
+
+		first := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		router.ServeHTTP(first, req)
+		second := httptest.NewRecorder()
+		router.ServeHTTP(second, req)
+
+		assert.NotEqual(GinkgoT(), first.Header().Get(RequestIDHeader), second.Header().Get(RequestIDHeader))
+	})
+})
@@ -0,0 +1,72 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nymtech/node-status-api/models"
+)
+
+// HandlerTimeouts bound how long the handlers get to respond. Reads are the GET and HEAD requests, writes all the
+// other ones, so that a stuck write doesn't hold a connection for as long as a big report legitimately takes. A zero
+// timeout leaves its requests unbounded. So are the paths starting with one of the Exempt prefixes, as the responses
+// of bounded requests are buffered and their connections can't be taken over, which streams need. The writes to the
+// UnboundedWrites paths aren't bounded either. A timed out handler keeps running, so a write answered with 503 may
+// still get stored, and clients told to resend their statuses on a 503 would store them twice.
+type HandlerTimeouts struct {
+	Read            time.Duration
+	Write           time.Duration
+	Exempt          []string
+	UnboundedWrites []string
+}
+
+// WithTimeouts wraps the handler so that requests running out of time get 503 with the same error body as the
+// handlers reply with. Whatever the handler writes past that point is discarded.
+func WithTimeouts(handler http.Handler, timeouts HandlerTimeouts) http.Handler {
+	message, _ := json.Marshal(models.Error{Code: http.StatusServiceUnavailable, Message: "request timed out"})
+	read := bounded(handler, timeouts.Read, string(message))
+	write := bounded(handler, timeouts.Write, string(message))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, prefix := range timeouts.Exempt {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				handler.ServeHTTP(w, r)
+				return
+			}
+		}
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			read.ServeHTTP(w, r)
+			return
+		}
+		for _, path := range timeouts.UnboundedWrites {
+			if r.URL.Path == path {
+				handler.ServeHTTP(w, r)
+				return
+			}
+		}
+		write.ServeHTTP(w, r)
+	})
+}
+
+func bounded(handler http.Handler, timeout time.Duration, message string) http.Handler {
+	if timeout <= 0 {
+		return handler
+	}
+	return http.TimeoutHandler(handler, timeout, message)
+}
@@ -0,0 +1,85 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ = Describe("WithTimeouts", func() {
+	slowRouter := func(delay time.Duration) *gin.Engine {
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		slow := func(c *gin.Context) {
+			time.Sleep(delay)
+			c.JSON(http.StatusOK, gin.H{"ok": true})
+		}
+		router.GET("/report", slow)
+		router.GET("/status", slow)
+		router.POST("/status", slow)
+		router.POST("/stream", slow)
+		return router
+	}
+	serve := func(handler http.Handler, method string, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		return resp
+	}
+	timeouts := HandlerTimeouts{Read: time.Second, Write: 20 * time.Millisecond, Exempt: []string{"/stream"}}
+
+	It("should cut a slow write off at the write timeout", func() {
+		handler := WithTimeouts(slowRouter(100*time.Millisecond), timeouts)
+
+		start := time.Now()
+		resp := serve(handler, "POST", "/status")
+
+		assert.Equal(GinkgoT(), http.StatusServiceUnavailable, resp.Code)
+		assert.JSONEq(GinkgoT(), `{"code":503,"error":"request timed out"}`, resp.Body.String())
+		assert.Less(GinkgoT(), int64(time.Since(start)), int64(100*time.Millisecond))
+	})
+
+	It("should let a slow read take up to the read timeout", func() {
+		handler := WithTimeouts(slowRouter(100*time.Millisecond), timeouts)
+
+		assert.Equal(GinkgoT(), http.StatusOK, serve(handler, "GET", "/report").Code)
+	})
+
+	It("should leave the exempt paths unbounded", func() {
+		handler := WithTimeouts(slowRouter(100*time.Millisecond), timeouts)
+
+		assert.Equal(GinkgoT(), http.StatusOK, serve(handler, "POST", "/stream").Code)
+	})
+
+	It("should leave the unbounded writes unbounded, but not the reads of the same path", func() {
+		handler := WithTimeouts(slowRouter(100*time.Millisecond), HandlerTimeouts{Read: 20 * time.Millisecond, Write: 20 * time.Millisecond, UnboundedWrites: []string{"/status"}})
+
+		assert.Equal(GinkgoT(), http.StatusOK, serve(handler, "POST", "/status").Code)
+		assert.Equal(GinkgoT(), http.StatusServiceUnavailable, serve(handler, "GET", "/status").Code)
+	})
+
+	It("should leave the requests unbounded without a timeout", func() {
+		handler := WithTimeouts(slowRouter(50*time.Millisecond), HandlerTimeouts{})
+
+		assert.Equal(GinkgoT(), http.StatusOK, serve(handler, "POST", "/status").Code)
+		assert.Equal(GinkgoT(), http.StatusOK, serve(handler, "GET", "/report").Code)
+	})
+})
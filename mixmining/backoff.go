@@ -0,0 +1,50 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mixmining
+
+import (
+	"math/rand"
+	"time"
+)
+
+// maxJitterFraction is the biggest fraction of the interval randomly added to each delay,
+// so that multiple instances don't all hit the database at the same instant
+const maxJitterFraction = 0.1
+
+// maxBackoffShift caps the exponential backoff at 2^maxBackoffShift times the regular interval
+const maxBackoffShift = 3
+
+// backoff computes delays between the runs of a periodic background job.
+type backoff struct {
+	interval time.Duration
+	failures int
+}
+
+func newBackoff(interval time.Duration) backoff {
+	return backoff{interval: interval}
+}
+
+// next returns the delay before the next run given the result of the last one. After a successful run it's the
+// regular interval, after consecutive failures it doubles each time up to the cap. Jitter is added in both cases.
+func (b *backoff) next(err error) time.Duration {
+	if err == nil {
+		b.failures = 0
+	} else if b.failures < maxBackoffShift {
+		b.failures++
+	}
+
+	delay := b.interval << b.failures
+	return delay + time.Duration(rand.Float64()*maxJitterFraction*float64(delay))
+}
@@ -0,0 +1,75 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mixmining
+
+import (
+	"sync"
+
+	"github.com/nymtech/node-status-api/models"
+)
+
+// DefaultSubscriberBufferSize is the number of statuses buffered for each stream subscriber
+// before further statuses get dropped for it.
+const DefaultSubscriberBufferSize = 256
+
+// Broker fans out newly created mix statuses to all of its subscribers.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[chan models.PersistedMixStatus]struct{}
+	bufferSize  int
+}
+
+// NewBroker constructor
+func NewBroker(bufferSize int) *Broker {
+	return &Broker{
+		subscribers: make(map[chan models.PersistedMixStatus]struct{}),
+		bufferSize:  bufferSize,
+	}
+}
+
+// Subscribe registers a new subscriber. The returned function unsubscribes it and closes the channel.
+func (broker *Broker) Subscribe() (<-chan models.PersistedMixStatus, func()) {
+	ch := make(chan models.PersistedMixStatus, broker.bufferSize)
+
+	broker.mu.Lock()
+	broker.subscribers[ch] = struct{}{}
+	broker.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			broker.mu.Lock()
+			delete(broker.subscribers, ch)
+			broker.mu.Unlock()
+			close(ch)
+		})
+	}
+
+	return ch, unsubscribe
+}
+
+// Publish sends the status to all subscribers. It never blocks: if a subscriber's buffer is full,
+// the status is dropped for that subscriber so that a slow consumer can't hold up status ingestion.
+func (broker *Broker) Publish(status models.PersistedMixStatus) {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+
+	for ch := range broker.subscribers {
+		select {
+		case ch <- status:
+		default:
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mixmining
+
+import (
+	"github.com/nymtech/node-status-api/mixmining/fixtures"
+	. "github.com/onsi/ginkgo"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ = Describe("Broker", func() {
+	Describe("publishing a status", func() {
+		It("should deliver it to every subscriber", func() {
+			broker := NewBroker(1)
+			first, unsubscribeFirst := broker.Subscribe()
+			defer unsubscribeFirst()
+			second, unsubscribeSecond := broker.Subscribe()
+			defer unsubscribeSecond()
+
+			broker.Publish(fixtures.GoodPersistedMixStatus())
+
+			assert.Equal(GinkgoT(), fixtures.GoodPersistedMixStatus(), <-first)
+			assert.Equal(GinkgoT(), fixtures.GoodPersistedMixStatus(), <-second)
+		})
+
+		It("should not block on a subscriber with a full buffer", func() {
+			broker := NewBroker(1)
+			statuses, unsubscribe := broker.Subscribe()
+			defer unsubscribe()
+
+			broker.Publish(fixtures.GoodPersistedMixStatus())
+			broker.Publish(fixtures.GoodPersistedMixStatus())
+
+			assert.Len(GinkgoT(), statuses, 1)
+		})
+
+		It("should not deliver it to unsubscribed subscribers", func() {
+			broker := NewBroker(1)
+			statuses, unsubscribe := broker.Subscribe()
+			unsubscribe()
+			unsubscribe()
+
+			broker.Publish(fixtures.GoodPersistedMixStatus())
+
+			_, open := <-statuses
+			assert.False(GinkgoT(), open)
+		})
+	})
+})
@@ -0,0 +1,41 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mixmining
+
+import (
+	"time"
+
+	"github.com/BorisBorshevsky/timemock"
+)
+
+// Clock tells the service what time it is, and lets its periodic jobs wait for the next run
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is the real clock, the one services use unless given another one. It reads the time through timemock,
+// so that the tests freezing that one keep working.
+type SystemClock struct{}
+
+// Now returns the current time
+func (SystemClock) Now() time.Time {
+	return timemock.Now()
+}
+
+// After waits for the duration to elapse, then sends the current time on the returned channel
+func (SystemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
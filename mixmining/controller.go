@@ -15,30 +15,125 @@
 package mixmining
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/didip/tollbooth"
-	"github.com/didip/tollbooth_gin"
+	"github.com/gin-contrib/gzip"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/nymtech/node-status-api/models"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/websocket"
 )
 
 // Config for this controller
 type Config struct {
-	BatchMixSanitizer     BatchMixSanitizer     // batch mix reports
-	BatchGatewaySanitizer BatchGatewaySanitizer // batch mix reports
-	GenericSanitizer      GenericSanitizer      // originally introduced for what was in mix registration
-	Sanitizer             MixStatusSanitizer    // mix reports
+	BatchMixSanitizer     BatchMixSanitizer      // batch mix reports
+	BatchGatewaySanitizer BatchGatewaySanitizer  // batch mix reports
+	GenericSanitizer      GenericSanitizer       // originally introduced for what was in mix registration
+	Sanitizer             MixStatusSanitizer     // mix reports
+	GatewaySanitizer      GatewayStatusSanitizer // gateway reports
 	Service               IService
+	CompressionLevel      *int          // gzip level used for report responses, nil means gzip.DefaultCompression
+	MaxBatchSize          int           // maximum number of statuses in a single batch, 0 means DefaultMaxBatchSize
+	MaxBodyBytes          int64         // maximum size of a request body, 0 means DefaultMaxBodyBytes
+	WriteRateLimit        float64       // status submissions per second allowed from a single client, 0 means DefaultWriteRateLimit
+	ReadRateLimit         float64       // report and history requests per second allowed from a single client, 0 means DefaultReadRateLimit
+	MaxServedReportAge    time.Duration // reports whose most recent status is older are gone, 0 means DefaultMaxServedReportAge
+	IdempotencyKeyTTL     time.Duration // how long batch idempotency keys are remembered, 0 means DefaultIdempotencyKeyTTL
+	QueryTimeout          time.Duration // how long the queries behind a report request may take, 0 means DefaultQueryTimeout
+	MaxConcurrentRequests int           // requests handled at the same time before further ones get 503, 0 means DefaultMaxConcurrentRequests
+	StatusStaleAfter      time.Duration // mixnodes without a status for longer are stale, 0 means DefaultStatusStaleAfter
+	DegradedUptime        int           // last hour uptime under which a mixnode that's up is degraded, 0 means DefaultDegradedUptime
+	// OwnerOptional lets statuses leave the owner empty, e.g. to probe nodes nobody claimed yet. The owner of such
+	// statuses and of the reports built from them stays blank.
+	OwnerOptional bool
+	// NormalizeIdentifiers passes the pubkeys looked up through NormalizeIdentifier. It must match the setting of the
+	// sanitizers, so that the lookups find the statuses written.
+	NormalizeIdentifiers bool
+	// RejectOwnerChanges rejects the mix statuses under another owner than the one of the report of their node, so that
+	// nodes can't change hands through the monitor. Otherwise the report takes the new owner and the change is recorded.
+	RejectOwnerChanges bool
+	// UnknownIPVersions is how statuses with an ip version other than 4 or 6 are handled, one of the UnknownIPVersions*
+	// modes. "" means UnknownIPVersionsStore.
+	UnknownIPVersions string
+	// Clock tells the time the reports are judged against and the idempotency keys expire by. It should be the same
+	// as the one of the service, nil means SystemClock.
+	Clock Clock
 }
 
+// How statuses with an ip version other than 4 or 6 are handled. They never make it into any report, so storing them
+// only takes up space.
+const (
+	// UnknownIPVersionsStore stores them like any other status
+	UnknownIPVersionsStore = "store"
+	// UnknownIPVersionsLog stores them, logging a warning for each of them
+	UnknownIPVersionsLog = "log"
+	// UnknownIPVersionsReject rejects them as invalid
+	UnknownIPVersionsReject = "reject"
+)
+
+// DefaultWriteRateLimit is generous, as statuses are only ever submitted by trusted network monitors
+const DefaultWriteRateLimit = 10
+
+// DefaultReadRateLimit applies to the public endpoints
+const DefaultReadRateLimit = 1
+
+// DefaultMaxBatchSize is the maximum number of statuses accepted in a single batch unless configured otherwise
+const DefaultMaxBatchSize = 50000
+
+// DefaultMaxBodyBytes is the maximum size of a request body unless configured otherwise
+const DefaultMaxBodyBytes = 16 << 20
+
+// DefaultQueryTimeout bounds the report requests, so that a slow database makes them fail rather than hang
+const DefaultQueryTimeout = 10 * time.Second
+
+// DefaultMaxConcurrentRequests is the number of requests handled at the same time unless configured otherwise
+const DefaultMaxConcurrentRequests = 64
+
+// loadSheddingRetryAfter is how many seconds a client turned away for the load is told to wait
+const loadSheddingRetryAfter = "1"
+
+// DefaultMaxServedReportAge matches DefaultStaleAfter, so that a node left out of the full report as stale is gone from
+// its own report too. Its report is only purged once the node has no status left, until then it's answered with 410.
+const DefaultMaxServedReportAge = DefaultStaleAfter
+
 // controller is the status controller
 type controller struct {
 	service               IService
 	sanitizer             MixStatusSanitizer
+	gatewaySanitizer      GatewayStatusSanitizer
 	genericSanitizer      GenericSanitizer
 	batchMixSanitizer     BatchMixSanitizer
 	batchGatewaySanitizer BatchGatewaySanitizer
+	compressionLevel      int
+	maxBatchSize          int
+	maxBodyBytes          int64
+	writeRateLimit        float64
+	readRateLimit         float64
+	maxServedReportAge    time.Duration
+	idempotencyKeys       *idempotencyKeys
+	ownerOptional         bool
+	queryTimeout          time.Duration
+	statusStaleAfter      time.Duration
+	degradedUptime        int
+	// inFlight holds a token for each request being handled
+	inFlight             chan struct{}
+	normalizeIdentifiers bool
+	unknownIPVersions    string
+	rejectOwnerChanges   bool
+	clock                Clock
 }
 
 // Controller ...
@@ -49,44 +144,232 @@ type Controller interface {
 
 // New returns a new mixmining.Controller
 func New(cfg Config) Controller {
-	return &controller{cfg.Service, cfg.Sanitizer, cfg.GenericSanitizer, cfg.BatchMixSanitizer, cfg.BatchGatewaySanitizer}
+	// 0 is gzip.NoCompression, so only a missing level picks the default
+	compressionLevel := gzip.DefaultCompression
+	if cfg.CompressionLevel != nil {
+		compressionLevel = *cfg.CompressionLevel
+	}
+	maxBatchSize := cfg.MaxBatchSize
+	if maxBatchSize == 0 {
+		maxBatchSize = DefaultMaxBatchSize
+	}
+	maxBodyBytes := cfg.MaxBodyBytes
+	if maxBodyBytes == 0 {
+		maxBodyBytes = DefaultMaxBodyBytes
+	}
+	writeRateLimit := cfg.WriteRateLimit
+	if writeRateLimit == 0 {
+		writeRateLimit = DefaultWriteRateLimit
+	}
+	readRateLimit := cfg.ReadRateLimit
+	if readRateLimit == 0 {
+		readRateLimit = DefaultReadRateLimit
+	}
+	maxServedReportAge := cfg.MaxServedReportAge
+	if maxServedReportAge == 0 {
+		maxServedReportAge = DefaultMaxServedReportAge
+	}
+	idempotencyKeyTTL := cfg.IdempotencyKeyTTL
+	if idempotencyKeyTTL == 0 {
+		idempotencyKeyTTL = DefaultIdempotencyKeyTTL
+	}
+	clock := cfg.Clock
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	queryTimeout := cfg.QueryTimeout
+	if queryTimeout == 0 {
+		queryTimeout = DefaultQueryTimeout
+	}
+	maxConcurrentRequests := cfg.MaxConcurrentRequests
+	if maxConcurrentRequests == 0 {
+		maxConcurrentRequests = DefaultMaxConcurrentRequests
+	}
+	statusStaleAfter := cfg.StatusStaleAfter
+	if statusStaleAfter == 0 {
+		statusStaleAfter = DefaultStatusStaleAfter
+	}
+	degradedUptime := cfg.DegradedUptime
+	if degradedUptime == 0 {
+		degradedUptime = DefaultDegradedUptime
+	}
+	unknownIPVersions := cfg.UnknownIPVersions
+	if unknownIPVersions == "" {
+		unknownIPVersions = UnknownIPVersionsStore
+	}
+	return &controller{
+		service:               cfg.Service,
+		sanitizer:             cfg.Sanitizer,
+		gatewaySanitizer:      cfg.GatewaySanitizer,
+		genericSanitizer:      cfg.GenericSanitizer,
+		batchMixSanitizer:     cfg.BatchMixSanitizer,
+		batchGatewaySanitizer: cfg.BatchGatewaySanitizer,
+		compressionLevel:      compressionLevel,
+		maxBatchSize:          maxBatchSize,
+		maxBodyBytes:          maxBodyBytes,
+		writeRateLimit:        writeRateLimit,
+		readRateLimit:         readRateLimit,
+		maxServedReportAge:    maxServedReportAge,
+		idempotencyKeys:       newIdempotencyKeys(idempotencyKeyTTL, maxIdempotencyKeys, clock),
+		ownerOptional:         cfg.OwnerOptional,
+		queryTimeout:          queryTimeout,
+		statusStaleAfter:      statusStaleAfter,
+		degradedUptime:        degradedUptime,
+		inFlight:              make(chan struct{}, maxConcurrentRequests),
+		normalizeIdentifiers:  cfg.NormalizeIdentifiers,
+		unknownIPVersions:     unknownIPVersions,
+		rejectOwnerChanges:    cfg.RejectOwnerChanges,
+		clock:                 clock,
+	}
 }
 
 func (controller *controller) RegisterRoutes(router *gin.Engine) {
-	// use that limiter if no other is specified (1 request per second)
-	lmt := tollbooth_gin.LimitHandler(tollbooth.NewLimiter(1, nil))
+	// separate limiters, so that dashboards polling the reports can't starve the monitor's status submissions
+	writeLmt := newRateLimiter(controller.writeRateLimit)
+	readLmt := newRateLimiter(controller.readRateLimit)
+	// reports can get quite big, so compress them whenever the client accepts it
+	compress := gzip.Gzip(controller.compressionLevel)
+	limitBody := controller.limitBodySize
+	// monitors may gzip their batches
+	decompress := controller.decompressBody
+	// and retry them with the same idempotency key
+	deduplicate := controller.deduplicate
+	// report requests fail with 503 rather than hang when the database is slow
+	bound := controller.boundQueries
+	// and past a number of requests in flight, further ones are turned away rather than queueing up on the
+	// database. The stream is left out as its connections stay open for as long as the client listens.
+	shed := controller.shedLoad
+
+	router.POST("/api/status/mixnode", writeLmt, shed, limitBody, controller.CreateMixStatus)
+	router.POST("/api/status/mixnode/batch", writeLmt, shed, limitBody, decompress, deduplicate, controller.BatchCreateMixStatus)
+	router.POST("/api/status/mixnode/batch/validate", writeLmt, shed, limitBody, decompress, controller.ValidateBatchMixStatus)
+	router.POST("/api/status/mixnode/history/bulk", readLmt, shed, limitBody, controller.ListMixMeasurementsBulk)
+	router.GET("/api/status/mixnode/:pubkey/history", readLmt, shed, controller.ListMixMeasurements)
+	router.GET("/api/status/mixnode/:pubkey/report", readLmt, shed, compress, bound, controller.GetMixStatusReport)
+	router.GET("/api/status/mixnode/:pubkey/summary", readLmt, shed, controller.GetMixNodeSummary)
+	router.GET("/api/status/mixnode/:pubkey/uptime-at", readLmt, shed, controller.GetMixUptimeAt)
+	router.GET("/api/status/mixnode/:pubkey/uptime/raw", readLmt, shed, controller.GetMixRawUptime)
+	router.GET("/api/status/mixnode/:pubkey/lifetime", readLmt, shed, controller.GetMixLifetime)
+	router.GET("/api/status/mixnode/:pubkey/ownership", readLmt, shed, controller.ListOwnershipChanges)
+	router.GET("/api/status/fullmixreport", readLmt, shed, compress, bound, controller.BatchGetMixStatusReport)
+	// under mixnodes rather than mixnode, as gin 1.6 doesn't let a static segment sit next to mixnode/:pubkey
+	router.GET("/api/status/mixnodes/aggregate", readLmt, shed, controller.AggregateMixUptime)
+	router.GET("/api/status/mixnodes/alerts", readLmt, shed, controller.DetectMixUptimeDrops)
+	router.GET("/api/status/mixnodes/top", readLmt, shed, controller.TopMixReports)
+	router.GET("/api/status/mixnodes/compare", readLmt, shed, bound, controller.CompareMixReports)
+	router.GET("/api/status/mixnodes/epochs/:epoch", readLmt, shed, compress, bound, controller.GetMixEpochReport)
+	router.GET("/api/status/mixnodes/stream", readLmt, controller.StreamMixStatus)
 
-	router.POST("/api/status/mixnode", lmt, controller.CreateMixStatus)
-	router.POST("/api/status/mixnode/batch", lmt, controller.BatchCreateMixStatus)
-	router.GET("/api/status/mixnode/:pubkey/history", lmt, controller.ListMixMeasurements)
-	router.GET("/api/status/mixnode/:pubkey/report", lmt, controller.GetMixStatusReport)
-	router.GET("/api/status/fullmixreport", lmt, controller.BatchGetMixStatusReport)
+	router.POST("/api/status/gateway", writeLmt, shed, limitBody, controller.CreateGatewayStatus)
+	router.POST("/api/status/gateway/batch", writeLmt, shed, limitBody, decompress, deduplicate, controller.BatchCreateGatewayStatus)
+	router.GET("/api/status/gateway/:pubkey/history", readLmt, shed, controller.ListGatewayMeasurements)
+	router.GET("/api/status/gateway/:pubkey/report", readLmt, shed, compress, bound, controller.GetGatewayStatusReport)
+	router.GET("/api/status/gateway/:pubkey/lifetime", readLmt, shed, controller.GetGatewayLifetime)
+	router.GET("/api/status/fullgatewayreport", readLmt, shed, compress, bound, controller.BatchGetGatewayStatusReport)
 
+	router.GET("/api/status/network/history", readLmt, shed, controller.ListNetworkUptimeHistory)
 
-	router.POST("/api/status/gateway", lmt, controller.CreateGatewayStatus)
-	router.POST("/api/status/gateway/batch", lmt, controller.BatchCreateGatewayStatus)
-	router.GET("/api/status/gateway/:pubkey/history", lmt, controller.ListGatewayMeasurements)
-	router.GET("/api/status/gateway/:pubkey/report", lmt, controller.GetGatewayStatusReport)
-	router.GET("/api/status/fullgatewayreport", lmt, controller.BatchGetGatewayStatusReport)
+	router.GET("/api/status/stats", readLmt, shed, controller.GetStats)
+	router.GET("/api/status/owners", readLmt, shed, controller.ListOwners)
+	router.GET("/api/status/schema", readLmt, shed, controller.GetPayloadSchema)
+
+	// exports and imports run for as long as the dataset takes to go through, so they're left out of the load
+	// shedding like the stream, and the import body isn't capped
+	router.GET("/api/admin/export", readLmt, controller.ExportData)
+	router.POST("/api/admin/import", writeLmt, controller.ImportData)
+	// the other routes that rewrite the stored data are grouped with them, so that they're all only served to local
+	// connections. There's no gin conflict to work around here, so the node routes keep the singular mixnode.
+	router.POST("/api/admin/recompute-all", writeLmt, shed, controller.RecomputeAllReports)
+	router.POST("/api/admin/mixnode/:pubkey/recompute", writeLmt, shed, controller.RecomputeMixReport)
+	router.POST("/api/admin/backfill", writeLmt, shed, controller.BackfillMixReports)
+	router.DELETE("/api/admin/mixnode/:pubkey/statuses/:ipversion/:timestamp", writeLmt, shed, controller.RetractMixStatus)
+	router.DELETE("/api/admin/gateway/:pubkey/statuses/:ipversion/:timestamp", writeLmt, shed, controller.RetractGatewayStatus)
 }
 
 // ListMixMeasurements lists mixnode statuses
 // @Summary Lists mixnode activity
-// @Description Lists all mixnode statuses for a given node pubkey
+// @Description Lists all mixnode statuses for a given node pubkey. With `up`, only lists the statuses of the `ipVersion` (4 by default) during the last `hours` hours (24 by default, capped at the status retention period) that were up or down, e.g. to look at the failed measurements of the node.
 // @ID listMixStatuses
 // @Accept  json
 // @Produce  json
 // @Tags status
 // @Param pubkey path string true "Mixnode Pubkey"
+// @Param up query bool false "Only list the statuses in that state"
+// @Param ipVersion query string false "IP version, 4 by default, only used along with up"
+// @Param hours query int false "Size of the time window in hours, only used along with up"
+// @Param envelope query bool false "Wrap the statuses in a models.Envelope"
 // @Success 200 {array} models.MixStatus
 // @Failure 400 {object} models.Error
 // @Failure 404 {object} models.Error
 // @Failure 500 {object} models.Error
 // @Router /api/status/mixnode/{pubkey}/history [get]
 func (controller *controller) ListMixMeasurements(c *gin.Context) {
-	pubkey := c.Param("pubkey")
-	measurements := controller.service.ListMixStatus(pubkey)
-	c.JSON(http.StatusOK, measurements)
+	envelope, ok := wantsEnvelope(c)
+	if !ok {
+		return
+	}
+	pubkey := controller.pubkeyParam(c)
+	if state, filtered := c.GetQuery("up"); filtered {
+		up, err := strconv.ParseBool(state)
+		if err != nil {
+			respondWithError(c, http.StatusBadRequest, "up must be either true or false")
+			return
+		}
+		ipVersion := c.DefaultQuery("ipVersion", "4")
+		if ipVersion != "4" && ipVersion != "6" {
+			respondWithError(c, http.StatusBadRequest, "ipVersion must be either 4 or 6")
+			return
+		}
+		hours, err := strconv.Atoi(c.DefaultQuery("hours", "24"))
+		if err != nil || hours <= 0 {
+			respondWithError(c, http.StatusBadRequest, "hours must be a positive integer")
+			return
+		}
+		if maxHours := int(StatusRetention.Hours()); hours > maxHours {
+			hours = maxHours
+		}
+		respondWithList(c, controller.clock, envelope, controller.service.ListMixStatusByState(c.Request.Context(), pubkey, ipVersion, up, hours))
+		return
+	}
+	measurements := controller.service.ListMixStatus(c.Request.Context(), pubkey)
+	respondWithList(c, controller.clock, envelope, measurements)
+}
+
+// ListMixMeasurementsBulk ...
+// @Summary Lists the activity of multiple mixnodes
+// @Description Lists the most recent statuses of each of the given mixnodes, at most `limit` (1000 by default and at most) of them per node, keyed by the node pubkey. At most 100 nodes can be asked for at once.
+// @ID listMixStatusesBulk
+// @Accept  json
+// @Produce  json
+// @Tags status
+// @Param   object      body   models.BulkHistoryRequest     true  "object"
+// @Success 200 {object} map[string][]models.PersistedMixStatus
+// @Failure 400 {object} models.ValidationError
+// @Failure 413 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /api/status/mixnode/history/bulk [post]
+func (controller *controller) ListMixMeasurementsBulk(c *gin.Context) {
+	var request models.BulkHistoryRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		if isBodyTooLarge(err) {
+			respondWithError(c, http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		respondWithBindingError(c, err, request)
+		return
+	}
+	if len(request.PubKeys) > MaxBulkHistoryKeys {
+		respondWithError(c, http.StatusBadRequest, fmt.Sprintf("at most %d pubkeys can be asked for at once", MaxBulkHistoryKeys))
+		return
+	}
+	if request.Limit == 0 || request.Limit > MaxHistoryLimit {
+		request.Limit = MaxHistoryLimit
+	}
+	for i := range request.PubKeys {
+		request.PubKeys[i] = normalizeIf(controller.normalizeIdentifiers, request.PubKeys[i])
+	}
+
+	c.JSON(http.StatusOK, controller.service.ListMixStatusBulk(c.Request.Context(), request.PubKeys, request.Limit))
 }
 
 // CreateMixStatus ...
@@ -97,52 +380,404 @@ func (controller *controller) ListMixMeasurements(c *gin.Context) {
 // @Produce  json
 // @Tags status
 // @Param   object      body   models.MixStatus     true  "object"
-// @Success 201
-// @Failure 400 {object} models.Error
+// @Success 201 {object} models.OK
+// @Failure 400 {object} models.ValidationError
 // @Failure 403 {object} models.Error
 // @Failure 404 {object} models.Error
 // @Failure 500 {object} models.Error
+// @Failure 503 {object} models.Error
 // @Router /api/status/mixnode [post]
 func (controller *controller) CreateMixStatus(c *gin.Context) {
-	remoteIP := c.ClientIP()
-	if !(remoteIP == "127.0.0.1" || remoteIP == "::1" || c.Request.RemoteAddr == "127.0.0.1" || c.Request.RemoteAddr == "::1") {
-		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+	if !isTrustedSource(c) {
+		respondWithError(c, http.StatusForbidden, "forbidden")
 		return
 	}
 	var status models.MixStatus
 	if err := c.ShouldBindJSON(&status); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondWithBindingError(c, err, status)
 		return
 	}
 	sanitized := controller.sanitizer.Sanitize(status)
-	persisted := controller.service.CreateMixStatus(sanitized)
-	controller.service.SaveMixStatusReport(persisted)
+	if err := controller.checkOwner(sanitized.Owner); err != nil {
+		respondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := controller.checkIPVersion(sanitized.IPVersion); err != nil {
+		respondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(controller.changedOwners(c, []models.MixStatus{sanitized})) > 0 {
+		respondWithError(c, http.StatusBadRequest, errOwnerChange.Error())
+		return
+	}
+	controller.warnUnknownIPVersion(sanitized.PubKey, sanitized.IPVersion)
+	persisted, err := controller.service.CreateMixStatus(sanitized)
+	if err != nil {
+		respondWithError(c, http.StatusServiceUnavailable, storeFailedMessage)
+		return
+	}
+	// the statuses are already stored, so the report must get updated even if the client goes away in the meantime
+	controller.service.SaveMixStatusReport(context.Background(), persisted)
 
-	c.JSON(http.StatusCreated, gin.H{"ok": true})
+	c.JSON(http.StatusCreated, models.OK{OK: true})
 }
 
 // GetMixStatusReport ...
 // @Summary Retrieves a summary report of historical mix status
-// @Description Provides summary uptime statistics for last 5 minutes, day, week, and month
+// @Description Provides summary uptime statistics for last 5 minutes, day, week, and month. An uptime of -1 means there weren't enough statuses during the window to tell, which includes windows without any status. Earlier versions reported 0 for those.
 // @ID getMixStatusReport
 // @Accept  json
 // @Produce  json
 // @Tags status
 // @Param pubkey path string true "Mixnode Pubkey"
-// @Success 200
+// @Param If-None-Match header string false "ETag of a previously retrieved report"
+// @Success 200 {object} models.MixStatusReport
+// @Success 304
 // @Failure 400 {object} models.Error
 // @Failure 404 {object} models.Error
+// @Failure 410 {object} models.Error
 // @Failure 500 {object} models.Error
+// @Failure 503 {object} models.Error
 // @Router /api/status/mixnode/{pubkey}/report [get]
 func (controller *controller) GetMixStatusReport(c *gin.Context) {
-	pubkey := c.Param("pubkey")
-	report := controller.service.GetMixStatusReport(pubkey)
-	if (report == models.MixStatusReport{}) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	pubkey := controller.pubkeyParam(c)
+	report, err := controller.service.GetMixStatusReport(c.Request.Context(), pubkey)
+	if queriesTimedOut(c) {
+		return
+	}
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, "failed to load the report")
+		return
+	}
+	if report.PubKey == "" {
+		respondWithError(c, http.StatusNotFound, "not found")
+		return
+	}
+	// reports saved before the timestamps were recorded have no known age, so they're served as they are
+	oldestServed := controller.clock.Now().Add(-controller.maxServedReportAge).UnixNano()
+	if mostRecent := report.MostRecentTimestamp(); mostRecent != 0 && mostRecent < oldestServed {
+		respondWithError(c, http.StatusGone, "the node hasn't reported any status recently")
+		return
+	}
+	controller.setMixStatus(&report)
+	respondWithETag(c, http.StatusOK, report)
+}
+
+// GetMixNodeSummary ...
+// @Summary Retrieves everything needed to show the current state of a mixnode
+// @Description Provides the summary report of a mixnode along with its uptime trends, telling whether the last hour uptime is higher or lower than the last day one, and the time of its most recent status
+// @ID getMixNodeSummary
+// @Accept  json
+// @Produce  json
+// @Tags status
+// @Param pubkey path string true "Mixnode Pubkey"
+// @Success 200 {object} models.MixNodeSummary
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /api/status/mixnode/{pubkey}/summary [get]
+func (controller *controller) GetMixNodeSummary(c *gin.Context) {
+	pubkey := controller.pubkeyParam(c)
+	summary := controller.service.GetMixNodeSummary(c.Request.Context(), pubkey)
+	if summary.Report.PubKey == "" {
+		respondWithError(c, http.StatusNotFound, "not found")
+		return
+	}
+	controller.setMixStatus(&summary.Report)
+	c.JSON(http.StatusOK, summary)
+}
+
+// CompareMixReports ...
+// @Summary Compares the reports of two mixnodes
+// @Description Provides the reports of mixnodes `a` and `b` side by side, along with the differences between their uptimes during every window, `b` minus `a`. Windows either of them doesn't have enough statuses during are left out of the differences, and so are the ipv6 ones unless both of them ever reported ipv6 statuses.
+// @ID compareMixReports
+// @Accept  json
+// @Produce  json
+// @Tags status
+// @Param a query string true "Pubkey of the first mixnode"
+// @Param b query string true "Pubkey of the second mixnode"
+// @Success 200 {object} models.MixComparison
+// @Failure 400 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Failure 503 {object} models.Error
+// @Router /api/status/mixnodes/compare [get]
+func (controller *controller) CompareMixReports(c *gin.Context) {
+	params := []string{"a", "b"}
+	var pubkeys [2]string
+	for i, param := range params {
+		pubkeys[i] = normalizeIf(controller.normalizeIdentifiers, c.Query(param))
+		if pubkeys[i] == "" {
+			respondWithError(c, http.StatusBadRequest, fmt.Sprintf("%s must be the pubkey of a mixnode", param))
+			return
+		}
+	}
+
+	var reports [2]models.MixStatusReport
+	for i, param := range params {
+		pubkey := pubkeys[i]
+		report, err := controller.service.GetMixStatusReport(c.Request.Context(), pubkey)
+		if queriesTimedOut(c) {
+			return
+		}
+		if err != nil {
+			respondWithError(c, http.StatusInternalServerError, "failed to load the report")
+			return
+		}
+		if report.PubKey == "" {
+			respondWithError(c, http.StatusNotFound, fmt.Sprintf("mixnode %s (%s) not found", param, pubkey))
+			return
+		}
+		controller.setMixStatus(&report)
+		reports[i] = report
+	}
+	c.JSON(http.StatusOK, compareMixReports(reports[0], reports[1]))
+}
+
+// GetMixLifetime ...
+// @Summary Tells when a mixnode was first and last seen
+// @Description Provides the timestamps of the first and the most recent retained statuses of the mixnode, along with the number of statuses it reported in between
+// @ID getMixLifetime
+// @Accept  json
+// @Produce  json
+// @Tags status
+// @Param pubkey path string true "Mixnode Pubkey"
+// @Success 200 {object} models.NodeLifetime
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /api/status/mixnode/{pubkey}/lifetime [get]
+func (controller *controller) GetMixLifetime(c *gin.Context) {
+	respondWithLifetime(c, controller.service.GetMixLifetime(c.Request.Context(), controller.pubkeyParam(c)))
+}
+
+// ListOwnershipChanges ...
+// @Summary Lists the ownership changes of a mixnode
+// @Description Lists every time the mixnode reported a status under another owner than the one of its report, oldest first. The report took the new owner each time.
+// @ID listOwnershipChanges
+// @Accept  json
+// @Produce  json
+// @Tags status
+// @Param pubkey path string true "Mixnode Pubkey"
+// @Success 200 {array} models.OwnershipChange
+// @Failure 500 {object} models.Error
+// @Router /api/status/mixnode/{pubkey}/ownership [get]
+func (controller *controller) ListOwnershipChanges(c *gin.Context) {
+	c.JSON(http.StatusOK, controller.service.ListOwnershipChanges(c.Request.Context(), controller.pubkeyParam(c)))
+}
+
+// GetGatewayLifetime ...
+// @Summary Tells when a gateway was first and last seen
+// @Description Provides the timestamps of the first and the most recent retained statuses of the gateway, along with the number of statuses it reported in between
+// @ID getGatewayLifetime
+// @Accept  json
+// @Produce  json
+// @Tags status
+// @Param pubkey path string true "Gateway Pubkey"
+// @Success 200 {object} models.NodeLifetime
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /api/status/gateway/{pubkey}/lifetime [get]
+func (controller *controller) GetGatewayLifetime(c *gin.Context) {
+	respondWithLifetime(c, controller.service.GetGatewayLifetime(c.Request.Context(), controller.pubkeyParam(c)))
+}
+
+// setMixStatus fills in the overall state of the mixnode as of now
+func (controller *controller) setMixStatus(report *models.MixStatusReport) {
+	now := controller.clock.Now().UnixNano()
+	report.Status = mixNodeStatus(*report, now, controller.statusStaleAfter, controller.degradedUptime)
+}
+
+// setMixStatuses fills in the overall state of each of the mixnodes
+func (controller *controller) setMixStatuses(reports []models.MixStatusReport) {
+	for i := range reports {
+		controller.setMixStatus(&reports[i])
+	}
+}
+
+// respondWithLifetime responds with the lifetime, or with 404 if the node has no statuses
+func respondWithLifetime(c *gin.Context, lifetime models.NodeLifetime) {
+	if lifetime.TotalMeasurements == 0 {
+		respondWithError(c, http.StatusNotFound, "not found")
+		return
+	}
+	c.JSON(http.StatusOK, lifetime)
+}
+
+// RecomputeMixReport ...
+// @Summary Rebuilds the report of a mixnode from its statuses
+// @Description Recomputes every uptime window of the mixnode report from all of its retained statuses and replaces the stored report, in case it drifted from the underlying data. Only available to connections from the local machine, forwarding headers are ignored.
+// @ID recomputeMixReport
+// @Accept  json
+// @Produce  json
+// @Tags admin
+// @Param pubkey path string true "Mixnode Pubkey"
+// @Success 200 {object} models.MixStatusReport
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /api/admin/mixnode/{pubkey}/recompute [post]
+func (controller *controller) RecomputeMixReport(c *gin.Context) {
+	if !isLocalConnection(c) {
+		respondWithError(c, http.StatusForbidden, "forbidden")
+		return
+	}
+	// the report must get replaced as a whole even if the client goes away in the meantime
+	report, err := controller.service.RecomputeMixReport(context.Background(), controller.pubkeyParam(c))
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, "failed to save the report")
+		return
+	}
+	if report.PubKey == "" {
+		respondWithError(c, http.StatusNotFound, "not found")
+		return
 	}
 	c.JSON(http.StatusOK, report)
 }
 
+// GetMixUptimeAt ...
+// @Summary Retrieves the uptime of a mixnode at a past instant
+// @Description Calculates the uptime of the mixnode over the `window` minutes (60 by default) up to `timestamp`, out of the retained statuses. The window is capped at the status retention period. An uptime of -1 means there weren't enough statuses during the window to tell, which includes windows without any status. Earlier versions reported 0 for those.
+// @ID getMixUptimeAt
+// @Accept  json
+// @Produce  json
+// @Tags status
+// @Param pubkey path string true "Mixnode Pubkey"
+// @Param timestamp query int true "End of the window as unix nanoseconds"
+// @Param window query int false "Size of the window in minutes"
+// @Param ipVersion query string false "IP version, 4 by default"
+// @Success 200 {object} models.MixUptimeAt
+// @Failure 400 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /api/status/mixnode/{pubkey}/uptime-at [get]
+func (controller *controller) GetMixUptimeAt(c *gin.Context) {
+	timestamp, err := strconv.ParseInt(c.Query("timestamp"), 10, 64)
+	if err != nil || timestamp <= 0 {
+		respondWithError(c, http.StatusBadRequest, "timestamp must be a positive integer")
+		return
+	}
+	window, err := strconv.Atoi(c.DefaultQuery("window", "60"))
+	if err != nil || window <= 0 {
+		respondWithError(c, http.StatusBadRequest, "window must be a positive integer")
+		return
+	}
+	if maxWindow := int(StatusRetention.Minutes()); window > maxWindow {
+		window = maxWindow
+	}
+	ipVersion := c.DefaultQuery("ipVersion", "4")
+	if ipVersion != "4" && ipVersion != "6" {
+		respondWithError(c, http.StatusBadRequest, "ipVersion must be either 4 or 6")
+		return
+	}
+
+	pubkey := controller.pubkeyParam(c)
+	uptime := controller.service.CalculateMixUptimeAt(c.Request.Context(), pubkey, ipVersion, timestamp, time.Duration(window)*time.Minute)
+	c.JSON(http.StatusOK, models.MixUptimeAt{
+		PubKey:        pubkey,
+		IPVersion:     ipVersion,
+		Timestamp:     timestamp,
+		WindowMinutes: window,
+		Uptime:        uptime,
+	})
+}
+
+// GetMixRawUptime ...
+// @Summary Retrieves the counts behind the uptimes of a mixnode
+// @Description Provides, for every configured uptime window and ip version, the number of statuses of the mixnode during the window and how many of them were up, so that clients can work out the uptime with whatever precision they need. The counts are there even for the windows with too few statuses for an uptime.
+// @ID getMixRawUptime
+// @Accept  json
+// @Produce  json
+// @Tags status
+// @Param pubkey path string true "Mixnode Pubkey"
+// @Success 200 {object} models.MixRawUptime
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /api/status/mixnode/{pubkey}/uptime/raw [get]
+func (controller *controller) GetMixRawUptime(c *gin.Context) {
+	raw := controller.service.GetMixRawUptime(c.Request.Context(), controller.pubkeyParam(c))
+	for _, windows := range []map[string]models.UptimeCount{raw.IPV4, raw.IPV6} {
+		for _, count := range windows {
+			if count.Total > 0 {
+				c.JSON(http.StatusOK, raw)
+				return
+			}
+		}
+	}
+	respondWithError(c, http.StatusNotFound, "not found")
+}
+
+// RetractMixStatus ...
+// @Summary Retracts a mixnode status
+// @Description Lets the network monitor retract a status it reported, e.g. because it realised it mis-measured. The status is identified by the ip version and the timestamp it was stored with. It stops counting towards uptime and the report of the node gets recomputed without it. Only available to connections from the local machine, forwarding headers are ignored.
+// @ID retractMixStatus
+// @Accept  json
+// @Produce  json
+// @Tags admin
+// @Param pubkey path string true "Mixnode Pubkey"
+// @Param ipversion path string true "IP version of the status"
+// @Param timestamp path int true "Timestamp of the status"
+// @Success 200 {object} models.OK
+// @Failure 400 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /api/admin/mixnode/{pubkey}/statuses/{ipversion}/{timestamp} [delete]
+func (controller *controller) RetractMixStatus(c *gin.Context) {
+	if !isLocalConnection(c) {
+		respondWithError(c, http.StatusForbidden, "forbidden")
+		return
+	}
+	timestamp, err := strconv.ParseInt(c.Param("timestamp"), 10, 64)
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "timestamp must be an integer")
+		return
+	}
+	// the report must get recomputed even if the client goes away in the meantime
+	if !controller.service.RetractMixStatus(context.Background(), controller.pubkeyParam(c), c.Param("ipversion"), timestamp) {
+		respondWithError(c, http.StatusNotFound, "not found")
+		return
+	}
+	c.JSON(http.StatusOK, models.OK{OK: true})
+}
+
+// BackfillMixReports ...
+// @Summary Rebuilds missing mixnode reports
+// @Description Recomputes the report of every mixnode that has retained statuses but no stored report, e.g. after the reports were wiped. The same happens on startup. Only available to connections from the local machine, forwarding headers are ignored.
+// @ID backfillMixReports
+// @Accept  json
+// @Produce  json
+// @Tags admin
+// @Success 200 {object} models.Backfill
+// @Failure 403 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /api/admin/backfill [post]
+func (controller *controller) BackfillMixReports(c *gin.Context) {
+	if !isLocalConnection(c) {
+		respondWithError(c, http.StatusForbidden, "forbidden")
+		return
+	}
+	// same as with a single recompute, don't stop halfway through if the client goes away
+	c.JSON(http.StatusOK, controller.service.BackfillMixReports(context.Background()))
+}
+
+// RecomputeAllReports ...
+// @Summary Rebuilds the reports of all nodes
+// @Description Recomputes the report of every mixnode and gateway with retained statuses from those statuses, e.g. after the way uptime gets calculated changed. Only available to connections from the local machine, forwarding headers are ignored.
+// @ID recomputeAllReports
+// @Accept  json
+// @Produce  json
+// @Tags admin
+// @Success 200 {object} models.Recomputation
+// @Failure 403 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /api/admin/recompute-all [post]
+func (controller *controller) RecomputeAllReports(c *gin.Context) {
+	if !isLocalConnection(c) {
+		respondWithError(c, http.StatusForbidden, "forbidden")
+		return
+	}
+	// same as with a single recompute, don't stop halfway through if the client goes away
+	c.JSON(http.StatusOK, controller.service.RecomputeAllReports(context.Background()))
+}
 
 // BatchCreateMixStatus ...
 // @Summary Lets the network monitor create a new uptime status for multiple mixes
@@ -152,48 +787,479 @@ func (controller *controller) GetMixStatusReport(c *gin.Context) {
 // @Produce  json
 // @Tags status
 // @Param   object      body   models.BatchMixStatus     true  "object"
-// @Success 201
-// @Failure 400 {object} models.Error
+// @Param   Content-Encoding header string false "gzip to send a compressed body"
+// @Param   Idempotency-Key header string false "Unique key of the batch, retrying it with the same key doesn't store it twice"
+// @Param   partial query bool false "Store the valid statuses even if some are invalid, and tell which ones were stored"
+// @Success 201 {object} models.BatchResult "With partial, models.OK otherwise"
+// @Failure 400 {object} models.ValidationError
 // @Failure 403 {object} models.Error
+// @Failure 409 {object} models.Error
+// @Failure 413 {object} models.Error
 // @Failure 404 {object} models.Error
 // @Failure 500 {object} models.Error
+// @Failure 503 {object} models.Error
 // @Router /api/status/mixnode/batch [post]
 func (controller *controller) BatchCreateMixStatus(c *gin.Context) {
-	remoteIP := c.ClientIP()
-	if !(remoteIP == "127.0.0.1" || remoteIP == "::1" || c.Request.RemoteAddr == "127.0.0.1" || c.Request.RemoteAddr == "::1") {
-		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+	if !isTrustedSource(c) {
+		respondWithError(c, http.StatusForbidden, "forbidden")
 		return
 	}
-	var status models.BatchMixStatus
-	if err := c.ShouldBindJSON(&status); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	partial, ok := boolQuery(c, "partial")
+	if !ok {
+		return
+	}
+	status, ok := controller.bindBatchMixStatus(c)
+	if !ok {
+		return
+	}
+	if partial {
+		controller.batchCreatePartialMixStatus(c, status)
+		return
+	}
+	if fields := validateBatchEntries(status.Status); len(fields) > 0 {
+		respondWithValidationError(c, invalidBatchMessage, fields)
+		return
+	}
+	sanitized := controller.batchMixSanitizer.Sanitize(status)
+	// sanitizing may empty a required field, e.g. a pubkey made only of markup, so the statuses are validated again
+	// the way the dry run and the partial batches see them, so that all of them agree on which ones are stored
+	if fields := validateBatchEntries(sanitized.Status); len(fields) > 0 {
+		respondWithValidationError(c, invalidBatchMessage, fields)
+		return
+	}
+	if fields := controller.batchIPVersionErrors(len(sanitized.Status), func(i int) string { return sanitized.Status[i].IPVersion }); len(fields) > 0 {
+		respondWithValidationError(c, invalidBatchMessage, fields)
 		return
 	}
+	if changed := controller.changedOwners(c, sanitized.Status); len(changed) > 0 {
+		fields := make([]models.FieldError, 0, len(changed))
+		for _, i := range changed {
+			fields = append(fields, ownerChangeFieldError(i))
+		}
+		respondWithValidationError(c, invalidBatchMessage, fields)
+		return
+	}
+
+	if !controller.storeBatchMixStatus(c, sanitized) {
+		return
+	}
+	c.JSON(http.StatusCreated, models.OK{OK: true})
+}
+
+// batchCreatePartialMixStatus stores the statuses of the batch that are valid once sanitized and tells which of them
+// were left out and why, so that a single bad entry doesn't cost the monitor the whole batch. The batch is only
+// rejected if none of its statuses is valid. Retries with the same idempotency key get models.OK rather than the
+// result, same as for whole batches.
+func (controller *controller) batchCreatePartialMixStatus(c *gin.Context, status models.BatchMixStatus) {
 	sanitized := controller.batchMixSanitizer.Sanitize(status)
 
-	persisted := controller.service.BatchCreateMixStatus(sanitized)
-	controller.service.SaveBatchMixStatusReport(persisted)
+	result := models.BatchResult{Stored: []int{}, Failed: []models.FailedStatus{}}
+	valid := models.BatchMixStatus{Epoch: sanitized.Epoch, Status: make([]models.MixStatus, 0, len(sanitized.Status))}
+	var fields []models.FieldError
+	errs := controller.validateMixStatuses(c, sanitized.Status)
+	for i, mixStatus := range sanitized.Status {
+		if err := errs[i]; err != nil {
+			failed := models.FailedStatus{Index: i, Error: err.Error(), Fields: entryFieldErrors(err, mixStatus, i)}
+			if errors.Is(err, errMissingOwner) {
+				failed.Fields = []models.FieldError{{Field: fmt.Sprintf("status[%d].owner", i), Reason: "required"}}
+			}
+			if errors.Is(err, errUnknownIPVersion) {
+				failed.Fields = []models.FieldError{ipVersionFieldError(i)}
+			}
+			if errors.Is(err, errOwnerChange) {
+				failed.Fields = []models.FieldError{ownerChangeFieldError(i)}
+			}
+			result.Failed = append(result.Failed, failed)
+			fields = append(fields, failed.Fields...)
+			continue
+		}
+		result.Stored = append(result.Stored, i)
+		valid.Status = append(valid.Status, mixStatus)
+	}
+	if len(valid.Status) == 0 {
+		respondWithValidationError(c, invalidBatchMessage, fields)
+		return
+	}
 
-	c.JSON(http.StatusCreated, gin.H{"ok": true})
+	if !controller.storeBatchMixStatus(c, valid) {
+		return
+	}
+	c.JSON(http.StatusCreated, result)
+}
+
+// storeBatchMixStatus stores the sanitized batch and updates the reports of its nodes. It responds with 503 if the
+// statuses couldn't be stored.
+func (controller *controller) storeBatchMixStatus(c *gin.Context, sanitized models.BatchMixStatus) bool {
+	for _, mixStatus := range sanitized.Status {
+		controller.warnUnknownIPVersion(mixStatus.PubKey, mixStatus.IPVersion)
+	}
+	persisted, err := controller.service.BatchCreateMixStatus(sanitized)
+	if err != nil {
+		respondWithError(c, http.StatusServiceUnavailable, storeFailedMessage)
+		return false
+	}
+	// the statuses are already stored, so the report must get updated even if the client goes away in the meantime
+	controller.service.SaveBatchMixStatusReport(context.Background(), persisted)
+	return true
+}
+
+// ValidateBatchMixStatus ...
+// @Summary Lets monitor authors check what would be stored for a batch of mix statuses
+// @Description Binds and sanitizes the batch exactly like the batch creation endpoint does and returns the sanitized statuses, each flagged with whether it's valid. Nothing gets stored.
+// @ID validateBatchMixStatus
+// @Accept  json
+// @Produce  json
+// @Tags status
+// @Param   object      body   models.BatchMixStatus     true  "object"
+// @Param   Content-Encoding header string false "gzip to send a compressed body"
+// @Success 200 {object} models.BatchMixStatusValidation
+// @Failure 400 {object} models.ValidationError
+// @Failure 413 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /api/status/mixnode/batch/validate [post]
+func (controller *controller) ValidateBatchMixStatus(c *gin.Context) {
+	status, ok := controller.bindBatchMixStatus(c)
+	if !ok {
+		return
+	}
+	sanitized := controller.batchMixSanitizer.Sanitize(status)
+
+	validation := models.BatchMixStatusValidation{Status: make([]models.ValidatedMixStatus, len(sanitized.Status))}
+	errs := controller.validateMixStatuses(c, sanitized.Status)
+	for i, mixStatus := range sanitized.Status {
+		validation.Status[i] = models.ValidatedMixStatus{Status: mixStatus, Valid: true}
+		if err := errs[i]; err != nil {
+			validation.Status[i].Valid = false
+			validation.Status[i].Error = err.Error()
+		}
+	}
+
+	c.JSON(http.StatusOK, validation)
+}
+
+// pubkeyParam returns the pubkey the request is about, normalized the same way as the pubkeys written
+func (controller *controller) pubkeyParam(c *gin.Context) string {
+	return normalizeIf(controller.normalizeIdentifiers, c.Param("pubkey"))
+}
+
+// validateMixStatus validates a sanitized status of a batch on its own, owner included
+func (controller *controller) validateMixStatus(status models.MixStatus) error {
+	if err := binding.Validator.ValidateStruct(status); err != nil {
+		return err
+	}
+	if err := controller.checkOwner(status.Owner); err != nil {
+		return err
+	}
+	return controller.checkIPVersion(status.IPVersion)
+}
+
+// validateMixStatuses validates every sanitized status of a batch like validateMixStatus, rejecting the ones that
+// would change the owner of their node too if owner changes are rejected. The errors are nil for the valid statuses.
+func (controller *controller) validateMixStatuses(c *gin.Context, statuses []models.MixStatus) []error {
+	errs := make([]error, len(statuses))
+	for i, status := range statuses {
+		errs[i] = controller.validateMixStatus(status)
+	}
+	for _, i := range controller.changedOwners(c, statuses) {
+		if errs[i] == nil {
+			errs[i] = errOwnerChange
+		}
+	}
+	return errs
+}
+
+// errOwnerChange rejects mix statuses that would change the owner of their node, if owner changes are rejected
+var errOwnerChange = errors.New("owner differs from the owner of the node")
+
+// changedOwners returns the indices of the sanitized statuses that would change the owner of their node, if owner
+// changes are rejected
+func (controller *controller) changedOwners(c *gin.Context, statuses []models.MixStatus) []int {
+	if !controller.rejectOwnerChanges {
+		return nil
+	}
+	return controller.service.ChangedMixOwners(c.Request.Context(), statuses)
+}
+
+// ownerChangeFieldError reports the owner change of the status at the given index of a batch
+func ownerChangeFieldError(index int) models.FieldError {
+	return models.FieldError{Field: fmt.Sprintf("status[%d].owner", index), Reason: "unchanged"}
+}
+
+// errMissingOwner rejects statuses without an owner unless owners are optional
+var errMissingOwner = errors.New("owner is required")
+
+// checkOwner tells whether the owner of a sanitized status is acceptable. It's checked after sanitizing, as an owner
+// made of nothing but markup ends up empty.
+func (controller *controller) checkOwner(owner string) error {
+	if owner == "" && !controller.ownerOptional {
+		return errMissingOwner
+	}
+	return nil
+}
+
+// errUnknownIPVersion rejects statuses with an ip version other than 4 or 6 if they're to be rejected
+var errUnknownIPVersion = errors.New("ipVersion must be either 4 or 6")
+
+func isKnownIPVersion(ipVersion string) bool {
+	return ipVersion == "4" || ipVersion == "6"
+}
+
+// checkIPVersion tells whether the ip version of a sanitized status is acceptable
+func (controller *controller) checkIPVersion(ipVersion string) error {
+	if !isKnownIPVersion(ipVersion) && controller.unknownIPVersions == UnknownIPVersionsReject {
+		return errUnknownIPVersion
+	}
+	return nil
+}
+
+// warnUnknownIPVersion logs a status about to be stored with an unknown ip version, if operators asked for it
+func (controller *controller) warnUnknownIPVersion(pubkey string, ipVersion string) {
+	if !isKnownIPVersion(ipVersion) && controller.unknownIPVersions == UnknownIPVersionsLog {
+		logrus.WithFields(logrus.Fields{"pubKey": pubkey, "ipVersion": ipVersion}).Warn("storing a status with an unknown ip version, it won't count towards any report")
+	}
+}
+
+// batchIPVersionErrors lists the statuses of a batch of the given size whose ip version gets rejected
+func (controller *controller) batchIPVersionErrors(size int, ipVersion func(i int) string) []models.FieldError {
+	var fields []models.FieldError
+	for i := 0; i < size; i++ {
+		if controller.checkIPVersion(ipVersion(i)) != nil {
+			fields = append(fields, ipVersionFieldError(i))
+		}
+	}
+	return fields
+}
+
+// ipVersionFieldError reports the unknown ip version of the status at the given index of a batch
+func ipVersionFieldError(index int) models.FieldError {
+	return models.FieldError{Field: fmt.Sprintf("status[%d].ipVersion", index), Reason: "oneof=4 6"}
+}
+
+// bindBatchMixStatus binds the batch from the request body, responding with an error if it's malformed or too big.
+func (controller *controller) bindBatchMixStatus(c *gin.Context) (models.BatchMixStatus, bool) {
+	var status models.BatchMixStatus
+	if err := c.ShouldBindJSON(&status); err != nil {
+		if isBodyTooLarge(err) {
+			respondWithError(c, http.StatusRequestEntityTooLarge, err.Error())
+			return status, false
+		}
+		respondWithBindingError(c, err, status)
+		return status, false
+	}
+	if len(status.Status) > controller.maxBatchSize {
+		respondWithError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("batch contains %d statuses, at most %d are allowed", len(status.Status), controller.maxBatchSize))
+		return status, false
+	}
+	return status, true
 }
 
 // BatchGetMixStatusReport ...
 // @Summary Retrieves a summary report of historical mix status
-// @Description Provides summary uptime statistics for last 5 minutes, day, week, and month
+// @Description Provides summary uptime statistics for last 5 minutes, day, week, and month. An uptime of -1 means there weren't enough statuses during the window to tell, which includes windows without any status. Earlier versions reported 0 for those.
 // @ID batchGetMixStatusReport
 // @Accept  json
 // @Produce  json
 // @Tags status
-// @Success 200
+// @Param If-None-Match header string false "ETag of a previously retrieved report"
+// @Param fields query string false "Comma separated json names of the only report fields to include, e.g. pubKey,lastHourIPV4"
+// @Success 200 {object} models.BatchMixStatusReport
+// @Success 304
 // @Failure 400 {object} models.Error
 // @Failure 404 {object} models.Error
 // @Failure 500 {object} models.Error
+// @Failure 503 {object} models.Error
 // @Router /api/status/fullmixreport [get]
 func (controller *controller) BatchGetMixStatusReport(c *gin.Context) {
-	report := controller.service.BatchGetMixStatusReport()
+	fields, ok := requestedFields[models.MixStatusReport](c)
+	if !ok {
+		return
+	}
+	report := controller.service.BatchGetMixStatusReport(c.Request.Context())
+	if queriesTimedOut(c) {
+		return
+	}
+	controller.setMixStatuses(report.Report)
+	respondWithReports(c, fields, report, report.Report)
+}
+
+// AggregateMixUptime ...
+// @Summary Retrieves aggregated uptime of all active mixnodes
+// @Description Provides mean, median, min and max uptime of all non-stale mixnodes over the last `hours` hours (24 by default). The window is capped at the status retention period. It's under mixnodes rather than mixnode, as that path can't be routed next to mixnode/{pubkey}.
+// @ID aggregateMixUptime
+// @Accept  json
+// @Produce  json
+// @Tags status
+// @Param hours query int false "Size of the time window in hours"
+// @Success 200 {object} models.MixUptimeAggregate
+// @Failure 400 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /api/status/mixnodes/aggregate [get]
+func (controller *controller) AggregateMixUptime(c *gin.Context) {
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if err != nil || hours <= 0 {
+		respondWithError(c, http.StatusBadRequest, "hours must be a positive integer")
+		return
+	}
+	if maxHours := int(StatusRetention.Hours()); hours > maxHours {
+		hours = maxHours
+	}
+
+	c.JSON(http.StatusOK, controller.service.AggregateMixUptime(c.Request.Context(), hours))
+}
+
+// DefaultUptimeDropThreshold is the drop in percentage points flagged unless the request asks for another one
+const DefaultUptimeDropThreshold = 40
+
+// DetectMixUptimeDrops ...
+// @Summary Lists the mixnodes whose uptime dropped sharply
+// @Description Lists the non-stale mixnodes whose uptime during the last 5 minutes is more than `drop` percentage points (40 by default) below their uptime during the last hour, once per affected ip version and biggest drops first. Windows without enough data are never flagged.
+// @ID detectMixUptimeDrops
+// @Accept  json
+// @Produce  json
+// @Tags status
+// @Param drop query int false "Drop in percentage points, between 0 and 100"
+// @Param envelope query bool false "Wrap the drops in a models.Envelope"
+// @Success 200 {array} models.MixUptimeDrop
+// @Failure 400 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /api/status/mixnodes/alerts [get]
+func (controller *controller) DetectMixUptimeDrops(c *gin.Context) {
+	envelope, ok := wantsEnvelope(c)
+	if !ok {
+		return
+	}
+	threshold, err := strconv.Atoi(c.DefaultQuery("drop", strconv.Itoa(DefaultUptimeDropThreshold)))
+	if err != nil || threshold < 0 || threshold > 100 {
+		respondWithError(c, http.StatusBadRequest, "drop must be an integer between 0 and 100")
+		return
+	}
+
+	respondWithList(c, controller.clock, envelope, controller.service.DetectMixUptimeDrops(c.Request.Context(), threshold))
+}
+
+// ListNetworkUptimeHistory ...
+// @Summary Lists the uptime history of the whole network
+// @Description Lists the samples of the mean last hour uptime of the active mixnodes taken on each reports update during the last `hours` hours (24 by default), oldest first. Samples older than the configured horizon are gone.
+// @ID listNetworkUptimeHistory
+// @Accept  json
+// @Produce  json
+// @Tags status
+// @Param hours query int false "Size of the time window in hours"
+// @Param envelope query bool false "Wrap the samples in a models.Envelope"
+// @Success 200 {array} models.NetworkUptimeSample
+// @Failure 400 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /api/status/network/history [get]
+func (controller *controller) ListNetworkUptimeHistory(c *gin.Context) {
+	envelope, ok := wantsEnvelope(c)
+	if !ok {
+		return
+	}
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if err != nil || hours <= 0 {
+		respondWithError(c, http.StatusBadRequest, "hours must be a positive integer")
+		return
+	}
+
+	respondWithList(c, controller.clock, envelope, controller.service.ListNetworkUptimeHistory(c.Request.Context(), hours))
+}
+
+// TopMixReports ...
+// @Summary Retrieves the reports of the best mixnodes
+// @Description Provides the reports of the `n` mixnodes (20 by default, at most 1000) with the highest value of the uptime `field` (lastDayIPV4 by default). The field must be one of last5MinutesIPV4, lastHourIPV4, lastDayIPV4 or their IPV6 counterparts.
+// @ID topMixReports
+// @Accept  json
+// @Produce  json
+// @Tags status
+// @Param n query int false "Number of reports"
+// @Param field query string false "Uptime field to rank by"
+// @Success 200 {object} models.BatchMixStatusReport
+// @Failure 400 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /api/status/mixnodes/top [get]
+func (controller *controller) TopMixReports(c *gin.Context) {
+	n, err := strconv.Atoi(c.DefaultQuery("n", "20"))
+	if err != nil || n <= 0 {
+		respondWithError(c, http.StatusBadRequest, "n must be a positive integer")
+		return
+	}
+	if n > MaxReportSize {
+		n = MaxReportSize
+	}
+	field := c.DefaultQuery("field", "lastDayIPV4")
+	if !isRankableMixReportField(field) {
+		respondWithError(c, http.StatusBadRequest, fmt.Sprintf("mixnodes can't be ranked by %q", field))
+		return
+	}
+
+	report := controller.service.TopMixReports(c.Request.Context(), field, n)
+	controller.setMixStatuses(report.Report)
 	c.JSON(http.StatusOK, report)
 }
 
+// GetMixEpochReport ...
+// @Summary Retrieves what was measured during an incentive epoch
+// @Description Provides every mixnode status the monitors attributed to the epoch, in the order they came in, along with the uptime of each node and ip version over them. Statuses that didn't tell their epoch aren't attributed to any. An uptime of -1 means there weren't enough statuses to tell.
+// @ID getMixEpochReport
+// @Accept  json
+// @Produce  json
+// @Tags status
+// @Param epoch path int true "Epoch"
+// @Success 200 {object} models.MixEpochReport
+// @Failure 400 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Failure 503 {object} models.Error
+// @Router /api/status/mixnodes/epochs/{epoch} [get]
+func (controller *controller) GetMixEpochReport(c *gin.Context) {
+	epoch, err := strconv.ParseUint(c.Param("epoch"), 10, 64)
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "epoch must be a non-negative integer")
+		return
+	}
+
+	report := controller.service.GetMixEpochReport(c.Request.Context(), epoch)
+	if queriesTimedOut(c) {
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// StreamMixStatus ...
+// @Summary Streams newly created mix statuses
+// @Description Upgrades the connection to a websocket and pushes every newly created mix status to it as JSON. Statuses are dropped for clients that can't keep up.
+// @ID streamMixStatus
+// @Produce  json
+// @Tags status
+// @Success 101 {object} models.PersistedMixStatus
+// @Failure 400 {object} models.Error
+// @Router /api/status/mixnodes/stream [get]
+func (controller *controller) StreamMixStatus(c *gin.Context) {
+	// subscribe before the handshake completes so that the client doesn't miss statuses created right after it
+	statuses, unsubscribe := controller.service.SubscribeMixStatuses()
+	defer unsubscribe()
+
+	server := websocket.Server{Handler: func(conn *websocket.Conn) {
+		// the client isn't expected to send anything, reading only lets us notice when it goes away
+		disconnected := make(chan struct{})
+		go func() {
+			_, _ = io.Copy(io.Discard, conn)
+			close(disconnected)
+		}()
+
+		for {
+			select {
+			case <-disconnected:
+				return
+			case status := <-statuses:
+				if err := websocket.JSON.Send(conn, status); err != nil {
+					return
+				}
+			}
+		}
+	}}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
 // ListGatewayMeasurements lists mixnode statuses
 // @Summary Lists mixnode activity
 // @Description Lists all gateway statuses for a given node pubkey
@@ -202,15 +1268,20 @@ func (controller *controller) BatchGetMixStatusReport(c *gin.Context) {
 // @Produce  json
 // @Tags status
 // @Param pubkey path string true "Gateway Pubkey"
+// @Param envelope query bool false "Wrap the statuses in a models.Envelope"
 // @Success 200 {array} models.GatewayStatus
 // @Failure 400 {object} models.Error
 // @Failure 404 {object} models.Error
 // @Failure 500 {object} models.Error
 // @Router /api/status/gateway/{pubkey}/history [get]
 func (controller *controller) ListGatewayMeasurements(c *gin.Context) {
-	pubkey := c.Param("pubkey")
-	measurements := controller.service.ListGatewayStatus(pubkey)
-	c.JSON(http.StatusOK, measurements)
+	envelope, ok := wantsEnvelope(c)
+	if !ok {
+		return
+	}
+	pubkey := controller.pubkeyParam(c)
+	measurements := controller.service.ListGatewayStatus(c.Request.Context(), pubkey)
+	respondWithList(c, controller.clock, envelope, measurements)
 }
 
 // CreateGatewayStatus ...
@@ -221,50 +1292,71 @@ func (controller *controller) ListGatewayMeasurements(c *gin.Context) {
 // @Produce  json
 // @Tags status
 // @Param   object      body   models.GatewayStatus     true  "object"
-// @Success 201
-// @Failure 400 {object} models.Error
+// @Success 201 {object} models.OK
+// @Failure 400 {object} models.ValidationError
 // @Failure 403 {object} models.Error
 // @Failure 404 {object} models.Error
 // @Failure 500 {object} models.Error
+// @Failure 503 {object} models.Error
 // @Router /api/status/gateway [post]
 func (controller *controller) CreateGatewayStatus(c *gin.Context) {
-	remoteIP := c.ClientIP()
-	if !(remoteIP == "127.0.0.1" || remoteIP == "::1" || c.Request.RemoteAddr == "127.0.0.1" || c.Request.RemoteAddr == "::1") {
-		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+	if !isTrustedSource(c) {
+		respondWithError(c, http.StatusForbidden, "forbidden")
 		return
 	}
 	var status models.GatewayStatus
 	if err := c.ShouldBindJSON(&status); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondWithBindingError(c, err, status)
+		return
+	}
+	sanitized := controller.gatewaySanitizer.Sanitize(status)
+	if err := controller.checkOwner(sanitized.Owner); err != nil {
+		respondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := controller.checkIPVersion(sanitized.IPVersion); err != nil {
+		respondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	controller.warnUnknownIPVersion(sanitized.PubKey, sanitized.IPVersion)
+	persisted, err := controller.service.CreateGatewayStatus(sanitized)
+	if err != nil {
+		respondWithError(c, http.StatusServiceUnavailable, storeFailedMessage)
 		return
 	}
-	controller.genericSanitizer.Sanitize(status)
-	persisted := controller.service.CreateGatewayStatus(status)
-	controller.service.SaveGatewayStatusReport(persisted)
+	// the statuses are already stored, so the report must get updated even if the client goes away in the meantime
+	controller.service.SaveGatewayStatusReport(context.Background(), persisted)
 
-	c.JSON(http.StatusCreated, gin.H{"ok": true})
+	c.JSON(http.StatusCreated, models.OK{OK: true})
 }
 
 // GetGatewayStatusReport ...
 // @Summary Retrieves a summary report of historical gateway status
-// @Description Provides summary uptime statistics for last 5 minutes, day, week, and month
+// @Description Provides summary uptime statistics for last 5 minutes, day, week, and month. An uptime of -1 means there weren't enough statuses during the window to tell, which includes windows without any status. Earlier versions reported 0 for those.
 // @ID getGatewayStatusReport
 // @Accept  json
 // @Produce  json
 // @Tags status
 // @Param pubkey path string true "Gateway Pubkey"
-// @Success 200
+// @Param If-None-Match header string false "ETag of a previously retrieved report"
+// @Success 200 {object} models.GatewayStatusReport
+// @Success 304
 // @Failure 400 {object} models.Error
 // @Failure 404 {object} models.Error
 // @Failure 500 {object} models.Error
+// @Failure 503 {object} models.Error
 // @Router /api/status/gateway/{pubkey}/report [get]
 func (controller *controller) GetGatewayStatusReport(c *gin.Context) {
-	pubkey := c.Param("pubkey")
-	report := controller.service.GetGatewayStatusReport(pubkey)
-	if (report == models.GatewayStatusReport{}) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	pubkey := controller.pubkeyParam(c)
+	report := controller.service.GetGatewayStatusReport(c.Request.Context(), pubkey)
+	if queriesTimedOut(c) {
+		return
 	}
-	c.JSON(http.StatusOK, report)
+	if report.PubKey == "" {
+		respondWithError(c, http.StatusNotFound, "not found")
+		return
+	}
+	respondWithETag(c, http.StatusOK, report)
 }
 
 // BatchCreateGatewayStatus ...
@@ -275,44 +1367,407 @@ func (controller *controller) GetGatewayStatusReport(c *gin.Context) {
 // @Produce  json
 // @Tags status
 // @Param   object      body   models.BatchGatewayStatus     true  "object"
-// @Success 201
-// @Failure 400 {object} models.Error
+// @Param   Content-Encoding header string false "gzip to send a compressed body"
+// @Param   Idempotency-Key header string false "Unique key of the batch, retrying it with the same key doesn't store it twice"
+// @Success 201 {object} models.OK
+// @Failure 400 {object} models.ValidationError
 // @Failure 403 {object} models.Error
+// @Failure 409 {object} models.Error
+// @Failure 413 {object} models.Error
 // @Failure 404 {object} models.Error
 // @Failure 500 {object} models.Error
+// @Failure 503 {object} models.Error
 // @Router /api/status/gateway/batch [post]
 func (controller *controller) BatchCreateGatewayStatus(c *gin.Context) {
-	remoteIP := c.ClientIP()
-	if !(remoteIP == "127.0.0.1" || remoteIP == "::1" || c.Request.RemoteAddr == "127.0.0.1" || c.Request.RemoteAddr == "::1") {
-		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+	if !isTrustedSource(c) {
+		respondWithError(c, http.StatusForbidden, "forbidden")
 		return
 	}
 	var status models.BatchGatewayStatus
 	if err := c.ShouldBindJSON(&status); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if isBodyTooLarge(err) {
+			respondWithError(c, http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		respondWithBindingError(c, err, status)
+		return
+	}
+	if len(status.Status) > controller.maxBatchSize {
+		respondWithError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("batch contains %d statuses, at most %d are allowed", len(status.Status), controller.maxBatchSize))
+		return
+	}
+	if fields := validateBatchEntries(status.Status); len(fields) > 0 {
+		respondWithValidationError(c, invalidBatchMessage, fields)
 		return
 	}
 
 	sanitized := controller.batchGatewaySanitizer.Sanitize(status)
-	persisted := controller.service.BatchCreateGatewayStatus(sanitized)
-	controller.service.SaveBatchGatewayStatusReport(persisted)
+	if fields := controller.batchIPVersionErrors(len(sanitized.Status), func(i int) string { return sanitized.Status[i].IPVersion }); len(fields) > 0 {
+		respondWithValidationError(c, invalidBatchMessage, fields)
+		return
+	}
+	for _, gatewayStatus := range sanitized.Status {
+		controller.warnUnknownIPVersion(gatewayStatus.PubKey, gatewayStatus.IPVersion)
+	}
+	persisted, err := controller.service.BatchCreateGatewayStatus(sanitized)
+	if err != nil {
+		respondWithError(c, http.StatusServiceUnavailable, storeFailedMessage)
+		return
+	}
+	// the statuses are already stored, so the report must get updated even if the client goes away in the meantime
+	controller.service.SaveBatchGatewayStatusReport(context.Background(), persisted)
 
-	c.JSON(http.StatusCreated, gin.H{"ok": true})
+	c.JSON(http.StatusCreated, models.OK{OK: true})
 }
 
 // BatchGetGatewayStatusReport ...
 // @Summary Retrieves a summary report of historical gateway status
-// @Description Provides summary uptime statistics for last 5 minutes, day, week, and month
+// @Description Provides summary uptime statistics for last 5 minutes, day, week, and month. An uptime of -1 means there weren't enough statuses during the window to tell, which includes windows without any status. Earlier versions reported 0 for those.
 // @ID batchGetGatewayStatusReport
 // @Accept  json
 // @Produce  json
 // @Tags status
-// @Success 200
+// @Param If-None-Match header string false "ETag of a previously retrieved report"
+// @Param fields query string false "Comma separated json names of the only report fields to include, e.g. pubKey,lastHourIPV4"
+// @Success 200 {object} models.BatchGatewayStatusReport
+// @Success 304
 // @Failure 400 {object} models.Error
 // @Failure 404 {object} models.Error
 // @Failure 500 {object} models.Error
+// @Failure 503 {object} models.Error
 // @Router /api/status/fullgatewayreport [get]
 func (controller *controller) BatchGetGatewayStatusReport(c *gin.Context) {
-	report := controller.service.BatchGetGatewayStatusReport()
-	c.JSON(http.StatusOK, report)
+	fields, ok := requestedFields[models.GatewayStatusReport](c)
+	if !ok {
+		return
+	}
+	report := controller.service.BatchGetGatewayStatusReport(c.Request.Context())
+	if queriesTimedOut(c) {
+		return
+	}
+	respondWithReports(c, fields, report, report.Report)
+}
+
+// GetStats ...
+// @Summary Tells how many statuses are stored
+// @Description Provides the number of stored mix and gateway statuses, the number of nodes active during the last day and the timestamp of the oldest status still retained. lastReportUpdate tells when the reports were last updated, if it falls behind by more than a few 10 minute intervals, the reports are going stale. ingestionLag summarises how long the mix statuses received during the last hour took to get from the monitor to the server, going by the measuredAt the monitor set on them
+// @ID getStats
+// @Accept  json
+// @Produce  json
+// @Tags status
+// @Success 200 {object} models.StatusStats
+// @Failure 500 {object} models.Error
+// @Router /api/status/stats [get]
+func (controller *controller) GetStats(c *gin.Context) {
+	c.JSON(http.StatusOK, controller.service.GetStats(c.Request.Context()))
 }
+
+// ListOwners ...
+// @Summary Lists all known node owners
+// @Description Provides the sorted list of every distinct owner that submitted a mix or a gateway status still retained. Empty owners are left out.
+// @ID listOwners
+// @Accept  json
+// @Produce  json
+// @Tags status
+// @Param envelope query bool false "Wrap the owners in a models.Envelope"
+// @Success 200 {array} string
+// @Failure 400 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /api/status/owners [get]
+func (controller *controller) ListOwners(c *gin.Context) {
+	envelope, ok := wantsEnvelope(c)
+	if !ok {
+		return
+	}
+	respondWithList(c, controller.clock, envelope, controller.service.ListOwners(c.Request.Context()))
+}
+
+// ExportData ...
+// @Summary Exports the whole dataset
+// @Description Streams every mix and gateway report, and every retained status if asked for, as newline-delimited models.ExportRecord, so that the service can be backed up while it keeps running. Only available to connections from the local machine, forwarding headers are ignored.
+// @ID exportData
+// @Produce  json
+// @Tags admin
+// @Param statuses query bool false "Include the statuses, not just the reports"
+// @Success 200 {object} models.ExportRecord
+// @Failure 400 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Router /api/admin/export [get]
+func (controller *controller) ExportData(c *gin.Context) {
+	if !isLocalConnection(c) {
+		respondWithError(c, http.StatusForbidden, "forbidden")
+		return
+	}
+	statuses, err := strconv.ParseBool(c.DefaultQuery("statuses", "false"))
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "statuses must be either true or false")
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+	encoder := json.NewEncoder(c.Writer)
+	err = controller.service.ExportData(c.Request.Context(), statuses, func(record models.ExportRecord) error {
+		return encoder.Encode(record)
+	})
+	if err != nil {
+		// the response is already underway, so it just ends early
+		logrus.WithError(err).Error("failed to export the data")
+	}
+}
+
+// ImportData ...
+// @Summary Imports an export into a fresh database
+// @Description Stores the newline-delimited models.ExportRecord of an export, e.g. to restore a backup or to move to another database. The database mustn't hold any statuses yet. Records stored before a failure stay stored. Only available to connections from the local machine, forwarding headers are ignored.
+// @ID importData
+// @Accept  json
+// @Produce  json
+// @Tags admin
+// @Success 200 {object} models.Import
+// @Failure 400 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 409 {object} models.Error
+// @Failure 503 {object} models.Error
+// @Router /api/admin/import [post]
+func (controller *controller) ImportData(c *gin.Context) {
+	if !isLocalConnection(c) {
+		respondWithError(c, http.StatusForbidden, "forbidden")
+		return
+	}
+
+	decoder := json.NewDecoder(c.Request.Body)
+	next := func() (models.ExportRecord, error) {
+		var record models.ExportRecord
+		err := decoder.Decode(&record)
+		if err != nil && err != io.EOF {
+			err = fmt.Errorf("%w: %v", errInvalidRecord, err)
+		}
+		return record, err
+	}
+	// same as with the recomputations, don't stop halfway through if the client goes away
+	imported, err := controller.service.ImportData(context.Background(), next)
+	switch {
+	case errors.Is(err, errDatabaseNotEmpty):
+		respondWithError(c, http.StatusConflict, err.Error())
+	case errors.Is(err, errInvalidRecord):
+		respondWithError(c, http.StatusBadRequest, err.Error())
+	case err != nil:
+		respondWithError(c, http.StatusServiceUnavailable, storeFailedMessage)
+	default:
+		c.JSON(http.StatusOK, imported)
+	}
+}
+
+// GetPayloadSchema ...
+// @Summary Describes the accepted status payloads
+// @Description Provides a JSON Schema of every mix and gateway status payload, generated from the same struct tags the handlers bind with. Meant for monitors written in other languages.
+// @ID getPayloadSchema
+// @Accept  json
+// @Produce  json
+// @Tags status
+// @Success 200 {object} models.JSONSchema
+// @Router /api/status/schema [get]
+func (controller *controller) GetPayloadSchema(c *gin.Context) {
+	c.JSON(http.StatusOK, models.PayloadSchema())
+}
+
+// RetractGatewayStatus ...
+// @Summary Retracts a gateway status
+// @Description Same as retracting a mixnode status, but for gateways. Only available to connections from the local machine, forwarding headers are ignored.
+// @ID retractGatewayStatus
+// @Accept  json
+// @Produce  json
+// @Tags admin
+// @Param pubkey path string true "Gateway Pubkey"
+// @Param ipversion path string true "IP version of the status"
+// @Param timestamp path int true "Timestamp of the status"
+// @Success 200 {object} models.OK
+// @Failure 400 {object} models.Error
+// @Failure 403 {object} models.Error
+// @Failure 404 {object} models.Error
+// @Failure 500 {object} models.Error
+// @Router /api/admin/gateway/{pubkey}/statuses/{ipversion}/{timestamp} [delete]
+func (controller *controller) RetractGatewayStatus(c *gin.Context) {
+	if !isLocalConnection(c) {
+		respondWithError(c, http.StatusForbidden, "forbidden")
+		return
+	}
+	timestamp, err := strconv.ParseInt(c.Param("timestamp"), 10, 64)
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, "timestamp must be an integer")
+		return
+	}
+	if !controller.service.RetractGatewayStatus(context.Background(), controller.pubkeyParam(c), c.Param("ipversion"), timestamp) {
+		respondWithError(c, http.StatusNotFound, "not found")
+		return
+	}
+	c.JSON(http.StatusOK, models.OK{OK: true})
+}
+
+// isTrustedSource checks whether the request came from the local machine, which is where the network monitor runs.
+func isTrustedSource(c *gin.Context) bool {
+	return isLoopback(c.ClientIP()) || isLoopback(c.Request.RemoteAddr)
+}
+
+// isLocalConnection checks whether the connection itself comes from the local machine. Unlike isTrustedSource it
+// ignores the X-Forwarded-For and X-Real-Ip headers, which anyone can set, so it guards the admin routes, which dump,
+// overwrite or rewrite the stored data.
+func isLocalConnection(c *gin.Context) bool {
+	return isLoopback(c.Request.RemoteAddr)
+}
+
+// isLoopback checks whether the address is a loopback one. The address may contain a port and an IPv6 zone,
+// and IPv6 addresses may be written in either their compressed or expanded form.
+func isLoopback(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	if zoneStart := strings.IndexByte(host, '%'); zoneStart >= 0 {
+		host = host[:zoneStart]
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// newRateLimiter creates a limiter allowing the given number of requests per second from each client
+// to each route. Clients are told apart by the address they connect from, as headers are trivial to spoof.
+func newRateLimiter(requestsPerSecond float64) gin.HandlerFunc {
+	lmt := tollbooth.NewLimiter(requestsPerSecond, nil)
+	lmt.SetIPLookups([]string{"RemoteAddr"})
+	// reply with the same error body as the handlers do
+	message, _ := json.Marshal(models.Error{Code: http.StatusTooManyRequests, Message: "too many requests"})
+	lmt.SetMessage(string(message)).SetMessageContentType("application/json; charset=utf-8")
+	// a throttled client gets its next request through once the next token comes in, telling it so keeps well
+	// behaved monitors from retrying straight away and staying throttled
+	retryAfter := strconv.Itoa(int(math.Ceil(1 / requestsPerSecond)))
+	return func(c *gin.Context) {
+		if httpError := tollbooth.LimitByRequest(lmt, c.Writer, c.Request); httpError != nil {
+			c.Header("Retry-After", retryAfter)
+			c.Header("X-RateLimit-Remaining", "0")
+			c.Data(httpError.StatusCode, lmt.GetMessageContentType(), []byte(httpError.Message))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// storeFailedMessage is the message of the responses to statuses that couldn't be stored, e.g. because the database
+// stayed locked. Nothing was stored, so the statuses can be sent again.
+const storeFailedMessage = "failed to store the statuses, try again later"
+
+// respondWithError replies with the error body documented for every failure.
+func respondWithError(c *gin.Context, code int, message string) {
+	c.JSON(code, models.Error{Code: code, Message: message})
+}
+
+// boundQueries gives the database queries made while handling the request at most the query timeout to complete
+func (controller *controller) boundQueries(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), controller.queryTimeout)
+	defer cancel()
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+}
+
+// shedLoad turns the request away with 503 if the maximum number of requests are already being handled, so that
+// a flood of slow requests can't pin every database connection and wedge the service
+func (controller *controller) shedLoad(c *gin.Context) {
+	select {
+	case controller.inFlight <- struct{}{}:
+		defer func() { <-controller.inFlight }()
+		c.Next()
+	default:
+		c.Header("Retry-After", loadSheddingRetryAfter)
+		respondWithError(c, http.StatusServiceUnavailable, "too many requests in flight, try again later")
+		c.Abort()
+	}
+}
+
+// queriesTimedOut responds with 503 if the queries of the request ran out of time, as whatever they returned is
+// incomplete then
+func queriesTimedOut(c *gin.Context) bool {
+	if c.Request.Context().Err() != context.DeadlineExceeded {
+		return false
+	}
+	respondWithError(c, http.StatusServiceUnavailable, "the database took too long to answer")
+	return true
+}
+
+// limitBodySize caps the number of bytes that can be read from the request body, so that a huge payload
+// gets rejected before it's fully read into memory.
+func (controller *controller) limitBodySize(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, controller.maxBodyBytes)
+	c.Next()
+}
+
+// isBodyTooLarge checks whether the error was caused by the request body exceeding the size limit.
+func isBodyTooLarge(err error) bool {
+	return strings.Contains(err.Error(), "http: request body too large")
+}
+
+// wantsEnvelope tells whether the client asked for a list to come wrapped in a models.Envelope with ?envelope=true.
+// Lists are bare by default, so that existing clients keep working. It responds with 400 if the parameter is invalid.
+func wantsEnvelope(c *gin.Context) (bool, bool) {
+	return boolQuery(c, "envelope")
+}
+
+// boolQuery reads an optional boolean query parameter, false if it's missing. It responds with 400 if it's invalid.
+func boolQuery(c *gin.Context, name string) (bool, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return false, true
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, fmt.Sprintf("%s must be either true or false", name))
+		return false, false
+	}
+	return parsed, true
+}
+
+// respondWithList responds with the list as it is, or wrapped in a models.Envelope generated at the time of the clock
+func respondWithList[T any](c *gin.Context, clock Clock, envelope bool, items []T) {
+	if !envelope {
+		c.JSON(http.StatusOK, items)
+		return
+	}
+	c.JSON(http.StatusOK, models.Envelope{
+		Data:        items,
+		Count:       len(items),
+		GeneratedAt: clock.Now().UnixNano(),
+	})
+}
+
+// respondWithETag serializes the response and tags it with a hash of its content. Reports only change
+// whenever the updater runs, so if the client already holds the exact same version (as indicated by
+// the If-None-Match header), we reply with 304 and skip sending the (potentially huge) body again.
+func respondWithETag(c *gin.Context, code int, obj interface{}) {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	hash := sha256.Sum256(body)
+	etag := fmt.Sprintf("\"%x\"", hash)
+	c.Header("ETag", etag)
+
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(code, "application/json; charset=utf-8", body)
+}
+
+// etagMatches checks whether any of the comma-separated tags in an If-None-Match header matches the etag.
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
@@ -16,17 +16,30 @@ package mixmining
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"time"
 
 	"github.com/nymtech/node-status-api/models"
 
+	"github.com/BorisBorshevsky/timemock"
 	"github.com/gin-gonic/gin"
+	"github.com/microcosm-cc/bluemonday"
 	"github.com/nymtech/node-status-api/mixmining/fixtures"
 	"github.com/nymtech/node-status-api/mixmining/mocks"
 	. "github.com/onsi/ginkgo"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/net/websocket"
 )
 
 var _ = Describe("Controller", func() {
@@ -36,7 +49,28 @@ var _ = Describe("Controller", func() {
 				router, _, _, _, _ := SetupRouter()
 				badJSON, _ := json.Marshal(fixtures.XSSMixStatus())
 				resp := performNonLocalRequest(router, "POST", "/api/status/mixnode", badJSON)
+				var response models.Error
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
 				assert.Equal(GinkgoT(), 403, resp.Result().StatusCode)
+				assert.Equal(GinkgoT(), models.Error{Code: 403, Message: "forbidden"}, response)
+			})
+		})
+
+		Context("that is missing 'Up'", func() {
+			It("should be rejected, naming the invalid field", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				status := fixtures.GoodMixStatus()
+				status.Up = nil
+				statusJSON, _ := json.Marshal(status)
+
+				resp := performLocalHostRequest(router, "POST", "/api/status/mixnode", statusJSON)
+				var response models.ValidationError
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 400, resp.Code)
+				assert.Equal(GinkgoT(), []models.FieldError{{Field: "up", Reason: "required"}}, response.Fields)
+				mockService.AssertNotCalled(GinkgoT(), "CreateMixStatus", mock.Anything)
 			})
 		})
 
@@ -48,15 +82,19 @@ var _ = Describe("Controller", func() {
 				status.Up = &boolfalse
 
 				savedStatus := fixtures.GoodPersistedMixStatus()
-				savedStatus.Up = &boolfalse
+				savedStatus.Up = false
 
 				mockSanitizer.On("Sanitize", status).Return(status)
-				mockService.On("CreateMixStatus", status).Return(savedStatus)
-				mockService.On("SaveMixStatusReport", savedStatus).Return(models.MixStatusReport{})
+				mockService.On("CreateMixStatus", status).Return(savedStatus, nil)
+				mockService.On("SaveMixStatusReport", mock.Anything, savedStatus).Return(models.MixStatusReport{})
 
 				falseJSON, _ := json.Marshal(status)
 				resp := performLocalHostRequest(router, "POST", "/api/status/mixnode", falseJSON)
+				var response models.OK
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
 				assert.Equal(GinkgoT(), 201, resp.Code)
+				assert.Equal(GinkgoT(), models.OK{OK: true}, response)
 
 			})
 		})
@@ -66,8 +104,8 @@ var _ = Describe("Controller", func() {
 				router, mockService, mockSanitizer, _, _ := SetupRouter()
 
 				mockSanitizer.On("Sanitize", fixtures.XSSMixStatus()).Return(fixtures.GoodMixStatus())
-				mockService.On("CreateMixStatus", fixtures.GoodMixStatus()).Return(fixtures.GoodPersistedMixStatus())
-				mockService.On("SaveMixStatusReport", fixtures.GoodPersistedMixStatus()).Return(models.MixStatusReport{})
+				mockService.On("CreateMixStatus", fixtures.GoodMixStatus()).Return(fixtures.GoodPersistedMixStatus(), nil)
+				mockService.On("SaveMixStatusReport", mock.Anything, fixtures.GoodPersistedMixStatus()).Return(models.MixStatusReport{})
 				badJSON, _ := json.Marshal(fixtures.XSSMixStatus())
 
 				resp := performLocalHostRequest(router, "POST", "/api/status/mixnode", badJSON)
@@ -79,27 +117,251 @@ var _ = Describe("Controller", func() {
 				mockService.AssertCalled(GinkgoT(), "CreateMixStatus", fixtures.GoodMixStatus())
 			})
 		})
+
+		Context("when it can't be stored", func() {
+			It("should respond with 503 and leave the report alone", func() {
+				router, mockService, mockSanitizer, _, _ := SetupRouter()
+
+				mockSanitizer.On("Sanitize", fixtures.GoodMixStatus()).Return(fixtures.GoodMixStatus())
+				mockService.On("CreateMixStatus", fixtures.GoodMixStatus()).Return(fixtures.GoodPersistedMixStatus(), errors.New("database is locked"))
+				body, _ := json.Marshal(fixtures.GoodMixStatus())
+
+				resp := performLocalHostRequest(router, "POST", "/api/status/mixnode", body)
+
+				assert.Equal(GinkgoT(), http.StatusServiceUnavailable, resp.Code)
+				mockService.AssertNotCalled(GinkgoT(), "SaveMixStatusReport", mock.Anything, mock.Anything)
+			})
+		})
+
+		Context("without an owner", func() {
+			It("should be rejected while owners are required", func() {
+				router, mockService, mockSanitizer, _, _ := SetupRouter()
+				status := fixtures.GoodMixStatus()
+				status.Owner = ""
+				mockSanitizer.On("Sanitize", status).Return(status)
+
+				ownerlessJSON, _ := json.Marshal(status)
+				resp := performLocalHostRequest(router, "POST", "/api/status/mixnode", ownerlessJSON)
+				var response models.Error
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 400, resp.Code)
+				assert.Equal(GinkgoT(), "owner is required", response.Message)
+				mockService.AssertNotCalled(GinkgoT(), "CreateMixStatus", mock.Anything)
+			})
+			It("should be saved with a blank owner once owners are optional", func() {
+				router, mockService, mockSanitizer, _, _ := SetupRouterWithConfig(Config{OwnerOptional: true})
+				status := fixtures.GoodMixStatus()
+				status.Owner = ""
+				savedStatus := fixtures.GoodPersistedMixStatus()
+				savedStatus.Owner = ""
+				mockSanitizer.On("Sanitize", status).Return(status)
+				mockService.On("CreateMixStatus", status).Return(savedStatus, nil)
+				mockService.On("SaveMixStatusReport", mock.Anything, savedStatus).Return(models.MixStatusReport{})
+
+				ownerlessJSON, _ := json.Marshal(status)
+				resp := performLocalHostRequest(router, "POST", "/api/status/mixnode", ownerlessJSON)
+
+				assert.Equal(GinkgoT(), 201, resp.Code)
+				mockService.AssertCalled(GinkgoT(), "CreateMixStatus", status)
+			})
+		})
+	})
+
+	Describe("normalizing the identifiers", func() {
+		It("should find a status written with a padded pubkey through the trimmed one", func() {
+			db := NewDb(true)
+			cfg := Config{
+				Service:              newTestService(db),
+				Sanitizer:            NewMixStatusSanitizer(bluemonday.UGCPolicy(), bluemonday.StrictPolicy(), true),
+				ReadRateLimit:        100,
+				NormalizeIdentifiers: true,
+			}
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			New(cfg).RegisterRoutes(router)
+
+			status := fixtures.GoodMixStatus()
+			status.PubKey = "  pubkey2\n"
+			paddedJSON, _ := json.Marshal(status)
+			assert.Equal(GinkgoT(), 201, performLocalHostRequest(router, "POST", "/api/status/mixnode", paddedJSON).Code)
+
+			for _, url := range []string{"/api/status/mixnode/pubkey2/history", "/api/status/mixnode/%20pubkey2%20/history"} {
+				resp := performLocalHostRequest(router, "GET", url, nil)
+				var response []models.PersistedMixStatus
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 200, resp.Code, url)
+				assert.Len(GinkgoT(), response, 1, url)
+				assert.Equal(GinkgoT(), "pubkey2", response[0].PubKey, url)
+			}
+		})
+	})
+
+	Describe("retrieving a report from a slow database", func() {
+		It("should fail with 503 once the query timeout passes rather than hang", func() {
+			router, mockService, _, _, _ := SetupRouterWithConfig(Config{QueryTimeout: 50 * time.Millisecond})
+			mockService.On("GetMixStatusReport", mock.Anything, "key").Run(func(args mock.Arguments) {
+				<-args.Get(0).(context.Context).Done()
+			}).Return(models.MixStatusReport{}, nil)
+
+			start := time.Now()
+			resp := performRequest(router, "GET", "/api/status/mixnode/key/report", nil)
+
+			assert.Equal(GinkgoT(), 503, resp.Code)
+			assert.Less(GinkgoT(), int64(time.Since(start)), int64(time.Second))
+		})
+		It("should serve the full report as usual when the queries complete in time", func() {
+			router, mockService, _, _, _ := SetupRouterWithConfig(Config{QueryTimeout: time.Second})
+			mockService.On("BatchGetMixStatusReport", mock.Anything).Return(models.BatchMixStatusReport{Report: []models.MixStatusReport{}})
+
+			resp := performRequest(router, "GET", "/api/status/fullmixreport", nil)
+			assert.Equal(GinkgoT(), 200, resp.Code)
+		})
+	})
+
+	Describe("handling more requests than allowed at once", func() {
+		It("should turn the extra ones away with 503 while the others are in flight", func() {
+			router, mockService, _, _, _ := SetupRouterWithConfig(Config{ReadRateLimit: 100, MaxConcurrentRequests: 1})
+			entered := make(chan struct{})
+			release := make(chan struct{})
+			mockService.On("ListOwners", mock.Anything).Run(func(args mock.Arguments) {
+				entered <- struct{}{}
+				<-release
+			}).Return([]string{}).Once()
+			mockService.On("ListOwners", mock.Anything).Return([]string{})
+
+			done := make(chan int)
+			go func() {
+				done <- performLocalHostRequest(router, "GET", "/api/status/owners", nil).Code
+			}()
+			<-entered
+
+			resp := performLocalHostRequest(router, "GET", "/api/status/owners", nil)
+			assert.Equal(GinkgoT(), 503, resp.Code)
+			assert.Equal(GinkgoT(), "1", resp.Header().Get("Retry-After"))
+
+			close(release)
+			assert.Equal(GinkgoT(), 200, <-done)
+			assert.Equal(GinkgoT(), 200, performLocalHostRequest(router, "GET", "/api/status/owners", nil).Code)
+		})
 	})
 
 	Describe("retrieving a mix status report (overview)", func() {
 		Context("when a report does not yet exist", func() {
 			It("should 404", func() {
 				router, mockService, _, _, _ := SetupRouter()
-				mockService.On("GetMixStatusReport", fixtures.MixStatusReport().PubKey).Return(models.MixStatusReport{})
+				mockService.On("GetMixStatusReport", mock.Anything, fixtures.MixStatusReport().PubKey).Return(models.MixStatusReport{}, nil)
 				resp := performLocalHostRequest(router, "GET", "/api/status/mixnode/node/key1/report", nil)
 				assert.Equal(GinkgoT(), 404, resp.Result().StatusCode)
 			})
 		})
 
+		Context("when the service has no report for an existing route", func() {
+			It("should only write the 404 response", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				mockService.On("GetMixStatusReport", mock.Anything, "key1").Return(models.MixStatusReport{}, nil)
+				resp := performLocalHostRequest(router, "GET", "/api/status/mixnode/key1/report", nil)
+
+				var response models.Error
+				err := json.Unmarshal([]byte(resp.Body.String()), &response)
+				assert.Nil(GinkgoT(), err)
+				assert.Equal(GinkgoT(), 404, resp.Code)
+				assert.Equal(GinkgoT(), models.Error{Code: 404, Message: "not found"}, response)
+			})
+		})
+
 		Context("when a report exists", func() {
 			It("should return the report", func() {
 				router, mockService, _, _, _ := SetupRouter()
-				mockService.On("GetMixStatusReport", fixtures.MixStatusReport().PubKey).Return(fixtures.MixStatusReport())
+				mockService.On("GetMixStatusReport", mock.Anything, fixtures.MixStatusReport().PubKey).Return(fixtures.MixStatusReport(), nil)
 				resp := performLocalHostRequest(router, "GET", "/api/status/mixnode/key1/report", nil)
 				var response models.MixStatusReport
 				json.Unmarshal([]byte(resp.Body.String()), &response)
+				expected := fixtures.MixStatusReport()
+				expected.Status = models.NodeUp
 				assert.Equal(GinkgoT(), 200, resp.Result().StatusCode)
-				assert.Equal(GinkgoT(), fixtures.MixStatusReport(), response)
+				assert.Equal(GinkgoT(), expected, response)
+			})
+		})
+
+		Context("when the report can't be loaded", func() {
+			It("should return 500 rather than 404", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				mockService.On("GetMixStatusReport", mock.Anything, "key1").Return(models.MixStatusReport{}, errors.New("disk I/O error"))
+				resp := performLocalHostRequest(router, "GET", "/api/status/mixnode/key1/report", nil)
+
+				var response models.Error
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+				assert.Equal(GinkgoT(), 500, resp.Code)
+				assert.Equal(GinkgoT(), models.Error{Code: 500, Message: "failed to load the report"}, response)
+			})
+		})
+
+		Context("when the most recent status of the report is older than the maximum served age", func() {
+			It("should return 410", func() {
+				router, mockService, _, _, _ := SetupRouterWithConfig(Config{MaxServedReportAge: time.Hour})
+				report := fixtures.MixStatusReport()
+				report.MostRecentIPV4Timestamp = timemock.Now().Add(-time.Hour * 2).UnixNano()
+				report.MostRecentIPV6Timestamp = timemock.Now().Add(-time.Hour * 3).UnixNano()
+				mockService.On("GetMixStatusReport", mock.Anything, "key1").Return(report, nil)
+
+				resp := performLocalHostRequest(router, "GET", "/api/status/mixnode/key1/report", nil)
+				assert.Equal(GinkgoT(), 410, resp.Code)
+			})
+		})
+
+		Context("when the clock of the controller is past the maximum served age of the report", func() {
+			It("should return 410 going by that clock", func() {
+				clock := fixedClock{now: time.Now().Add(time.Hour * 2)}
+				router, mockService, _, _, _ := SetupRouterWithConfig(Config{MaxServedReportAge: time.Hour, Clock: clock})
+				report := fixtures.MixStatusReport()
+				report.MostRecentIPV4Timestamp = time.Now().UnixNano()
+				report.MostRecentIPV6Timestamp = time.Now().UnixNano()
+				mockService.On("GetMixStatusReport", mock.Anything, "key1").Return(report, nil)
+
+				resp := performLocalHostRequest(router, "GET", "/api/status/mixnode/key1/report", nil)
+				assert.Equal(GinkgoT(), 410, resp.Code)
+			})
+		})
+
+		Context("once the purge ran", func() {
+			It("should answer 410 for the stale nodes whose report is kept and 404 for the purged ones", func() {
+				db := NewDb(true)
+				service := newTestService(db)
+				gin.SetMode(gin.TestMode)
+				router := gin.New()
+				New(Config{Service: service, ReadRateLimit: 100, WriteRateLimit: 100}).RegisterRoutes(router)
+
+				now := time.Now()
+				lastHeard := map[string]int64{
+					"active": now.Add(-time.Hour).UnixNano(),
+					"stale":  now.Add(-3 * 24 * time.Hour).UnixNano(),
+					"purged": now.Add(-8 * 24 * time.Hour).UnixNano(),
+				}
+				for pubkey, timestamp := range lastHeard {
+					db.AddMixStatus(models.PersistedMixStatus{PubKey: pubkey, Owner: "owner", IPVersion: "4", Up: true, Timestamp: timestamp})
+					db.SaveMixStatusReport(models.MixStatusReport{PubKey: pubkey, Owner: "owner", MostRecentIPV4Timestamp: timestamp})
+				}
+
+				service.StartupPurge()
+
+				assert.Equal(GinkgoT(), 200, performLocalHostRequest(router, "GET", "/api/status/mixnode/active/report", nil).Code)
+				assert.Equal(GinkgoT(), 410, performLocalHostRequest(router, "GET", "/api/status/mixnode/stale/report", nil).Code)
+				assert.Equal(GinkgoT(), 404, performLocalHostRequest(router, "GET", "/api/status/mixnode/purged/report", nil).Code)
+			})
+		})
+
+		Context("when the most recent status of the report is within the maximum served age", func() {
+			It("should return the report", func() {
+				router, mockService, _, _, _ := SetupRouterWithConfig(Config{MaxServedReportAge: time.Hour})
+				report := fixtures.MixStatusReport()
+				report.MostRecentIPV4Timestamp = timemock.Now().Add(-time.Hour * 2).UnixNano()
+				report.MostRecentIPV6Timestamp = timemock.Now().Add(-time.Minute).UnixNano()
+				mockService.On("GetMixStatusReport", mock.Anything, "key1").Return(report, nil)
+
+				resp := performLocalHostRequest(router, "GET", "/api/status/mixnode/key1/report", nil)
+				assert.Equal(GinkgoT(), 200, resp.Code)
 			})
 		})
 	})
@@ -108,7 +370,7 @@ var _ = Describe("Controller", func() {
 		Context("when no statuses have yet been saved", func() {
 			It("returns an empty list", func() {
 				router, mockService, _, _, _ := SetupRouter()
-				mockService.On("ListMixStatus", "foo").Return([]models.PersistedMixStatus{})
+				mockService.On("ListMixStatus", mock.Anything, "foo").Return([]models.PersistedMixStatus{})
 				resp := performLocalHostRequest(router, "GET", "/api/status/mixnode/foo/history", nil)
 
 				assert.Equal(GinkgoT(), 200, resp.Code)
@@ -117,7 +379,7 @@ var _ = Describe("Controller", func() {
 		Context("when some statuses exist", func() {
 			It("should return the list of statuses as json", func() {
 				router, mockService, _, _, _ := SetupRouter()
-				mockService.On("ListMixStatus", "pubkey1").Return(fixtures.MixStatusesList())
+				mockService.On("ListMixStatus", mock.Anything, "pubkey1").Return(fixtures.MixStatusesList())
 				url := "/api/status/mixnode/pubkey1/history"
 				resp := performLocalHostRequest(router, "GET", url, nil)
 				var response []models.PersistedMixStatus
@@ -127,6 +389,143 @@ var _ = Describe("Controller", func() {
 				assert.Equal(GinkgoT(), fixtures.MixStatusesList(), response)
 			})
 		})
+		Context("when asked for an envelope", func() {
+			It("should wrap the statuses in it", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				mockService.On("ListMixStatus", mock.Anything, "pubkey1").Return(fixtures.MixStatusesList())
+				now := Now()
+				resp := performLocalHostRequest(router, "GET", "/api/status/mixnode/pubkey1/history?envelope=true", nil)
+				var response struct {
+					Data        []models.PersistedMixStatus `json:"data"`
+					Count       int                         `json:"count"`
+					GeneratedAt int64                       `json:"generatedAt"`
+				}
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.Equal(GinkgoT(), fixtures.MixStatusesList(), response.Data)
+				assert.Equal(GinkgoT(), len(fixtures.MixStatusesList()), response.Count)
+				assert.Equal(GinkgoT(), now, response.GeneratedAt)
+			})
+			It("should keep an empty list a list", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				mockService.On("ListOwners", mock.Anything).Return([]string{})
+				now := Now()
+				resp := performLocalHostRequest(router, "GET", "/api/status/owners?envelope=true", nil)
+
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.JSONEq(GinkgoT(), fmt.Sprintf(`{"data": [], "count": 0, "generatedAt": %d}`, now), resp.Body.String())
+			})
+		})
+		Context("when only asking for the down statuses", func() {
+			It("should list them for the last day of ipv4 by default", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				down := []models.PersistedMixStatus{{PubKey: "pubkey1", IPVersion: "4", Up: false, Timestamp: 1}}
+				mockService.On("ListMixStatusByState", mock.Anything, "pubkey1", "4", false, 24).Return(down)
+				resp := performLocalHostRequest(router, "GET", "/api/status/mixnode/pubkey1/history?up=false", nil)
+				var response []models.PersistedMixStatus
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.Equal(GinkgoT(), down, response)
+				mockService.AssertNotCalled(GinkgoT(), "ListMixStatus", mock.Anything, mock.Anything)
+			})
+			It("should pass on the ip version and the window", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				mockService.On("ListMixStatusByState", mock.Anything, "pubkey1", "6", true, 3).Return([]models.PersistedMixStatus{})
+				resp := performLocalHostRequest(router, "GET", "/api/status/mixnode/pubkey1/history?up=true&ipVersion=6&hours=3", nil)
+
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.Equal(GinkgoT(), "[]", resp.Body.String())
+			})
+			It("should reject an invalid filter", func() {
+				for _, query := range []string{"up=foomp", "up=false&ipVersion=5", "up=false&hours=0"} {
+					router, mockService, _, _, _ := SetupRouter()
+					resp := performLocalHostRequest(router, "GET", "/api/status/mixnode/pubkey1/history?"+query, nil)
+
+					assert.Equal(GinkgoT(), 400, resp.Code, query)
+					mockService.AssertNotCalled(GinkgoT(), "ListMixStatusByState", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				}
+			})
+		})
+		Context("when the envelope parameter is invalid", func() {
+			It("should return 400", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				resp := performLocalHostRequest(router, "GET", "/api/status/mixnode/pubkey1/history?envelope=foomp", nil)
+
+				assert.Equal(GinkgoT(), 400, resp.Code)
+				mockService.AssertNotCalled(GinkgoT(), "ListMixStatus", mock.Anything, mock.Anything)
+			})
+		})
+	})
+
+	Describe("listing statuses for multiple nodes", func() {
+		Context("without specifying the limit", func() {
+			It("should list as many statuses per node as for a single one", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				history := map[string][]models.PersistedMixStatus{"pubkey1": fixtures.MixStatusesList(), "pubkey2": {}}
+				mockService.On("ListMixStatusBulk", mock.Anything, []string{"pubkey1", "pubkey2"}, MaxHistoryLimit).Return(history)
+				resp := performRequest(router, "POST", "/api/status/mixnode/history/bulk", []byte(`{"pubKeys": ["pubkey1", "pubkey2"]}`))
+
+				var response map[string][]models.PersistedMixStatus
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.Equal(GinkgoT(), history, response)
+			})
+		})
+		Context("with a limit", func() {
+			It("should pass it on", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				mockService.On("ListMixStatusBulk", mock.Anything, []string{"pubkey1"}, 5).Return(map[string][]models.PersistedMixStatus{})
+				resp := performRequest(router, "POST", "/api/status/mixnode/history/bulk", []byte(`{"pubKeys": ["pubkey1"], "limit": 5}`))
+
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				mockService.AssertCalled(GinkgoT(), "ListMixStatusBulk", mock.Anything, []string{"pubkey1"}, 5)
+			})
+		})
+		Context("with too many, no pubkeys or an invalid limit", func() {
+			It("should return 400", func() {
+				tooMany, _ := json.Marshal(models.BulkHistoryRequest{PubKeys: make([]string, MaxBulkHistoryKeys+1)})
+				for _, body := range []string{string(tooMany), `{"pubKeys": []}`, `{}`, `{"pubKeys": ["pubkey1"], "limit": -1}`} {
+					router, mockService, _, _, _ := SetupRouter()
+					resp := performRequest(router, "POST", "/api/status/mixnode/history/bulk", []byte(body))
+					assert.Equal(GinkgoT(), 400, resp.Code, body)
+					mockService.AssertNotCalled(GinkgoT(), "ListMixStatusBulk", mock.Anything, mock.Anything, mock.Anything)
+				}
+			})
+		})
+	})
+
+	Describe("Checking whether a request comes from a trusted source", func() {
+		for _, address := range []string{"127.0.0.1", "127.0.0.1:12345", "::1", "[::1]:12345", "0:0:0:0:0:0:0:1", "[0:0:0:0:0:0:0:1]:12345", "[::1%lo]:12345"} {
+			address := address
+			It(fmt.Sprintf("should accept the loopback address %s", address), func() {
+				assert.True(GinkgoT(), isLoopback(address))
+			})
+		}
+		for _, address := range []string{"1.1.1.1", "1.1.1.1:12345", "[2001:db8::1]:12345", "localhost", ""} {
+			address := address
+			It(fmt.Sprintf("should reject the address %q", address), func() {
+				assert.False(GinkgoT(), isLoopback(address))
+			})
+		}
+
+		Context("when the monitor connects over IPv6", func() {
+			It("should accept its statuses", func() {
+				router, mockService, mockSanitizer, _, _ := SetupRouter()
+				mockSanitizer.On("Sanitize", fixtures.GoodMixStatus()).Return(fixtures.GoodMixStatus())
+				mockService.On("CreateMixStatus", fixtures.GoodMixStatus()).Return(fixtures.GoodPersistedMixStatus(), nil)
+				mockService.On("SaveMixStatusReport", mock.Anything, fixtures.GoodPersistedMixStatus()).Return(models.MixStatusReport{})
+				goodJSON, _ := json.Marshal(fixtures.GoodMixStatus())
+
+				req, _ := http.NewRequest("POST", "/api/status/mixnode", bytes.NewBuffer(goodJSON))
+				req.RemoteAddr = "[0:0:0:0:0:0:0:1]:12345"
+				resp := httptest.NewRecorder()
+				router.ServeHTTP(resp, req)
+
+				assert.Equal(GinkgoT(), 201, resp.Code)
+			})
+		})
 	})
 
 	Describe("Creating batch mix status", func() {
@@ -147,12 +546,12 @@ var _ = Describe("Controller", func() {
 					singleStatusBatch := models.BatchMixStatus{Status: []models.MixStatus{fixtures.GoodMixStatus()}}
 					singleStatusBatch.Status[0].Up = &boolfalse
 
-					savedStatus := []models.PersistedMixStatus{{MixStatus: fixtures.GoodMixStatus(), Timestamp: 1234}}
-					savedStatus[0].Up = &boolfalse
+					savedStatus := []models.PersistedMixStatus{models.NewPersistedMixStatus(fixtures.GoodMixStatus(), 1234)}
+					savedStatus[0].Up = false
 
 					mockBatchSanitizer.On("Sanitize", singleStatusBatch).Return(singleStatusBatch)
-					mockService.On("BatchCreateMixStatus", singleStatusBatch).Return(savedStatus)
-					mockService.On("SaveBatchMixStatusReport", savedStatus).Return(models.BatchMixStatusReport{Report: []models.MixStatusReport{}})
+					mockService.On("BatchCreateMixStatus", singleStatusBatch).Return(savedStatus, nil)
+					mockService.On("SaveBatchMixStatusReport", mock.Anything, savedStatus).Return(models.BatchMixStatusReport{Report: []models.MixStatusReport{}})
 
 					falseJSON, _ := json.Marshal(singleStatusBatch)
 					resp := performLocalHostRequest(router, "POST", "/api/status/mixnode/batch", falseJSON)
@@ -166,11 +565,11 @@ var _ = Describe("Controller", func() {
 					router, mockService, _, _, mockBatchSanitizer := SetupRouter()
 					singleXSSStatusBatch := models.BatchMixStatus{Status: []models.MixStatus{fixtures.XSSMixStatus()}}
 					singleStatusBatch := models.BatchMixStatus{Status: []models.MixStatus{fixtures.GoodMixStatus()}}
-					savedStatus := []models.PersistedMixStatus{{MixStatus: fixtures.GoodMixStatus(), Timestamp: 1234}}
+					savedStatus := []models.PersistedMixStatus{models.NewPersistedMixStatus(fixtures.GoodMixStatus(), 1234)}
 
 					mockBatchSanitizer.On("Sanitize", singleXSSStatusBatch).Return(singleStatusBatch)
-					mockService.On("BatchCreateMixStatus", singleStatusBatch).Return(savedStatus)
-					mockService.On("SaveBatchMixStatusReport", savedStatus).Return(models.BatchMixStatusReport{Report: []models.MixStatusReport{}})
+					mockService.On("BatchCreateMixStatus", singleStatusBatch).Return(savedStatus, nil)
+					mockService.On("SaveBatchMixStatusReport", mock.Anything, savedStatus).Return(models.BatchMixStatusReport{Report: []models.MixStatusReport{}})
 					badJSON, _ := json.Marshal(singleXSSStatusBatch)
 
 					resp := performLocalHostRequest(router, "POST", "/api/status/mixnode/batch", badJSON)
@@ -184,14 +583,31 @@ var _ = Describe("Controller", func() {
 			})
 		})
 
+		Context("Containing a status missing 'Up'", func() {
+			It("should reject the batch, naming the invalid field", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				batch := fixtures.GoodBatchMixStatus()
+				batch.Status[2].Up = nil
+				batchJSON, _ := json.Marshal(batch)
+
+				resp := performLocalHostRequest(router, "POST", "/api/status/mixnode/batch", batchJSON)
+				var response models.ValidationError
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 400, resp.Code)
+				assert.Equal(GinkgoT(), []models.FieldError{{Field: "status[2].up", Reason: "required"}}, response.Fields)
+				mockService.AssertNotCalled(GinkgoT(), "BatchCreateMixStatus", mock.Anything)
+			})
+		})
+
 		Context("Containing multiple status data", func() {
 			Context("containing xss", func() {
 				It("should strip the xss attack, save the individual mix status, and update the status report for the given node", func() {
 					router, mockService, _, _, mockBatchSanitizer := SetupRouter()
 
 					mockBatchSanitizer.On("Sanitize", fixtures.XSSBatchMixStatus()).Return(fixtures.GoodBatchMixStatus())
-					mockService.On("BatchCreateMixStatus", fixtures.GoodBatchMixStatus()).Return(fixtures.GoodPersistedBatchMixStatus())
-					mockService.On("SaveBatchMixStatusReport", fixtures.GoodPersistedBatchMixStatus()).Return(models.BatchMixStatusReport{Report: []models.MixStatusReport{}})
+					mockService.On("BatchCreateMixStatus", fixtures.GoodBatchMixStatus()).Return(fixtures.GoodPersistedBatchMixStatus(), nil)
+					mockService.On("SaveBatchMixStatusReport", mock.Anything, fixtures.GoodPersistedBatchMixStatus()).Return(models.BatchMixStatusReport{Report: []models.MixStatusReport{}})
 					badJSON, _ := json.Marshal(fixtures.XSSBatchMixStatus())
 
 					resp := performLocalHostRequest(router, "POST", "/api/status/mixnode/batch", badJSON)
@@ -207,63 +623,1739 @@ var _ = Describe("Controller", func() {
 
 	})
 
-	Describe("Retrieving full batch mix status report", func() {
-		Context("when no reports exist yet", func() {
-			It("should return empty report", func() {
-				router, mockService, _, _, _ := SetupRouter()
-				mockService.On("BatchGetMixStatusReport").Return(models.BatchMixStatusReport{Report: []models.MixStatusReport{}})
-				resp := performLocalHostRequest(router, "GET", "/api/status/fullmixreport", nil)
-				assert.Equal(GinkgoT(), 200, resp.Result().StatusCode)
+	Describe("Creating a batch mix status in part", func() {
+		var db *Db
+		var router *gin.Engine
+		BeforeEach(func() {
+			db = NewDb(true)
+			gin.SetMode(gin.TestMode)
+			router = gin.New()
+			policy := bluemonday.UGCPolicy()
+			New(Config{
+				Service:           newTestService(db),
+				BatchMixSanitizer: NewBatchMixSanitizer(policy, bluemonday.StrictPolicy(), false),
+			}).RegisterRoutes(router)
+		})
 
-				var response models.BatchMixStatusReport
+		Context("with an invalid status among valid ones", func() {
+			It("should store the valid ones and enumerate the invalid one", func() {
+				batch := fixtures.GoodBatchMixStatus()
+				batch.Status[1].PubKey = "broken"
+				batch.Status[1].Up = nil
+				batchJSON, _ := json.Marshal(batch)
+
+				resp := performLocalHostRequest(router, "POST", "/api/status/mixnode/batch?partial=true", batchJSON)
+				var response models.BatchResult
 				json.Unmarshal([]byte(resp.Body.String()), &response)
 
-				assert.Equal(GinkgoT(), len(response.Report), 0)
+				assert.Equal(GinkgoT(), 201, resp.Code)
+				assert.Equal(GinkgoT(), []int{0, 2}, response.Stored)
+				assert.Len(GinkgoT(), response.Failed, 1)
+				assert.Equal(GinkgoT(), 1, response.Failed[0].Index)
+				assert.Equal(GinkgoT(), []models.FieldError{{Field: "status[1].up", Reason: "required"}}, response.Failed[0].Fields)
+
+				assert.Len(GinkgoT(), db.ListMixStatus(context.Background(), batch.Status[0].PubKey, 10), 1)
+				assert.Empty(GinkgoT(), db.ListMixStatus(context.Background(), batch.Status[1].PubKey, 10))
+				assert.Len(GinkgoT(), db.ListMixStatus(context.Background(), batch.Status[2].PubKey, 10), 1)
 			})
 		})
+		Context("with a status missing its owner", func() {
+			It("should name the owner as the invalid field", func() {
+				batch := fixtures.GoodBatchMixStatus()
+				batch.Status[0].Owner = ""
+				batchJSON, _ := json.Marshal(batch)
 
-		Context("when a report exists", func() {
-			It("should return the report", func() {
-				router, mockService, _, _, _ := SetupRouter()
-				reqReport := models.BatchMixStatusReport{Report: []models.MixStatusReport{fixtures.MixStatusReport()}}
-				mockService.On("BatchGetMixStatusReport").Return(reqReport)
-				resp := performLocalHostRequest(router, "GET", "/api/status/fullmixreport", nil)
-				var response models.BatchMixStatusReport
+				resp := performLocalHostRequest(router, "POST", "/api/status/mixnode/batch?partial=true", batchJSON)
+				var response models.BatchResult
 				json.Unmarshal([]byte(resp.Body.String()), &response)
-				assert.Equal(GinkgoT(), 200, resp.Result().StatusCode)
-				assert.Equal(GinkgoT(), reqReport, response)
+
+				assert.Equal(GinkgoT(), 201, resp.Code)
+				assert.Equal(GinkgoT(), []models.FailedStatus{{Index: 0, Error: errMissingOwner.Error(), Fields: []models.FieldError{{Field: "status[0].owner", Reason: "required"}}}}, response.Failed)
+			})
+		})
+		Context("without any valid status", func() {
+			It("should reject the batch", func() {
+				batch := fixtures.GoodBatchMixStatus()
+				for i := range batch.Status {
+					batch.Status[i].IPVersion = ""
+				}
+				batchJSON, _ := json.Marshal(batch)
+
+				resp := performLocalHostRequest(router, "POST", "/api/status/mixnode/batch?partial=true", batchJSON)
+				var response models.ValidationError
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 400, resp.Code)
+				assert.Len(GinkgoT(), response.Fields, len(batch.Status))
+				assert.Equal(GinkgoT(), int64(0), db.CountMixStatuses(context.Background()))
+			})
+		})
+		Context("with an invalid partial flag", func() {
+			It("should return 400", func() {
+				batchJSON, _ := json.Marshal(fixtures.GoodBatchMixStatus())
+
+				resp := performLocalHostRequest(router, "POST", "/api/status/mixnode/batch?partial=maybe", batchJSON)
+				assert.Equal(GinkgoT(), 400, resp.Code)
 			})
 		})
 	})
-})
 
-func SetupRouter() (*gin.Engine, *mocks.IService, *mocks.Sanitizer, *mocks.GenericSanitizer, *mocks.BatchSanitizer) {
-	mockSanitizer := new(mocks.Sanitizer)
-	mockBatchSanitizer := new(mocks.BatchSanitizer)
-	mockGenericSanitizer := new(mocks.GenericSanitizer)
-	mockService := new(mocks.IService)
+	Describe("Handling statuses with an unknown ip version", func() {
+		var db *Db
+		setupRouter := func(mode string) *gin.Engine {
+			db = NewDb(true)
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			policy := bluemonday.UGCPolicy()
+			New(Config{
+				Service:           newTestService(db),
+				Sanitizer:         NewMixStatusSanitizer(policy, bluemonday.StrictPolicy(), false),
+				BatchMixSanitizer: NewBatchMixSanitizer(policy, bluemonday.StrictPolicy(), false),
+				UnknownIPVersions: mode,
+			}).RegisterRoutes(router)
+			return router
+		}
+		unknownStatus := func() []byte {
+			status := fixtures.GoodMixStatus()
+			status.IPVersion = "7"
+			body, _ := json.Marshal(status)
+			return body
+		}
+		unknownBatch := func() []byte {
+			batch := fixtures.GoodBatchMixStatus()
+			batch.Status[1].PubKey = "unknown"
+			batch.Status[1].IPVersion = "7"
+			body, _ := json.Marshal(batch)
+			return body
+		}
 
-	// on startup there will be no nodes
-	mockService.On("MixCount").Return(0)
-	mockService.On("GatewayCount").Return(0)
-	mockService.On("StartupPurge")
+		Context("by default", func() {
+			It("should store them", func() {
+				router := setupRouter("")
 
-	cfg := Config{
-		BatchMixSanitizer: mockBatchSanitizer,
-		GenericSanitizer:  mockGenericSanitizer,
-		Sanitizer:         mockSanitizer,
-		Service:           mockService,
-	}
-	gin.SetMode(gin.TestMode)
-	router := gin.Default()
-	controller := New(cfg)
-	controller.RegisterRoutes(router)
-	return router, mockService, mockSanitizer, mockGenericSanitizer, mockBatchSanitizer
-}
-func performLocalHostRequest(r http.Handler, method, path string, body []byte) *httptest.ResponseRecorder {
-	buf := bytes.NewBuffer(body)
-	req, _ := http.NewRequest(method, path, buf)
-	req.RemoteAddr = "127.0.0.1:12345"
+				resp := performLocalHostRequest(router, "POST", "/api/status/mixnode", unknownStatus())
+
+				assert.Equal(GinkgoT(), 201, resp.Code)
+				assert.Equal(GinkgoT(), int64(1), db.CountMixStatuses(context.Background()))
+			})
+		})
+		Context("when they're to be logged", func() {
+			It("should store them and log a warning for each", func() {
+				hook := logtest.NewGlobal()
+				defer logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+				router := setupRouter(UnknownIPVersionsLog)
+
+				resp := performLocalHostRequest(router, "POST", "/api/status/mixnode/batch", unknownBatch())
+
+				assert.Equal(GinkgoT(), 201, resp.Code)
+				assert.Len(GinkgoT(), db.ListMixStatus(context.Background(), "unknown", 10), 1)
+				assert.Len(GinkgoT(), hook.AllEntries(), 1)
+				assert.Equal(GinkgoT(), logrus.WarnLevel, hook.LastEntry().Level)
+				assert.Equal(GinkgoT(), "unknown", hook.LastEntry().Data["pubKey"])
+				assert.Equal(GinkgoT(), "7", hook.LastEntry().Data["ipVersion"])
+			})
+		})
+		Context("when they're to be rejected", func() {
+			It("should reject a single one", func() {
+				router := setupRouter(UnknownIPVersionsReject)
+
+				resp := performLocalHostRequest(router, "POST", "/api/status/mixnode", unknownStatus())
+				var response models.Error
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 400, resp.Code)
+				assert.Equal(GinkgoT(), errUnknownIPVersion.Error(), response.Message)
+				assert.Equal(GinkgoT(), int64(0), db.CountMixStatuses(context.Background()))
+			})
+			It("should reject a batch with any of them, naming them", func() {
+				router := setupRouter(UnknownIPVersionsReject)
+
+				resp := performLocalHostRequest(router, "POST", "/api/status/mixnode/batch", unknownBatch())
+				var response models.ValidationError
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 400, resp.Code)
+				assert.Equal(GinkgoT(), []models.FieldError{{Field: "status[1].ipVersion", Reason: "oneof=4 6"}}, response.Fields)
+				assert.Equal(GinkgoT(), int64(0), db.CountMixStatuses(context.Background()))
+			})
+			It("should only leave them out of a partial batch", func() {
+				router := setupRouter(UnknownIPVersionsReject)
+
+				resp := performLocalHostRequest(router, "POST", "/api/status/mixnode/batch?partial=true", unknownBatch())
+				var response models.BatchResult
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 201, resp.Code)
+				assert.Equal(GinkgoT(), []int{0, 2}, response.Stored)
+				assert.Equal(GinkgoT(), []models.FailedStatus{{Index: 1, Error: errUnknownIPVersion.Error(), Fields: []models.FieldError{{Field: "status[1].ipVersion", Reason: "oneof=4 6"}}}}, response.Failed)
+				assert.Empty(GinkgoT(), db.ListMixStatus(context.Background(), "unknown", 10))
+			})
+		})
+	})
+
+	Describe("Handling owner changes", func() {
+		var db *Db
+		setupRouter := func(reject bool) *gin.Engine {
+			db = NewDb(true)
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			policy := bluemonday.UGCPolicy()
+			New(Config{
+				Service:            newTestService(db),
+				Sanitizer:          NewMixStatusSanitizer(policy, bluemonday.StrictPolicy(), false),
+				BatchMixSanitizer:  NewBatchMixSanitizer(policy, bluemonday.StrictPolicy(), false),
+				ReadRateLimit:      100,
+				RejectOwnerChanges: reject,
+			}).RegisterRoutes(router)
+
+			body, _ := json.Marshal(fixtures.GoodMixStatus())
+			assert.Equal(GinkgoT(), 201, performLocalHostRequest(router, "POST", "/api/status/mixnode", body).Code)
+			return router
+		}
+		transferred := func() models.MixStatus {
+			status := fixtures.GoodMixStatus()
+			status.Owner = "someone else"
+			return status
+		}
+
+		Context("by default", func() {
+			It("should let the node change hands and record it", func() {
+				router := setupRouter(false)
+				body, _ := json.Marshal(transferred())
+
+				resp := performLocalHostRequest(router, "POST", "/api/status/mixnode", body)
+				assert.Equal(GinkgoT(), 201, resp.Code)
+
+				resp = performRequest(router, "GET", fmt.Sprintf("/api/status/mixnode/%s/ownership", fixtures.GoodMixStatus().PubKey), nil)
+				var changes []models.OwnershipChange
+				json.Unmarshal([]byte(resp.Body.String()), &changes)
+
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.Len(GinkgoT(), changes, 1)
+				assert.Equal(GinkgoT(), fixtures.GoodMixStatus().Owner, changes[0].PreviousOwner)
+				assert.Equal(GinkgoT(), "someone else", changes[0].Owner)
+			})
+		})
+		Context("when owner changes are rejected", func() {
+			It("should reject a single status under another owner", func() {
+				router := setupRouter(true)
+				body, _ := json.Marshal(transferred())
+
+				resp := performLocalHostRequest(router, "POST", "/api/status/mixnode", body)
+				var response models.Error
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 400, resp.Code)
+				assert.Equal(GinkgoT(), errOwnerChange.Error(), response.Message)
+				assert.Equal(GinkgoT(), int64(1), db.CountMixStatuses(context.Background()))
+			})
+			It("should reject a batch with any of them, naming them", func() {
+				router := setupRouter(true)
+				fresh := fixtures.GoodMixStatus()
+				fresh.PubKey = "fresh"
+				batch := models.BatchMixStatus{Status: []models.MixStatus{fixtures.GoodMixStatus(), fresh, transferred()}}
+				body, _ := json.Marshal(batch)
+
+				resp := performLocalHostRequest(router, "POST", "/api/status/mixnode/batch", body)
+				var response models.ValidationError
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 400, resp.Code)
+				assert.Equal(GinkgoT(), []models.FieldError{{Field: "status[2].owner", Reason: "unchanged"}}, response.Fields)
+				assert.Equal(GinkgoT(), int64(1), db.CountMixStatuses(context.Background()))
+			})
+			It("should accept the statuses under the same owner", func() {
+				router := setupRouter(true)
+				body, _ := json.Marshal(fixtures.GoodMixStatus())
+
+				resp := performLocalHostRequest(router, "POST", "/api/status/mixnode", body)
+				assert.Equal(GinkgoT(), 201, resp.Code)
+			})
+		})
+	})
+
+	Describe("Detecting mix uptime drops", func() {
+		Context("without specifying the drop", func() {
+			It("should flag drops of more than 40 points", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				drops := []models.MixUptimeDrop{{PubKey: "key", Owner: "owner", IPVersion: "4", LastHour: 95, Last5Minutes: 10, Drop: 85}}
+				mockService.On("DetectMixUptimeDrops", mock.Anything, DefaultUptimeDropThreshold).Return(drops)
+				resp := performLocalHostRequest(router, "GET", "/api/status/mixnodes/alerts", nil)
+
+				var response []models.MixUptimeDrop
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.Equal(GinkgoT(), drops, response)
+			})
+		})
+		Context("with a custom drop", func() {
+			It("should pass it on", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				mockService.On("DetectMixUptimeDrops", mock.Anything, 25).Return([]models.MixUptimeDrop{})
+				resp := performLocalHostRequest(router, "GET", "/api/status/mixnodes/alerts?drop=25", nil)
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.Equal(GinkgoT(), "[]", resp.Body.String())
+			})
+		})
+		Context("with an invalid drop", func() {
+			It("should return 400", func() {
+				for _, drop := range []string{"-1", "101", "foomp"} {
+					router, mockService, _, _, _ := SetupRouter()
+					resp := performLocalHostRequest(router, "GET", "/api/status/mixnodes/alerts?drop="+drop, nil)
+					assert.Equal(GinkgoT(), 400, resp.Code)
+					mockService.AssertNotCalled(GinkgoT(), "DetectMixUptimeDrops", mock.Anything, mock.Anything)
+				}
+			})
+		})
+	})
+
+	Describe("Getting the lifetime of a node", func() {
+		Context("when the mixnode reported statuses", func() {
+			It("should tell when it was first and last seen", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				lifetime := models.NodeLifetime{FirstSeen: 100, LastSeen: 300, TotalMeasurements: 3}
+				mockService.On("GetMixLifetime", mock.Anything, "key").Return(lifetime)
+				resp := performLocalHostRequest(router, "GET", "/api/status/mixnode/key/lifetime", nil)
+
+				var response models.NodeLifetime
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.Equal(GinkgoT(), lifetime, response)
+			})
+		})
+		Context("when the node has no statuses", func() {
+			It("should return 404", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				mockService.On("GetMixLifetime", mock.Anything, "key").Return(models.NodeLifetime{})
+				mockService.On("GetGatewayLifetime", mock.Anything, "key").Return(models.NodeLifetime{})
+
+				assert.Equal(GinkgoT(), 404, performLocalHostRequest(router, "GET", "/api/status/mixnode/key/lifetime", nil).Code)
+				assert.Equal(GinkgoT(), 404, performLocalHostRequest(router, "GET", "/api/status/gateway/key/lifetime", nil).Code)
+			})
+		})
+	})
+
+	Describe("Listing the network uptime history", func() {
+		Context("without specifying the window", func() {
+			It("should list the samples of the last day", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				samples := []models.NetworkUptimeSample{{Timestamp: 1, MeanUptime: 75, ActiveCount: 2}}
+				mockService.On("ListNetworkUptimeHistory", mock.Anything, 24).Return(samples)
+				resp := performLocalHostRequest(router, "GET", "/api/status/network/history", nil)
+
+				var response []models.NetworkUptimeSample
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.Equal(GinkgoT(), samples, response)
+			})
+		})
+		Context("with an invalid window", func() {
+			It("should return 400", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				resp := performLocalHostRequest(router, "GET", "/api/status/network/history?hours=0", nil)
+				assert.Equal(GinkgoT(), 400, resp.Code)
+				mockService.AssertNotCalled(GinkgoT(), "ListNetworkUptimeHistory", mock.Anything, mock.Anything)
+			})
+		})
+	})
+
+	Describe("Aggregating mix uptime", func() {
+		Context("without specifying the window", func() {
+			It("should aggregate over the last day", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				aggregate := models.MixUptimeAggregate{Hours: 24, Nodes: 1, IPV4: models.UptimeStatistics{Mean: 90, Median: 90, Min: 90, Max: 90}}
+				mockService.On("AggregateMixUptime", mock.Anything, 24).Return(aggregate)
+				resp := performLocalHostRequest(router, "GET", "/api/status/mixnodes/aggregate", nil)
+
+				var response models.MixUptimeAggregate
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.Equal(GinkgoT(), aggregate, response)
+			})
+		})
+		Context("with a window longer than the retention period", func() {
+			It("should cap it at the retention period", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				mockService.On("AggregateMixUptime", mock.Anything, 168).Return(models.MixUptimeAggregate{Hours: 168})
+				resp := performLocalHostRequest(router, "GET", "/api/status/mixnodes/aggregate?hours=1000", nil)
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				mockService.AssertCalled(GinkgoT(), "AggregateMixUptime", mock.Anything, 168)
+			})
+		})
+		Context("with an invalid window", func() {
+			It("should return 400", func() {
+				for _, hours := range []string{"0", "-5", "foomp"} {
+					router, mockService, _, _, _ := SetupRouter()
+					resp := performLocalHostRequest(router, "GET", "/api/status/mixnodes/aggregate?hours="+hours, nil)
+					assert.Equal(GinkgoT(), 400, resp.Code)
+					mockService.AssertNotCalled(GinkgoT(), "AggregateMixUptime", mock.Anything, mock.Anything)
+				}
+			})
+		})
+	})
+
+	Describe("Retrieving the uptime of a mixnode at a past instant", func() {
+		Context("without specifying the window or ip version", func() {
+			It("should calculate the ipv4 uptime over the hour up to the timestamp", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				mockService.On("CalculateMixUptimeAt", mock.Anything, "foo", "4", int64(1000), time.Hour).Return(75)
+				resp := performLocalHostRequest(router, "GET", "/api/status/mixnode/foo/uptime-at?timestamp=1000", nil)
+
+				var response models.MixUptimeAt
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.Equal(GinkgoT(), models.MixUptimeAt{PubKey: "foo", IPVersion: "4", Timestamp: 1000, WindowMinutes: 60, Uptime: 75}, response)
+			})
+		})
+		Context("with a window longer than the retention period", func() {
+			It("should cap it at the retention period", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				mockService.On("CalculateMixUptimeAt", mock.Anything, "foo", "6", int64(1000), StatusRetention).Return(-1)
+				resp := performLocalHostRequest(router, "GET", "/api/status/mixnode/foo/uptime-at?timestamp=1000&window=100000&ipVersion=6", nil)
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				mockService.AssertCalled(GinkgoT(), "CalculateMixUptimeAt", mock.Anything, "foo", "6", int64(1000), StatusRetention)
+			})
+		})
+		Context("with invalid parameters", func() {
+			It("should return 400", func() {
+				for _, query := range []string{"", "timestamp=foomp", "timestamp=-1", "timestamp=1000&window=0", "timestamp=1000&ipVersion=5"} {
+					router, mockService, _, _, _ := SetupRouter()
+					resp := performLocalHostRequest(router, "GET", "/api/status/mixnode/foo/uptime-at?"+query, nil)
+					assert.Equal(GinkgoT(), 400, resp.Code)
+					mockService.AssertNotCalled(GinkgoT(), "CalculateMixUptimeAt", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				}
+			})
+		})
+	})
+
+	Describe("Rate limiting", func() {
+		Context("when a client exceeds its limit", func() {
+			It("should not throttle other clients", func() {
+				router, mockService, _, _, _ := SetupRouterWithConfig(Config{ReadRateLimit: 1})
+				mockService.On("ListMixStatus", mock.Anything, "pubkey1").Return(fixtures.MixStatusesList())
+
+				assert.Equal(GinkgoT(), 200, performNonLocalRequest(router, "GET", "/api/status/mixnode/pubkey1/history", nil).Code)
+				assert.Equal(GinkgoT(), 429, performNonLocalRequest(router, "GET", "/api/status/mixnode/pubkey1/history", nil).Code)
+				assert.Equal(GinkgoT(), 200, performLocalHostRequest(router, "GET", "/api/status/mixnode/pubkey1/history", nil).Code)
+			})
+		})
+
+		Context("when a client is throttled", func() {
+			It("should reply with the error body", func() {
+				router, mockService, _, _, _ := SetupRouterWithConfig(Config{ReadRateLimit: 1})
+				mockService.On("ListMixStatus", mock.Anything, "pubkey1").Return(fixtures.MixStatusesList())
+
+				performNonLocalRequest(router, "GET", "/api/status/mixnode/pubkey1/history", nil)
+				resp := performNonLocalRequest(router, "GET", "/api/status/mixnode/pubkey1/history", nil)
+				var response models.Error
+				err := json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Nil(GinkgoT(), err)
+				assert.Equal(GinkgoT(), 429, resp.Code)
+				assert.Equal(GinkgoT(), 429, response.Code)
+				assert.Equal(GinkgoT(), "application/json; charset=utf-8", resp.Header().Get("Content-Type"))
+			})
+		})
+
+		Context("when a client exceeds its limit", func() {
+			It("should tell it how long to back off for", func() {
+				router, mockService, _, _, _ := SetupRouterWithConfig(Config{ReadRateLimit: 0.5})
+				mockService.On("ListMixStatus", mock.Anything, "pubkey1").Return(fixtures.MixStatusesList())
+
+				allowed := performNonLocalRequest(router, "GET", "/api/status/mixnode/pubkey1/history", nil)
+				assert.Equal(GinkgoT(), "", allowed.Header().Get("Retry-After"))
+
+				resp := performNonLocalRequest(router, "GET", "/api/status/mixnode/pubkey1/history", nil)
+				assert.Equal(GinkgoT(), 429, resp.Code)
+				assert.Equal(GinkgoT(), "2", resp.Header().Get("Retry-After"))
+				assert.Equal(GinkgoT(), "0", resp.Header().Get("X-RateLimit-Remaining"))
+			})
+		})
+
+		Context("when reads are being throttled", func() {
+			It("should still accept status submissions from the same client", func() {
+				router, mockService, mockSanitizer, _, _ := SetupRouterWithConfig(Config{ReadRateLimit: 1})
+				mockService.On("ListMixStatus", mock.Anything, "pubkey1").Return(fixtures.MixStatusesList())
+				mockSanitizer.On("Sanitize", fixtures.GoodMixStatus()).Return(fixtures.GoodMixStatus())
+				mockService.On("CreateMixStatus", fixtures.GoodMixStatus()).Return(fixtures.GoodPersistedMixStatus(), nil)
+				mockService.On("SaveMixStatusReport", mock.Anything, fixtures.GoodPersistedMixStatus()).Return(models.MixStatusReport{})
+				goodJSON, _ := json.Marshal(fixtures.GoodMixStatus())
+
+				performLocalHostRequest(router, "GET", "/api/status/mixnode/pubkey1/history", nil)
+				assert.Equal(GinkgoT(), 429, performLocalHostRequest(router, "GET", "/api/status/mixnode/pubkey1/history", nil).Code)
+				assert.Equal(GinkgoT(), 201, performLocalHostRequest(router, "POST", "/api/status/mixnode", goodJSON).Code)
+			})
+		})
+	})
+
+	Describe("Validating a batch mix status", func() {
+		Context("when every status is fine", func() {
+			It("should return the sanitized statuses without saving them", func() {
+				router, mockService, _, _, mockBatchSanitizer := SetupRouter()
+				mockBatchSanitizer.On("Sanitize", fixtures.XSSBatchMixStatus()).Return(fixtures.GoodBatchMixStatus())
+				badJSON, _ := json.Marshal(fixtures.XSSBatchMixStatus())
+
+				resp := performNonLocalRequest(router, "POST", "/api/status/mixnode/batch/validate", badJSON)
+				var response models.BatchMixStatusValidation
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.Len(GinkgoT(), response.Status, 3)
+				for i, validated := range response.Status {
+					assert.True(GinkgoT(), validated.Valid)
+					assert.Equal(GinkgoT(), fixtures.GoodBatchMixStatus().Status[i], validated.Status)
+				}
+				mockService.AssertNotCalled(GinkgoT(), "BatchCreateMixStatus", mock.Anything)
+				mockService.AssertNotCalled(GinkgoT(), "SaveBatchMixStatusReport", mock.Anything, mock.Anything)
+			})
+		})
+
+		Context("when a status is missing 'Up'", func() {
+			It("should flag just that status as invalid", func() {
+				router, _, _, _, mockBatchSanitizer := SetupRouter()
+				batch := fixtures.GoodBatchMixStatus()
+				batch.Status[1].Up = nil
+				mockBatchSanitizer.On("Sanitize", batch).Return(batch)
+				batchJSON, _ := json.Marshal(batch)
+
+				resp := performLocalHostRequest(router, "POST", "/api/status/mixnode/batch/validate", batchJSON)
+				var response models.BatchMixStatusValidation
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.True(GinkgoT(), response.Status[0].Valid)
+				assert.False(GinkgoT(), response.Status[1].Valid)
+				assert.Contains(GinkgoT(), response.Status[1].Error, "Up")
+				assert.True(GinkgoT(), response.Status[2].Valid)
+			})
+		})
+
+		Context("when a status is missing its owner", func() {
+			It("should flag it as invalid while owners are required", func() {
+				router, _, _, _, mockBatchSanitizer := SetupRouter()
+				batch := fixtures.GoodBatchMixStatus()
+				batch.Status[0].Owner = ""
+				mockBatchSanitizer.On("Sanitize", batch).Return(batch)
+				batchJSON, _ := json.Marshal(batch)
+
+				resp := performLocalHostRequest(router, "POST", "/api/status/mixnode/batch/validate", batchJSON)
+				var response models.BatchMixStatusValidation
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.False(GinkgoT(), response.Status[0].Valid)
+				assert.Equal(GinkgoT(), "owner is required", response.Status[0].Error)
+				assert.True(GinkgoT(), response.Status[1].Valid)
+			})
+		})
+
+		Context("with the sanitizers of the server", func() {
+			It("should flag the statuses that creating the batch would reject", func() {
+				gin.SetMode(gin.TestMode)
+				router := gin.New()
+				policy, identifierPolicy := bluemonday.UGCPolicy(), bluemonday.StrictPolicy()
+				db := NewDb(true)
+				New(Config{
+					Service:           newTestService(db),
+					BatchMixSanitizer: NewBatchMixSanitizer(policy, identifierPolicy, false),
+				}).RegisterRoutes(router)
+
+				for _, status := range []string{
+					`{"pubKey":"key","owner":"owner","ipVersion":"4"}`,
+					`{"pubKey":"<b></b>","owner":"owner","ipVersion":"4","up":true}`,
+				} {
+					batchJSON := []byte(`{"status":[` + status + `]}`)
+
+					resp := performLocalHostRequest(router, "POST", "/api/status/mixnode/batch/validate", batchJSON)
+					var response models.BatchMixStatusValidation
+					json.Unmarshal([]byte(resp.Body.String()), &response)
+					assert.Equal(GinkgoT(), 200, resp.Code)
+					assert.False(GinkgoT(), response.Status[0].Valid, status)
+
+					resp = performLocalHostRequest(router, "POST", "/api/status/mixnode/batch", batchJSON)
+					assert.Equal(GinkgoT(), 400, resp.Code, status)
+				}
+				assert.Equal(GinkgoT(), int64(0), db.CountMixStatuses(context.Background()))
+			})
+		})
+
+		Context("when the batch isn't valid json", func() {
+			It("should return 400", func() {
+				router, _, _, _, mockBatchSanitizer := SetupRouter()
+				resp := performLocalHostRequest(router, "POST", "/api/status/mixnode/batch/validate", []byte("{"))
+
+				assert.Equal(GinkgoT(), 400, resp.Code)
+				mockBatchSanitizer.AssertNotCalled(GinkgoT(), "Sanitize", mock.Anything)
+			})
+		})
+	})
+
+	Describe("Creating an oversized batch mix status", func() {
+		Context("with more statuses than allowed", func() {
+			It("should reject it with 413 before sanitizing or saving anything", func() {
+				router, mockService, _, _, mockBatchSanitizer := SetupRouterWithConfig(Config{MaxBatchSize: 2})
+				goodJSON, _ := json.Marshal(fixtures.GoodBatchMixStatus())
+				resp := performLocalHostRequest(router, "POST", "/api/status/mixnode/batch", goodJSON)
+
+				assert.Equal(GinkgoT(), 413, resp.Code)
+				mockBatchSanitizer.AssertNotCalled(GinkgoT(), "Sanitize", mock.Anything)
+				mockService.AssertNotCalled(GinkgoT(), "BatchCreateMixStatus", mock.Anything)
+			})
+		})
+
+		Context("with exactly as many statuses as allowed", func() {
+			It("should save the statuses", func() {
+				router, mockService, _, _, mockBatchSanitizer := SetupRouterWithConfig(Config{MaxBatchSize: 3})
+				mockBatchSanitizer.On("Sanitize", fixtures.GoodBatchMixStatus()).Return(fixtures.GoodBatchMixStatus())
+				mockService.On("BatchCreateMixStatus", fixtures.GoodBatchMixStatus()).Return(fixtures.GoodPersistedBatchMixStatus(), nil)
+				mockService.On("SaveBatchMixStatusReport", mock.Anything, fixtures.GoodPersistedBatchMixStatus()).Return(models.BatchMixStatusReport{Report: []models.MixStatusReport{}})
+				goodJSON, _ := json.Marshal(fixtures.GoodBatchMixStatus())
+				resp := performLocalHostRequest(router, "POST", "/api/status/mixnode/batch", goodJSON)
+
+				assert.Equal(GinkgoT(), 201, resp.Code)
+			})
+		})
+
+		Context("with a body bigger than allowed", func() {
+			It("should reject it with 413", func() {
+				goodJSON, _ := json.Marshal(fixtures.GoodBatchMixStatus())
+				router, mockService, _, _, _ := SetupRouterWithConfig(Config{MaxBodyBytes: int64(len(goodJSON) - 1)})
+				resp := performLocalHostRequest(router, "POST", "/api/status/mixnode/batch", goodJSON)
+
+				assert.Equal(GinkgoT(), 413, resp.Code)
+				mockService.AssertNotCalled(GinkgoT(), "BatchCreateMixStatus", mock.Anything)
+			})
+		})
+	})
+
+	Describe("Creating a gzipped batch mix status", func() {
+		Context("with a valid body", func() {
+			It("should store the same statuses as the uncompressed batch", func() {
+				router, mockService, _, _, mockBatchSanitizer := SetupRouter()
+				mockBatchSanitizer.On("Sanitize", fixtures.GoodBatchMixStatus()).Return(fixtures.GoodBatchMixStatus())
+				mockService.On("BatchCreateMixStatus", fixtures.GoodBatchMixStatus()).Return(fixtures.GoodPersistedBatchMixStatus(), nil)
+				mockService.On("SaveBatchMixStatusReport", mock.Anything, fixtures.GoodPersistedBatchMixStatus()).Return(models.BatchMixStatusReport{Report: []models.MixStatusReport{}})
+				goodJSON, _ := json.Marshal(fixtures.GoodBatchMixStatus())
+				resp := performGzippedLocalHostRequest(router, "POST", "/api/status/mixnode/batch", gzipped(goodJSON))
+
+				assert.Equal(GinkgoT(), 201, resp.Code)
+				mockService.AssertCalled(GinkgoT(), "BatchCreateMixStatus", fixtures.GoodBatchMixStatus())
+			})
+		})
+
+		Context("with a body that isn't gzipped", func() {
+			It("should reject it with 400", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				goodJSON, _ := json.Marshal(fixtures.GoodBatchMixStatus())
+				resp := performGzippedLocalHostRequest(router, "POST", "/api/status/mixnode/batch", goodJSON)
+
+				assert.Equal(GinkgoT(), 400, resp.Code)
+				mockService.AssertNotCalled(GinkgoT(), "BatchCreateMixStatus", mock.Anything)
+			})
+		})
+
+		Context("with a body that decompresses into more than allowed", func() {
+			It("should reject it with 413", func() {
+				goodJSON, _ := json.Marshal(fixtures.GoodBatchMixStatus())
+				router, mockService, _, _, _ := SetupRouterWithConfig(Config{MaxBodyBytes: int64(len(goodJSON) - 1)})
+				body := gzipped(goodJSON)
+				assert.Less(GinkgoT(), len(body), len(goodJSON)-1)
+
+				resp := performGzippedLocalHostRequest(router, "POST", "/api/status/mixnode/batch", body)
+				assert.Equal(GinkgoT(), 413, resp.Code)
+				mockService.AssertNotCalled(GinkgoT(), "BatchCreateMixStatus", mock.Anything)
+			})
+		})
+	})
+
+	Describe("Creating a batch mix status with an idempotency key", func() {
+		Context("when the same batch is sent twice", func() {
+			It("should only store it once", func() {
+				router, mockService, _, _, mockBatchSanitizer := SetupRouter()
+				mockBatchSanitizer.On("Sanitize", fixtures.GoodBatchMixStatus()).Return(fixtures.GoodBatchMixStatus())
+				mockService.On("BatchCreateMixStatus", fixtures.GoodBatchMixStatus()).Return(fixtures.GoodPersistedBatchMixStatus(), nil)
+				mockService.On("SaveBatchMixStatusReport", mock.Anything, fixtures.GoodPersistedBatchMixStatus()).Return(models.BatchMixStatusReport{Report: []models.MixStatusReport{}})
+				goodJSON, _ := json.Marshal(fixtures.GoodBatchMixStatus())
+
+				first := performIdempotentLocalHostRequest(router, "/api/status/mixnode/batch", goodJSON, "batch-1")
+				second := performIdempotentLocalHostRequest(router, "/api/status/mixnode/batch", goodJSON, "batch-1")
+
+				assert.Equal(GinkgoT(), 201, first.Code)
+				assert.Equal(GinkgoT(), 201, second.Code)
+				assert.Equal(GinkgoT(), first.Body.String(), second.Body.String())
+				mockService.AssertNumberOfCalls(GinkgoT(), "BatchCreateMixStatus", 1)
+			})
+		})
+
+		Context("when the first attempt failed", func() {
+			It("should store the retried batch", func() {
+				router, mockService, _, _, mockBatchSanitizer := SetupRouter()
+				mockBatchSanitizer.On("Sanitize", fixtures.GoodBatchMixStatus()).Return(fixtures.GoodBatchMixStatus())
+				mockService.On("BatchCreateMixStatus", fixtures.GoodBatchMixStatus()).Return(fixtures.GoodPersistedBatchMixStatus(), nil)
+				mockService.On("SaveBatchMixStatusReport", mock.Anything, fixtures.GoodPersistedBatchMixStatus()).Return(models.BatchMixStatusReport{Report: []models.MixStatusReport{}})
+				goodJSON, _ := json.Marshal(fixtures.GoodBatchMixStatus())
+
+				failed := performIdempotentLocalHostRequest(router, "/api/status/mixnode/batch", goodJSON[:10], "batch-1")
+				retried := performIdempotentLocalHostRequest(router, "/api/status/mixnode/batch", goodJSON, "batch-1")
+
+				assert.Equal(GinkgoT(), 400, failed.Code)
+				assert.Equal(GinkgoT(), 201, retried.Code)
+				mockService.AssertNumberOfCalls(GinkgoT(), "BatchCreateMixStatus", 1)
+			})
+		})
+
+		Context("when different batches are sent", func() {
+			It("should store both of them", func() {
+				router, mockService, _, _, mockBatchSanitizer := SetupRouter()
+				mockBatchSanitizer.On("Sanitize", fixtures.GoodBatchMixStatus()).Return(fixtures.GoodBatchMixStatus())
+				mockService.On("BatchCreateMixStatus", fixtures.GoodBatchMixStatus()).Return(fixtures.GoodPersistedBatchMixStatus(), nil)
+				mockService.On("SaveBatchMixStatusReport", mock.Anything, fixtures.GoodPersistedBatchMixStatus()).Return(models.BatchMixStatusReport{Report: []models.MixStatusReport{}})
+				goodJSON, _ := json.Marshal(fixtures.GoodBatchMixStatus())
+
+				performIdempotentLocalHostRequest(router, "/api/status/mixnode/batch", goodJSON, "batch-1")
+				performIdempotentLocalHostRequest(router, "/api/status/mixnode/batch", goodJSON, "batch-2")
+
+				mockService.AssertNumberOfCalls(GinkgoT(), "BatchCreateMixStatus", 2)
+			})
+		})
+	})
+
+	Describe("Retrieving stats", func() {
+		It("should return them", func() {
+			router, mockService, _, _, _ := SetupRouter()
+			stats := models.StatusStats{MixStatuses: 3000, GatewayStatuses: 200, ActiveMixnodes: 2, ActiveGateways: 1, OldestStatusTimestamp: 1234, LastReportUpdate: 5678}
+			mockService.On("GetStats", mock.Anything).Return(stats)
+
+			resp := performRequest(router, "GET", "/api/status/stats", nil)
+			var response models.StatusStats
+			json.Unmarshal([]byte(resp.Body.String()), &response)
+
+			assert.Equal(GinkgoT(), 200, resp.Code)
+			assert.Equal(GinkgoT(), stats, response)
+		})
+	})
+
+	Describe("Listing owners", func() {
+		It("should return them", func() {
+			router, mockService, _, _, _ := SetupRouter()
+			mockService.On("ListOwners", mock.Anything).Return([]string{"alice", "bob"})
+
+			resp := performRequest(router, "GET", "/api/status/owners", nil)
+			var response []string
+			json.Unmarshal([]byte(resp.Body.String()), &response)
+
+			assert.Equal(GinkgoT(), 200, resp.Code)
+			assert.Equal(GinkgoT(), []string{"alice", "bob"}, response)
+		})
+	})
+
+	Describe("Retrieving the payload schema", func() {
+		It("should define every status payload", func() {
+			router, _, _, _, _ := SetupRouter()
+
+			resp := performRequest(router, "GET", "/api/status/schema", nil)
+			var response models.JSONSchema
+			json.Unmarshal([]byte(resp.Body.String()), &response)
+
+			assert.Equal(GinkgoT(), 200, resp.Code)
+			assert.ElementsMatch(GinkgoT(), []string{"pubKey", "ipVersion", "up"}, response.Definitions["MixStatus"].Required)
+			assert.Contains(GinkgoT(), response.Definitions, "BatchGatewayStatus")
+		})
+	})
+
+	Describe("Retrieving a mixnode summary", func() {
+		Context("when the node is unknown", func() {
+			It("should return 404", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				mockService.On("GetMixNodeSummary", mock.Anything, "key1").Return(models.MixNodeSummary{})
+
+				resp := performRequest(router, "GET", "/api/status/mixnode/key1/summary", nil)
+				assert.Equal(GinkgoT(), 404, resp.Code)
+			})
+		})
+		Context("when the node is known", func() {
+			It("should return the summary", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				summary := models.MixNodeSummary{
+					Report:               fixtures.MixStatusReport(),
+					TrendIPV4:            models.TrendSteady,
+					TrendIPV6:            models.TrendSteady,
+					MostRecentStatusTime: 1234,
+				}
+				mockService.On("GetMixNodeSummary", mock.Anything, "key1").Return(summary)
+
+				resp := performRequest(router, "GET", "/api/status/mixnode/key1/summary", nil)
+				var response models.MixNodeSummary
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				summary.Report.Status = models.NodeUp
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.Equal(GinkgoT(), summary, response)
+			})
+		})
+	})
+
+	Describe("Comparing two mixnodes", func() {
+		reportB := func() models.MixStatusReport {
+			report := fixtures.MixStatusReport()
+			report.PubKey = "key2"
+			report.Last5MinutesIPV4 = InsufficientData
+			report.LastHourIPV4 = 80
+			report.LastDayIPV4 = 95
+			report.LastHourIPV6 = 50
+			return report
+		}
+		Context("when both nodes are known", func() {
+			It("should return both reports with the differences between their uptimes", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				mockService.On("GetMixStatusReport", mock.Anything, "key1").Return(fixtures.MixStatusReport(), nil)
+				mockService.On("GetMixStatusReport", mock.Anything, "key2").Return(reportB(), nil)
+
+				resp := performRequest(router, "GET", "/api/status/mixnodes/compare?a=key1&b=key2", nil)
+				var response models.MixComparison
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.Equal(GinkgoT(), "key1", response.A.PubKey)
+				assert.Equal(GinkgoT(), "key2", response.B.PubKey)
+				assert.Equal(GinkgoT(), models.NodeDegraded, response.B.Status)
+				assert.Equal(GinkgoT(), models.Uptimes{LastHourWindow: -20, LastDayWindow: -5}, response.DeltasIPV4)
+				assert.Empty(GinkgoT(), response.DeltasIPV6, "neither of them reported ipv6 statuses")
+			})
+			It("should compare their ipv6 uptimes if both reported ipv6 statuses", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				reportA := fixtures.MixStatusReport()
+				reportA.HasIPV6Data = true
+				withIPV6 := reportB()
+				withIPV6.HasIPV6Data = true
+				mockService.On("GetMixStatusReport", mock.Anything, "key1").Return(reportA, nil)
+				mockService.On("GetMixStatusReport", mock.Anything, "key2").Return(withIPV6, nil)
+
+				resp := performRequest(router, "GET", "/api/status/mixnodes/compare?a=key1&b=key2", nil)
+				var response models.MixComparison
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.Equal(GinkgoT(), models.Uptimes{Last5MinutesWindow: 0, LastHourWindow: -50, LastDayWindow: 0}, response.DeltasIPV6)
+			})
+		})
+		Context("when one of the nodes is unknown", func() {
+			It("should return 404 telling which one", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				mockService.On("GetMixStatusReport", mock.Anything, "key1").Return(fixtures.MixStatusReport(), nil)
+				mockService.On("GetMixStatusReport", mock.Anything, "key3").Return(models.MixStatusReport{}, nil)
+
+				resp := performRequest(router, "GET", "/api/status/mixnodes/compare?a=key1&b=key3", nil)
+				var response models.Error
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 404, resp.Code)
+				assert.Equal(GinkgoT(), "mixnode b (key3) not found", response.Message)
+			})
+		})
+		Context("when a pubkey is missing", func() {
+			It("should return 400", func() {
+				router, mockService, _, _, _ := SetupRouter()
+
+				resp := performRequest(router, "GET", "/api/status/mixnodes/compare?a=key1", nil)
+				assert.Equal(GinkgoT(), 400, resp.Code)
+				mockService.AssertNotCalled(GinkgoT(), "GetMixStatusReport", mock.Anything, mock.Anything)
+			})
+		})
+	})
+
+	Describe("Recomputing a mixnode report", func() {
+		Context("from a host other than localhost", func() {
+			It("should fail", func() {
+				router, mockService, _, _, _ := SetupRouter()
+
+				resp := performNonLocalRequest(router, "POST", "/api/admin/mixnode/key1/recompute", nil)
+				assert.Equal(GinkgoT(), 403, resp.Code)
+				mockService.AssertNotCalled(GinkgoT(), "RecomputeMixReport", mock.Anything, mock.Anything)
+			})
+		})
+		Context("from a host only claiming to be forwarded from localhost", func() {
+			It("should fail", func() {
+				router, mockService, _, _, _ := SetupRouter()
+
+				resp := performForwardedLocalHostRequest(router, "POST", "/api/admin/mixnode/key1/recompute")
+				assert.Equal(GinkgoT(), 403, resp.Code)
+				mockService.AssertNotCalled(GinkgoT(), "RecomputeMixReport", mock.Anything, mock.Anything)
+			})
+		})
+		Context("when the node has no statuses", func() {
+			It("should return 404", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				mockService.On("RecomputeMixReport", mock.Anything, "key1").Return(models.MixStatusReport{}, nil)
+
+				resp := performLocalHostRequest(router, "POST", "/api/admin/mixnode/key1/recompute", nil)
+				assert.Equal(GinkgoT(), 404, resp.Code)
+			})
+		})
+		Context("when the node has statuses", func() {
+			It("should return the recomputed report", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				mockService.On("RecomputeMixReport", mock.Anything, "key1").Return(fixtures.MixStatusReport(), nil)
+
+				resp := performLocalHostRequest(router, "POST", "/api/admin/mixnode/key1/recompute", nil)
+				var response models.MixStatusReport
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.Equal(GinkgoT(), fixtures.MixStatusReport(), response)
+			})
+		})
+		Context("when the report can't be saved", func() {
+			It("should return 500", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				mockService.On("RecomputeMixReport", mock.Anything, "key1").Return(models.MixStatusReport{}, errors.New("disk I/O error"))
+
+				resp := performLocalHostRequest(router, "POST", "/api/admin/mixnode/key1/recompute", nil)
+				assert.Equal(GinkgoT(), 500, resp.Code)
+			})
+		})
+	})
+
+	Describe("Retrieving the top mixnodes", func() {
+		Context("without any parameters", func() {
+			It("should return the top 20 by last day IPv4 uptime", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				top := models.BatchMixStatusReport{Report: []models.MixStatusReport{fixtures.MixStatusReport()}}
+				mockService.On("TopMixReports", mock.Anything, "lastDayIPV4", 20).Return(top)
+
+				resp := performRequest(router, "GET", "/api/status/mixnodes/top", nil)
+				var response models.BatchMixStatusReport
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.Equal(GinkgoT(), top, response)
+			})
+		})
+		Context("with a field that isn't allowed", func() {
+			It("should return 400 without querying anything", func() {
+				router, mockService, _, _, _ := SetupRouter()
+
+				resp := performRequest(router, "GET", "/api/status/mixnodes/top?field=owner", nil)
+				assert.Equal(GinkgoT(), 400, resp.Code)
+				mockService.AssertNotCalled(GinkgoT(), "TopMixReports", mock.Anything, mock.Anything, mock.Anything)
+			})
+		})
+		Context("with an invalid n", func() {
+			It("should return 400", func() {
+				router, _, _, _, _ := SetupRouter()
+
+				resp := performRequest(router, "GET", "/api/status/mixnodes/top?n=0", nil)
+				assert.Equal(GinkgoT(), 400, resp.Code)
+			})
+		})
+		Context("with a huge n", func() {
+			It("should cap it", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				mockService.On("TopMixReports", mock.Anything, "lastHourIPV6", MaxReportSize).Return(models.BatchMixStatusReport{Report: []models.MixStatusReport{}})
+
+				resp := performRequest(router, "GET", "/api/status/mixnodes/top?n=1000000&field=lastHourIPV6", nil)
+				assert.Equal(GinkgoT(), 200, resp.Code)
+			})
+		})
+	})
+
+	Describe("Retracting a mix status", func() {
+		Context("when the request doesn't come from a trusted source", func() {
+			It("should return 403", func() {
+				router, mockService, _, _, _ := SetupRouter()
+
+				resp := performNonLocalRequest(router, "DELETE", "/api/admin/mixnode/key1/statuses/4/1234", nil)
+				assert.Equal(GinkgoT(), 403, resp.Code)
+				mockService.AssertNotCalled(GinkgoT(), "RetractMixStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			})
+		})
+		Context("when the request only claims to be forwarded from the local machine", func() {
+			It("should return 403", func() {
+				router, mockService, _, _, _ := SetupRouter()
+
+				resp := performForwardedLocalHostRequest(router, "DELETE", "/api/admin/mixnode/key1/statuses/4/1234")
+				assert.Equal(GinkgoT(), 403, resp.Code)
+				mockService.AssertNotCalled(GinkgoT(), "RetractMixStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			})
+		})
+		Context("with a malformed timestamp", func() {
+			It("should return 400", func() {
+				router, _, _, _, _ := SetupRouter()
+
+				resp := performLocalHostRequest(router, "DELETE", "/api/admin/mixnode/key1/statuses/4/yesterday", nil)
+				assert.Equal(GinkgoT(), 400, resp.Code)
+			})
+		})
+		Context("when there's no such status", func() {
+			It("should return 404", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				mockService.On("RetractMixStatus", mock.Anything, "key1", "4", int64(1234)).Return(false)
+
+				resp := performLocalHostRequest(router, "DELETE", "/api/admin/mixnode/key1/statuses/4/1234", nil)
+				assert.Equal(GinkgoT(), 404, resp.Code)
+			})
+		})
+		Context("when the status exists", func() {
+			It("should retract it", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				mockService.On("RetractMixStatus", mock.Anything, "key1", "4", int64(1234)).Return(true)
+
+				resp := performLocalHostRequest(router, "DELETE", "/api/admin/mixnode/key1/statuses/4/1234", nil)
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				mockService.AssertCalled(GinkgoT(), "RetractMixStatus", mock.Anything, "key1", "4", int64(1234))
+			})
+		})
+	})
+
+	Describe("Retracting a gateway status", func() {
+		It("should retract it", func() {
+			router, mockService, _, _, _ := SetupRouter()
+			mockService.On("RetractGatewayStatus", mock.Anything, "key1", "6", int64(1234)).Return(true)
+
+			resp := performLocalHostRequest(router, "DELETE", "/api/admin/gateway/key1/statuses/6/1234", nil)
+			assert.Equal(GinkgoT(), 200, resp.Code)
+			mockService.AssertCalled(GinkgoT(), "RetractGatewayStatus", mock.Anything, "key1", "6", int64(1234))
+		})
+		It("should not trust a forwarded loopback address", func() {
+			router, mockService, _, _, _ := SetupRouter()
+
+			resp := performForwardedLocalHostRequest(router, "DELETE", "/api/admin/gateway/key1/statuses/6/1234")
+			assert.Equal(GinkgoT(), 403, resp.Code)
+			mockService.AssertNotCalled(GinkgoT(), "RetractGatewayStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	})
+
+	Describe("Backfilling mix reports", func() {
+		Context("when the request doesn't come from a trusted source", func() {
+			It("should return 403", func() {
+				router, mockService, _, _, _ := SetupRouter()
+
+				resp := performNonLocalRequest(router, "POST", "/api/admin/backfill", nil)
+				assert.Equal(GinkgoT(), 403, resp.Code)
+				mockService.AssertNotCalled(GinkgoT(), "BackfillMixReports", mock.Anything)
+			})
+		})
+		Context("when the request only claims to be forwarded from the local machine", func() {
+			It("should return 403", func() {
+				router, mockService, _, _, _ := SetupRouter()
+
+				resp := performForwardedLocalHostRequest(router, "POST", "/api/admin/backfill")
+				assert.Equal(GinkgoT(), 403, resp.Code)
+				mockService.AssertNotCalled(GinkgoT(), "BackfillMixReports", mock.Anything)
+			})
+		})
+		Context("when the request comes from a trusted source", func() {
+			It("should return the backfilled nodes", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				mockService.On("BackfillMixReports", mock.Anything).Return(models.Backfill{PubKeys: []string{"key1"}})
+
+				resp := performLocalHostRequest(router, "POST", "/api/admin/backfill", nil)
+				var response models.Backfill
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.Equal(GinkgoT(), models.Backfill{PubKeys: []string{"key1"}}, response)
+			})
+		})
+	})
+
+	Describe("Recomputing all reports", func() {
+		Context("when the request doesn't come from a trusted source", func() {
+			It("should return 403", func() {
+				router, mockService, _, _, _ := SetupRouter()
+
+				resp := performNonLocalRequest(router, "POST", "/api/admin/recompute-all", nil)
+				assert.Equal(GinkgoT(), 403, resp.Code)
+				mockService.AssertNotCalled(GinkgoT(), "RecomputeAllReports", mock.Anything)
+			})
+		})
+		Context("when the request only claims to be forwarded from the local machine", func() {
+			It("should return 403", func() {
+				router, mockService, _, _, _ := SetupRouter()
+
+				resp := performForwardedLocalHostRequest(router, "POST", "/api/admin/recompute-all")
+				assert.Equal(GinkgoT(), 403, resp.Code)
+				mockService.AssertNotCalled(GinkgoT(), "RecomputeAllReports", mock.Anything)
+			})
+		})
+		Context("when the request comes from a trusted source", func() {
+			It("should return the summary", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				summary := models.Recomputation{Mixnodes: 2, Gateways: 1, Failed: []string{"key1"}}
+				mockService.On("RecomputeAllReports", mock.Anything).Return(summary)
+
+				resp := performLocalHostRequest(router, "POST", "/api/admin/recompute-all", nil)
+				var response models.Recomputation
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.Equal(GinkgoT(), summary, response)
+			})
+		})
+	})
+
+	Describe("Retrieving full batch mix status report", func() {
+		Context("when no reports exist yet", func() {
+			It("should return empty report", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				mockService.On("BatchGetMixStatusReport", mock.Anything).Return(models.BatchMixStatusReport{Report: []models.MixStatusReport{}})
+				resp := performLocalHostRequest(router, "GET", "/api/status/fullmixreport", nil)
+				assert.Equal(GinkgoT(), 200, resp.Result().StatusCode)
+
+				var response models.BatchMixStatusReport
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), len(response.Report), 0)
+			})
+		})
+
+		Context("when a report exists", func() {
+			It("should return the report", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				reqReport := models.BatchMixStatusReport{Report: []models.MixStatusReport{fixtures.MixStatusReport()}}
+				mockService.On("BatchGetMixStatusReport", mock.Anything).Return(reqReport)
+				resp := performLocalHostRequest(router, "GET", "/api/status/fullmixreport", nil)
+				var response models.BatchMixStatusReport
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+				assert.Equal(GinkgoT(), 200, resp.Result().StatusCode)
+				assert.Equal(GinkgoT(), reqReport, response)
+			})
+		})
+
+		Context("when the client accepts gzip encoding", func() {
+			It("should return the compressed report", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				reqReport := models.BatchMixStatusReport{Report: []models.MixStatusReport{fixtures.MixStatusReport()}}
+				mockService.On("BatchGetMixStatusReport", mock.Anything).Return(reqReport)
+
+				req, _ := http.NewRequest("GET", "/api/status/fullmixreport", nil)
+				req.Header.Set("Accept-Encoding", "gzip")
+				resp := httptest.NewRecorder()
+				router.ServeHTTP(resp, req)
+
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.Equal(GinkgoT(), "gzip", resp.Header().Get("Content-Encoding"))
+
+				reader, err := gzip.NewReader(resp.Body)
+				assert.Nil(GinkgoT(), err)
+				body, err := ioutil.ReadAll(reader)
+				assert.Nil(GinkgoT(), err)
+
+				var response models.BatchMixStatusReport
+				json.Unmarshal(body, &response)
+				assert.Equal(GinkgoT(), reqReport, response)
+			})
+		})
+
+		Context("when the compression is turned off", func() {
+			It("should return the report gzip-encoded but uncompressed", func() {
+				level := gzip.NoCompression
+				router, mockService, _, _, _ := SetupRouterWithConfig(Config{CompressionLevel: &level})
+				reqReport := models.BatchMixStatusReport{Report: []models.MixStatusReport{fixtures.MixStatusReport()}}
+				mockService.On("BatchGetMixStatusReport", mock.Anything).Return(reqReport)
+
+				req, _ := http.NewRequest("GET", "/api/status/fullmixreport", nil)
+				req.Header.Set("Accept-Encoding", "gzip")
+				resp := httptest.NewRecorder()
+				router.ServeHTTP(resp, req)
+
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.Equal(GinkgoT(), "gzip", resp.Header().Get("Content-Encoding"))
+				plain, _ := json.Marshal(reqReport)
+				assert.Contains(GinkgoT(), resp.Body.String(), string(plain))
+			})
+		})
+
+		Context("when requested again with the returned ETag", func() {
+			It("should return 304 without a body", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				reqReport := models.BatchMixStatusReport{Report: []models.MixStatusReport{fixtures.MixStatusReport()}}
+				mockService.On("BatchGetMixStatusReport", mock.Anything).Return(reqReport)
+				resp := performLocalHostRequest(router, "GET", "/api/status/fullmixreport", nil)
+				etag := resp.Header().Get("ETag")
+				assert.NotEmpty(GinkgoT(), etag)
+
+				req, _ := http.NewRequest("GET", "/api/status/fullmixreport", nil)
+				req.Header.Set("If-None-Match", etag)
+				secondResp := httptest.NewRecorder()
+				router.ServeHTTP(secondResp, req)
+
+				assert.Equal(GinkgoT(), 304, secondResp.Code)
+				assert.Empty(GinkgoT(), secondResp.Body.String())
+			})
+		})
+
+		Context("when requested with an outdated ETag", func() {
+			It("should return the full report", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				reqReport := models.BatchMixStatusReport{Report: []models.MixStatusReport{fixtures.MixStatusReport()}}
+				mockService.On("BatchGetMixStatusReport", mock.Anything).Return(reqReport)
+
+				req, _ := http.NewRequest("GET", "/api/status/fullmixreport", nil)
+				req.Header.Set("If-None-Match", `"outdated"`)
+				resp := httptest.NewRecorder()
+				router.ServeHTTP(resp, req)
+
+				var response models.BatchMixStatusReport
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.Equal(GinkgoT(), reqReport, response)
+			})
+		})
+
+		Context("when some of the nodes stopped reporting", func() {
+			It("should tell the overall state of each of them", func() {
+				router, mockService, _, _, _ := SetupRouterWithConfig(Config{StatusStaleAfter: 10 * time.Minute})
+				now := timemock.Now()
+				reporting := fixtures.MixStatusReport()
+				reporting.MostRecentIPV4Timestamp = now.Add(-time.Minute).UnixNano()
+				stopped := fixtures.MixStatusReport()
+				stopped.PubKey = "key2"
+				stopped.MostRecentIPV4Timestamp = now.Add(-time.Hour).UnixNano()
+				mockService.On("BatchGetMixStatusReport", mock.Anything).Return(models.BatchMixStatusReport{Report: []models.MixStatusReport{reporting, stopped}})
+
+				resp := performLocalHostRequest(router, "GET", "/api/status/fullmixreport?fields=pubKey,status", nil)
+				var response map[string][]map[string]interface{}
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.Equal(GinkgoT(), []map[string]interface{}{
+					{"pubKey": "key1", "status": models.NodeUp},
+					{"pubKey": "key2", "status": models.NodeStale},
+				}, response["report"])
+			})
+		})
+
+		Context("when only some fields are requested", func() {
+			It("should only return those fields of each report", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				report := fixtures.MixStatusReport()
+				mockService.On("BatchGetMixStatusReport", mock.Anything).Return(models.BatchMixStatusReport{Report: []models.MixStatusReport{report}})
+
+				resp := performLocalHostRequest(router, "GET", "/api/status/fullmixreport?fields=pubKey,%20lastHourIPV4", nil)
+				var response map[string][]map[string]interface{}
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.Equal(GinkgoT(), []map[string]interface{}{{"pubKey": report.PubKey, "lastHourIPV4": float64(report.LastHourIPV4)}}, response["report"])
+			})
+		})
+
+		Context("when an unknown field is requested", func() {
+			It("should be rejected", func() {
+				router, mockService, _, _, _ := SetupRouter()
+
+				resp := performLocalHostRequest(router, "GET", "/api/status/fullmixreport?fields=pubKey,version", nil)
+
+				assert.Equal(GinkgoT(), 400, resp.Code)
+				mockService.AssertNotCalled(GinkgoT(), "BatchGetMixStatusReport", mock.Anything)
+			})
+		})
+	})
+
+	Describe("Retrieving the counts behind the uptimes of a mixnode", func() {
+		Context("when the node has statuses", func() {
+			It("should return the counts of every window", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				raw := models.MixRawUptime{
+					PubKey: "key1",
+					IPV4:   map[string]models.UptimeCount{LastHourWindow: {Up: 998, Total: 1000}},
+					IPV6:   map[string]models.UptimeCount{LastHourWindow: {Up: 0, Total: 0}},
+				}
+				mockService.On("GetMixRawUptime", mock.Anything, "key1").Return(raw)
+
+				resp := performRequest(router, "GET", "/api/status/mixnode/key1/uptime/raw", nil)
+
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.JSONEq(GinkgoT(), `{"pubKey":"key1","ipv4":{"lastHour":{"up":998,"total":1000}},"ipv6":{"lastHour":{"up":0,"total":0}}}`, resp.Body.String())
+			})
+		})
+		Context("when the node has no statuses during any of the windows", func() {
+			It("should return 404", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				empty := map[string]models.UptimeCount{LastHourWindow: {}}
+				mockService.On("GetMixRawUptime", mock.Anything, "key1").Return(models.MixRawUptime{PubKey: "key1", IPV4: empty, IPV6: empty})
+
+				resp := performRequest(router, "GET", "/api/status/mixnode/key1/uptime/raw", nil)
+				assert.Equal(GinkgoT(), 404, resp.Code)
+			})
+		})
+	})
+
+	Describe("Retrieving what was measured during an epoch", func() {
+		Context("when the epoch isn't a non-negative integer", func() {
+			It("should return 400", func() {
+				router, mockService, _, _, _ := SetupRouter()
+
+				resp := performRequest(router, "GET", "/api/status/mixnodes/epochs/-1", nil)
+
+				assert.Equal(GinkgoT(), 400, resp.Code)
+				mockService.AssertNotCalled(GinkgoT(), "GetMixEpochReport", mock.Anything, mock.Anything)
+			})
+		})
+		Context("when nothing was measured during the epoch", func() {
+			It("should return empty lists", func() {
+				db := NewDb(true)
+				gin.SetMode(gin.TestMode)
+				router := gin.New()
+				New(Config{Service: newTestService(db)}).RegisterRoutes(router)
+
+				resp := performRequest(router, "GET", "/api/status/mixnodes/epochs/3", nil)
+
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.JSONEq(GinkgoT(), `{"epoch":3,"uptimes":[],"statuses":[]}`, resp.Body.String())
+			})
+		})
+		Context("when statuses were measured during the epoch", func() {
+			It("should return them along with the uptimes", func() {
+				router, mockService, _, _, _ := SetupRouter()
+				epoch := uint64(3)
+				status := fixtures.GoodPersistedMixStatus()
+				status.Epoch = &epoch
+				report := models.MixEpochReport{
+					Epoch:    epoch,
+					Uptimes:  []models.MixEpochUptime{{PubKey: status.PubKey, Owner: status.Owner, IPVersion: status.IPVersion, Uptime: 100, Measurements: 1}},
+					Statuses: []models.PersistedMixStatus{status},
+				}
+				mockService.On("GetMixEpochReport", mock.Anything, epoch).Return(report)
+
+				resp := performRequest(router, "GET", "/api/status/mixnodes/epochs/3", nil)
+				var response models.MixEpochReport
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.Equal(GinkgoT(), report, response)
+			})
+		})
+	})
+
+	Describe("exporting and importing the dataset", func() {
+		routerFor := func(db *Db) *gin.Engine {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			New(Config{Service: newTestService(db), ReadRateLimit: 100, WriteRateLimit: 100}).RegisterRoutes(router)
+			return router
+		}
+		rtt := 12
+		clientsHostUp := true
+		mixStatuses := []models.PersistedMixStatus{
+			{PubKey: "mix1", Owner: "owner", IPVersion: "4", Up: true, RTTMillis: &rtt, Timestamp: 100, MeasuredAt: 90},
+			{PubKey: "mix1", Owner: "owner", IPVersion: "6", Up: false, Timestamp: 200, MeasuredAt: 200},
+		}
+		gatewayStatuses := []models.PersistedGatewayStatus{
+			{PubKey: "gateway1", Owner: "owner", IPVersion: "4", Up: true, Timestamp: 150, ClientsHostUp: &clientsHostUp},
+		}
+		exportFrom := func(db *Db) []byte {
+			db.BatchAddMixStatus(append(mixStatuses, models.PersistedMixStatus{PubKey: "mix1", Owner: "owner", IPVersion: "4", Timestamp: 300}))
+			db.RetractMixStatus(context.Background(), "mix1", "4", 300)
+			db.BatchAddGatewayStatus(gatewayStatuses)
+			db.SaveBatchMixStatusReport(models.BatchMixStatusReport{Report: []models.MixStatusReport{
+				{PubKey: "mix1", Owner: "owner", LastDayIPV4: 100, HasIPV6Data: true, UptimesIPV4: models.Uptimes{LastDayWindow: 100}},
+			}})
+			db.SaveBatchGatewayStatusReport(models.BatchGatewayStatusReport{Report: []models.GatewayStatusReport{
+				{PubKey: "gateway1", Owner: "owner", LastDayIPV4: 100, ClientsLastDayIPV4: 100},
+			}})
+
+			resp := performLocalHostRequest(routerFor(db), "GET", "/api/admin/export?statuses=true", nil)
+			assert.Equal(GinkgoT(), 200, resp.Code)
+			assert.Equal(GinkgoT(), "application/x-ndjson", resp.Header().Get("Content-Type"))
+			return resp.Body.Bytes()
+		}
+
+		It("should restore the reports and the statuses into an empty db", func() {
+			source := NewDb(true)
+			export := exportFrom(source)
+			assert.Len(GinkgoT(), strings.Split(strings.TrimSpace(string(export)), "\n"), 5)
+
+			target := NewDb(true)
+			resp := performLocalHostRequest(routerFor(target), "POST", "/api/admin/import", export)
+			var imported models.Import
+			json.Unmarshal(resp.Body.Bytes(), &imported)
+
+			assert.Equal(GinkgoT(), 200, resp.Code)
+			assert.Equal(GinkgoT(), models.Import{MixReports: 1, GatewayReports: 1, MixStatuses: 2, GatewayStatuses: 1}, imported)
+			assert.Equal(GinkgoT(), loadMixReport(source, "mix1"), loadMixReport(target, "mix1"))
+			assert.Equal(GinkgoT(), source.LoadGatewayReport(context.Background(), "gateway1"), target.LoadGatewayReport(context.Background(), "gateway1"))
+			assert.ElementsMatch(GinkgoT(), mixStatuses, target.ListMixStatus(context.Background(), "mix1", 10))
+			assert.Equal(GinkgoT(), gatewayStatuses, target.ListGatewayStatus(context.Background(), "gateway1", 10))
+		})
+
+		It("should only export the reports unless asked for the statuses", func() {
+			db := NewDb(true)
+			db.SaveBatchMixStatusReport(models.BatchMixStatusReport{Report: []models.MixStatusReport{{PubKey: "mix1"}}})
+
+			resp := performLocalHostRequest(routerFor(db), "GET", "/api/admin/export", nil)
+			var record models.ExportRecord
+			json.Unmarshal(resp.Body.Bytes(), &record)
+
+			assert.Equal(GinkgoT(), 200, resp.Code)
+			assert.Equal(GinkgoT(), models.ExportMixReport, record.Kind)
+			assert.Equal(GinkgoT(), "mix1", record.MixReport.PubKey)
+		})
+
+		It("should refuse to import into a db that already holds statuses", func() {
+			db := NewDb(true)
+			export := exportFrom(db)
+
+			resp := performLocalHostRequest(routerFor(db), "POST", "/api/admin/import", export)
+
+			assert.Equal(GinkgoT(), 409, resp.Code)
+			assert.Equal(GinkgoT(), int64(2), db.CountMixStatuses(context.Background()))
+		})
+
+		It("should reject records it doesn't know", func() {
+			resp := performLocalHostRequest(routerFor(NewDb(true)), "POST", "/api/admin/import", []byte(`{"kind":"mixStatus"}`))
+
+			assert.Equal(GinkgoT(), 400, resp.Code)
+		})
+
+		It("should only be available to trusted sources", func() {
+			router := routerFor(NewDb(true))
+
+			assert.Equal(GinkgoT(), 403, performNonLocalRequest(router, "GET", "/api/admin/export", nil).Code)
+			assert.Equal(GinkgoT(), 403, performNonLocalRequest(router, "POST", "/api/admin/import", nil).Code)
+		})
+
+		It("should not trust a forwarded loopback address", func() {
+			router := routerFor(NewDb(true))
+
+			for method, path := range map[string]string{"GET": "/api/admin/export", "POST": "/api/admin/import"} {
+				req, _ := http.NewRequest(method, path, nil)
+				req.Header.Set("X-Forwarded-For", "127.0.0.1")
+				req.RemoteAddr = "1.1.1.1:12345"
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				assert.Equal(GinkgoT(), 403, w.Code)
+			}
+		})
+	})
+
+	Describe("streaming mix statuses", func() {
+		It("should push statuses created after the client connected", func() {
+			mockDb := new(mocks.IDb)
+			mockDb.On("AddMixStatus", mock.Anything).Return(nil)
+			mockDb.On("LoadMixReport", mock.Anything, mock.Anything).Return(models.MixStatusReport{}, nil)
+			mockDb.On("ListMixStatusSince", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]models.PersistedMixStatus{})
+			mockDb.On("SaveMixStatusReportIfUnchanged", mock.Anything).Return(true)
+			mockSanitizer := new(mocks.Sanitizer)
+			mockSanitizer.On("Sanitize", fixtures.GoodMixStatus()).Return(fixtures.GoodMixStatus())
+
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			New(Config{Sanitizer: mockSanitizer, Service: newTestService(mockDb)}).RegisterRoutes(router)
+			server := httptest.NewServer(router)
+			defer server.Close()
+
+			wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/status/mixnodes/stream"
+			conn, err := websocket.Dial(wsURL, "", server.URL)
+			assert.Nil(GinkgoT(), err)
+			defer conn.Close()
+
+			statusJSON, _ := json.Marshal(fixtures.GoodMixStatus())
+			resp, err := http.Post(server.URL+"/api/status/mixnode", "application/json", bytes.NewReader(statusJSON))
+			assert.Nil(GinkgoT(), err)
+			resp.Body.Close()
+			assert.Equal(GinkgoT(), 201, resp.StatusCode)
+
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			var received models.PersistedMixStatus
+			err = websocket.JSON.Receive(conn, &received)
+			assert.Nil(GinkgoT(), err)
+			assert.Equal(GinkgoT(), fixtures.GoodMixStatus().PubKey, received.PubKey)
+			assert.Equal(GinkgoT(), fixtures.GoodMixStatus().IPVersion, received.IPVersion)
+		})
+	})
+})
+
+var _ = Describe("Gateway controller", func() {
+	Describe("creating a gateway status", func() {
+		Context("from a host other than localhost", func() {
+			It("should fail", func() {
+				router, mockService, _, _ := SetupGatewayRouter()
+				badJSON, _ := json.Marshal(fixtures.XSSGatewayStatus())
+				resp := performNonLocalRequest(router, "POST", "/api/status/gateway", badJSON)
+				assert.Equal(GinkgoT(), 403, resp.Result().StatusCode)
+				mockService.AssertNotCalled(GinkgoT(), "CreateGatewayStatus", mock.Anything)
+			})
+		})
+
+		Context("that has 'false' set for 'Up'", func() {
+			It("should save the gateway status", func() {
+				boolfalse := false
+				router, mockService, mockSanitizer, _ := SetupGatewayRouter()
+				status := fixtures.GoodGatewayStatus()
+				status.Up = &boolfalse
+
+				savedStatus := fixtures.GoodPersistedGatewayStatus()
+				savedStatus.Up = false
+
+				mockSanitizer.On("Sanitize", status).Return(status)
+				mockService.On("CreateGatewayStatus", status).Return(savedStatus, nil)
+				mockService.On("SaveGatewayStatusReport", mock.Anything, savedStatus).Return(models.GatewayStatusReport{})
+
+				falseJSON, _ := json.Marshal(status)
+				resp := performLocalHostRequest(router, "POST", "/api/status/gateway", falseJSON)
+				assert.Equal(GinkgoT(), 201, resp.Code)
+				mockService.AssertCalled(GinkgoT(), "SaveGatewayStatusReport", mock.Anything, savedStatus)
+			})
+		})
+
+		Context("containing xss", func() {
+			It("should strip the xss attack, save the individual gateway status, and update the status report for the given node", func() {
+				router, mockService, mockSanitizer, _ := SetupGatewayRouter()
+
+				mockSanitizer.On("Sanitize", fixtures.XSSGatewayStatus()).Return(fixtures.GoodGatewayStatus())
+				mockService.On("CreateGatewayStatus", fixtures.GoodGatewayStatus()).Return(fixtures.GoodPersistedGatewayStatus(), nil)
+				mockService.On("SaveGatewayStatusReport", mock.Anything, fixtures.GoodPersistedGatewayStatus()).Return(models.GatewayStatusReport{})
+				badJSON, _ := json.Marshal(fixtures.XSSGatewayStatus())
+
+				resp := performLocalHostRequest(router, "POST", "/api/status/gateway", badJSON)
+
+				assert.Equal(GinkgoT(), 201, resp.Code)
+				mockSanitizer.AssertCalled(GinkgoT(), "Sanitize", fixtures.XSSGatewayStatus())
+				mockService.AssertCalled(GinkgoT(), "CreateGatewayStatus", fixtures.GoodGatewayStatus())
+			})
+		})
+
+		Context("without an owner", func() {
+			It("should be rejected while owners are required", func() {
+				router, mockService, mockSanitizer, _ := SetupGatewayRouter()
+				status := fixtures.GoodGatewayStatus()
+				status.Owner = ""
+				mockSanitizer.On("Sanitize", status).Return(status)
+
+				ownerlessJSON, _ := json.Marshal(status)
+				resp := performLocalHostRequest(router, "POST", "/api/status/gateway", ownerlessJSON)
+
+				assert.Equal(GinkgoT(), 400, resp.Code)
+				mockService.AssertNotCalled(GinkgoT(), "CreateGatewayStatus", mock.Anything)
+			})
+			It("should be saved with a blank owner once owners are optional", func() {
+				mockGatewaySanitizer := new(mocks.GatewaySanitizer)
+				router, mockService, _, _, _ := SetupRouterWithConfig(Config{GatewaySanitizer: mockGatewaySanitizer, OwnerOptional: true})
+				status := fixtures.GoodGatewayStatus()
+				status.Owner = ""
+				savedStatus := fixtures.GoodPersistedGatewayStatus()
+				savedStatus.Owner = ""
+				mockGatewaySanitizer.On("Sanitize", status).Return(status)
+				mockService.On("CreateGatewayStatus", status).Return(savedStatus, nil)
+				mockService.On("SaveGatewayStatusReport", mock.Anything, savedStatus).Return(models.GatewayStatusReport{})
+
+				ownerlessJSON, _ := json.Marshal(status)
+				resp := performLocalHostRequest(router, "POST", "/api/status/gateway", ownerlessJSON)
+
+				assert.Equal(GinkgoT(), 201, resp.Code)
+				mockService.AssertCalled(GinkgoT(), "CreateGatewayStatus", status)
+			})
+		})
+	})
+
+	Describe("retrieving a gateway status report (overview)", func() {
+		Context("when a report does not yet exist", func() {
+			It("should only write the 404 response", func() {
+				router, mockService, _, _ := SetupGatewayRouter()
+				mockService.On("GetGatewayStatusReport", mock.Anything, "key1").Return(models.GatewayStatusReport{})
+				resp := performLocalHostRequest(router, "GET", "/api/status/gateway/key1/report", nil)
+
+				var response models.Error
+				err := json.Unmarshal([]byte(resp.Body.String()), &response)
+				assert.Nil(GinkgoT(), err)
+				assert.Equal(GinkgoT(), 404, resp.Code)
+				assert.Equal(GinkgoT(), models.Error{Code: 404, Message: "not found"}, response)
+			})
+		})
+
+		Context("when a report exists", func() {
+			It("should return the report", func() {
+				router, mockService, _, _ := SetupGatewayRouter()
+				mockService.On("GetGatewayStatusReport", mock.Anything, fixtures.GatewayStatusReport().PubKey).Return(fixtures.GatewayStatusReport())
+				resp := performLocalHostRequest(router, "GET", "/api/status/gateway/key1/report", nil)
+				var response models.GatewayStatusReport
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+				assert.Equal(GinkgoT(), 200, resp.Result().StatusCode)
+				assert.Equal(GinkgoT(), fixtures.GatewayStatusReport(), response)
+			})
+		})
+	})
+
+	Describe("listing statuses for a gateway", func() {
+		Context("when some statuses exist", func() {
+			It("should return the list of statuses as json", func() {
+				router, mockService, _, _ := SetupGatewayRouter()
+				mockService.On("ListGatewayStatus", mock.Anything, "pubkey1").Return(fixtures.GatewayStatusesList())
+				resp := performLocalHostRequest(router, "GET", "/api/status/gateway/pubkey1/history", nil)
+				var response []models.PersistedGatewayStatus
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.Equal(GinkgoT(), fixtures.GatewayStatusesList(), response)
+			})
+		})
+	})
+
+	Describe("Creating batch gateway status", func() {
+		Context("from a host other than localhost", func() {
+			It("should fail", func() {
+				router, _, _, _ := SetupGatewayRouter()
+				goodJSON, _ := json.Marshal(fixtures.GoodBatchGatewayStatus())
+				resp := performNonLocalRequest(router, "POST", "/api/status/gateway/batch", goodJSON)
+				assert.Equal(GinkgoT(), 403, resp.Result().StatusCode)
+			})
+		})
+
+		Context("Containing multiple status data", func() {
+			Context("containing xss", func() {
+				It("should strip the xss attack, save the individual gateway statuses, and update the status report for the given nodes", func() {
+					router, mockService, _, mockBatchSanitizer := SetupGatewayRouter()
+
+					mockBatchSanitizer.On("Sanitize", fixtures.XSSBatchGatewayStatus()).Return(fixtures.GoodBatchGatewayStatus())
+					mockService.On("BatchCreateGatewayStatus", fixtures.GoodBatchGatewayStatus()).Return(fixtures.GoodPersistedBatchGatewayStatus(), nil)
+					mockService.On("SaveBatchGatewayStatusReport", mock.Anything, fixtures.GoodPersistedBatchGatewayStatus()).Return(models.BatchGatewayStatusReport{Report: []models.GatewayStatusReport{}})
+					badJSON, _ := json.Marshal(fixtures.XSSBatchGatewayStatus())
+
+					resp := performLocalHostRequest(router, "POST", "/api/status/gateway/batch", badJSON)
+
+					assert.Equal(GinkgoT(), 201, resp.Code)
+					mockBatchSanitizer.AssertCalled(GinkgoT(), "Sanitize", fixtures.XSSBatchGatewayStatus())
+					mockService.AssertCalled(GinkgoT(), "BatchCreateGatewayStatus", fixtures.GoodBatchGatewayStatus())
+					mockService.AssertCalled(GinkgoT(), "SaveBatchGatewayStatusReport", mock.Anything, fixtures.GoodPersistedBatchGatewayStatus())
+				})
+			})
+		})
+
+		Context("Containing invalid statuses", func() {
+			It("should reject the batch, naming every invalid field", func() {
+				router, mockService, _, mockBatchSanitizer := SetupGatewayRouter()
+				rtt := -1
+				batch := fixtures.GoodBatchGatewayStatus()
+				batch.Status[0].PubKey = ""
+				batch.Status[1].RTTMillis = &rtt
+				batchJSON, _ := json.Marshal(batch)
+
+				resp := performLocalHostRequest(router, "POST", "/api/status/gateway/batch", batchJSON)
+				var response models.ValidationError
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 400, resp.Code)
+				expected := []models.FieldError{
+					{Field: "status[0].pubKey", Reason: "required"},
+					{Field: "status[1].rttMillis", Reason: "min=0"},
+				}
+				assert.Equal(GinkgoT(), expected, response.Fields)
+				mockBatchSanitizer.AssertNotCalled(GinkgoT(), "Sanitize", mock.Anything)
+				mockService.AssertNotCalled(GinkgoT(), "BatchCreateGatewayStatus", mock.Anything)
+			})
+		})
+
+		Context("with more statuses than allowed", func() {
+			It("should reject it with 413 before sanitizing or saving anything", func() {
+				mockBatchSanitizer := new(mocks.BatchGatewaySanitizer)
+				router, mockService, _, _, _ := SetupRouterWithConfig(Config{MaxBatchSize: 2, BatchGatewaySanitizer: mockBatchSanitizer})
+				goodJSON, _ := json.Marshal(fixtures.GoodBatchGatewayStatus())
+				resp := performLocalHostRequest(router, "POST", "/api/status/gateway/batch", goodJSON)
+
+				assert.Equal(GinkgoT(), 413, resp.Code)
+				mockBatchSanitizer.AssertNotCalled(GinkgoT(), "Sanitize", mock.Anything)
+				mockService.AssertNotCalled(GinkgoT(), "BatchCreateGatewayStatus", mock.Anything)
+			})
+		})
+	})
+
+	Describe("Retrieving full batch gateway status report", func() {
+		Context("when a report exists", func() {
+			It("should return the report", func() {
+				router, mockService, _, _ := SetupGatewayRouter()
+				reqReport := models.BatchGatewayStatusReport{Report: []models.GatewayStatusReport{fixtures.GatewayStatusReport()}}
+				mockService.On("BatchGetGatewayStatusReport", mock.Anything).Return(reqReport)
+				resp := performLocalHostRequest(router, "GET", "/api/status/fullgatewayreport", nil)
+
+				var response models.BatchGatewayStatusReport
+				json.Unmarshal([]byte(resp.Body.String()), &response)
+
+				assert.Equal(GinkgoT(), 200, resp.Code)
+				assert.Equal(GinkgoT(), reqReport, response)
+			})
+		})
+	})
+})
+
+func SetupRouter() (*gin.Engine, *mocks.IService, *mocks.Sanitizer, *mocks.GenericSanitizer, *mocks.BatchSanitizer) {
+	return SetupRouterWithConfig(Config{})
+}
+
+// SetupRouterWithConfig sets up the router like SetupRouter, but keeps the non-mock settings and the gateway sanitizers of the provided config
+func SetupRouterWithConfig(cfg Config) (*gin.Engine, *mocks.IService, *mocks.Sanitizer, *mocks.GenericSanitizer, *mocks.BatchSanitizer) {
+	mockSanitizer := new(mocks.Sanitizer)
+	mockBatchSanitizer := new(mocks.BatchSanitizer)
+	mockGenericSanitizer := new(mocks.GenericSanitizer)
+	mockService := new(mocks.IService)
+
+	// on startup there will be no nodes
+	mockService.On("MixCount", mock.Anything).Return(0)
+	mockService.On("GatewayCount", mock.Anything).Return(0)
+	mockService.On("StartupPurge")
+
+	cfg.BatchMixSanitizer = mockBatchSanitizer
+	cfg.GenericSanitizer = mockGenericSanitizer
+	cfg.Sanitizer = mockSanitizer
+	cfg.Service = mockService
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	controller := New(cfg)
+	controller.RegisterRoutes(router)
+	return router, mockService, mockSanitizer, mockGenericSanitizer, mockBatchSanitizer
+}
+
+// SetupGatewayRouter sets up the router with mocks of the sanitizers used by the gateway endpoints
+func SetupGatewayRouter() (*gin.Engine, *mocks.IService, *mocks.GatewaySanitizer, *mocks.BatchGatewaySanitizer) {
+	mockGatewaySanitizer := new(mocks.GatewaySanitizer)
+	mockBatchGatewaySanitizer := new(mocks.BatchGatewaySanitizer)
+	router, mockService, _, _, _ := SetupRouterWithConfig(Config{
+		GatewaySanitizer:      mockGatewaySanitizer,
+		BatchGatewaySanitizer: mockBatchGatewaySanitizer,
+	})
+	return router, mockService, mockGatewaySanitizer, mockBatchGatewaySanitizer
+}
+
+func performLocalHostRequest(r http.Handler, method, path string, body []byte) *httptest.ResponseRecorder {
+	buf := bytes.NewBuffer(body)
+	req, _ := http.NewRequest(method, path, buf)
+	req.RemoteAddr = "127.0.0.1:12345"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func performGzippedLocalHostRequest(r http.Handler, method, path string, body []byte) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+	req.Header.Set("Content-Encoding", "gzip")
+	req.RemoteAddr = "127.0.0.1:12345"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func performIdempotentLocalHostRequest(r http.Handler, path string, body []byte, key string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", path, bytes.NewBuffer(body))
+	req.Header.Set(IdempotencyKeyHeader, key)
+	req.RemoteAddr = "127.0.0.1:12345"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func gzipped(body []byte) []byte {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	writer.Write(body)
+	writer.Close()
+	return buf.Bytes()
+}
+
+// performForwardedLocalHostRequest comes from a remote address but claims to be forwarded from the local machine
+func performForwardedLocalHostRequest(r http.Handler, method, path string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, nil)
+	req.Header.Set("X-Forwarded-For", "127.0.0.1")
+	req.RemoteAddr = "1.1.1.1:12345"
 	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
 	return w
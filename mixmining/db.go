@@ -15,66 +15,151 @@
 package mixmining
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/nymtech/node-status-api/models"
+	"github.com/sirupsen/logrus"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"io/ioutil"
 	"log"
 	"os"
 	"os/user"
 	"path"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
 // IDb holds status information
 type IDb interface {
-	AddMixStatus(models.PersistedMixStatus)
-	BatchAddMixStatus(status []models.PersistedMixStatus)
-	ListMixStatus(pubkey string, limit int) []models.PersistedMixStatus
-	ListMixStatusDateRange(pubkey string, ipVersion string, start int64, end int64) []models.PersistedMixStatus
-	LoadMixReport(pubkey string) models.MixStatusReport
-	LoadNonStaleMixReports() models.BatchMixStatusReport
-	BatchLoadMixReports(pubkeys []string) models.BatchMixStatusReport
-	BatchLoadAllMixReports() models.BatchMixStatusReport
+	AddMixStatus(models.PersistedMixStatus) error
+	BatchAddMixStatus(status []models.PersistedMixStatus) error
+	ListMixStatus(ctx context.Context, pubkey string, limit int) []models.PersistedMixStatus
+	ListMixStatusBulk(ctx context.Context, pubkeys []string, limit int) []models.PersistedMixStatus
+	ListMixStatusDateRange(ctx context.Context, pubkey string, ipVersion string, start int64, end int64) []models.PersistedMixStatus
+	LoadMixReport(ctx context.Context, pubkey string) (models.MixStatusReport, error)
+	BatchLoadMixReports(ctx context.Context, pubkeys []string) models.BatchMixStatusReport
+	TopMixReports(ctx context.Context, field string, n int) models.BatchMixStatusReport
+	BatchLoadAllMixReports(ctx context.Context) models.BatchMixStatusReport
 	RemoveMixReports(pubkeys []string)
-	SaveMixStatusReport(models.MixStatusReport)
+	SaveMixStatusReport(models.MixStatusReport) error
+	SaveMixStatusReportIfUnchanged(models.MixStatusReport) bool
 	SaveBatchMixStatusReport(models.BatchMixStatusReport)
+	SaveBatchMixStatusReportIfUnchanged(models.BatchMixStatusReport) []string
 
-	ListMixStatusSince(pubkey string, ipVersion string, since int64) []models.PersistedMixStatus
+	ListMixStatusSince(ctx context.Context, pubkey string, ipVersion string, since int64) []models.PersistedMixStatus
+	ListMixStatusByState(ctx context.Context, pubkey string, ipVersion string, up bool, since int64) []models.PersistedMixStatus
+	ListMixStatusByEpoch(ctx context.Context, epoch uint64) []models.PersistedMixStatus
 	RemoveOldMixStatuses(before int64)
-	GetActiveMixes(since int64) []string
-
-
-	AddGatewayStatus(models.PersistedGatewayStatus)
-	BatchAddGatewayStatus(status []models.PersistedGatewayStatus)
-	ListGatewayStatus(pubkey string, limit int) []models.PersistedGatewayStatus
-	ListGatewayStatusDateRange(pubkey string, ipVersion string, start int64, end int64) []models.PersistedGatewayStatus
-	LoadGatewayReport(pubkey string) models.GatewayStatusReport
-	LoadNonStaleGatewayReports() models.BatchGatewayStatusReport
-	BatchLoadGatewayReports(pubkeys []string) models.BatchGatewayStatusReport
-	SaveGatewayStatusReport(models.GatewayStatusReport)
+	RetractMixStatus(ctx context.Context, pubkey string, ipVersion string, timestamp int64) bool
+	GetActiveMixes(ctx context.Context, ipVersion string, since int64) []string
+	GetMixesWithoutReport(ctx context.Context, since int64) []string
+
+
+	AddGatewayStatus(models.PersistedGatewayStatus) error
+	BatchAddGatewayStatus(status []models.PersistedGatewayStatus) error
+	ListGatewayStatus(ctx context.Context, pubkey string, limit int) []models.PersistedGatewayStatus
+	ListGatewayStatusDateRange(ctx context.Context, pubkey string, ipVersion string, start int64, end int64) []models.PersistedGatewayStatus
+	LoadGatewayReport(ctx context.Context, pubkey string) models.GatewayStatusReport
+	BatchLoadGatewayReports(ctx context.Context, pubkeys []string) models.BatchGatewayStatusReport
+	SaveGatewayStatusReport(models.GatewayStatusReport) error
 	SaveBatchGatewayStatusReport(models.BatchGatewayStatusReport)
 
-	ListGatewayStatusSince(pubkey string, ipVersion string, since int64) []models.PersistedGatewayStatus
+	ListGatewayStatusSince(ctx context.Context, pubkey string, ipVersion string, since int64) []models.PersistedGatewayStatus
 	RemoveOldGatewayStatuses(before int64)
-	GetActiveGateways(since int64) []string
+	RetractGatewayStatus(ctx context.Context, pubkey string, ipVersion string, timestamp int64) bool
+	GetActiveGateways(ctx context.Context, since int64) []string
+
+	CountMixStatuses(ctx context.Context) int64
+	CountGatewayStatuses(ctx context.Context) int64
+	OldestStatusTimestamp(ctx context.Context) int64
+	MixIngestionLag(ctx context.Context, since int64) models.IngestionLag
+	MixLifetime(ctx context.Context, pubkey string) models.NodeLifetime
+	GatewayLifetime(ctx context.Context, pubkey string) models.NodeLifetime
+	QueryTimeouts() int64
+
+	AddNetworkUptimeSample(sample models.NetworkUptimeSample)
+	ListNetworkUptimeSamples(ctx context.Context, since int64) []models.NetworkUptimeSample
+	RemoveOldNetworkUptimeSamples(before int64)
+	AddOwnershipChanges(changes []models.OwnershipChange)
+	ListOwnershipChanges(ctx context.Context, pubkey string) []models.OwnershipChange
+	MixReportOwners(ctx context.Context, pubkeys []string) map[string]string
+
+	DistinctMixOwners(ctx context.Context) []string
+	DistinctGatewayOwners(ctx context.Context) []string
+
+	EachMixReport(ctx context.Context, fn func(models.MixStatusReport) error) error
+	EachGatewayReport(ctx context.Context, fn func(models.GatewayStatusReport) error) error
+	EachMixStatus(ctx context.Context, fn func(models.PersistedMixStatus) error) error
+	EachGatewayStatus(ctx context.Context, fn func(models.PersistedGatewayStatus) error) error
+
+	Vacuum(ctx context.Context) (int64, error)
+	Ping(ctx context.Context) error
 }
 
-const MaxReportSize = 2000
+// MaxReportSize is the number of reports saved by a single statement. Each report binds a variable per column,
+// so with over 20 columns per report it has to stay well below the sqlite limit of 32766 variables divided by 20.
+const MaxReportSize = 1000
 const MaxStatusesPerInsertion = 3000
 
+// MaxKeysPerQuery is the number of pubkeys looked up by a single IN query. Each of them binds a variable, so looking
+// up every node of a large network at once would exceed the sqlite limit of 32766 variables.
+const MaxKeysPerQuery = 1000
+
+// DbDirEnv and DbFileEnv name the environment variables overriding the directory and the file name of the database.
+// DbParamsEnv overrides the connection parameters appended to the DSN, set it to an empty string to use none of them.
+// DbMaxOpenConnsEnv overrides the size of the connection pool.
+// DbInMemoryEnv tells whether to keep the database in memory rather than in a file. Tests do so unless it's set to
+// false, everything else only if it's set to true, e.g. for ephemeral runs that don't need to keep any data.
+const (
+	DbDirEnv          = "NYM_DB_DIR"
+	DbFileEnv         = "NYM_DB_FILE"
+	DbParamsEnv       = "NYM_DB_PARAMS"
+	DbMaxOpenConnsEnv = "NYM_DB_MAX_OPEN_CONNS"
+	DbInMemoryEnv     = "NYM_DB_IN_MEMORY"
+
+	defaultDbFile = "mixmining.db"
+	// WAL lets the dashboard reads proceed while reports are written and the busy timeout makes concurrent writers
+	// wait for each other instead of failing with "database is locked"
+	defaultDbParams       = "_journal_mode=WAL&_busy_timeout=5000"
+	defaultDbMaxOpenConns = 4
+)
+
 // Db is a hashtable that holds mixnode uptime mixmining
 type Db struct {
 	orm *gorm.DB
+	// queryTimeouts counts the queries that failed because their context's deadline passed
+	queryTimeouts int64
 }
 
 // NewDb constructor
 func NewDb(isTest bool) *Db {
-	database, err := gorm.Open(sqlite.Open(dbPath(isTest)), &gorm.Config{})
+	if dbInMemory(isTest) {
+		return newDb(inMemoryDialector())
+	}
+	return newDb(sqlite.Open(dbDSN(dbPath(isTest))))
+}
+
+func newDb(dialector gorm.Dialector) *Db {
+	database, err := gorm.Open(dialector, &gorm.Config{})
 	if err != nil {
 		panic("Failed to connect to orm!")
 	}
 
+	sqlDB, err := sqlDBOf(database)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// sqlite serializes the writes anyway, so a small pool is enough and keeps writers from piling up on the lock
+	sqlDB.SetMaxOpenConns(dbMaxOpenConns())
+	// an in-memory database goes away with its last connection, so the idle ones are kept open rather than closed
+	sqlDB.SetMaxIdleConns(dbMaxOpenConns())
+
 	// mix status migration
 	if err := database.AutoMigrate(&models.PersistedMixStatus{}); err != nil {
 		log.Fatal(err)
@@ -90,12 +175,47 @@ func NewDb(isTest bool) *Db {
 		log.Fatal(err)
 	}
 
+	if err := database.AutoMigrate(&models.NetworkUptimeSample{}); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := database.AutoMigrate(&models.OwnershipChange{}); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := runMigrations(database, migrations, SystemClock{}); err != nil {
+		log.Fatal(err)
+	}
+
 	d := Db{
-		database,
+		orm: database,
 	}
+	d.countQueryTimeouts()
 	return &d
 }
 
+// countQueryTimeouts registers a callback after every kind of statement counting the ones that ran out of time
+func (db *Db) countQueryTimeouts() {
+	count := func(tx *gorm.DB) {
+		if tx.Error != nil && tx.Statement.Context != nil && tx.Statement.Context.Err() == context.DeadlineExceeded {
+			atomic.AddInt64(&db.queryTimeouts, 1)
+		}
+	}
+	callbacks := db.orm.Callback()
+	for _, processor := range []interface {
+		Register(name string, fn func(*gorm.DB)) error
+	}{callbacks.Create(), callbacks.Query(), callbacks.Update(), callbacks.Delete(), callbacks.Row(), callbacks.Raw()} {
+		if err := processor.Register("nym:count_query_timeouts", count); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// QueryTimeouts returns the number of queries that failed because their deadline passed since the db was opened
+func (db *Db) QueryTimeouts() int64 {
+	return atomic.LoadInt64(&db.queryTimeouts)
+}
+
 func dbPath(isTest bool) string {
 	if isTest {
 		db, err := ioutil.TempFile("", "test_mixmining.db")
@@ -105,30 +225,71 @@ func dbPath(isTest bool) string {
 		return db.Name()
 	}
 
-	usr, err := user.Current()
-	if err != nil {
-		log.Fatal(err)
+	dir := os.Getenv(DbDirEnv)
+	if dir == "" {
+		usr, err := user.Current()
+		if err != nil {
+			log.Fatal(err)
+		}
+		dir = path.Join(usr.HomeDir, ".nym")
 	}
-	dbPath := path.Join(usr.HomeDir, ".nym")
-	if err := os.MkdirAll(dbPath, os.ModePerm); err != nil {
+	file := os.Getenv(DbFileEnv)
+	if file == "" {
+		file = defaultDbFile
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
 		log.Fatal(err)
 	}
-	db := path.Join(dbPath, "mixmining.db")
-	fmt.Printf("db is: %s\n", db)
+	db := path.Join(dir, file)
+	logrus.WithField("path", db).Info("using database")
 	return db
 }
 
-// Add saves a PersistedMixStatus
-func (db *Db) AddMixStatus(status models.PersistedMixStatus) {
-	db.orm.Create(status)
+// dbDSN appends the connection parameters to the database path. They're passed in the DSN rather than executed as
+// pragmas after opening, so that they apply to every connection in the pool.
+func dbDSN(dbPath string) string {
+	params := defaultDbParams
+	if value, ok := os.LookupEnv(DbParamsEnv); ok {
+		params = value
+	}
+	if params == "" {
+		return dbPath
+	}
+	return dbPath + "?" + params
 }
 
-// If only there was some *GENERIC* way to not repeat this code...
-func splitPersistedMixStatuses(statusList []models.PersistedMixStatus, chunkSize int) [][]models.PersistedMixStatus {
-	dataCopy := make([]models.PersistedMixStatus, len(statusList))
-	copy(dataCopy, statusList)
+func dbInMemory(isTest bool) bool {
+	value := os.Getenv(DbInMemoryEnv)
+	if value == "" {
+		return isTest
+	}
+	inMemory, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("%s must be either true or false, got %q", DbInMemoryEnv, value)
+	}
+	return inMemory
+}
 
-	var chunks [][]models.PersistedMixStatus
+func dbMaxOpenConns() int {
+	value := os.Getenv(DbMaxOpenConnsEnv)
+	if value == "" {
+		return defaultDbMaxOpenConns
+	}
+	conns, err := strconv.Atoi(value)
+	if err != nil || conns <= 0 {
+		log.Fatalf("%s must be a positive integer, got %q", DbMaxOpenConnsEnv, value)
+	}
+	return conns
+}
+
+// chunkSlice splits the slice into chunks of at most chunkSize elements. The chunks don't share memory with the
+// original slice. There's always at least one chunk, even if the slice is empty.
+func chunkSlice[T any](items []T, chunkSize int) [][]T {
+	dataCopy := make([]T, len(items))
+	copy(dataCopy, items)
+
+	var chunks [][]T
 	for chunkSize < len(dataCopy) {
 		dataCopy, chunks = dataCopy[chunkSize:], append(chunks, dataCopy[0:chunkSize:chunkSize])
 	}
@@ -136,39 +297,170 @@ func splitPersistedMixStatuses(statusList []models.PersistedMixStatus, chunkSize
 	return append(chunks, dataCopy)
 }
 
-// BatchAdd saves multiple PersistedMixStatus
-func (db *Db) BatchAddMixStatus(status []models.PersistedMixStatus) {
+// findByPubKeys finds the records of the nodes with the given pubkeys, looking them up in chunks of at most
+// MaxKeysPerQuery. Only the given columns are read, all of them if there are none.
+func findByPubKeys[T any](ctx context.Context, db *Db, pubkeys []string, columns ...string) ([]T, error) {
+	records := make([]T, 0)
+	for _, chunk := range chunkSlice(pubkeys, MaxKeysPerQuery) {
+		query := db.orm.WithContext(ctx)
+		if len(columns) > 0 {
+			query = query.Select(columns)
+		}
+		var found []T
+		if err := query.Where("pub_key IN ?", chunk).Find(&found).Error; err != nil {
+			return nil, err
+		}
+		records = append(records, found...)
+	}
+	return records, nil
+}
+
+// saveInChunks creates or updates the records in chunks of at most chunkSize, so that a single statement
+// doesn't exceed the sqlite limit of SQL variables. All the chunks are saved in a single transaction, so if any
+// of them fails none of the records get written, rather than leaving some nodes updated and others not.
+func saveInChunks[T any](db *Db, records []T, chunkSize int, description string) {
+	err := db.orm.Transaction(func(tx *gorm.DB) error {
+		for _, chunk := range chunkSlice(records, chunkSize) {
+			if len(chunk) == 0 {
+				continue
+			}
+			if result := tx.Save(chunk); result.Error != nil {
+				return result.Error
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("%s save error: %+v", description, err)
+	}
+}
+
+// maxLockedAttempts is the number of times a write is attempted while the database is locked, and lockedRetryDelay
+// the delay before the first retry, doubled before each of the next ones. The busy timeout already makes writers wait
+// for each other, but it doesn't cover every case, e.g. a reader of the WAL turning into a writer while another
+// connection writes, which fails straight away.
+const (
+	maxLockedAttempts = 4
+	lockedRetryDelay  = 50 * time.Millisecond
+)
+
+// isLocked tells whether the error is sqlite failing to get a lock, which may succeed if tried again a bit later
+func isLocked(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "database is locked") || strings.Contains(msg, "database table is locked")
+}
+
+// retryLocked runs the write, running it again with an increasing delay while it fails because the database is
+// locked, up to maxLockedAttempts times. Any other error is returned straight away.
+func retryLocked(write func() error) error {
+	delay := lockedRetryDelay
+	err := write()
+	for attempt := 1; attempt < maxLockedAttempts && isLocked(err); attempt++ {
+		time.Sleep(delay)
+		delay *= 2
+		err = write()
+	}
+	return err
+}
+
+// createInChunks creates the records in chunks of at most MaxStatusesPerInsertion, all of them in a single
+// transaction which is retried if the database is locked. The failure is logged if it persists.
+func createInChunks[T any](db *Db, records []T, description string) error {
 	// with statuses > 7000 statuses I was getting `save error: too many SQL variables[GIN]` error so I had to split
 	// the create operation
-	for _, statusChunk := range splitPersistedMixStatuses(status, MaxStatusesPerInsertion) {
-		db.orm.Create(statusChunk)
+	chunks := chunkSlice(records, MaxStatusesPerInsertion)
+	err := retryLocked(func() error {
+		return db.orm.Transaction(func(tx *gorm.DB) error {
+			for _, chunk := range chunks {
+				if len(chunk) == 0 {
+					continue
+				}
+				if result := tx.Create(chunk); result.Error != nil {
+					return result.Error
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("statuses", len(records)).Errorf("failed to create %s", description)
 	}
+	return err
+}
+
+// Add saves a PersistedMixStatus
+func (db *Db) AddMixStatus(status models.PersistedMixStatus) error {
+	return createInChunks(db, []models.PersistedMixStatus{status}, "mix status")
+}
+
+// BatchAdd saves multiple PersistedMixStatus
+func (db *Db) BatchAddMixStatus(status []models.PersistedMixStatus) error {
+	return createInChunks(db, status, "mix statuses")
 }
 
 // List returns all models.PersistedMixStatus in the orm
-func (db *Db) ListMixStatus(pubkey string, limit int) []models.PersistedMixStatus {
+func (db *Db) ListMixStatus(ctx context.Context, pubkey string, limit int) []models.PersistedMixStatus {
 	var statuses []models.PersistedMixStatus
-	if err := db.orm.Order("timestamp desc").Limit(limit).Where("pub_key = ?", pubkey).Find(&statuses).Error; err != nil {
+	if err := db.orm.WithContext(ctx).Order("timestamp desc").Limit(limit).Where("pub_key = ?", pubkey).Find(&statuses).Error; err != nil {
+		return make([]models.PersistedMixStatus, 0)
+	}
+	return statuses
+}
+
+// ListMixStatusBulk lists the most recent statuses of each of the nodes, at most `limit` of them per node, out of a
+// single query. They're ordered by pubkey, then from the most recent one.
+func (db *Db) ListMixStatusBulk(ctx context.Context, pubkeys []string, limit int) []models.PersistedMixStatus {
+	var statuses []models.PersistedMixStatus
+	// resultant query:
+	// SELECT * FROM (SELECT *, ROW_NUMBER() OVER (PARTITION BY pub_key ORDER BY timestamp desc) AS position FROM persisted_mix_statuses WHERE pub_key IN ?) WHERE position <= ? ORDER BY pub_key, timestamp desc;
+	// the subquery is aliased to the table name for the soft delete scope, same as in ListMixStatusSince
+	numbered := db.orm.Model(&models.PersistedMixStatus{}).Select("*, ROW_NUMBER() OVER (PARTITION BY pub_key ORDER BY timestamp desc) AS position").Where("pub_key IN ?", pubkeys)
+	if err := db.orm.WithContext(ctx).Table("(?) AS persisted_mix_statuses", numbered).Where("position <= ?", limit).Order("pub_key, timestamp desc").Find(&statuses).Error; err != nil {
+		fmt.Printf("ERROR while listing statuses of multiple nodes %+v", err)
 		return make([]models.PersistedMixStatus, 0)
 	}
 	return statuses
 }
 
 // ListDateRange lists all persisted mix statuses for a node for either IPv4 or IPv6 within the specified date range
-func (db *Db) ListMixStatusDateRange(pubkey string, ipVersion string, start int64, end int64) []models.PersistedMixStatus {
+func (db *Db) ListMixStatusDateRange(ctx context.Context, pubkey string, ipVersion string, start int64, end int64) []models.PersistedMixStatus {
 	var statuses []models.PersistedMixStatus
-	if err := db.orm.Order("timestamp desc").Where("pub_key = ?", pubkey).Where("ip_version = ?", ipVersion).Where("timestamp >= ?", start).Where("timestamp <= ?", end).Find(&statuses).Error; err != nil {
+	if err := db.orm.WithContext(ctx).Order("timestamp desc").Where("pub_key = ?", pubkey).Where("ip_version = ?", ipVersion).Where("timestamp >= ?", start).Where("timestamp <= ?", end).Find(&statuses).Error; err != nil {
 		return make([]models.PersistedMixStatus, 0)
 	}
 	return statuses
 }
 
 // ListMixStatusSinceWithLimit lists all persisted mix statuses for a node for either IPv4 or IPv6 since the specified timestamp with the maximum of `limit` results
-func (db *Db) ListMixStatusSince(pubkey string, ipVersion string, since int64) []models.PersistedMixStatus {
+func (db *Db) ListMixStatusSince(ctx context.Context, pubkey string, ipVersion string, since int64) []models.PersistedMixStatus {
 	var statuses []models.PersistedMixStatus
 	// resultant query:
 	// SELECT * FROM (SELECT * FROM persisted_mix_statuses p WHERE p.pub_key = ? AND p.ip_version = ? AND p.timestamp >= ? ) ORDER BY timestamp desc;
-	if err := db.orm.Table("(?)", db.orm.Model(&models.PersistedMixStatus{}).Where("pub_key = ?", pubkey).Where("ip_version = ?", ipVersion).Where("timestamp >= ?", since)).Order("timestamp desc").Find(&statuses).Error; err != nil {
+	// the subquery is aliased to the table name, as the soft delete scope refers to its deleted_at column through it
+	if err := db.orm.WithContext(ctx).Table("(?) AS persisted_mix_statuses", db.orm.Model(&models.PersistedMixStatus{}).Where("pub_key = ?", pubkey).Where("ip_version = ?", ipVersion).Where("timestamp >= ?", since)).Order("timestamp desc").Find(&statuses).Error; err != nil {
+		return make([]models.PersistedMixStatus, 0)
+	}
+	return statuses
+}
+
+// ListMixStatusByState lists the persisted mix statuses for a node for either IPv4 or IPv6 since the specified
+// timestamp that were either up or down, from the most recent one
+func (db *Db) ListMixStatusByState(ctx context.Context, pubkey string, ipVersion string, up bool, since int64) []models.PersistedMixStatus {
+	var statuses []models.PersistedMixStatus
+	if err := db.orm.WithContext(ctx).Order("timestamp desc").Where("pub_key = ?", pubkey).Where("ip_version = ?", ipVersion).Where("up = ?", up).Where("timestamp >= ?", since).Find(&statuses).Error; err != nil {
+		return make([]models.PersistedMixStatus, 0)
+	}
+	return statuses
+}
+
+// ListMixStatusByEpoch lists the persisted mix statuses of all nodes the monitors attributed to the given epoch, in the
+// order they were received
+func (db *Db) ListMixStatusByEpoch(ctx context.Context, epoch uint64) []models.PersistedMixStatus {
+	var statuses []models.PersistedMixStatus
+	if err := db.orm.WithContext(ctx).Order("timestamp").Where("epoch = ?", epoch).Find(&statuses).Error; err != nil {
 		return make([]models.PersistedMixStatus, 0)
 	}
 	return statuses
@@ -181,79 +473,253 @@ func (db *Db) RemoveOldMixStatuses(before int64) {
 	}
 }
 
-// SaveMixStatusReport creates or updates a status summary report for a given mixnode in the database
-func (db *Db) SaveMixStatusReport(report models.MixStatusReport) {
-	create := db.orm.Save(report)
+// AddNetworkUptimeSample saves a sample of the uptime of the network
+func (db *Db) AddNetworkUptimeSample(sample models.NetworkUptimeSample) {
+	if err := db.orm.Create(&sample).Error; err != nil {
+		fmt.Printf("ERROR while saving network uptime sample %+v", err)
+	}
+}
+
+// ListNetworkUptimeSamples lists the network uptime samples taken since the given timestamp, oldest first
+func (db *Db) ListNetworkUptimeSamples(ctx context.Context, since int64) []models.NetworkUptimeSample {
+	samples := []models.NetworkUptimeSample{}
+	if err := db.orm.WithContext(ctx).Where("timestamp >= ?", since).Order("timestamp").Find(&samples).Error; err != nil {
+		fmt.Printf("ERROR while listing network uptime samples %+v", err)
+		return []models.NetworkUptimeSample{}
+	}
+	return samples
+}
+
+// AddOwnershipChanges saves the ownership changes of mixnodes
+func (db *Db) AddOwnershipChanges(changes []models.OwnershipChange) {
+	if len(changes) == 0 {
+		return
+	}
+	if err := db.orm.Create(&changes).Error; err != nil {
+		fmt.Printf("ERROR while saving ownership changes %+v", err)
+	}
+}
+
+// ListOwnershipChanges lists the ownership changes of the mixnode, oldest first
+func (db *Db) ListOwnershipChanges(ctx context.Context, pubkey string) []models.OwnershipChange {
+	changes := []models.OwnershipChange{}
+	if err := db.orm.WithContext(ctx).Where("pub_key = ?", pubkey).Order("timestamp").Find(&changes).Error; err != nil {
+		fmt.Printf("ERROR while listing ownership changes %+v", err)
+		return []models.OwnershipChange{}
+	}
+	return changes
+}
+
+// MixReportOwners returns the owners of the reports of the given mixnodes by pubkey. Mixnodes without a report are
+// left out.
+func (db *Db) MixReportOwners(ctx context.Context, pubkeys []string) map[string]string {
+	owners := make(map[string]string)
+	reports, err := findByPubKeys[models.MixStatusReport](ctx, db, pubkeys, "pub_key", "owner")
+	if err != nil {
+		fmt.Printf("ERROR while retrieving mix report owners %+v", err)
+		return owners
+	}
+	for _, report := range reports {
+		owners[report.PubKey] = report.Owner
+	}
+	return owners
+}
+
+// RemoveOldNetworkUptimeSamples removes the network uptime samples taken before the provided timestamp
+func (db *Db) RemoveOldNetworkUptimeSamples(before int64) {
+	if err := db.orm.Where("timestamp < ?", before).Delete(&models.NetworkUptimeSample{}).Error; err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "failed to remove old network uptime samples from the database - %v\n", err)
+	}
+}
+
+// RetractMixStatus soft deletes the status the node reported for the ip version at the given timestamp, so that it
+// doesn't count towards uptime anymore. It returns false if there's no such status.
+func (db *Db) RetractMixStatus(ctx context.Context, pubkey string, ipVersion string, timestamp int64) bool {
+	result := db.orm.WithContext(ctx).Where("pub_key = ? AND ip_version = ? AND timestamp = ?", pubkey, ipVersion, timestamp).Delete(&models.PersistedMixStatus{})
+	if result.Error != nil {
+		fmt.Printf("ERROR while retracting mix status %+v", result.Error)
+		return false
+	}
+	return result.RowsAffected > 0
+}
+
+// SaveMixStatusReport creates or updates a status summary report for a given mixnode in the database, whatever
+// version of it is stored. The stored version still gets bumped, so that the single status updates that loaded the
+// report before it got overwritten retry instead of overwriting it in turn. It's a single upsert rather than an update
+// followed by an insert when nothing got updated, so that two first saves of the same report racing each other both
+// succeed, the last one winning, instead of the second insert failing on the primary key.
+func (db *Db) SaveMixStatusReport(report models.MixStatusReport) error {
+	columns, err := upsertedColumns(db.orm, &report, "version")
+	if err != nil {
+		fmt.Printf("Mix status report creation error: %+v", err)
+		return err
+	}
+	bump := clause.Assignment{Column: clause.Column{Name: "version"}, Value: gorm.Expr("mix_status_reports.version + 1")}
+	upsert := clause.OnConflict{
+		Columns:   []clause.Column{{Name: "pub_key"}},
+		DoUpdates: append(clause.AssignmentColumns(columns), bump),
+	}
+	report.Version = 1
+	create := db.orm.Clauses(upsert).Create(&report)
 	if create.Error != nil {
 		fmt.Printf("Mix status report creation error: %+v", create.Error)
 	}
+	return create.Error
 }
 
-// SaveBatchMixStatusReport creates or updates a status summary report for multiple mixnodes in the database
-func (db *Db) SaveBatchMixStatusReport(report models.BatchMixStatusReport) {
-	// with statuses of > 3500 nodes I was getting `save error: too many SQL variables[GIN]` error so I had to split
-	// the save operation
-	save := func (db *Db, report models.BatchMixStatusReport) {
-		if len(report.Report) == 0 {
-			return
-		}
-		if result := db.orm.Save(report.Report); result.Error != nil {
-			fmt.Printf("Batch Mix status report save error: %+v", result.Error)
+// upsertedColumns lists the columns of the model an upsert overwrites, that is all of them but its primary key and the
+// omitted ones
+func upsertedColumns(orm *gorm.DB, model interface{}, omit ...string) ([]string, error) {
+	stmt := &gorm.Statement{DB: orm}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+	omitted := make(map[string]bool, len(omit))
+	for _, column := range omit {
+		omitted[column] = true
+	}
+
+	columns := []string{}
+	for _, field := range stmt.Schema.Fields {
+		if field.DBName != "" && !field.PrimaryKey && !omitted[field.DBName] {
+			columns = append(columns, field.DBName)
 		}
 	}
+	return columns, nil
+}
+
+// SaveMixStatusReportIfUnchanged creates or updates a status summary report for a given mixnode only if nobody saved
+// it since it was loaded, that is if the stored version still matches the report's. It tells whether it saved it.
+// The stored version gets bumped, so that another writer that loaded the same version fails rather than overwrites it.
+func (db *Db) SaveMixStatusReportIfUnchanged(report models.MixStatusReport) bool {
+	saved, err := saveMixReportIfUnchanged(db.orm, &report)
+	if err != nil {
+		fmt.Printf("Mix status report save error: %+v", err)
+		return false
+	}
+	return saved
+}
 
-	if len(report.Report) < MaxReportSize {
-		save(db, report)
-	} else {
-		chunks := report.SplitToChunks(MaxReportSize)
-		for _, reportChunk := range chunks {
-			save(db, reportChunk)
+// SaveBatchMixStatusReportIfUnchanged is SaveMixStatusReportIfUnchanged for multiple mixnodes. It saves the reports
+// that are still unchanged, bumping their versions in the batch as well, and returns the pubkeys of the other ones.
+// The reports are saved in chunks, each in a transaction of its own, so that the single status updates aren't held
+// up for the whole batch. If a chunk fails none of its reports are saved, and they're returned along with the
+// changed ones.
+func (db *Db) SaveBatchMixStatusReportIfUnchanged(report models.BatchMixStatusReport) []string {
+	changed := []string{}
+	for start := 0; start < len(report.Report); start += MaxReportSize {
+		end := start + MaxReportSize
+		if end > len(report.Report) {
+			end = len(report.Report)
+		}
+		chunk := report.Report[start:end]
+
+		// the versions are only bumped in the batch once the transaction got committed
+		var chunkChanged []string
+		versions := make([]int64, len(chunk))
+		err := db.orm.Transaction(func(tx *gorm.DB) error {
+			chunkChanged = nil
+			for i := range chunk {
+				saving := chunk[i]
+				saved, err := saveMixReportIfUnchanged(tx, &saving)
+				if err != nil {
+					return err
+				}
+				if !saved {
+					chunkChanged = append(chunkChanged, saving.PubKey)
+				}
+				versions[i] = saving.Version
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Printf("Batch Mix status report save error: %+v", err)
+			for i := range chunk {
+				changed = append(changed, chunk[i].PubKey)
+			}
+			continue
 		}
+		for i := range chunk {
+			chunk[i].Version = versions[i]
+		}
+		changed = append(changed, chunkChanged...)
 	}
+	return changed
 }
 
-// LoadReport retrieves a models.MixStatusReport.
-// If a report isn't found, it crudely generates a new instance and returns that instead.
-func (db *Db) LoadMixReport(pubkey string) models.MixStatusReport {
-	var report models.MixStatusReport
+// saveMixReportIfUnchanged saves the report within the transaction unless its stored version moved on since it was
+// loaded, bumping the version of the report once it's saved
+func saveMixReportIfUnchanged(tx *gorm.DB, report *models.MixStatusReport) (bool, error) {
+	loadedVersion := report.Version
+	bumped := *report
+	bumped.Version++
 
-	if retrieve := db.orm.First(&report, "pub_key = ?", pubkey); retrieve.Error != nil {
-		fmt.Printf("ERROR while retrieving mix status report %+v", retrieve.Error)
-		return models.MixStatusReport{}
+	update := tx.Model(&bumped).Where("version = ?", loadedVersion).Select("*").Updates(bumped)
+	if update.Error != nil {
+		return false, update.Error
 	}
-	return report
+	if update.RowsAffected == 0 {
+		if loadedVersion != 0 {
+			return false, nil
+		}
+		// the report didn't exist when it was loaded, it must not have been created by someone else in the meantime
+		create := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&bumped)
+		if create.Error != nil {
+			return false, create.Error
+		}
+		if create.RowsAffected == 0 {
+			return false, nil
+		}
+	}
+	report.Version = bumped.Version
+	return true, nil
 }
 
-// LoadNonStaleReports retrieves a models.BatchMixStatusReport, such that each mixnode
-// in the retrieved report must have been online for at least a single measurement in the last day.
-// If a report isn't found, it crudely generates a new instance and returns that instead.
-func (db *Db) LoadNonStaleMixReports() models.BatchMixStatusReport {
-	var reports []models.MixStatusReport
+// SaveBatchMixStatusReport creates or updates a status summary report for multiple mixnodes in the database, whatever
+// versions of them are stored, e.g. when importing them into a fresh database. The versions of the reports get bumped,
+// so that single status updates racing the batch retry instead of overwriting it. Reports loaded and updated while
+// others may save them go through SaveBatchMixStatusReportIfUnchanged instead.
+func (db *Db) SaveBatchMixStatusReport(report models.BatchMixStatusReport) {
+	for i := range report.Report {
+		report.Report[i].Version++
+	}
+	// with statuses of > 3500 nodes I was getting `save error: too many SQL variables[GIN]` error so I had to split
+	// the save operation
+	saveInChunks(db, report.Report, MaxReportSize, "Batch Mix status report")
+}
 
-	if retrieve := db.orm.Where("last_day_ip_v4 > 0").Or("last_day_ip_v6 > 0").Find(&reports); retrieve.Error != nil {
-		fmt.Printf("ERROR while retrieving multiple mix status report %+v", retrieve.Error)
-		return models.BatchMixStatusReport{Report: make([]models.MixStatusReport, 0)}
+// LoadReport retrieves a models.MixStatusReport.
+// If a report isn't found, it crudely generates a new instance and returns that instead. That's expected for nodes
+// that never reported, so only the other failures are returned, along with the same empty report.
+func (db *Db) LoadMixReport(ctx context.Context, pubkey string) (models.MixStatusReport, error) {
+	var report models.MixStatusReport
+
+	if retrieve := db.orm.WithContext(ctx).First(&report, "pub_key = ?", pubkey); retrieve.Error != nil {
+		if errors.Is(retrieve.Error, gorm.ErrRecordNotFound) {
+			return models.MixStatusReport{}, nil
+		}
+		logrus.WithError(retrieve.Error).WithField("pubKey", pubkey).Error("failed to retrieve the mix status report")
+		return models.MixStatusReport{}, retrieve.Error
 	}
-	return models.BatchMixStatusReport{Report: reports}
+	return report, nil
 }
 
 // BatchLoadReports retrieves a models.BatchMixStatusReport based on provided set of public keys.
 // If a report isn't found, it crudely generates a new instance and returns that instead.
-func (db *Db) BatchLoadMixReports(pubkeys []string) models.BatchMixStatusReport {
-	var reports []models.MixStatusReport
-
-	if retrieve := db.orm.Where("pub_key IN ?", pubkeys).Find(&reports); retrieve.Error != nil {
-		fmt.Printf("ERROR while retrieving multiple mix status report %+v", retrieve.Error)
+func (db *Db) BatchLoadMixReports(ctx context.Context, pubkeys []string) models.BatchMixStatusReport {
+	reports, err := findByPubKeys[models.MixStatusReport](ctx, db, pubkeys)
+	if err != nil {
+		fmt.Printf("ERROR while retrieving multiple mix status report %+v", err)
 		return models.BatchMixStatusReport{Report: make([]models.MixStatusReport, 0)}
 	}
 	return models.BatchMixStatusReport{Report: reports}
 }
 
 // BatchLoadAllMixReports retrieves a models.BatchMixStatusReport containing data of all nodes
-func (db *Db) BatchLoadAllMixReports() models.BatchMixStatusReport {
+func (db *Db) BatchLoadAllMixReports(ctx context.Context) models.BatchMixStatusReport {
 	var reports []models.MixStatusReport
 
-	if retrieve := db.orm.Find(&reports); retrieve.Error != nil {
+	if retrieve := db.orm.WithContext(ctx).Find(&reports); retrieve.Error != nil {
 		fmt.Printf("ERROR while retrieving all mix status report %+v", retrieve.Error)
 		return models.BatchMixStatusReport{Report: make([]models.MixStatusReport, 0)}
 	}
@@ -262,15 +728,23 @@ func (db *Db) BatchLoadAllMixReports() models.BatchMixStatusReport {
 
 // RemoveMixReports removes MixReports of nodes specified by the provided public keys.
 func (db *Db) RemoveMixReports(pubkeys []string) {
-	if err := db.orm.Unscoped().Where("pub_key IN ?", pubkeys).Delete(&models.MixStatusReport{}).Error; err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "failed to remove old reports from the database - %v\n", err)
+	for _, chunk := range chunkSlice(pubkeys, MaxKeysPerQuery) {
+		if err := db.orm.Unscoped().Where("pub_key IN ?", chunk).Delete(&models.MixStatusReport{}).Error; err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "failed to remove old reports from the database - %v\n", err)
+		}
 	}
 }
 
-func (db *Db) GetActiveMixes(since int64) []string {
+// GetActiveMixes lists the nodes that reported any status since the timestamp, only counting the statuses of the ip
+// version unless it's empty
+func (db *Db) GetActiveMixes(ctx context.Context, ipVersion string, since int64) []string {
 	var reports []models.PersistedMixStatus
 
-	if err := db.orm.Select("pub_key").Where("timestamp > ?", since).Group("pub_key").Find(&reports).Error; err != nil {
+	query := db.orm.WithContext(ctx).Select("pub_key").Where("timestamp > ?", since)
+	if ipVersion != "" {
+		query = query.Where("ip_version = ?", ipVersion)
+	}
+	if err := query.Group("pub_key").Find(&reports).Error; err != nil {
 		fmt.Printf("ERROR while retrieving currently active nodes %+v", err)
 		return []string{}
 	}
@@ -302,56 +776,40 @@ func (db *Db) GetActiveMixes(since int64) []string {
 
 
 // Add saves a PersistedGatewayStatus
-func (db *Db) AddGatewayStatus(status models.PersistedGatewayStatus) {
-	db.orm.Create(status)
-}
-
-// If only there was some *GENERIC* way to not repeat this code...
-func splitPersistedGatewayStatuses(statusList []models.PersistedGatewayStatus, chunkSize int) [][]models.PersistedGatewayStatus {
-	dataCopy := make([]models.PersistedGatewayStatus, len(statusList))
-	copy(dataCopy, statusList)
-
-	var chunks [][]models.PersistedGatewayStatus
-	for chunkSize < len(dataCopy) {
-		dataCopy, chunks = dataCopy[chunkSize:], append(chunks, dataCopy[0:chunkSize:chunkSize])
-	}
-
-	return append(chunks, dataCopy)
+func (db *Db) AddGatewayStatus(status models.PersistedGatewayStatus) error {
+	return createInChunks(db, []models.PersistedGatewayStatus{status}, "gateway status")
 }
 
 // BatchAdd saves multiple PersistedGatewayStatus
-func (db *Db) BatchAddGatewayStatus(status []models.PersistedGatewayStatus) {
-	// with statuses > 7000 statuses I was getting `save error: too many SQL variables[GIN]` error so I had to split
-	// the create operation
-	for _, statusChunk := range splitPersistedGatewayStatuses(status, MaxStatusesPerInsertion) {
-		db.orm.Create(statusChunk)
-	}
+func (db *Db) BatchAddGatewayStatus(status []models.PersistedGatewayStatus) error {
+	return createInChunks(db, status, "gateway statuses")
 }
 
 // List returns all models.PersistedGatewayStatus in the orm
-func (db *Db) ListGatewayStatus(pubkey string, limit int) []models.PersistedGatewayStatus {
+func (db *Db) ListGatewayStatus(ctx context.Context, pubkey string, limit int) []models.PersistedGatewayStatus {
 	var statuses []models.PersistedGatewayStatus
-	if err := db.orm.Order("timestamp desc").Limit(limit).Where("pub_key = ?", pubkey).Find(&statuses).Error; err != nil {
+	if err := db.orm.WithContext(ctx).Order("timestamp desc").Limit(limit).Where("pub_key = ?", pubkey).Find(&statuses).Error; err != nil {
 		return make([]models.PersistedGatewayStatus, 0)
 	}
 	return statuses
 }
 
 // ListDateRange lists all persisted gateway statuses for a node for either IPv4 or IPv6 within the specified date range
-func (db *Db) ListGatewayStatusDateRange(pubkey string, ipVersion string, start int64, end int64) []models.PersistedGatewayStatus {
+func (db *Db) ListGatewayStatusDateRange(ctx context.Context, pubkey string, ipVersion string, start int64, end int64) []models.PersistedGatewayStatus {
 	var statuses []models.PersistedGatewayStatus
-	if err := db.orm.Order("timestamp desc").Where("pub_key = ?", pubkey).Where("ip_version = ?", ipVersion).Where("timestamp >= ?", start).Where("timestamp <= ?", end).Find(&statuses).Error; err != nil {
+	if err := db.orm.WithContext(ctx).Order("timestamp desc").Where("pub_key = ?", pubkey).Where("ip_version = ?", ipVersion).Where("timestamp >= ?", start).Where("timestamp <= ?", end).Find(&statuses).Error; err != nil {
 		return make([]models.PersistedGatewayStatus, 0)
 	}
 	return statuses
 }
 
 // ListGatewayStatusSinceWithLimit lists all persisted gateway statuses for a node for either IPv4 or IPv6 since the specified timestamp with the maximum of `limit` results
-func (db *Db) ListGatewayStatusSince(pubkey string, ipVersion string, since int64) []models.PersistedGatewayStatus {
+func (db *Db) ListGatewayStatusSince(ctx context.Context, pubkey string, ipVersion string, since int64) []models.PersistedGatewayStatus {
 	var statuses []models.PersistedGatewayStatus
 	// resultant query:
 	// SELECT * FROM (SELECT * FROM persisted_gateway_statuses p WHERE p.pub_key = ? AND p.ip_version = ? AND p.timestamp >= ? ) ORDER BY timestamp desc;
-	if err := db.orm.Table("(?)", db.orm.Model(&models.PersistedGatewayStatus{}).Where("pub_key = ?", pubkey).Where("ip_version = ?", ipVersion).Where("timestamp >= ?", since)).Order("timestamp desc").Find(&statuses).Error; err != nil {
+	// the subquery is aliased to the table name, as the soft delete scope refers to its deleted_at column through it
+	if err := db.orm.WithContext(ctx).Table("(?) AS persisted_gateway_statuses", db.orm.Model(&models.PersistedGatewayStatus{}).Where("pub_key = ?", pubkey).Where("ip_version = ?", ipVersion).Where("timestamp >= ?", since)).Order("timestamp desc").Find(&statuses).Error; err != nil {
 		return make([]models.PersistedGatewayStatus, 0)
 	}
 	return statuses
@@ -364,78 +822,60 @@ func (db *Db) RemoveOldGatewayStatuses(before int64) {
 	}
 }
 
+// RetractGatewayStatus soft deletes the status the gateway reported for the ip version at the given timestamp.
+// It returns false if there's no such status.
+func (db *Db) RetractGatewayStatus(ctx context.Context, pubkey string, ipVersion string, timestamp int64) bool {
+	result := db.orm.WithContext(ctx).Where("pub_key = ? AND ip_version = ? AND timestamp = ?", pubkey, ipVersion, timestamp).Delete(&models.PersistedGatewayStatus{})
+	if result.Error != nil {
+		fmt.Printf("ERROR while retracting gateway status %+v", result.Error)
+		return false
+	}
+	return result.RowsAffected > 0
+}
+
 // SaveGatewayStatusReport creates or updates a status summary report for a given gateway in the database
-func (db *Db) SaveGatewayStatusReport(report models.GatewayStatusReport) {
+func (db *Db) SaveGatewayStatusReport(report models.GatewayStatusReport) error {
 	create := db.orm.Save(report)
 	if create.Error != nil {
 		fmt.Printf("Gateway status report creation error: %+v", create.Error)
 	}
+	return create.Error
 }
 
 // SaveBatchGatewayStatusReport creates or updates a status summary report for multiple mixnodes in the database
 func (db *Db) SaveBatchGatewayStatusReport(report models.BatchGatewayStatusReport) {
 	// with statuses of > 3500 nodes I was getting `save error: too many SQL variables[GIN]` error so I had to split
 	// the save operation
-	save := func (db *Db, report models.BatchGatewayStatusReport) {
-		if len(report.Report) == 0 {
-			return
-		}
-		if result := db.orm.Save(report.Report); result.Error != nil {
-			fmt.Printf("Batch Gateway status report save error: %+v", result.Error)
-		}
-	}
-
-	if len(report.Report) < MaxReportSize {
-		save(db, report)
-	} else {
-		chunks := report.SplitToChunks(MaxReportSize)
-		for _, reportChunk := range chunks {
-			save(db, reportChunk)
-		}
-	}
+	saveInChunks(db, report.Report, MaxReportSize, "Batch Gateway status report")
 }
 
 // LoadReport retrieves a models.GatewayStatusReport.
 // If a report isn't found, it crudely generates a new instance and returns that instead.
-func (db *Db) LoadGatewayReport(pubkey string) models.GatewayStatusReport {
+func (db *Db) LoadGatewayReport(ctx context.Context, pubkey string) models.GatewayStatusReport {
 	var report models.GatewayStatusReport
 
-	if retrieve := db.orm.First(&report, "pub_key = ?", pubkey); retrieve.Error != nil {
+	if retrieve := db.orm.WithContext(ctx).First(&report, "pub_key = ?", pubkey); retrieve.Error != nil {
 		fmt.Printf("ERROR while retrieving mix status report %+v", retrieve.Error)
 		return models.GatewayStatusReport{}
 	}
 	return report
 }
 
-// LoadNonStaleReports retrieves a models.BatchGatewayStatusReport, such that each gateway
-// in the retrieved report must have been online for at least a single measurement in the last day.
-// If a report isn't found, it crudely generates a new instance and returns that instead.
-func (db *Db) LoadNonStaleGatewayReports() models.BatchGatewayStatusReport {
-	var reports []models.GatewayStatusReport
-
-	if retrieve := db.orm.Where("last_day_ip_v4 > 0").Or("last_day_ip_v6 > 0").Find(&reports); retrieve.Error != nil {
-		fmt.Printf("ERROR while retrieving multiple gateway status report %+v", retrieve.Error)
-		return models.BatchGatewayStatusReport{Report: make([]models.GatewayStatusReport, 0)}
-	}
-	return models.BatchGatewayStatusReport{Report: reports}
-}
-
 // BatchLoadReports retrieves a models.BatchGatewayStatusReport based on provided set of public keys.
 // If a report isn't found, it crudely generates a new instance and returns that instead.
-func (db *Db) BatchLoadGatewayReports(pubkeys []string) models.BatchGatewayStatusReport {
-	var reports []models.GatewayStatusReport
-
-	if retrieve := db.orm.Where("pub_key IN ?", pubkeys).Find(&reports); retrieve.Error != nil {
-		fmt.Printf("ERROR while retrieving multiple gatweway status report %+v", retrieve.Error)
+func (db *Db) BatchLoadGatewayReports(ctx context.Context, pubkeys []string) models.BatchGatewayStatusReport {
+	reports, err := findByPubKeys[models.GatewayStatusReport](ctx, db, pubkeys)
+	if err != nil {
+		fmt.Printf("ERROR while retrieving multiple gatweway status report %+v", err)
 		return models.BatchGatewayStatusReport{Report: make([]models.GatewayStatusReport, 0)}
 	}
 	return models.BatchGatewayStatusReport{Report: reports}
 }
 
-func (db *Db) GetActiveGateways(since int64) []string {
+func (db *Db) GetActiveGateways(ctx context.Context, since int64) []string {
 	var reports []models.PersistedGatewayStatus
 
-	if err := db.orm.Select("pub_key").Where("timestamp > ?", since).Group("pub_key").Find(&reports).Error; err != nil {
+	if err := db.orm.WithContext(ctx).Select("pub_key").Where("timestamp > ?", since).Group("pub_key").Find(&reports).Error; err != nil {
 		fmt.Printf("ERROR while retrieving currently active nodes %+v", err)
 		return []string{}
 	}
@@ -447,3 +887,237 @@ func (db *Db) GetActiveGateways(since int64) []string {
 
 	return keys
 }
+
+// CountMixStatuses returns the number of mix statuses currently stored
+func (db *Db) CountMixStatuses(ctx context.Context) int64 {
+	var count int64
+	if err := db.orm.WithContext(ctx).Model(&models.PersistedMixStatus{}).Count(&count).Error; err != nil {
+		fmt.Printf("ERROR while counting mix statuses %+v", err)
+	}
+	return count
+}
+
+// CountGatewayStatuses returns the number of gateway statuses currently stored
+func (db *Db) CountGatewayStatuses(ctx context.Context) int64 {
+	var count int64
+	if err := db.orm.WithContext(ctx).Model(&models.PersistedGatewayStatus{}).Count(&count).Error; err != nil {
+		fmt.Printf("ERROR while counting gateway statuses %+v", err)
+	}
+	return count
+}
+
+// OldestStatusTimestamp returns the timestamp of the oldest mix or gateway status still stored, or 0 if there are none.
+func (db *Db) OldestStatusTimestamp(ctx context.Context) int64 {
+	var oldest int64
+	for _, model := range []interface{}{&models.PersistedMixStatus{}, &models.PersistedGatewayStatus{}} {
+		var timestamp sql.NullInt64
+		if err := db.orm.WithContext(ctx).Model(model).Select("MIN(timestamp)").Row().Scan(&timestamp); err != nil {
+			fmt.Printf("ERROR while retrieving the oldest status %+v", err)
+			continue
+		}
+		if timestamp.Valid && (oldest == 0 || timestamp.Int64 < oldest) {
+			oldest = timestamp.Int64
+		}
+	}
+	return oldest
+}
+
+// MixIngestionLag summarises the ingestion lag of the mix statuses received since the given timestamp. Statuses
+// stored before MeasuredAt existed have it set to 0 and are left out.
+func (db *Db) MixIngestionLag(ctx context.Context, since int64) models.IngestionLag {
+	var count int64
+	var average sql.NullFloat64
+	var max sql.NullInt64
+	err := db.orm.WithContext(ctx).
+		Model(&models.PersistedMixStatus{}).
+		Select("COUNT(*), AVG(timestamp - measured_at), MAX(timestamp - measured_at)").
+		Where("timestamp >= ? AND measured_at > 0", since).
+		Row().
+		Scan(&count, &average, &max)
+	if err != nil {
+		fmt.Printf("ERROR while computing the ingestion lag %+v", err)
+		return models.IngestionLag{}
+	}
+	return models.IngestionLag{
+		Statuses:      count,
+		AverageMillis: int64(average.Float64) / int64(time.Millisecond),
+		MaxMillis:     max.Int64 / int64(time.Millisecond),
+	}
+}
+
+// MixLifetime tells when the mixnode reported its first and its most recent status and how many of them it reported
+func (db *Db) MixLifetime(ctx context.Context, pubkey string) models.NodeLifetime {
+	return db.lifetime(ctx, &models.PersistedMixStatus{}, pubkey)
+}
+
+// GatewayLifetime is MixLifetime for gateways
+func (db *Db) GatewayLifetime(ctx context.Context, pubkey string) models.NodeLifetime {
+	return db.lifetime(ctx, &models.PersistedGatewayStatus{}, pubkey)
+}
+
+// lifetime aggregates the statuses of the node of the given model, without loading any of them
+func (db *Db) lifetime(ctx context.Context, model interface{}, pubkey string) models.NodeLifetime {
+	var firstSeen, lastSeen sql.NullInt64
+	var count int64
+	err := db.orm.WithContext(ctx).
+		Model(model).
+		Select("MIN(timestamp), MAX(timestamp), COUNT(*)").
+		// gorm 1.20 only adds the soft delete scope in its Query callback, which Row doesn't run, so the retracted
+		// statuses are left out explicitly
+		Where("pub_key = ? AND deleted_at IS NULL", pubkey).
+		Row().
+		Scan(&firstSeen, &lastSeen, &count)
+	if err != nil {
+		fmt.Printf("ERROR while computing the lifetime of %s %+v", pubkey, err)
+		return models.NodeLifetime{}
+	}
+	return models.NodeLifetime{FirstSeen: firstSeen.Int64, LastSeen: lastSeen.Int64, TotalMeasurements: count}
+}
+
+// rankableMixReportColumns maps the report fields mixnodes can be ranked by to their columns. Nothing but these
+// columns may ever end up in the ORDER BY clause.
+var rankableMixReportColumns = map[string]string{
+	"last5MinutesIPV4": "last5_minutes_ip_v4",
+	"lastHourIPV4":     "last_hour_ip_v4",
+	"lastDayIPV4":      "last_day_ip_v4",
+	"last5MinutesIPV6": "last5_minutes_ip_v6",
+	"lastHourIPV6":     "last_hour_ip_v6",
+	"lastDayIPV6":      "last_day_ip_v6",
+}
+
+// isRankableMixReportField checks whether mixnodes can be ranked by the given report field
+func isRankableMixReportField(field string) bool {
+	_, ok := rankableMixReportColumns[field]
+	return ok
+}
+
+// TopMixReports retrieves the n reports with the highest value of the given field, which must be one of
+// rankableMixReportColumns. Ties are broken by pubkey, so that the order is stable.
+func (db *Db) TopMixReports(ctx context.Context, field string, n int) models.BatchMixStatusReport {
+	reports := []models.MixStatusReport{}
+	column, ok := rankableMixReportColumns[field]
+	if !ok {
+		fmt.Printf("ERROR mix reports can't be ranked by %q", field)
+		return models.BatchMixStatusReport{Report: reports}
+	}
+
+	order := clause.OrderBy{Columns: []clause.OrderByColumn{
+		{Column: clause.Column{Name: column}, Desc: true},
+		{Column: clause.Column{Name: "pub_key"}},
+	}}
+	if err := db.orm.WithContext(ctx).Clauses(order).Limit(n).Find(&reports).Error; err != nil {
+		fmt.Printf("ERROR while retrieving top mix status reports %+v", err)
+		return models.BatchMixStatusReport{Report: []models.MixStatusReport{}}
+	}
+	return models.BatchMixStatusReport{Report: reports}
+}
+
+// GetMixesWithoutReport returns the pubkeys of mixnodes that reported a status since the given timestamp but have no
+// report stored, e.g. because the reports were wiped.
+func (db *Db) GetMixesWithoutReport(ctx context.Context, since int64) []string {
+	keys := []string{}
+	reported := db.orm.WithContext(ctx).Model(&models.MixStatusReport{}).Select("pub_key")
+	if err := db.orm.WithContext(ctx).Model(&models.PersistedMixStatus{}).Distinct("pub_key").Where("timestamp > ?", since).Where("pub_key NOT IN (?)", reported).Order("pub_key").Pluck("pub_key", &keys).Error; err != nil {
+		fmt.Printf("ERROR while retrieving nodes without a report %+v", err)
+		return []string{}
+	}
+	return keys
+}
+
+// DistinctMixOwners returns every non-empty owner that has submitted at least a single mix status still stored
+func (db *Db) DistinctMixOwners(ctx context.Context) []string {
+	return db.distinctOwners(ctx, &models.PersistedMixStatus{})
+}
+
+// DistinctGatewayOwners returns every non-empty owner that has submitted at least a single gateway status still stored
+func (db *Db) DistinctGatewayOwners(ctx context.Context) []string {
+	return db.distinctOwners(ctx, &models.PersistedGatewayStatus{})
+}
+
+func (db *Db) distinctOwners(ctx context.Context, model interface{}) []string {
+	var owners []string
+	if err := db.orm.WithContext(ctx).Model(model).Distinct("owner").Where("owner <> ''").Order("owner").Pluck("owner", &owners).Error; err != nil {
+		fmt.Printf("ERROR while retrieving distinct owners %+v", err)
+		return []string{}
+	}
+	return owners
+}
+
+// Ping checks whether the database connection is still usable by running a trivial query against it.
+func (db *Db) Ping(ctx context.Context) error {
+	return db.orm.WithContext(ctx).Exec("SELECT 1").Error
+}
+
+// eachRow passes every row the query selects to fn, one by one. They're read through a cursor rather than loaded at
+// once, so that millions of statuses don't have to fit in memory. It stops at the first error fn returns.
+func eachRow[T any](db *Db, query *gorm.DB, fn func(T) error) error {
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var row T
+		if err := db.orm.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// EachMixReport passes every stored mix report to fn, ordered by pubkey
+func (db *Db) EachMixReport(ctx context.Context, fn func(models.MixStatusReport) error) error {
+	return eachRow(db, db.orm.WithContext(ctx).Model(&models.MixStatusReport{}).Order("pub_key"), fn)
+}
+
+// EachGatewayReport passes every stored gateway report to fn, ordered by pubkey
+func (db *Db) EachGatewayReport(ctx context.Context, fn func(models.GatewayStatusReport) error) error {
+	return eachRow(db, db.orm.WithContext(ctx).Model(&models.GatewayStatusReport{}).Order("pub_key"), fn)
+}
+
+// EachMixStatus passes every stored mix status to fn, oldest first. Retracted statuses are left out.
+func (db *Db) EachMixStatus(ctx context.Context, fn func(models.PersistedMixStatus) error) error {
+	// Rows doesn't run the Query callback either, same as Row in lifetime
+	return eachRow(db, db.orm.WithContext(ctx).Model(&models.PersistedMixStatus{}).Where("deleted_at IS NULL").Order("timestamp"), fn)
+}
+
+// EachGatewayStatus passes every stored gateway status to fn, oldest first. Retracted statuses are left out.
+func (db *Db) EachGatewayStatus(ctx context.Context, fn func(models.PersistedGatewayStatus) error) error {
+	return eachRow(db, db.orm.WithContext(ctx).Model(&models.PersistedGatewayStatus{}).Where("deleted_at IS NULL").Order("timestamp"), fn)
+}
+
+// Vacuum rebuilds the database file, so that the space of the deleted rows goes back to the filesystem rather than
+// staying allocated to the file forever. It returns the number of bytes reclaimed. The database is locked while it
+// runs, which may take a while for a big one. Only sqlite needs it, on other databases it does nothing.
+func (db *Db) Vacuum(ctx context.Context) (int64, error) {
+	if db.orm.Dialector.Name() != "sqlite" {
+		return 0, nil
+	}
+	before, err := db.size(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err := db.orm.WithContext(ctx).Exec("VACUUM").Error; err != nil {
+		return 0, err
+	}
+	after, err := db.size(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return before - after, nil
+}
+
+// size returns the size of the sqlite database in bytes
+func (db *Db) size(ctx context.Context) (int64, error) {
+	var pageCount, pageSize int64
+	if err := db.orm.WithContext(ctx).Raw("PRAGMA page_count").Scan(&pageCount).Error; err != nil {
+		return 0, err
+	}
+	if err := db.orm.WithContext(ctx).Raw("PRAGMA page_size").Scan(&pageSize).Error; err != nil {
+		return 0, err
+	}
+	return pageCount * pageSize, nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mixmining
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/nymtech/node-status-api/models"
+)
+
+// benchmarkNodeCounts are the network sizes the report benchmarks run at
+var benchmarkNodeCounts = []int{1000, 5000, 10000, 40000}
+
+// benchmarkReports returns a report for each of n nodes, along with their pubkeys
+func benchmarkReports(n int) (models.BatchMixStatusReport, []string) {
+	reports := make([]models.MixStatusReport, n)
+	pubkeys := make([]string, n)
+	for i := range reports {
+		pubkeys[i] = fmt.Sprintf("node%05d", i)
+		reports[i] = models.MixStatusReport{PubKey: pubkeys[i], Owner: "owner", MostRecentIPV4: true, LastHourIPV4: 100, LastDayIPV4: 100}
+	}
+	return models.BatchMixStatusReport{Report: reports}, pubkeys
+}
+
+func BenchmarkBatchLoadMixReports(b *testing.B) {
+	for _, n := range benchmarkNodeCounts {
+		b.Run(fmt.Sprintf("%dk", n/1000), func(b *testing.B) {
+			db := NewDb(true)
+			reports, pubkeys := benchmarkReports(n)
+			db.SaveBatchMixStatusReport(reports)
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if loaded := db.BatchLoadMixReports(context.Background(), pubkeys); len(loaded.Report) != n {
+					b.Fatalf("loaded %d reports out of %d", len(loaded.Report), n)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSaveBatchMixStatusReport(b *testing.B) {
+	for _, n := range benchmarkNodeCounts {
+		b.Run(fmt.Sprintf("%dk", n/1000), func(b *testing.B) {
+			db := NewDb(true)
+			reports, _ := benchmarkReports(n)
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				db.SaveBatchMixStatusReport(reports)
+			}
+		})
+	}
+}
+
+func BenchmarkSaveBatchMixStatusReportIfUnchanged(b *testing.B) {
+	for _, n := range benchmarkNodeCounts {
+		b.Run(fmt.Sprintf("%dk", n/1000), func(b *testing.B) {
+			db := NewDb(true)
+			reports, _ := benchmarkReports(n)
+			db.SaveBatchMixStatusReportIfUnchanged(reports)
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if changed := db.SaveBatchMixStatusReportIfUnchanged(reports); len(changed) > 0 {
+					b.Fatalf("%d reports out of %d weren't saved", len(changed), n)
+				}
+			}
+		})
+	}
+}
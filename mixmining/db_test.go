@@ -15,11 +15,21 @@
 package mixmining
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/BorisBorshevsky/timemock"
 	"github.com/nymtech/node-status-api/mixmining/fixtures"
 	"github.com/nymtech/node-status-api/models"
 	. "github.com/onsi/ginkgo"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -29,11 +39,155 @@ var _ = Describe("The mixmining db", func() {
 			It("should have no mixmining statuses", func() {
 				db := NewDb(true)
 				db.orm.Exec("DELETE FROM persisted_mix_statuses")
-				assert.Len(GinkgoT(), db.ListMixStatus("foo", 5), 0)
+				assert.Len(GinkgoT(), db.ListMixStatus(context.Background(), "foo", 5), 0)
 			})
 		})
 	})
 
+	Describe("Resolving the db path", func() {
+		Context("when the directory and file name are configured", func() {
+			It("should create the directory and use the configured path", func() {
+				base, err := ioutil.TempDir("", "mixmining")
+				assert.Nil(GinkgoT(), err)
+				defer os.RemoveAll(base)
+				dir := path.Join(base, "nested", "dir")
+
+				os.Setenv(DbDirEnv, dir)
+				defer os.Unsetenv(DbDirEnv)
+				os.Setenv(DbFileEnv, "custom.db")
+				defer os.Unsetenv(DbFileEnv)
+
+				assert.Equal(GinkgoT(), path.Join(dir, "custom.db"), dbPath(false))
+				assert.DirExists(GinkgoT(), dir)
+			})
+		})
+	})
+
+	Describe("Opening the db", func() {
+		It("should use the WAL journal mode and a busy timeout", func() {
+			os.Setenv(DbInMemoryEnv, "false")
+			defer os.Unsetenv(DbInMemoryEnv)
+			db := NewDb(true)
+
+			var journalMode string
+			db.orm.Raw("PRAGMA journal_mode").Scan(&journalMode)
+			var busyTimeout int
+			db.orm.Raw("PRAGMA busy_timeout").Scan(&busyTimeout)
+
+			assert.Equal(GinkgoT(), "wal", journalMode)
+			assert.Equal(GinkgoT(), 5000, busyTimeout)
+		})
+
+		Context("in tests", func() {
+			It("should keep each database in memory on its own", func() {
+				first := NewDb(true)
+				second := NewDb(true)
+				first.AddMixStatus(models.PersistedMixStatus{PubKey: "aaa", IPVersion: "4", Timestamp: 100})
+
+				var file string
+				first.orm.Raw("SELECT file FROM pragma_database_list WHERE name = 'main'").Scan(&file)
+				assert.Equal(GinkgoT(), "", file)
+				assert.Equal(GinkgoT(), int64(1), first.CountMixStatuses(context.Background()))
+				assert.Equal(GinkgoT(), int64(0), second.CountMixStatuses(context.Background()))
+			})
+			It("should add the statuses while an export goes through them", func() {
+				db := NewDb(true)
+				db.BatchAddMixStatus([]models.PersistedMixStatus{
+					{PubKey: "aaa", IPVersion: "4", Timestamp: 100},
+					{PubKey: "bbb", IPVersion: "4", Timestamp: 200},
+				})
+
+				exported := 0
+				err := db.EachMixStatus(context.Background(), func(models.PersistedMixStatus) error {
+					exported++
+					if exported > 1 {
+						return nil
+					}
+					added := make(chan error, 1)
+					go func() {
+						added <- db.AddMixStatus(models.PersistedMixStatus{PubKey: "ccc", IPVersion: "4", Timestamp: 300})
+					}()
+					select {
+					case err := <-added:
+						return err
+					case <-time.After(time.Second):
+						return errors.New("the status wasn't added while the export was running")
+					}
+				})
+
+				assert.Nil(GinkgoT(), err)
+				assert.Equal(GinkgoT(), int64(3), db.CountMixStatuses(context.Background()))
+			})
+			It("should run the writes one after the other rather than fail them", func() {
+				db := NewDb(true)
+
+				errs := make(chan error, 10)
+				var wg sync.WaitGroup
+				for i := 0; i < 10; i++ {
+					wg.Add(1)
+					go func(i int) {
+						defer wg.Done()
+						statuses := make([]models.PersistedMixStatus, 100)
+						for j := range statuses {
+							statuses[j] = models.PersistedMixStatus{PubKey: fmt.Sprintf("key%d", i), IPVersion: "4", Timestamp: int64(j)}
+						}
+						errs <- db.BatchAddMixStatus(statuses)
+						db.SaveMixStatusReport(models.MixStatusReport{PubKey: fmt.Sprintf("key%d", i)})
+					}(i)
+				}
+				wg.Wait()
+				close(errs)
+
+				for err := range errs {
+					assert.Nil(GinkgoT(), err)
+				}
+				assert.Equal(GinkgoT(), int64(1000), db.CountMixStatuses(context.Background()))
+				assert.Len(GinkgoT(), db.BatchLoadAllMixReports(context.Background()).Report, 10)
+			})
+		})
+
+		Context("when the connection parameters are overridden", func() {
+			It("should use the configured ones", func() {
+				os.Setenv(DbParamsEnv, "_busy_timeout=100")
+				defer os.Unsetenv(DbParamsEnv)
+
+				assert.Equal(GinkgoT(), "test.db?_busy_timeout=100", dbDSN("test.db"))
+			})
+		})
+
+		Context("when the connection parameters are overridden with an empty string", func() {
+			It("should not use any", func() {
+				os.Setenv(DbParamsEnv, "")
+				defer os.Unsetenv(DbParamsEnv)
+
+				assert.Equal(GinkgoT(), "test.db", dbDSN("test.db"))
+			})
+		})
+	})
+
+	Describe("Listing the statuses of multiple nodes", func() {
+		It("should list the most recent ones of each node, up to the limit", func() {
+			db := NewDb(true)
+			statusAt := func(pubkey string, timestamp int64) models.PersistedMixStatus {
+				return models.PersistedMixStatus{PubKey: pubkey, Owner: "owner", IPVersion: "4", Up: true, Timestamp: timestamp}
+			}
+			db.BatchAddMixStatus([]models.PersistedMixStatus{
+				statusAt("aaa", 1), statusAt("aaa", 2), statusAt("aaa", 3),
+				statusAt("bbb", 1),
+				statusAt("ccc", 2), statusAt("ccc", 1),
+				statusAt("other", 4),
+			})
+			db.RetractMixStatus(context.Background(), "aaa", "4", 3)
+
+			statuses := db.ListMixStatusBulk(context.Background(), []string{"aaa", "bbb", "ccc"}, 2)
+			var keys []string
+			for _, status := range statuses {
+				keys = append(keys, fmt.Sprintf("%s@%d", status.PubKey, status.Timestamp))
+			}
+			assert.Equal(GinkgoT(), []string{"aaa@2", "aaa@1", "bbb@1", "ccc@2", "ccc@1"}, keys)
+		})
+	})
+
 	Describe("adding and retrieving measurements", func() {
 		Context("a new db", func() {
 			It("should add measurements to the db, with a timestamp, and be able to retrieve them afterwards", func() {
@@ -43,18 +197,32 @@ var _ = Describe("The mixmining db", func() {
 
 				// add one
 				db.AddMixStatus(status)
-				measurements := db.ListMixStatus(status.PubKey, 5)
+				measurements := db.ListMixStatus(context.Background(), status.PubKey, 5)
 				assert.Len(GinkgoT(), measurements, 1)
 				assert.Equal(GinkgoT(), status, measurements[0])
 
 				// add another
 				db.AddMixStatus(status)
-				measurements = db.ListMixStatus(status.PubKey, 5)
+				measurements = db.ListMixStatus(context.Background(), status.PubKey, 5)
 				assert.Len(GinkgoT(), measurements, 2)
 				assert.Equal(GinkgoT(), status, measurements[0])
 				assert.Equal(GinkgoT(), status, measurements[1])
 			})
 		})
+		Context("when only some of the statuses carry a round-trip time", func() {
+			It("should keep the round-trip times of those that do", func() {
+				db := NewDb(true)
+				rtt := 42
+				withRTT := fixtures.GoodPersistedMixStatus()
+				withRTT.RTTMillis = &rtt
+				withoutRTT := fixtures.GoodPersistedMixStatus()
+				withoutRTT.Timestamp--
+
+				db.BatchAddMixStatus([]models.PersistedMixStatus{withRTT, withoutRTT})
+				measurements := db.ListMixStatus(context.Background(), withRTT.PubKey, 5)
+				assert.Equal(GinkgoT(), []models.PersistedMixStatus{withRTT, withoutRTT}, measurements)
+			})
+		})
 	})
 
 	Describe("listing mix statuses within a date range", func() {
@@ -62,7 +230,7 @@ var _ = Describe("The mixmining db", func() {
 			It("should return an empty slice", func() {
 				db := NewDb(true)
 				db.orm.Exec("DELETE FROM persisted_mix_statuses")
-				assert.Len(GinkgoT(), db.ListMixStatusDateRange("foo", "6", 1, 1), 0)
+				assert.Len(GinkgoT(), db.ListMixStatusDateRange(context.Background(), "foo", "6", 1, 1), 0)
 			})
 		})
 		Context("when one status exists in the range and one outside", func() {
@@ -70,18 +238,12 @@ var _ = Describe("The mixmining db", func() {
 				db := NewDb(true)
 				db.orm.Exec("DELETE FROM persisted_mix_statuses")
 				data := fixtures.GoodMixStatus()
-				statusInRange := models.PersistedMixStatus{
-					MixStatus: data,
-					Timestamp: 500,
-				}
-				statusOutOfRange := models.PersistedMixStatus{
-					MixStatus: data,
-					Timestamp: 1000,
-				}
+				statusInRange := models.NewPersistedMixStatus(data, 500)
+				statusOutOfRange := models.NewPersistedMixStatus(data, 1000)
 				db.AddMixStatus(statusInRange)
 				db.AddMixStatus(statusOutOfRange)
 
-				result := db.ListMixStatusDateRange(data.PubKey, "6", 0, 500)
+				result := db.ListMixStatusDateRange(context.Background(), data.PubKey, "6", 0, 500)
 				assert.Len(GinkgoT(), result, 1)
 				assert.Equal(GinkgoT(), statusInRange, result[0])
 			})
@@ -95,23 +257,14 @@ var _ = Describe("The mixmining db", func() {
 
 				ip6data := fixtures.GoodMixStatus()
 				ip6data.IPVersion = "6"
-				ip4statusInRange := models.PersistedMixStatus{
-					MixStatus: ip4data,
-					Timestamp: 500,
-				}
-				ip6statusInRange := models.PersistedMixStatus{
-					MixStatus: ip6data,
-					Timestamp: 500,
-				}
-				ip4statusOutOfRange := models.PersistedMixStatus{
-					MixStatus: ip4data,
-					Timestamp: 1000,
-				}
+				ip4statusInRange := models.NewPersistedMixStatus(ip4data, 500)
+				ip6statusInRange := models.NewPersistedMixStatus(ip6data, 500)
+				ip4statusOutOfRange := models.NewPersistedMixStatus(ip4data, 1000)
 				db.AddMixStatus(ip4statusInRange)
 				db.AddMixStatus(ip6statusInRange)
 				db.AddMixStatus(ip4statusOutOfRange)
 
-				result := db.ListMixStatusDateRange(ip4statusInRange.PubKey, "4", 0, 500)
+				result := db.ListMixStatusDateRange(context.Background(), ip4statusInRange.PubKey, "4", 0, 500)
 				assert.Len(GinkgoT(), result, 1)
 				assert.Equal(GinkgoT(), ip4statusInRange, result[0])
 			})
@@ -123,7 +276,7 @@ var _ = Describe("The mixmining db", func() {
 			It("should return an empty slice", func() {
 				db := NewDb(true)
 				defer db.orm.Exec("DELETE FROM persisted_mix_statuses")
-				assert.Len(GinkgoT(), db.ListMixStatus("foo", 5), 0)
+				assert.Len(GinkgoT(), db.ListMixStatus(context.Background(), "foo", 5), 0)
 			})
 		})
 	})
@@ -145,7 +298,8 @@ var _ = Describe("The mixmining db", func() {
 					LastDayIPV6:      50,
 				}
 				db.SaveMixStatusReport(newReport)
-				saved := db.LoadMixReport(newReport.PubKey)
+				saved := loadMixReport(db, newReport.PubKey)
+				newReport.Version = 1
 				assert.Equal(GinkgoT(), newReport, saved)
 			})
 		})
@@ -171,7 +325,7 @@ var _ = Describe("The mixmining db", func() {
 				db.orm.Model(&models.MixStatusReport{}).Where("pub_key = ?", "key").Count(&firstCount)
 				assert.Equal(GinkgoT(), int64(1), firstCount)
 
-				report := db.LoadMixReport("key")
+				report := loadMixReport(db, "key")
 				report.Last5MinutesIPV4 = 666
 
 				db.SaveMixStatusReport(report)
@@ -180,10 +334,194 @@ var _ = Describe("The mixmining db", func() {
 				db.orm.Model(&models.MixStatusReport{}).Where("pub_key = ?", "key").Count(&secondCount)
 				assert.Equal(GinkgoT(), int64(1), secondCount)
 
-				reloadedReport := db.LoadMixReport("key")
+				reloadedReport := loadMixReport(db, "key")
 				assert.Equal(GinkgoT(), 666, reloadedReport.Last5MinutesIPV4)
 			})
 		})
+		Context("when two saves create the report at the same time", func() {
+			It("should store a single report, the one saved last", func() {
+				db := newFileDb()
+				db.orm.Exec("DELETE FROM mix_status_reports")
+
+				reports := []models.MixStatusReport{
+					{PubKey: "key", Owner: "alice", LastDayIPV4: 10},
+					{PubKey: "key", Owner: "bob", LastDayIPV4: 20},
+				}
+				start := make(chan struct{})
+				var wg sync.WaitGroup
+				for _, report := range reports {
+					wg.Add(1)
+					go func(report models.MixStatusReport) {
+						defer wg.Done()
+						<-start
+						db.SaveMixStatusReport(report)
+					}(report)
+				}
+				close(start)
+				wg.Wait()
+
+				var count int64
+				db.orm.Model(&models.MixStatusReport{}).Where("pub_key = ?", "key").Count(&count)
+				assert.Equal(GinkgoT(), int64(1), count)
+				saved := loadMixReport(db, "key")
+				assert.Equal(GinkgoT(), int64(2), saved.Version)
+				saved.Version = 0
+				assert.Contains(GinkgoT(), reports, saved)
+			})
+			It("should overwrite the report another save created right before it", func() {
+				db := newFileDb()
+				db.orm.Exec("DELETE FROM mix_status_reports")
+				// the other save creates the report after this one found it missing, but before it creates it
+				rivalled := false
+				db.orm.Callback().Create().Before("gorm:begin_transaction").Register("test:rival", func(*gorm.DB) {
+					if !rivalled {
+						rivalled = true
+						db.SaveMixStatusReport(models.MixStatusReport{PubKey: "key", Owner: "alice", LastDayIPV4: 10})
+					}
+				})
+				var failed int32
+				db.orm.Callback().Create().After("gorm:create").Register("test:failures", func(tx *gorm.DB) {
+					if tx.Error != nil {
+						atomic.AddInt32(&failed, 1)
+					}
+				})
+
+				db.SaveMixStatusReport(models.MixStatusReport{PubKey: "key", Owner: "bob", LastDayIPV4: 20})
+
+				assert.True(GinkgoT(), rivalled)
+				assert.Equal(GinkgoT(), int32(0), atomic.LoadInt32(&failed))
+				saved := loadMixReport(db, "key")
+				assert.Equal(GinkgoT(), "bob", saved.Owner)
+				assert.Equal(GinkgoT(), 20, saved.LastDayIPV4)
+			})
+			It("should overwrite the stored report, bumping its version", func() {
+				db := NewDb(true)
+				db.SaveMixStatusReportIfUnchanged(models.MixStatusReport{PubKey: "key", Owner: "alice", LastDayIPV4: 10})
+
+				db.SaveMixStatusReport(models.MixStatusReport{PubKey: "key", Owner: "bob", LastDayIPV6: 20})
+
+				saved := loadMixReport(db, "key")
+				assert.Equal(GinkgoT(), "bob", saved.Owner)
+				assert.Equal(GinkgoT(), 0, saved.LastDayIPV4)
+				assert.Equal(GinkgoT(), 20, saved.LastDayIPV6)
+				assert.Equal(GinkgoT(), int64(2), saved.Version)
+			})
+		})
+		Context("when the node never reported", func() {
+			It("should return an empty report without an error", func() {
+				db := NewDb(true)
+
+				report, err := db.LoadMixReport(context.Background(), "unknown")
+				assert.Nil(GinkgoT(), err)
+				assert.Equal(GinkgoT(), models.MixStatusReport{}, report)
+			})
+		})
+		Context("when the request has been cancelled", func() {
+			It("should not load the report", func() {
+				db := NewDb(true)
+				db.orm.Exec("DELETE FROM mix_status_reports")
+				db.SaveMixStatusReport(models.MixStatusReport{PubKey: "key", LastDayIPV4: 15})
+
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				report, err := db.LoadMixReport(ctx, "key")
+				assert.NotNil(GinkgoT(), err)
+				assert.Equal(GinkgoT(), models.MixStatusReport{}, report)
+			})
+		})
+	})
+
+	Describe("Saving a mix status report unless it changed", func() {
+		It("should create a report that doesn't exist yet", func() {
+			db := NewDb(true)
+
+			assert.True(GinkgoT(), db.SaveMixStatusReportIfUnchanged(models.MixStatusReport{PubKey: "key", LastDayIPV4: 15}))
+			saved := loadMixReport(db, "key")
+			assert.Equal(GinkgoT(), 15, saved.LastDayIPV4)
+			assert.Equal(GinkgoT(), int64(1), saved.Version)
+		})
+		It("should refuse to overwrite a report saved since it was loaded", func() {
+			db := NewDb(true)
+			db.SaveMixStatusReportIfUnchanged(models.MixStatusReport{PubKey: "key"})
+			first := loadMixReport(db, "key")
+			second := loadMixReport(db, "key")
+
+			first.LastDayIPV4 = 10
+			assert.True(GinkgoT(), db.SaveMixStatusReportIfUnchanged(first))
+			second.LastDayIPV6 = 20
+			assert.False(GinkgoT(), db.SaveMixStatusReportIfUnchanged(second))
+
+			saved := loadMixReport(db, "key")
+			assert.Equal(GinkgoT(), 10, saved.LastDayIPV4)
+			assert.Equal(GinkgoT(), 0, saved.LastDayIPV6)
+		})
+		It("should refuse to create a report someone else created since it was loaded", func() {
+			db := NewDb(true)
+			missing := loadMixReport(db, "key")
+			db.SaveMixStatusReportIfUnchanged(models.MixStatusReport{PubKey: "key", LastDayIPV4: 10})
+
+			missing.PubKey = "key"
+			missing.LastDayIPV6 = 20
+			assert.False(GinkgoT(), db.SaveMixStatusReportIfUnchanged(missing))
+			assert.Equal(GinkgoT(), 10, loadMixReport(db, "key").LastDayIPV4)
+		})
+		It("should refuse to overwrite a report recomputed since it was loaded", func() {
+			db := NewDb(true)
+			db.SaveMixStatusReport(models.MixStatusReport{PubKey: "key", LastDayIPV4: 10})
+			loaded := loadMixReport(db, "key")
+			db.SaveMixStatusReport(models.MixStatusReport{PubKey: "key", LastDayIPV4: 20})
+
+			loaded.LastDayIPV6 = 30
+			assert.False(GinkgoT(), db.SaveMixStatusReportIfUnchanged(loaded))
+			assert.Equal(GinkgoT(), 20, loadMixReport(db, "key").LastDayIPV4)
+		})
+		It("should update a report saved without a version", func() {
+			db := NewDb(true)
+			db.SaveMixStatusReport(models.MixStatusReport{PubKey: "key", LastDayIPV4: 10})
+			report := loadMixReport(db, "key")
+
+			report.LastDayIPV4 = 20
+			assert.True(GinkgoT(), db.SaveMixStatusReportIfUnchanged(report))
+			assert.Equal(GinkgoT(), 20, loadMixReport(db, "key").LastDayIPV4)
+		})
+	})
+
+	Describe("Saving a batch of mix status reports unless they changed", func() {
+		It("should save the unchanged reports and return the pubkeys of the other ones", func() {
+			db := NewDb(true)
+			db.SaveMixStatusReportIfUnchanged(models.MixStatusReport{PubKey: "stale"})
+			db.SaveMixStatusReportIfUnchanged(models.MixStatusReport{PubKey: "current"})
+			batch := db.BatchLoadMixReports(context.Background(), []string{"stale", "current"})
+			db.SaveMixStatusReportIfUnchanged(loadMixReport(db, "stale"))
+
+			for i := range batch.Report {
+				batch.Report[i].LastDayIPV4 = 10
+			}
+			batch.Report = append(batch.Report, models.MixStatusReport{PubKey: "fresh", LastDayIPV4: 10})
+			changed := db.SaveBatchMixStatusReportIfUnchanged(batch)
+
+			assert.Equal(GinkgoT(), []string{"stale"}, changed)
+			assert.Equal(GinkgoT(), 0, loadMixReport(db, "stale").LastDayIPV4)
+			for _, pubkey := range []string{"current", "fresh"} {
+				saved := loadMixReport(db, pubkey)
+				assert.Equal(GinkgoT(), 10, saved.LastDayIPV4)
+				for _, report := range batch.Report {
+					if report.PubKey == pubkey {
+						assert.Equal(GinkgoT(), saved.Version, report.Version, "the versions in the batch follow the saved ones")
+					}
+				}
+			}
+		})
+		It("should refuse to create a report someone else created since it was loaded", func() {
+			db := NewDb(true)
+			db.SaveMixStatusReport(models.MixStatusReport{PubKey: "key", LastDayIPV4: 10})
+
+			changed := db.SaveBatchMixStatusReportIfUnchanged(models.BatchMixStatusReport{Report: []models.MixStatusReport{{PubKey: "key", LastDayIPV4: 20}}})
+
+			assert.Equal(GinkgoT(), []string{"key"}, changed)
+			assert.Equal(GinkgoT(), 10, loadMixReport(db, "key").LastDayIPV4)
+		})
 	})
 
 	Describe("Getting active nodes", func() {
@@ -193,30 +531,22 @@ var _ = Describe("The mixmining db", func() {
 			db := NewDb(true)
 
 			status1 := models.PersistedMixStatus{
-				MixStatus: models.MixStatus{
-					PubKey:           "aaa",
-				},
+				PubKey:    "aaa",
 				Timestamp: now.UnixNano(),
 			}
 
 			status2 := models.PersistedMixStatus{
-				MixStatus: models.MixStatus{
-					PubKey:           "bbb",
-				},
+				PubKey:    "bbb",
 				Timestamp: now.UnixNano(),
 			}
 
 			status3 := models.PersistedMixStatus{
-				MixStatus: models.MixStatus{
-					PubKey:           "ccc",
-				},
+				PubKey:    "ccc",
 				Timestamp: now.UnixNano(),
 			}
 
 			status4Duplicate := models.PersistedMixStatus{
-				MixStatus: models.MixStatus{
-					PubKey:           "ccc",
-				},
+				PubKey:    "ccc",
 				Timestamp: timemock.Now().UnixNano(),
 			}
 
@@ -226,9 +556,479 @@ var _ = Describe("The mixmining db", func() {
 			db.AddMixStatus(status4Duplicate)
 
 			dayAgo := now.Add(time.Duration(-1) * time.Hour * 24).UnixNano()
-			active := db.GetActiveMixes(dayAgo)
+			active := db.GetActiveMixes(context.Background(), "", dayAgo)
 
 			assert.Equal(GinkgoT(), active, []string{"aaa", "bbb", "ccc"})
 		})
+		It("should only count the statuses of the ip version if one is given", func() {
+			db := NewDb(true)
+			db.BatchAddMixStatus([]models.PersistedMixStatus{
+				{PubKey: "both", IPVersion: "4", Timestamp: 100},
+				{PubKey: "both", IPVersion: "6", Timestamp: 100},
+				{PubKey: "v4only", IPVersion: "4", Timestamp: 100},
+			})
+
+			assert.Equal(GinkgoT(), []string{"both", "v4only"}, db.GetActiveMixes(context.Background(), "4", 0))
+			assert.Equal(GinkgoT(), []string{"both"}, db.GetActiveMixes(context.Background(), "6", 0))
+		})
+	})
+
+	Describe("Counting statuses", func() {
+		Context("for an empty db", func() {
+			It("should return zeros", func() {
+				db := NewDb(true)
+				assert.Equal(GinkgoT(), int64(0), db.CountMixStatuses(context.Background()))
+				assert.Equal(GinkgoT(), int64(0), db.CountGatewayStatuses(context.Background()))
+				assert.Equal(GinkgoT(), int64(0), db.OldestStatusTimestamp(context.Background()))
+			})
+		})
+		Context("after inserting statuses", func() {
+			It("should count them and find the oldest one", func() {
+				db := NewDb(true)
+				db.BatchAddMixStatus([]models.PersistedMixStatus{
+					{PubKey: "aaa", IPVersion: "4", Timestamp: 300},
+					{PubKey: "aaa", IPVersion: "6", Timestamp: 200},
+					{PubKey: "bbb", IPVersion: "4", Timestamp: 400},
+				})
+				db.BatchAddGatewayStatus([]models.PersistedGatewayStatus{
+					{PubKey: "ccc", IPVersion: "4", Timestamp: 100},
+					{PubKey: "ccc", IPVersion: "6", Timestamp: 500},
+				})
+
+				assert.Equal(GinkgoT(), int64(3), db.CountMixStatuses(context.Background()))
+				assert.Equal(GinkgoT(), int64(2), db.CountGatewayStatuses(context.Background()))
+				assert.Equal(GinkgoT(), int64(100), db.OldestStatusTimestamp(context.Background()))
+			})
+		})
+	})
+
+	Describe("Counting query timeouts", func() {
+		It("should count the queries that ran out of time", func() {
+			db := NewDb(true)
+			db.orm.Callback().Query().Before("gorm:query").Register("test:slow", func(tx *gorm.DB) {
+				<-tx.Statement.Context.Done()
+			})
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			report, err := db.LoadMixReport(ctx, "key")
+			assert.NotNil(GinkgoT(), err)
+			assert.Equal(GinkgoT(), models.MixStatusReport{}, report)
+			assert.Equal(GinkgoT(), int64(1), db.QueryTimeouts())
+		})
+		It("should leave the queries that completed in time out", func() {
+			db := NewDb(true)
+			loadMixReport(db, "key")
+			assert.Equal(GinkgoT(), int64(0), db.QueryTimeouts())
+		})
+	})
+
+	Describe("Computing the ingestion lag", func() {
+		It("should summarise the gap between the measurement and the reception of the recent statuses", func() {
+			db := NewDb(true)
+			ms := int64(time.Millisecond)
+			db.BatchAddMixStatus([]models.PersistedMixStatus{
+				{PubKey: "aaa", IPVersion: "4", Timestamp: 1000 * ms, MeasuredAt: 990 * ms},
+				{PubKey: "aaa", IPVersion: "6", Timestamp: 1000 * ms, MeasuredAt: 970 * ms},
+				{PubKey: "bbb", IPVersion: "4", Timestamp: 100 * ms, MeasuredAt: 0},
+				{PubKey: "bbb", IPVersion: "6", Timestamp: 50 * ms, MeasuredAt: 10 * ms},
+			})
+
+			lag := db.MixIngestionLag(context.Background(), 500*ms)
+			assert.Equal(GinkgoT(), models.IngestionLag{Statuses: 2, AverageMillis: 20, MaxMillis: 30}, lag)
+		})
+		It("should show no lag for statuses without a measurement time", func() {
+			db := NewDb(true)
+			status := models.NewPersistedMixStatus(models.MixStatus{PubKey: "aaa", IPVersion: "4"}, int64(time.Second))
+			db.AddMixStatus(status)
+
+			lag := db.MixIngestionLag(context.Background(), 0)
+			assert.Equal(GinkgoT(), models.IngestionLag{Statuses: 1}, lag)
+		})
+		It("should return zeros for an empty db", func() {
+			db := NewDb(true)
+			assert.Equal(GinkgoT(), models.IngestionLag{}, db.MixIngestionLag(context.Background(), 0))
+		})
+	})
+
+	Describe("Retracting statuses", func() {
+		It("should leave a retracted mix status out of every query", func() {
+			db := NewDb(true)
+			db.BatchAddMixStatus([]models.PersistedMixStatus{
+				{PubKey: "aaa", IPVersion: "4", Up: true, Timestamp: 100},
+				{PubKey: "aaa", IPVersion: "4", Up: false, Timestamp: 200},
+				{PubKey: "aaa", IPVersion: "6", Up: false, Timestamp: 200},
+			})
+
+			assert.True(GinkgoT(), db.RetractMixStatus(context.Background(), "aaa", "4", 200))
+			assert.Len(GinkgoT(), db.ListMixStatusSince(context.Background(), "aaa", "4", 0), 1)
+			assert.Len(GinkgoT(), db.ListMixStatus(context.Background(), "aaa", 10), 2)
+			assert.Equal(GinkgoT(), int64(2), db.CountMixStatuses(context.Background()))
+			var exported []int64
+			db.EachMixStatus(context.Background(), func(status models.PersistedMixStatus) error {
+				exported = append(exported, status.Timestamp)
+				return nil
+			})
+			assert.Equal(GinkgoT(), []int64{100, 200}, exported)
+		})
+		It("should report a status that doesn't exist", func() {
+			db := NewDb(true)
+			db.BatchAddMixStatus([]models.PersistedMixStatus{{PubKey: "aaa", IPVersion: "4", Timestamp: 100}})
+
+			assert.False(GinkgoT(), db.RetractMixStatus(context.Background(), "aaa", "4", 101))
+			assert.False(GinkgoT(), db.RetractMixStatus(context.Background(), "aaa", "6", 100))
+			assert.False(GinkgoT(), db.RetractGatewayStatus(context.Background(), "aaa", "4", 100))
+		})
+		It("should still purge retracted statuses once they're old", func() {
+			db := NewDb(true)
+			db.BatchAddGatewayStatus([]models.PersistedGatewayStatus{{PubKey: "aaa", IPVersion: "4", Timestamp: 100}})
+			assert.True(GinkgoT(), db.RetractGatewayStatus(context.Background(), "aaa", "4", 100))
+
+			db.RemoveOldGatewayStatuses(200)
+			var remaining int64
+			db.orm.Unscoped().Model(&models.PersistedGatewayStatus{}).Count(&remaining)
+			assert.Equal(GinkgoT(), int64(0), remaining)
+		})
+	})
+
+	Describe("Listing mix statuses by state", func() {
+		It("should only return the statuses in that state", func() {
+			db := NewDb(true)
+			db.BatchAddMixStatus([]models.PersistedMixStatus{
+				{PubKey: "aaa", IPVersion: "4", Up: true, Timestamp: 100},
+				{PubKey: "aaa", IPVersion: "4", Up: false, Timestamp: 200},
+				{PubKey: "aaa", IPVersion: "4", Up: true, Timestamp: 300},
+				{PubKey: "aaa", IPVersion: "4", Up: false, Timestamp: 400},
+				{PubKey: "aaa", IPVersion: "6", Up: false, Timestamp: 400},
+				{PubKey: "bbb", IPVersion: "4", Up: false, Timestamp: 400},
+				{PubKey: "aaa", IPVersion: "4", Up: false, Timestamp: 50},
+			})
+
+			var timestamps []int64
+			for _, status := range db.ListMixStatusByState(context.Background(), "aaa", "4", false, 100) {
+				assert.False(GinkgoT(), status.Up)
+				timestamps = append(timestamps, status.Timestamp)
+			}
+			assert.Equal(GinkgoT(), []int64{400, 200}, timestamps)
+		})
+	})
+
+	Describe("Listing mix statuses by epoch", func() {
+		It("should return the statuses of all nodes attributed to the epoch, oldest first", func() {
+			db := NewDb(true)
+			epoch, next := uint64(7), uint64(8)
+			db.BatchAddMixStatus([]models.PersistedMixStatus{
+				{PubKey: "aaa", IPVersion: "4", Timestamp: 300, Epoch: &epoch},
+				{PubKey: "bbb", IPVersion: "6", Timestamp: 100, Epoch: &epoch},
+				{PubKey: "aaa", IPVersion: "4", Timestamp: 200, Epoch: &next},
+				{PubKey: "aaa", IPVersion: "4", Timestamp: 250},
+				{PubKey: "ccc", IPVersion: "4", Timestamp: 400, Epoch: &epoch},
+			})
+			// retracted statuses don't count
+			assert.True(GinkgoT(), db.RetractMixStatus(context.Background(), "ccc", "4", 400))
+
+			var timestamps []int64
+			for _, status := range db.ListMixStatusByEpoch(context.Background(), epoch) {
+				assert.Equal(GinkgoT(), epoch, *status.Epoch)
+				timestamps = append(timestamps, status.Timestamp)
+			}
+			assert.Equal(GinkgoT(), []int64{100, 300}, timestamps)
+			assert.Empty(GinkgoT(), db.ListMixStatusByEpoch(context.Background(), 9))
+		})
+	})
+
+	Describe("Node lifetimes", func() {
+		It("should span the first and the last retained status of the mixnode", func() {
+			db := NewDb(true)
+			db.BatchAddMixStatus([]models.PersistedMixStatus{
+				{PubKey: "aaa", IPVersion: "4", Timestamp: 300},
+				{PubKey: "aaa", IPVersion: "6", Timestamp: 100},
+				{PubKey: "aaa", IPVersion: "4", Timestamp: 200},
+				{PubKey: "aaa", IPVersion: "4", Timestamp: 400},
+				{PubKey: "bbb", IPVersion: "4", Timestamp: 50},
+				{PubKey: "bbb", IPVersion: "4", Timestamp: 500},
+			})
+			// retracted statuses don't count
+			assert.True(GinkgoT(), db.RetractMixStatus(context.Background(), "aaa", "4", 400))
+
+			assert.Equal(GinkgoT(), models.NodeLifetime{FirstSeen: 100, LastSeen: 300, TotalMeasurements: 3}, db.MixLifetime(context.Background(), "aaa"))
+			assert.Equal(GinkgoT(), models.NodeLifetime{}, db.MixLifetime(context.Background(), "ccc"))
+		})
+		It("should do the same for gateways", func() {
+			db := NewDb(true)
+			db.BatchAddGatewayStatus([]models.PersistedGatewayStatus{
+				{PubKey: "aaa", IPVersion: "4", Timestamp: 100},
+				{PubKey: "aaa", IPVersion: "4", Timestamp: 200},
+			})
+			db.BatchAddMixStatus([]models.PersistedMixStatus{{PubKey: "aaa", IPVersion: "4", Timestamp: 900}})
+
+			assert.Equal(GinkgoT(), models.NodeLifetime{FirstSeen: 100, LastSeen: 200, TotalMeasurements: 2}, db.GatewayLifetime(context.Background(), "aaa"))
+		})
+	})
+
+	Describe("Network uptime samples", func() {
+		It("should list the samples since the given time, oldest first", func() {
+			db := NewDb(true)
+			db.AddNetworkUptimeSample(models.NetworkUptimeSample{Timestamp: 300, MeanUptime: 80, ActiveCount: 3})
+			db.AddNetworkUptimeSample(models.NetworkUptimeSample{Timestamp: 100, MeanUptime: 90, ActiveCount: 2})
+			db.AddNetworkUptimeSample(models.NetworkUptimeSample{Timestamp: 200, MeanUptime: 70, ActiveCount: 2})
+
+			assert.Equal(GinkgoT(), []models.NetworkUptimeSample{
+				{Timestamp: 200, MeanUptime: 70, ActiveCount: 2},
+				{Timestamp: 300, MeanUptime: 80, ActiveCount: 3},
+			}, db.ListNetworkUptimeSamples(context.Background(), 200))
+		})
+		It("should remove the samples past the horizon", func() {
+			db := NewDb(true)
+			db.AddNetworkUptimeSample(models.NetworkUptimeSample{Timestamp: 100})
+			db.AddNetworkUptimeSample(models.NetworkUptimeSample{Timestamp: 200})
+
+			db.RemoveOldNetworkUptimeSamples(200)
+			assert.Equal(GinkgoT(), []models.NetworkUptimeSample{{Timestamp: 200}}, db.ListNetworkUptimeSamples(context.Background(), 0))
+		})
+	})
+
+	Describe("Ranking mix reports", func() {
+		It("should return the best n nodes by the given field, breaking ties by pubkey", func() {
+			db := NewDb(true)
+			db.SaveBatchMixStatusReport(models.BatchMixStatusReport{Report: []models.MixStatusReport{
+				{PubKey: "aaa", LastDayIPV4: 50, LastDayIPV6: 100},
+				{PubKey: "bbb", LastDayIPV4: 90},
+				{PubKey: "ccc", LastDayIPV4: 70},
+				{PubKey: "ddd", LastDayIPV4: 90},
+			}})
+
+			var ranked []string
+			for _, report := range db.TopMixReports(context.Background(), "lastDayIPV4", 3).Report {
+				ranked = append(ranked, report.PubKey)
+			}
+			assert.Equal(GinkgoT(), []string{"bbb", "ddd", "ccc"}, ranked)
+			assert.Equal(GinkgoT(), "aaa", db.TopMixReports(context.Background(), "lastDayIPV6", 1).Report[0].PubKey)
+		})
+		It("should refuse to rank by anything but the allowed fields", func() {
+			db := NewDb(true)
+			db.SaveMixStatusReport(models.MixStatusReport{PubKey: "aaa"})
+
+			assert.Empty(GinkgoT(), db.TopMixReports(context.Background(), "pub_key; DROP TABLE mix_status_reports", 1).Report)
+			assert.Empty(GinkgoT(), db.TopMixReports(context.Background(), "owner", 1).Report)
+			assert.Len(GinkgoT(), db.TopMixReports(context.Background(), "lastDayIPV4", 1).Report, 1)
+		})
+	})
+
+	Describe("Finding mixnodes without a report", func() {
+		It("should only return recently active nodes that have no report", func() {
+			db := NewDb(true)
+			db.BatchAddMixStatus([]models.PersistedMixStatus{
+				{PubKey: "aaa", IPVersion: "4", Timestamp: 200},
+				{PubKey: "aaa", IPVersion: "6", Timestamp: 200},
+				{PubKey: "bbb", IPVersion: "4", Timestamp: 200},
+				{PubKey: "ccc", IPVersion: "4", Timestamp: 50},
+			})
+			db.SaveMixStatusReport(models.MixStatusReport{PubKey: "bbb"})
+
+			assert.Equal(GinkgoT(), []string{"aaa"}, db.GetMixesWithoutReport(context.Background(), 100))
+		})
+		It("should return an empty list rather than nil when there are none", func() {
+			db := NewDb(true)
+			assert.Equal(GinkgoT(), []string{}, db.GetMixesWithoutReport(context.Background(), 100))
+		})
+	})
+
+	Describe("Listing owners", func() {
+		It("should return each non-empty owner once", func() {
+			db := NewDb(true)
+			db.BatchAddMixStatus([]models.PersistedMixStatus{
+				{PubKey: "aaa", Owner: "alice", IPVersion: "4", Timestamp: 100},
+				{PubKey: "aaa", Owner: "alice", IPVersion: "6", Timestamp: 100},
+				{PubKey: "bbb", Owner: "alice", IPVersion: "4", Timestamp: 200},
+				{PubKey: "ccc", Owner: "bob", IPVersion: "4", Timestamp: 300},
+				{PubKey: "ddd", Owner: "", IPVersion: "4", Timestamp: 400},
+			})
+			db.BatchAddGatewayStatus([]models.PersistedGatewayStatus{
+				{PubKey: "eee", Owner: "bob", IPVersion: "4", Timestamp: 100},
+				{PubKey: "fff", Owner: "", IPVersion: "4", Timestamp: 200},
+			})
+
+			assert.Equal(GinkgoT(), []string{"alice", "bob"}, db.DistinctMixOwners(context.Background()))
+			assert.Equal(GinkgoT(), []string{"bob"}, db.DistinctGatewayOwners(context.Background()))
+		})
+	})
+
+	Describe("Splitting into chunks", func() {
+		It("should return a single empty chunk for an empty slice", func() {
+			assert.Equal(GinkgoT(), [][]int{{}}, chunkSlice([]int{}, 2))
+		})
+		It("should not split a slice of exactly the chunk size", func() {
+			assert.Equal(GinkgoT(), [][]int{{1, 2}}, chunkSlice([]int{1, 2}, 2))
+		})
+		It("should put the overflow into its own chunk", func() {
+			assert.Equal(GinkgoT(), [][]int{{1, 2}, {3}}, chunkSlice([]int{1, 2, 3}, 2))
+		})
+		It("should not share memory with the original slice", func() {
+			items := []int{1, 2, 3}
+			chunks := chunkSlice(items, 2)
+			chunks[0] = append(chunks[0], 42)
+			chunks[1][0] = 42
+			assert.Equal(GinkgoT(), []int{1, 2, 3}, items)
+		})
+	})
+
+	Describe("Saving more than fits into a single statement", func() {
+		It("should store every status", func() {
+			db := NewDb(true)
+			statuses := make([]models.PersistedMixStatus, MaxStatusesPerInsertion+1)
+			for i := range statuses {
+				statuses[i] = models.PersistedMixStatus{PubKey: fmt.Sprintf("key%d", i), IPVersion: "4", Timestamp: int64(i)}
+			}
+
+			db.BatchAddMixStatus(statuses)
+			assert.Equal(GinkgoT(), int64(MaxStatusesPerInsertion+1), db.CountMixStatuses(context.Background()))
+		})
+		It("should store every report", func() {
+			db := NewDb(true)
+			reports := make([]models.GatewayStatusReport, MaxReportSize+1)
+			pubkeys := make([]string, len(reports))
+			for i := range reports {
+				pubkeys[i] = fmt.Sprintf("key%d", i)
+				reports[i] = models.GatewayStatusReport{PubKey: pubkeys[i], LastDayIPV4: 100}
+			}
+
+			db.SaveBatchGatewayStatusReport(models.BatchGatewayStatusReport{Report: reports})
+			assert.Len(GinkgoT(), db.BatchLoadGatewayReports(context.Background(), pubkeys).Report, MaxReportSize+1)
+		})
+		It("should store every mix report along with its uptime windows", func() {
+			db := NewDb(true)
+			reports := make([]models.MixStatusReport, MaxReportSize+1)
+			pubkeys := make([]string, len(reports))
+			for i := range reports {
+				pubkeys[i] = fmt.Sprintf("key%d", i)
+				reports[i] = models.MixStatusReport{PubKey: pubkeys[i], UptimesIPV4: models.Uptimes{LastDayWindow: 100}}
+			}
+
+			db.SaveBatchMixStatusReport(models.BatchMixStatusReport{Report: reports})
+			loaded := db.BatchLoadMixReports(context.Background(), pubkeys).Report
+			assert.Len(GinkgoT(), loaded, MaxReportSize+1)
+			assert.Equal(GinkgoT(), models.Uptimes{LastDayWindow: 100}, loaded[0].UptimesIPV4)
+			assert.Nil(GinkgoT(), loaded[0].UptimesIPV6)
+		})
+		It("should load the reports of more nodes than sqlite can bind variables for", func() {
+			db := NewDb(true)
+			db.SaveBatchMixStatusReport(models.BatchMixStatusReport{Report: []models.MixStatusReport{{PubKey: "first"}, {PubKey: "last"}}})
+			pubkeys := []string{"first"}
+			for i := 0; i < 33000; i++ {
+				pubkeys = append(pubkeys, fmt.Sprintf("missing%d", i))
+			}
+			pubkeys = append(pubkeys, "last")
+
+			assert.Len(GinkgoT(), db.BatchLoadMixReports(context.Background(), pubkeys).Report, 2)
+			assert.Len(GinkgoT(), db.MixReportOwners(context.Background(), pubkeys), 2)
+
+			db.RemoveMixReports(pubkeys)
+			assert.Empty(GinkgoT(), db.BatchLoadMixReports(context.Background(), pubkeys).Report)
+		})
+		It("should not save any chunk if one of them fails", func() {
+			db := NewDb(true)
+			db.SaveMixStatusReport(models.MixStatusReport{PubKey: "rollback0", LastDayIPV4: 50})
+
+			reports := make([]models.MixStatusReport, MaxReportSize+1)
+			pubkeys := make([]string, len(reports))
+			for i := range reports {
+				pubkeys[i] = fmt.Sprintf("rollback%d", i)
+				reports[i] = models.MixStatusReport{PubKey: pubkeys[i], LastDayIPV4: 100}
+			}
+
+			chunks := 0
+			err := db.orm.Callback().Create().Before("gorm:create").Register("test:fail_second_chunk", func(tx *gorm.DB) {
+				chunks++
+				if chunks == 2 {
+					tx.AddError(errors.New("second chunk failed"))
+				}
+			})
+			assert.Nil(GinkgoT(), err)
+			defer db.orm.Callback().Create().Remove("test:fail_second_chunk")
+
+			db.SaveBatchMixStatusReport(models.BatchMixStatusReport{Report: reports})
+
+			assert.Equal(GinkgoT(), 2, chunks)
+			loaded := db.BatchLoadMixReports(context.Background(), pubkeys).Report
+			assert.Len(GinkgoT(), loaded, 1)
+			assert.Equal(GinkgoT(), 50, loaded[0].LastDayIPV4)
+		})
+	})
+
+	Describe("Vacuuming", func() {
+		It("should reclaim the space of the purged statuses", func() {
+			os.Setenv(DbInMemoryEnv, "false")
+			defer os.Unsetenv(DbInMemoryEnv)
+			db := NewDb(true)
+
+			statuses := make([]models.PersistedMixStatus, 5000)
+			for i := range statuses {
+				statuses[i] = models.PersistedMixStatus{PubKey: fmt.Sprintf("key%d", i), Owner: "owner", IPVersion: "4", Timestamp: int64(i)}
+			}
+			assert.Nil(GinkgoT(), db.BatchAddMixStatus(statuses))
+			db.RemoveOldMixStatuses(int64(len(statuses)))
+
+			reclaimed, err := db.Vacuum(context.Background())
+			assert.Nil(GinkgoT(), err)
+			assert.Greater(GinkgoT(), reclaimed, int64(0))
+			assert.Equal(GinkgoT(), int64(0), db.CountMixStatuses(context.Background()))
+		})
+	})
+
+	Describe("Retrying statuses while the db is locked", func() {
+		// failCreates makes the first `times` creates fail with the given error and returns the number of attempts
+		failCreates := func(db *Db, times int, failure error) *int {
+			attempts := 0
+			err := db.orm.Callback().Create().Before("gorm:create").Register("test:fail_creates", func(tx *gorm.DB) {
+				attempts++
+				if attempts <= times {
+					tx.AddError(failure)
+				}
+			})
+			assert.Nil(GinkgoT(), err)
+			return &attempts
+		}
+
+		It("should store them once the lock goes away", func() {
+			db := NewDb(true)
+			attempts := failCreates(db, 1, errors.New("database is locked"))
+			defer db.orm.Callback().Create().Remove("test:fail_creates")
+
+			assert.Nil(GinkgoT(), db.AddMixStatus(models.PersistedMixStatus{PubKey: "aaa", IPVersion: "4", Timestamp: 100}))
+			assert.Equal(GinkgoT(), 2, *attempts)
+			assert.Len(GinkgoT(), db.ListMixStatus(context.Background(), "aaa", 10), 1)
+		})
+		It("should give up and return the error if the lock stays", func() {
+			db := NewDb(true)
+			attempts := failCreates(db, maxLockedAttempts, errors.New("database table is locked"))
+			defer db.orm.Callback().Create().Remove("test:fail_creates")
+
+			err := db.BatchAddGatewayStatus([]models.PersistedGatewayStatus{{PubKey: "aaa", IPVersion: "4", Timestamp: 100}})
+			assert.NotNil(GinkgoT(), err)
+			assert.Equal(GinkgoT(), maxLockedAttempts, *attempts)
+			assert.Empty(GinkgoT(), db.ListGatewayStatus(context.Background(), "aaa", 10))
+		})
+		It("should not retry other errors", func() {
+			db := NewDb(true)
+			attempts := failCreates(db, 1, errors.New("constraint failed"))
+			defer db.orm.Callback().Create().Remove("test:fail_creates")
+
+			assert.NotNil(GinkgoT(), db.BatchAddMixStatus([]models.PersistedMixStatus{{PubKey: "aaa", IPVersion: "4", Timestamp: 100}}))
+			assert.Equal(GinkgoT(), 1, *attempts)
+		})
 	})
 })
+
+// newFileDb opens a test db kept in a file rather than in memory, for the tests racing writes against each other.
+// The in-memory one runs a single write at a time, so they'd never race there.
+func newFileDb() *Db {
+	return newDb(sqlite.Open(dbDSN(dbPath(true))))
+}
+
+// loadMixReport loads the report of the node, failing the test if it couldn't be loaded
+func loadMixReport(db IDb, pubkey string) models.MixStatusReport {
+	report, err := db.LoadMixReport(context.Background(), pubkey)
+	assert.Nil(GinkgoT(), err)
+	return report
+}
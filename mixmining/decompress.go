@@ -0,0 +1,58 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mixmining
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// decompressBody transparently decompresses gzipped request bodies, so that monitors on constrained links can
+// compress big batches. The decompressed body gets capped at the same size as the plain one, otherwise a small
+// compressed body could expand into gigabytes.
+func (controller *controller) decompressBody(c *gin.Context) {
+	if strings.EqualFold(strings.TrimSpace(c.GetHeader("Content-Encoding")), "gzip") {
+		c.Request.Body = http.MaxBytesReader(c.Writer, &gzipBody{compressed: c.Request.Body}, controller.maxBodyBytes)
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.ContentLength = -1
+	}
+	c.Next()
+}
+
+// gzipBody only starts decompressing once it's first read, so that a malformed body fails binding with the usual
+// error rather than getting rejected before the handler had a chance to check where the request came from.
+type gzipBody struct {
+	compressed io.ReadCloser
+	reader     *gzip.Reader
+}
+
+func (body *gzipBody) Read(p []byte) (int, error) {
+	if body.reader == nil {
+		reader, err := gzip.NewReader(body.compressed)
+		if err != nil {
+			return 0, err
+		}
+		body.reader = reader
+	}
+	return body.reader.Read(p)
+}
+
+func (body *gzipBody) Close() error {
+	return body.compressed.Close()
+}
@@ -0,0 +1,111 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mixmining
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// jsonFieldNames returns the names the fields of the struct are serialized under, leaving out the ignored ones
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names[name] = true
+	}
+	return names
+}
+
+// requestedFields reads the comma separated json names of the fields of T the client asked for with ?fields=. It
+// returns nil if the client didn't ask for specific ones, and responds with 400 if any of them isn't a field of T.
+func requestedFields[T any](c *gin.Context) ([]string, bool) {
+	value := c.Query("fields")
+	if value == "" {
+		return nil, true
+	}
+
+	known := jsonFieldNames(reflect.TypeOf(*new(T)))
+	var fields []string
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !known[field] {
+			respondWithError(c, http.StatusBadRequest, fmt.Sprintf("unknown field %q", field))
+			return nil, false
+		}
+		fields = append(fields, field)
+	}
+	if len(fields) == 0 {
+		respondWithError(c, http.StatusBadRequest, "fields must name at least one field")
+		return nil, false
+	}
+	return fields, true
+}
+
+// projectFields keeps only the given fields of each of the items, which go through a map to be serialized with
+// just those. Fields left out of the serialization of an item, e.g. empty ones with omitempty, stay left out.
+func projectFields[T any](items []T, fields []string) ([]map[string]json.RawMessage, error) {
+	projected := make([]map[string]json.RawMessage, len(items))
+	for i, item := range items {
+		serialized, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(serialized, &all); err != nil {
+			return nil, err
+		}
+		projected[i] = make(map[string]json.RawMessage, len(fields))
+		for _, field := range fields {
+			if value, ok := all[field]; ok {
+				projected[i][field] = value
+			}
+		}
+	}
+	return projected, nil
+}
+
+// respondWithReports responds with the batch report as it is, or with only the requested fields of each of its
+// reports, under the same "report" key
+func respondWithReports[T any](c *gin.Context, fields []string, batch interface{}, reports []T) {
+	if fields == nil {
+		respondWithETag(c, http.StatusOK, batch)
+		return
+	}
+	projected, err := projectFields(reports, fields)
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondWithETag(c, http.StatusOK, map[string]interface{}{"report": projected})
+}
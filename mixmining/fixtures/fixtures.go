@@ -18,24 +18,19 @@ import "github.com/nymtech/node-status-api/models"
 
 // MixStatusesList A list of mix statuses
 func MixStatusesList() []models.PersistedMixStatus {
-	booltrue := true
 	m1 := models.PersistedMixStatus{
-		MixStatus: models.MixStatus{
-			Owner:     "owner",
-			IPVersion: "6",
-			PubKey:    "pubkey1",
-			Up:        &booltrue,
-		},
+		Owner:     "owner",
+		IPVersion: "6",
+		PubKey:    "pubkey1",
+		Up:        true,
 		Timestamp: 123,
 	}
 
 	m2 := models.PersistedMixStatus{
-		MixStatus: models.MixStatus{
-			Owner:     "owner",
-			IPVersion: "6",
-			PubKey:    "pubkey1",
-			Up:        &booltrue,
-		},
+		Owner:     "owner",
+		IPVersion: "6",
+		PubKey:    "pubkey1",
+		Up:        true,
 		Timestamp: 1234,
 	}
 
@@ -123,10 +118,7 @@ func GoodBatchMixStatus() models.BatchMixStatus {
 
 // GoodPersistedMixStatus ...
 func GoodPersistedMixStatus() models.PersistedMixStatus {
-	return models.PersistedMixStatus{
-		MixStatus: GoodMixStatus(),
-		Timestamp: 1234,
-	}
+	return models.NewPersistedMixStatus(GoodMixStatus(), 1234)
 }
 
 // GoodPersistedBatchMixStatus ...
@@ -134,10 +126,7 @@ func GoodPersistedBatchMixStatus() []models.PersistedMixStatus {
 	mixStatus := GoodBatchMixStatus()
 	persisted := make([]models.PersistedMixStatus, len(mixStatus.Status))
 	for i, status := range mixStatus.Status {
-		persisted[i] = models.PersistedMixStatus{
-			MixStatus: status,
-			Timestamp: 1234,
-		}
+		persisted[i] = models.NewPersistedMixStatus(status, 1234)
 	}
 	return persisted
 }
@@ -156,3 +145,133 @@ func MixStatusReport() models.MixStatusReport {
 		LastDayIPV6:      100,
 	}
 }
+
+// GatewayStatusesList A list of gateway statuses
+func GatewayStatusesList() []models.PersistedGatewayStatus {
+	g1 := models.PersistedGatewayStatus{
+		Owner:     "owner",
+		IPVersion: "6",
+		PubKey:    "pubkey1",
+		Up:        true,
+		Timestamp: 123,
+	}
+
+	g2 := models.PersistedGatewayStatus{
+		Owner:     "owner",
+		IPVersion: "6",
+		PubKey:    "pubkey1",
+		Up:        true,
+		Timestamp: 1234,
+	}
+
+	statuses := []models.PersistedGatewayStatus{g1, g2}
+	return statuses
+}
+
+// XSSGatewayStatus ...
+func XSSGatewayStatus() models.GatewayStatus {
+	booltrue := true
+	xss := models.GatewayStatus{
+		Owner:     "owner",
+		IPVersion: "6",
+		PubKey:    "pubkey2<script>alert('gotcha')</script>",
+		Up:        &booltrue,
+	}
+	return xss
+}
+
+// GoodGatewayStatus ...
+func GoodGatewayStatus() models.GatewayStatus {
+	booltrue := true
+	return models.GatewayStatus{
+		Owner:     "owner",
+		IPVersion: "6",
+		PubKey:    "pubkey2",
+		Up:        &booltrue,
+	}
+}
+
+// XSSBatchGatewayStatus ...
+func XSSBatchGatewayStatus() models.BatchGatewayStatus {
+	booltrue := true
+	xss := models.BatchGatewayStatus{
+		Status: []models.GatewayStatus{
+			{
+				Owner:     "owner1",
+				IPVersion: "6",
+				PubKey:    "pubkey2<script>alert('gotcha')</script>",
+				Up:        &booltrue,
+			},
+			{
+				Owner:     "owner2",
+				IPVersion: "4",
+				PubKey:    "pubkey2<script>alert('gotcha')</script>",
+				Up:        &booltrue,
+			},
+			{
+				Owner:     "owner3",
+				IPVersion: "6",
+				PubKey:    "pubkey3<script>alert('gotcha')</script>",
+				Up:        &booltrue,
+			},
+		},
+	}
+	return xss
+}
+
+// GoodBatchGatewayStatus ...
+func GoodBatchGatewayStatus() models.BatchGatewayStatus {
+	booltrue := true
+	return models.BatchGatewayStatus{
+		Status: []models.GatewayStatus{
+			{
+				Owner:     "owner1",
+				IPVersion: "6",
+				PubKey:    "pubkey2",
+				Up:        &booltrue,
+			},
+			{
+				Owner:     "owner2",
+				IPVersion: "4",
+				PubKey:    "pubkey2",
+				Up:        &booltrue,
+			},
+			{
+				Owner:     "owner3",
+				IPVersion: "6",
+				PubKey:    "pubkey3",
+				Up:        &booltrue,
+			},
+		},
+	}
+}
+
+// GoodPersistedGatewayStatus ...
+func GoodPersistedGatewayStatus() models.PersistedGatewayStatus {
+	return models.NewPersistedGatewayStatus(GoodGatewayStatus(), 1234)
+}
+
+// GoodPersistedBatchGatewayStatus ...
+func GoodPersistedBatchGatewayStatus() []models.PersistedGatewayStatus {
+	gatewayStatus := GoodBatchGatewayStatus()
+	persisted := make([]models.PersistedGatewayStatus, len(gatewayStatus.Status))
+	for i, status := range gatewayStatus.Status {
+		persisted[i] = models.NewPersistedGatewayStatus(status, 1234)
+	}
+	return persisted
+}
+
+// GatewayStatusReport ...
+func GatewayStatusReport() models.GatewayStatusReport {
+	return models.GatewayStatusReport{
+		PubKey:           "key1",
+		MostRecentIPV4:   true,
+		Last5MinutesIPV4: 100,
+		LastHourIPV4:     100,
+		LastDayIPV4:      100,
+		MostRecentIPV6:   true,
+		Last5MinutesIPV6: 100,
+		LastHourIPV6:     100,
+		LastDayIPV6:      100,
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mixmining
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nymtech/node-status-api/models"
+)
+
+// IdempotencyKeyHeader names the header monitors can set on batch submissions, so that retrying a batch whose
+// response got lost doesn't store its statuses twice
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// DefaultIdempotencyKeyTTL is how long idempotency keys are remembered unless configured otherwise. It only needs
+// to outlast the retries of a monitor.
+const DefaultIdempotencyKeyTTL = 10 * time.Minute
+
+// maxIdempotencyKeys caps the number of remembered keys, the least recently used ones get forgotten first
+const maxIdempotencyKeys = 10000
+
+// idempotencyKeys remembers the keys of recently handled requests along with whether they're done or still
+// being handled.
+type idempotencyKeys struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	maxKeys int
+	order   *list.List // of *idempotencyKey, the most recently used at the front
+	keys    map[string]*list.Element
+	clock   Clock
+}
+
+type idempotencyKey struct {
+	key     string
+	done    bool
+	expires time.Time
+}
+
+func newIdempotencyKeys(ttl time.Duration, maxKeys int, clock Clock) *idempotencyKeys {
+	return &idempotencyKeys{
+		ttl:     ttl,
+		maxKeys: maxKeys,
+		order:   list.New(),
+		keys:    make(map[string]*list.Element),
+		clock:   clock,
+	}
+}
+
+// reserve claims the key for a request that's about to be handled. If the key is already taken, it returns false
+// along with whether the request that took it is done.
+func (keys *idempotencyKeys) reserve(key string) (bool, bool) {
+	keys.mu.Lock()
+	defer keys.mu.Unlock()
+
+	now := keys.clock.Now()
+	if element, ok := keys.keys[key]; ok {
+		entry := element.Value.(*idempotencyKey)
+		if now.Before(entry.expires) {
+			keys.order.MoveToFront(element)
+			return false, entry.done
+		}
+		keys.remove(element)
+	}
+
+	keys.keys[key] = keys.order.PushFront(&idempotencyKey{key: key, expires: now.Add(keys.ttl)})
+	for keys.order.Len() > keys.maxKeys {
+		keys.remove(keys.order.Back())
+	}
+	return true, false
+}
+
+// complete marks the request that reserved the key as done
+func (keys *idempotencyKeys) complete(key string) {
+	keys.mu.Lock()
+	defer keys.mu.Unlock()
+
+	if element, ok := keys.keys[key]; ok {
+		element.Value.(*idempotencyKey).done = true
+	}
+}
+
+// release forgets the key of a request that failed, so that it can be retried
+func (keys *idempotencyKeys) release(key string) {
+	keys.mu.Lock()
+	defer keys.mu.Unlock()
+
+	if element, ok := keys.keys[key]; ok {
+		keys.remove(element)
+	}
+}
+
+func (keys *idempotencyKeys) remove(element *list.Element) {
+	keys.order.Remove(element)
+	delete(keys.keys, element.Value.(*idempotencyKey).key)
+}
+
+// deduplicate makes sure a batch sent with an idempotency key only gets stored once. Repeating a request that
+// succeeded gets the same 201 response without storing anything, repeating one that's still being handled gets
+// a 409. The key is forgotten if the request fails, so that it can be retried.
+func (controller *controller) deduplicate(c *gin.Context) {
+	header := c.GetHeader(IdempotencyKeyHeader)
+	if header == "" {
+		c.Next()
+		return
+	}
+	// keys are only unique per endpoint
+	key := c.FullPath() + " " + header
+
+	reserved, done := controller.idempotencyKeys.reserve(key)
+	if !reserved {
+		if done {
+			c.AbortWithStatusJSON(http.StatusCreated, models.OK{OK: true})
+		} else {
+			c.AbortWithStatusJSON(http.StatusConflict, models.Error{Code: http.StatusConflict, Message: "a request with the same idempotency key is still being handled"})
+		}
+		return
+	}
+
+	c.Next()
+	if c.Writer.Status() == http.StatusCreated {
+		controller.idempotencyKeys.complete(key)
+	} else {
+		controller.idempotencyKeys.release(key)
+	}
+}
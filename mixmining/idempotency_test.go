@@ -0,0 +1,74 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mixmining
+
+import (
+	"time"
+
+	"github.com/BorisBorshevsky/timemock"
+	. "github.com/onsi/ginkgo"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ = Describe("Idempotency keys", func() {
+	It("should tell whether a reserved key is done", func() {
+		keys := newIdempotencyKeys(time.Minute, 10, SystemClock{})
+		reserved, _ := keys.reserve("foo")
+		assert.True(GinkgoT(), reserved)
+
+		reserved, done := keys.reserve("foo")
+		assert.False(GinkgoT(), reserved)
+		assert.False(GinkgoT(), done)
+
+		keys.complete("foo")
+		reserved, done = keys.reserve("foo")
+		assert.False(GinkgoT(), reserved)
+		assert.True(GinkgoT(), done)
+	})
+
+	It("should let a released key be reserved again", func() {
+		keys := newIdempotencyKeys(time.Minute, 10, SystemClock{})
+		keys.reserve("foo")
+		keys.release("foo")
+
+		reserved, _ := keys.reserve("foo")
+		assert.True(GinkgoT(), reserved)
+	})
+
+	It("should forget keys once they expire", func() {
+		start := timemock.Now()
+		defer timemock.Freeze(start)
+		keys := newIdempotencyKeys(time.Minute, 10, SystemClock{})
+		keys.reserve("foo")
+		keys.complete("foo")
+
+		timemock.Freeze(start.Add(time.Minute))
+		reserved, _ := keys.reserve("foo")
+		assert.True(GinkgoT(), reserved)
+	})
+
+	It("should forget the least recently used keys first", func() {
+		keys := newIdempotencyKeys(time.Minute, 2, SystemClock{})
+		keys.reserve("first")
+		keys.reserve("second")
+		keys.reserve("first")
+		keys.reserve("third")
+
+		reserved, _ := keys.reserve("first")
+		assert.False(GinkgoT(), reserved)
+		reserved, _ = keys.reserve("second")
+		assert.True(GinkgoT(), reserved)
+	})
+})
@@ -0,0 +1,154 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mixmining
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// sharedCacheDriver opens the connections of the in-memory databases. They read uncommitted data, so that readers
+// such as the export cursor never hold a lock on a table and keep the writers waiting, or wait for them.
+const sharedCacheDriver = "sqlite3_shared_cache"
+
+func init() {
+	sql.Register(sharedCacheDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			_, err := conn.Exec("PRAGMA read_uncommitted = true", nil)
+			return err
+		},
+	})
+}
+
+// inMemoryDbs numbers the in-memory databases, so that each of them gets its own name and so its own schema
+var inMemoryDbs int64
+
+// inMemoryDialector opens a new in-memory database, shared by all the connections of the pool
+func inMemoryDialector() gorm.Dialector {
+	dsn := fmt.Sprintf("file:mixmining%d?mode=memory&cache=shared", atomic.AddInt64(&inMemoryDbs, 1))
+	return memoryDialector{Dialector: sqlite.Dialector{DSN: dsn}}
+}
+
+// sqlDBOf returns the connection pool of the database, which for an in-memory one is wrapped in serializedWrites
+func sqlDBOf(database *gorm.DB) (*sql.DB, error) {
+	if pool, ok := database.ConnPool.(*serializedWrites); ok {
+		return pool.db, nil
+	}
+	return database.DB()
+}
+
+// memoryDialector is the sqlite dialector, except that its connections come from the sharedCacheDriver and its
+// writes are serialized
+type memoryDialector struct {
+	sqlite.Dialector
+}
+
+func (dialector memoryDialector) Initialize(db *gorm.DB) error {
+	if err := dialector.Dialector.Initialize(db); err != nil {
+		return err
+	}
+	// sql.Open doesn't connect yet, so nothing's lost swapping the pool the sqlite dialector opened for our own
+	if err := db.ConnPool.(*sql.DB).Close(); err != nil {
+		return err
+	}
+	pool, err := sql.Open(sharedCacheDriver, dialector.DSN)
+	if err != nil {
+		return err
+	}
+	db.ConnPool = &serializedWrites{db: pool, writing: make(chan struct{}, 1)}
+	return nil
+}
+
+// serializedWrites runs one write or transaction at a time. Connections sharing an in-memory database fail with
+// "database table is locked" rather than waiting for each other when they write at the same time, unlike the ones of
+// a database file whose busy timeout makes them wait, so they wait for their turn here instead. Reads run alongside.
+type serializedWrites struct {
+	db *sql.DB
+	// writing holds a value while a write or a transaction runs
+	writing chan struct{}
+}
+
+// acquire waits for the running write or transaction to finish, or for the context to be done
+func (pool *serializedWrites) acquire(ctx context.Context) error {
+	select {
+	case pool.writing <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (pool *serializedWrites) release() {
+	<-pool.writing
+}
+
+func (pool *serializedWrites) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return pool.db.PrepareContext(ctx, query)
+}
+
+func (pool *serializedWrites) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if err := pool.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer pool.release()
+	return pool.db.ExecContext(ctx, query, args...)
+}
+
+func (pool *serializedWrites) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return pool.db.QueryContext(ctx, query, args...)
+}
+
+func (pool *serializedWrites) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return pool.db.QueryRowContext(ctx, query, args...)
+}
+
+// BeginTx starts a transaction once the running write or transaction is done. The next one starts once it's committed
+// or rolled back.
+func (pool *serializedWrites) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	if err := pool.acquire(ctx); err != nil {
+		return nil, err
+	}
+	tx, err := pool.db.BeginTx(ctx, opts)
+	if err != nil {
+		pool.release()
+		return nil, err
+	}
+	return &serializedTx{Tx: tx, release: pool.release}, nil
+}
+
+// serializedTx is a transaction of serializedWrites, which lets the next write run once it's done
+type serializedTx struct {
+	*sql.Tx
+	release func()
+	// done makes sure release runs once, as gorm rolls back the transactions that failed to commit
+	done sync.Once
+}
+
+func (tx *serializedTx) Commit() error {
+	defer tx.done.Do(tx.release)
+	return tx.Tx.Commit()
+}
+
+func (tx *serializedTx) Rollback() error {
+	defer tx.done.Do(tx.release)
+	return tx.Tx.Rollback()
+}
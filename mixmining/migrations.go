@@ -0,0 +1,86 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mixmining
+
+import (
+	"fmt"
+
+	"github.com/nymtech/node-status-api/models"
+	"gorm.io/gorm"
+)
+
+// Migration is a named change to the data or the schema of the database that AutoMigrate can't make on its own,
+// e.g. backfilling a freshly added column. Each migration runs once, in its own transaction.
+type Migration struct {
+	Name    string
+	Migrate func(tx *gorm.DB) error
+}
+
+// schemaMigration records a migration that ran on the database
+type schemaMigration struct {
+	Name      string `gorm:"primaryKey"`
+	AppliedAt int64
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// migrations are run in order on startup, once the tables are auto migrated. Append new ones at the end and never
+// rename or reorder the existing ones, as the names are what tells whether they ran.
+var migrations = []Migration{
+	{
+		// reports saved before the flag existed only get it with their next ipv6 status otherwise
+		Name: "backfill mix report ipv6 data flag",
+		Migrate: func(tx *gorm.DB) error {
+			withIPV6 := tx.Model(&models.PersistedMixStatus{}).Distinct("pub_key").Where("ip_version = ?", "6")
+			return tx.Model(&models.MixStatusReport{}).Where("pub_key IN (?)", withIPV6).Update("has_ip_v6_data", true).Error
+		},
+	},
+}
+
+// runMigrations runs the migrations that haven't run on the database yet, in order. A failing migration is rolled
+// back and stops the ones after it, so that the next run picks up from it. The clock timestamps the migrations applied.
+func runMigrations(database *gorm.DB, steps []Migration, clock Clock) error {
+	if err := database.AutoMigrate(&schemaMigration{}); err != nil {
+		return err
+	}
+
+	var applied []string
+	if err := database.Model(&schemaMigration{}).Pluck("name", &applied).Error; err != nil {
+		return err
+	}
+	done := make(map[string]bool, len(applied))
+	for _, name := range applied {
+		done[name] = true
+	}
+
+	for _, migration := range steps {
+		if done[migration.Name] {
+			continue
+		}
+		err := database.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Migrate(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{Name: migration.Name, AppliedAt: clock.Now().UnixNano()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %q failed: %w", migration.Name, err)
+		}
+		done[migration.Name] = true
+	}
+	return nil
+}
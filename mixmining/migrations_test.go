@@ -0,0 +1,93 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mixmining
+
+import (
+	"errors"
+
+	"github.com/nymtech/node-status-api/models"
+	. "github.com/onsi/ginkgo"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+var _ = Describe("Migrations", func() {
+	appliedMigrations := func(db *Db) []string {
+		var applied []string
+		db.orm.Model(&schemaMigration{}).Order("applied_at, name").Pluck("name", &applied)
+		return applied
+	}
+
+	It("should run the pending migrations in order and record them", func() {
+		db := NewDb(true)
+		var ran []string
+		steps := []Migration{
+			{Name: "first", Migrate: func(tx *gorm.DB) error {
+				ran = append(ran, "first")
+				return tx.Create(&models.MixStatusReport{PubKey: "aaa"}).Error
+			}},
+			{Name: "second", Migrate: func(tx *gorm.DB) error {
+				ran = append(ran, "second")
+				return tx.Model(&models.MixStatusReport{}).Where("pub_key = ?", "aaa").Update("owner", "migrated").Error
+			}},
+		}
+
+		assert.Nil(GinkgoT(), runMigrations(db.orm, steps, SystemClock{}))
+		assert.Equal(GinkgoT(), []string{"first", "second"}, ran)
+		assert.Equal(GinkgoT(), "migrated", loadMixReport(db, "aaa").Owner)
+		assert.Contains(GinkgoT(), appliedMigrations(db), "first")
+		assert.Contains(GinkgoT(), appliedMigrations(db), "second")
+
+		// they already ran, so running them again does nothing
+		assert.Nil(GinkgoT(), runMigrations(db.orm, steps, SystemClock{}))
+		assert.Equal(GinkgoT(), []string{"first", "second"}, ran)
+	})
+
+	It("should roll back a failing migration and stop the ones after it", func() {
+		db := NewDb(true)
+		ranAfter := false
+		steps := []Migration{
+			{Name: "failing", Migrate: func(tx *gorm.DB) error {
+				tx.Create(&models.MixStatusReport{PubKey: "aaa"})
+				return errors.New("boom")
+			}},
+			{Name: "after", Migrate: func(tx *gorm.DB) error {
+				ranAfter = true
+				return nil
+			}},
+		}
+
+		assert.NotNil(GinkgoT(), runMigrations(db.orm, steps, SystemClock{}))
+		assert.False(GinkgoT(), ranAfter)
+		assert.Equal(GinkgoT(), "", loadMixReport(db, "aaa").PubKey)
+		assert.NotContains(GinkgoT(), appliedMigrations(db), "failing")
+	})
+
+	Describe("backfilling the mix report ipv6 data flag", func() {
+		It("should only flag the nodes that reported ipv6 statuses", func() {
+			db := NewDb(true)
+			db.BatchAddMixStatus([]models.PersistedMixStatus{
+				{PubKey: "both", IPVersion: "4", Timestamp: 100},
+				{PubKey: "both", IPVersion: "6", Timestamp: 100},
+				{PubKey: "v4only", IPVersion: "4", Timestamp: 100},
+			})
+			db.SaveBatchMixStatusReport(models.BatchMixStatusReport{Report: []models.MixStatusReport{{PubKey: "both"}, {PubKey: "v4only"}}})
+
+			assert.Nil(GinkgoT(), migrations[0].Migrate(db.orm))
+			assert.True(GinkgoT(), loadMixReport(db, "both").HasIPV6Data)
+			assert.False(GinkgoT(), loadMixReport(db, "v4only").HasIPV6Data)
+		})
+	})
+})
@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	context "context"
+
 	models "github.com/nymtech/node-status-api/models"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -13,32 +15,92 @@ type IDb struct {
 }
 
 // AddGatewayStatus provides a mock function with given fields: _a0
-func (_m *IDb) AddGatewayStatus(_a0 models.PersistedGatewayStatus) {
-	_m.Called(_a0)
+func (_m *IDb) AddGatewayStatus(_a0 models.PersistedGatewayStatus) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(models.PersistedGatewayStatus) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
 }
 
 // AddMixStatus provides a mock function with given fields: _a0
-func (_m *IDb) AddMixStatus(_a0 models.PersistedMixStatus) {
-	_m.Called(_a0)
+func (_m *IDb) AddMixStatus(_a0 models.PersistedMixStatus) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(models.PersistedMixStatus) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AddNetworkUptimeSample provides a mock function with given fields: sample
+func (_m *IDb) AddNetworkUptimeSample(sample models.NetworkUptimeSample) {
+	_m.Called(sample)
+}
+
+// AddOwnershipChanges provides a mock function with given fields: changes
+func (_m *IDb) AddOwnershipChanges(changes []models.OwnershipChange) {
+	_m.Called(changes)
 }
 
 // BatchAddGatewayStatus provides a mock function with given fields: status
-func (_m *IDb) BatchAddGatewayStatus(status []models.PersistedGatewayStatus) {
-	_m.Called(status)
+func (_m *IDb) BatchAddGatewayStatus(status []models.PersistedGatewayStatus) error {
+	ret := _m.Called(status)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]models.PersistedGatewayStatus) error); ok {
+		r0 = rf(status)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
 }
 
 // BatchAddMixStatus provides a mock function with given fields: status
-func (_m *IDb) BatchAddMixStatus(status []models.PersistedMixStatus) {
-	_m.Called(status)
+func (_m *IDb) BatchAddMixStatus(status []models.PersistedMixStatus) error {
+	ret := _m.Called(status)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]models.PersistedMixStatus) error); ok {
+		r0 = rf(status)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
 }
 
-// BatchLoadGatewayReports provides a mock function with given fields: pubkeys
-func (_m *IDb) BatchLoadGatewayReports(pubkeys []string) models.BatchGatewayStatusReport {
-	ret := _m.Called(pubkeys)
+// BatchLoadAllMixReports provides a mock function with given fields: ctx
+func (_m *IDb) BatchLoadAllMixReports(ctx context.Context) models.BatchMixStatusReport {
+	ret := _m.Called(ctx)
+
+	var r0 models.BatchMixStatusReport
+	if rf, ok := ret.Get(0).(func(context.Context) models.BatchMixStatusReport); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(models.BatchMixStatusReport)
+	}
+
+	return r0
+}
+
+// BatchLoadGatewayReports provides a mock function with given fields: ctx, pubkeys
+func (_m *IDb) BatchLoadGatewayReports(ctx context.Context, pubkeys []string) models.BatchGatewayStatusReport {
+	ret := _m.Called(ctx, pubkeys)
 
 	var r0 models.BatchGatewayStatusReport
-	if rf, ok := ret.Get(0).(func([]string) models.BatchGatewayStatusReport); ok {
-		r0 = rf(pubkeys)
+	if rf, ok := ret.Get(0).(func(context.Context, []string) models.BatchGatewayStatusReport); ok {
+		r0 = rf(ctx, pubkeys)
 	} else {
 		r0 = ret.Get(0).(models.BatchGatewayStatusReport)
 	}
@@ -46,13 +108,13 @@ func (_m *IDb) BatchLoadGatewayReports(pubkeys []string) models.BatchGatewayStat
 	return r0
 }
 
-// BatchLoadMixReports provides a mock function with given fields: pubkeys
-func (_m *IDb) BatchLoadMixReports(pubkeys []string) models.BatchMixStatusReport {
-	ret := _m.Called(pubkeys)
+// BatchLoadMixReports provides a mock function with given fields: ctx, pubkeys
+func (_m *IDb) BatchLoadMixReports(ctx context.Context, pubkeys []string) models.BatchMixStatusReport {
+	ret := _m.Called(ctx, pubkeys)
 
 	var r0 models.BatchMixStatusReport
-	if rf, ok := ret.Get(0).(func([]string) models.BatchMixStatusReport); ok {
-		r0 = rf(pubkeys)
+	if rf, ok := ret.Get(0).(func(context.Context, []string) models.BatchMixStatusReport); ok {
+		r0 = rf(ctx, pubkeys)
 	} else {
 		r0 = ret.Get(0).(models.BatchMixStatusReport)
 	}
@@ -60,13 +122,41 @@ func (_m *IDb) BatchLoadMixReports(pubkeys []string) models.BatchMixStatusReport
 	return r0
 }
 
-// GetActiveGateways provides a mock function with given fields: since
-func (_m *IDb) GetActiveGateways(since int64) []string {
-	ret := _m.Called(since)
+// CountGatewayStatuses provides a mock function with given fields: ctx
+func (_m *IDb) CountGatewayStatuses(ctx context.Context) int64 {
+	ret := _m.Called(ctx)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context) int64); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	return r0
+}
+
+// CountMixStatuses provides a mock function with given fields: ctx
+func (_m *IDb) CountMixStatuses(ctx context.Context) int64 {
+	ret := _m.Called(ctx)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context) int64); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	return r0
+}
+
+// DistinctGatewayOwners provides a mock function with given fields: ctx
+func (_m *IDb) DistinctGatewayOwners(ctx context.Context) []string {
+	ret := _m.Called(ctx)
 
 	var r0 []string
-	if rf, ok := ret.Get(0).(func(int64) []string); ok {
-		r0 = rf(since)
+	if rf, ok := ret.Get(0).(func(context.Context) []string); ok {
+		r0 = rf(ctx)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]string)
@@ -76,13 +166,13 @@ func (_m *IDb) GetActiveGateways(since int64) []string {
 	return r0
 }
 
-// GetActiveMixes provides a mock function with given fields: since
-func (_m *IDb) GetActiveMixes(since int64) []string {
-	ret := _m.Called(since)
+// DistinctMixOwners provides a mock function with given fields: ctx
+func (_m *IDb) DistinctMixOwners(ctx context.Context) []string {
+	ret := _m.Called(ctx)
 
 	var r0 []string
-	if rf, ok := ret.Get(0).(func(int64) []string); ok {
-		r0 = rf(since)
+	if rf, ok := ret.Get(0).(func(context.Context) []string); ok {
+		r0 = rf(ctx)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]string)
@@ -92,13 +182,131 @@ func (_m *IDb) GetActiveMixes(since int64) []string {
 	return r0
 }
 
-// ListGatewayStatus provides a mock function with given fields: pubkey, limit
-func (_m *IDb) ListGatewayStatus(pubkey string, limit int) []models.PersistedGatewayStatus {
-	ret := _m.Called(pubkey, limit)
+// EachGatewayReport provides a mock function with given fields: ctx, fn
+func (_m *IDb) EachGatewayReport(ctx context.Context, fn func(models.GatewayStatusReport) error) error {
+	ret := _m.Called(ctx, fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, func(models.GatewayStatusReport) error) error); ok {
+		r0 = rf(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EachGatewayStatus provides a mock function with given fields: ctx, fn
+func (_m *IDb) EachGatewayStatus(ctx context.Context, fn func(models.PersistedGatewayStatus) error) error {
+	ret := _m.Called(ctx, fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, func(models.PersistedGatewayStatus) error) error); ok {
+		r0 = rf(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EachMixReport provides a mock function with given fields: ctx, fn
+func (_m *IDb) EachMixReport(ctx context.Context, fn func(models.MixStatusReport) error) error {
+	ret := _m.Called(ctx, fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, func(models.MixStatusReport) error) error); ok {
+		r0 = rf(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EachMixStatus provides a mock function with given fields: ctx, fn
+func (_m *IDb) EachMixStatus(ctx context.Context, fn func(models.PersistedMixStatus) error) error {
+	ret := _m.Called(ctx, fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, func(models.PersistedMixStatus) error) error); ok {
+		r0 = rf(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GatewayLifetime provides a mock function with given fields: ctx, pubkey
+func (_m *IDb) GatewayLifetime(ctx context.Context, pubkey string) models.NodeLifetime {
+	ret := _m.Called(ctx, pubkey)
+
+	var r0 models.NodeLifetime
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.NodeLifetime); ok {
+		r0 = rf(ctx, pubkey)
+	} else {
+		r0 = ret.Get(0).(models.NodeLifetime)
+	}
+
+	return r0
+}
+
+// GetActiveGateways provides a mock function with given fields: ctx, since
+func (_m *IDb) GetActiveGateways(ctx context.Context, since int64) []string {
+	ret := _m.Called(ctx, since)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []string); ok {
+		r0 = rf(ctx, since)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	return r0
+}
+
+// GetActiveMixes provides a mock function with given fields: ctx, ipVersion, since
+func (_m *IDb) GetActiveMixes(ctx context.Context, ipVersion string, since int64) []string {
+	ret := _m.Called(ctx, ipVersion, since)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) []string); ok {
+		r0 = rf(ctx, ipVersion, since)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	return r0
+}
+
+// GetMixesWithoutReport provides a mock function with given fields: ctx, since
+func (_m *IDb) GetMixesWithoutReport(ctx context.Context, since int64) []string {
+	ret := _m.Called(ctx, since)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []string); ok {
+		r0 = rf(ctx, since)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	return r0
+}
+
+// ListGatewayStatus provides a mock function with given fields: ctx, pubkey, limit
+func (_m *IDb) ListGatewayStatus(ctx context.Context, pubkey string, limit int) []models.PersistedGatewayStatus {
+	ret := _m.Called(ctx, pubkey, limit)
 
 	var r0 []models.PersistedGatewayStatus
-	if rf, ok := ret.Get(0).(func(string, int) []models.PersistedGatewayStatus); ok {
-		r0 = rf(pubkey, limit)
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []models.PersistedGatewayStatus); ok {
+		r0 = rf(ctx, pubkey, limit)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]models.PersistedGatewayStatus)
@@ -108,13 +316,13 @@ func (_m *IDb) ListGatewayStatus(pubkey string, limit int) []models.PersistedGat
 	return r0
 }
 
-// ListGatewayStatusDateRange provides a mock function with given fields: pubkey, ipVersion, start, end
-func (_m *IDb) ListGatewayStatusDateRange(pubkey string, ipVersion string, start int64, end int64) []models.PersistedGatewayStatus {
-	ret := _m.Called(pubkey, ipVersion, start, end)
+// ListGatewayStatusDateRange provides a mock function with given fields: ctx, pubkey, ipVersion, start, end
+func (_m *IDb) ListGatewayStatusDateRange(ctx context.Context, pubkey string, ipVersion string, start int64, end int64) []models.PersistedGatewayStatus {
+	ret := _m.Called(ctx, pubkey, ipVersion, start, end)
 
 	var r0 []models.PersistedGatewayStatus
-	if rf, ok := ret.Get(0).(func(string, string, int64, int64) []models.PersistedGatewayStatus); ok {
-		r0 = rf(pubkey, ipVersion, start, end)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64, int64) []models.PersistedGatewayStatus); ok {
+		r0 = rf(ctx, pubkey, ipVersion, start, end)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]models.PersistedGatewayStatus)
@@ -124,13 +332,13 @@ func (_m *IDb) ListGatewayStatusDateRange(pubkey string, ipVersion string, start
 	return r0
 }
 
-// ListGatewayStatusSince provides a mock function with given fields: pubkey, ipVersion, since
-func (_m *IDb) ListGatewayStatusSince(pubkey string, ipVersion string, since int64) []models.PersistedGatewayStatus {
-	ret := _m.Called(pubkey, ipVersion, since)
+// ListGatewayStatusSince provides a mock function with given fields: ctx, pubkey, ipVersion, since
+func (_m *IDb) ListGatewayStatusSince(ctx context.Context, pubkey string, ipVersion string, since int64) []models.PersistedGatewayStatus {
+	ret := _m.Called(ctx, pubkey, ipVersion, since)
 
 	var r0 []models.PersistedGatewayStatus
-	if rf, ok := ret.Get(0).(func(string, string, int64) []models.PersistedGatewayStatus); ok {
-		r0 = rf(pubkey, ipVersion, since)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64) []models.PersistedGatewayStatus); ok {
+		r0 = rf(ctx, pubkey, ipVersion, since)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]models.PersistedGatewayStatus)
@@ -140,13 +348,61 @@ func (_m *IDb) ListGatewayStatusSince(pubkey string, ipVersion string, since int
 	return r0
 }
 
-// ListMixStatus provides a mock function with given fields: pubkey, limit
-func (_m *IDb) ListMixStatus(pubkey string, limit int) []models.PersistedMixStatus {
-	ret := _m.Called(pubkey, limit)
+// ListMixStatus provides a mock function with given fields: ctx, pubkey, limit
+func (_m *IDb) ListMixStatus(ctx context.Context, pubkey string, limit int) []models.PersistedMixStatus {
+	ret := _m.Called(ctx, pubkey, limit)
+
+	var r0 []models.PersistedMixStatus
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []models.PersistedMixStatus); ok {
+		r0 = rf(ctx, pubkey, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.PersistedMixStatus)
+		}
+	}
+
+	return r0
+}
+
+// ListMixStatusBulk provides a mock function with given fields: ctx, pubkeys, limit
+func (_m *IDb) ListMixStatusBulk(ctx context.Context, pubkeys []string, limit int) []models.PersistedMixStatus {
+	ret := _m.Called(ctx, pubkeys, limit)
+
+	var r0 []models.PersistedMixStatus
+	if rf, ok := ret.Get(0).(func(context.Context, []string, int) []models.PersistedMixStatus); ok {
+		r0 = rf(ctx, pubkeys, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.PersistedMixStatus)
+		}
+	}
+
+	return r0
+}
+
+// ListMixStatusByEpoch provides a mock function with given fields: ctx, epoch
+func (_m *IDb) ListMixStatusByEpoch(ctx context.Context, epoch uint64) []models.PersistedMixStatus {
+	ret := _m.Called(ctx, epoch)
+
+	var r0 []models.PersistedMixStatus
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) []models.PersistedMixStatus); ok {
+		r0 = rf(ctx, epoch)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.PersistedMixStatus)
+		}
+	}
+
+	return r0
+}
+
+// ListMixStatusByState provides a mock function with given fields: ctx, pubkey, ipVersion, up, since
+func (_m *IDb) ListMixStatusByState(ctx context.Context, pubkey string, ipVersion string, up bool, since int64) []models.PersistedMixStatus {
+	ret := _m.Called(ctx, pubkey, ipVersion, up, since)
 
 	var r0 []models.PersistedMixStatus
-	if rf, ok := ret.Get(0).(func(string, int) []models.PersistedMixStatus); ok {
-		r0 = rf(pubkey, limit)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, bool, int64) []models.PersistedMixStatus); ok {
+		r0 = rf(ctx, pubkey, ipVersion, up, since)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]models.PersistedMixStatus)
@@ -156,13 +412,13 @@ func (_m *IDb) ListMixStatus(pubkey string, limit int) []models.PersistedMixStat
 	return r0
 }
 
-// ListMixStatusDateRange provides a mock function with given fields: pubkey, ipVersion, start, end
-func (_m *IDb) ListMixStatusDateRange(pubkey string, ipVersion string, start int64, end int64) []models.PersistedMixStatus {
-	ret := _m.Called(pubkey, ipVersion, start, end)
+// ListMixStatusDateRange provides a mock function with given fields: ctx, pubkey, ipVersion, start, end
+func (_m *IDb) ListMixStatusDateRange(ctx context.Context, pubkey string, ipVersion string, start int64, end int64) []models.PersistedMixStatus {
+	ret := _m.Called(ctx, pubkey, ipVersion, start, end)
 
 	var r0 []models.PersistedMixStatus
-	if rf, ok := ret.Get(0).(func(string, string, int64, int64) []models.PersistedMixStatus); ok {
-		r0 = rf(pubkey, ipVersion, start, end)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64, int64) []models.PersistedMixStatus); ok {
+		r0 = rf(ctx, pubkey, ipVersion, start, end)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]models.PersistedMixStatus)
@@ -172,13 +428,13 @@ func (_m *IDb) ListMixStatusDateRange(pubkey string, ipVersion string, start int
 	return r0
 }
 
-// ListMixStatusSince provides a mock function with given fields: pubkey, ipVersion, since
-func (_m *IDb) ListMixStatusSince(pubkey string, ipVersion string, since int64) []models.PersistedMixStatus {
-	ret := _m.Called(pubkey, ipVersion, since)
+// ListMixStatusSince provides a mock function with given fields: ctx, pubkey, ipVersion, since
+func (_m *IDb) ListMixStatusSince(ctx context.Context, pubkey string, ipVersion string, since int64) []models.PersistedMixStatus {
+	ret := _m.Called(ctx, pubkey, ipVersion, since)
 
 	var r0 []models.PersistedMixStatus
-	if rf, ok := ret.Get(0).(func(string, string, int64) []models.PersistedMixStatus); ok {
-		r0 = rf(pubkey, ipVersion, since)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64) []models.PersistedMixStatus); ok {
+		r0 = rf(ctx, pubkey, ipVersion, since)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]models.PersistedMixStatus)
@@ -188,13 +444,45 @@ func (_m *IDb) ListMixStatusSince(pubkey string, ipVersion string, since int64)
 	return r0
 }
 
-// LoadGatewayReport provides a mock function with given fields: pubkey
-func (_m *IDb) LoadGatewayReport(pubkey string) models.GatewayStatusReport {
-	ret := _m.Called(pubkey)
+// ListNetworkUptimeSamples provides a mock function with given fields: ctx, since
+func (_m *IDb) ListNetworkUptimeSamples(ctx context.Context, since int64) []models.NetworkUptimeSample {
+	ret := _m.Called(ctx, since)
+
+	var r0 []models.NetworkUptimeSample
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []models.NetworkUptimeSample); ok {
+		r0 = rf(ctx, since)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.NetworkUptimeSample)
+		}
+	}
+
+	return r0
+}
+
+// ListOwnershipChanges provides a mock function with given fields: ctx, pubkey
+func (_m *IDb) ListOwnershipChanges(ctx context.Context, pubkey string) []models.OwnershipChange {
+	ret := _m.Called(ctx, pubkey)
+
+	var r0 []models.OwnershipChange
+	if rf, ok := ret.Get(0).(func(context.Context, string) []models.OwnershipChange); ok {
+		r0 = rf(ctx, pubkey)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.OwnershipChange)
+		}
+	}
+
+	return r0
+}
+
+// LoadGatewayReport provides a mock function with given fields: ctx, pubkey
+func (_m *IDb) LoadGatewayReport(ctx context.Context, pubkey string) models.GatewayStatusReport {
+	ret := _m.Called(ctx, pubkey)
 
 	var r0 models.GatewayStatusReport
-	if rf, ok := ret.Get(0).(func(string) models.GatewayStatusReport); ok {
-		r0 = rf(pubkey)
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.GatewayStatusReport); ok {
+		r0 = rf(ctx, pubkey)
 	} else {
 		r0 = ret.Get(0).(models.GatewayStatusReport)
 	}
@@ -202,48 +490,118 @@ func (_m *IDb) LoadGatewayReport(pubkey string) models.GatewayStatusReport {
 	return r0
 }
 
-// LoadMixReport provides a mock function with given fields: pubkey
-func (_m *IDb) LoadMixReport(pubkey string) models.MixStatusReport {
-	ret := _m.Called(pubkey)
+// LoadMixReport provides a mock function with given fields: ctx, pubkey
+func (_m *IDb) LoadMixReport(ctx context.Context, pubkey string) (models.MixStatusReport, error) {
+	ret := _m.Called(ctx, pubkey)
 
 	var r0 models.MixStatusReport
-	if rf, ok := ret.Get(0).(func(string) models.MixStatusReport); ok {
-		r0 = rf(pubkey)
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.MixStatusReport); ok {
+		r0 = rf(ctx, pubkey)
 	} else {
 		r0 = ret.Get(0).(models.MixStatusReport)
 	}
 
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, pubkey)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MixIngestionLag provides a mock function with given fields: ctx, since
+func (_m *IDb) MixIngestionLag(ctx context.Context, since int64) models.IngestionLag {
+	ret := _m.Called(ctx, since)
+
+	var r0 models.IngestionLag
+	if rf, ok := ret.Get(0).(func(context.Context, int64) models.IngestionLag); ok {
+		r0 = rf(ctx, since)
+	} else {
+		r0 = ret.Get(0).(models.IngestionLag)
+	}
+
 	return r0
 }
 
-// LoadNonStaleGatewayReports provides a mock function with given fields:
-func (_m *IDb) LoadNonStaleGatewayReports() models.BatchGatewayStatusReport {
-	ret := _m.Called()
+// MixLifetime provides a mock function with given fields: ctx, pubkey
+func (_m *IDb) MixLifetime(ctx context.Context, pubkey string) models.NodeLifetime {
+	ret := _m.Called(ctx, pubkey)
 
-	var r0 models.BatchGatewayStatusReport
-	if rf, ok := ret.Get(0).(func() models.BatchGatewayStatusReport); ok {
-		r0 = rf()
+	var r0 models.NodeLifetime
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.NodeLifetime); ok {
+		r0 = rf(ctx, pubkey)
 	} else {
-		r0 = ret.Get(0).(models.BatchGatewayStatusReport)
+		r0 = ret.Get(0).(models.NodeLifetime)
+	}
+
+	return r0
+}
+
+// MixReportOwners provides a mock function with given fields: ctx, pubkeys
+func (_m *IDb) MixReportOwners(ctx context.Context, pubkeys []string) map[string]string {
+	ret := _m.Called(ctx, pubkeys)
+
+	var r0 map[string]string
+	if rf, ok := ret.Get(0).(func(context.Context, []string) map[string]string); ok {
+		r0 = rf(ctx, pubkeys)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]string)
+		}
+	}
+
+	return r0
+}
+
+// OldestStatusTimestamp provides a mock function with given fields: ctx
+func (_m *IDb) OldestStatusTimestamp(ctx context.Context) int64 {
+	ret := _m.Called(ctx)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context) int64); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int64)
 	}
 
 	return r0
 }
 
-// LoadNonStaleMixReports provides a mock function with given fields:
-func (_m *IDb) LoadNonStaleMixReports() models.BatchMixStatusReport {
+// Ping provides a mock function with given fields: ctx
+func (_m *IDb) Ping(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// QueryTimeouts provides a mock function with given fields:
+func (_m *IDb) QueryTimeouts() int64 {
 	ret := _m.Called()
 
-	var r0 models.BatchMixStatusReport
-	if rf, ok := ret.Get(0).(func() models.BatchMixStatusReport); ok {
+	var r0 int64
+	if rf, ok := ret.Get(0).(func() int64); ok {
 		r0 = rf()
 	} else {
-		r0 = ret.Get(0).(models.BatchMixStatusReport)
+		r0 = ret.Get(0).(int64)
 	}
 
 	return r0
 }
 
+// RemoveMixReports provides a mock function with given fields: pubkeys
+func (_m *IDb) RemoveMixReports(pubkeys []string) {
+	_m.Called(pubkeys)
+}
+
 // RemoveOldGatewayStatuses provides a mock function with given fields: before
 func (_m *IDb) RemoveOldGatewayStatuses(before int64) {
 	_m.Called(before)
@@ -254,6 +612,39 @@ func (_m *IDb) RemoveOldMixStatuses(before int64) {
 	_m.Called(before)
 }
 
+// RemoveOldNetworkUptimeSamples provides a mock function with given fields: before
+func (_m *IDb) RemoveOldNetworkUptimeSamples(before int64) {
+	_m.Called(before)
+}
+
+// RetractGatewayStatus provides a mock function with given fields: ctx, pubkey, ipVersion, timestamp
+func (_m *IDb) RetractGatewayStatus(ctx context.Context, pubkey string, ipVersion string, timestamp int64) bool {
+	ret := _m.Called(ctx, pubkey, ipVersion, timestamp)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64) bool); ok {
+		r0 = rf(ctx, pubkey, ipVersion, timestamp)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// RetractMixStatus provides a mock function with given fields: ctx, pubkey, ipVersion, timestamp
+func (_m *IDb) RetractMixStatus(ctx context.Context, pubkey string, ipVersion string, timestamp int64) bool {
+	ret := _m.Called(ctx, pubkey, ipVersion, timestamp)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64) bool); ok {
+		r0 = rf(ctx, pubkey, ipVersion, timestamp)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // SaveBatchGatewayStatusReport provides a mock function with given fields: _a0
 func (_m *IDb) SaveBatchGatewayStatusReport(_a0 models.BatchGatewayStatusReport) {
 	_m.Called(_a0)
@@ -264,12 +655,95 @@ func (_m *IDb) SaveBatchMixStatusReport(_a0 models.BatchMixStatusReport) {
 	_m.Called(_a0)
 }
 
+// SaveBatchMixStatusReportIfUnchanged provides a mock function with given fields: _a0
+func (_m *IDb) SaveBatchMixStatusReportIfUnchanged(_a0 models.BatchMixStatusReport) []string {
+	ret := _m.Called(_a0)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(models.BatchMixStatusReport) []string); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	return r0
+}
+
 // SaveGatewayStatusReport provides a mock function with given fields: _a0
-func (_m *IDb) SaveGatewayStatusReport(_a0 models.GatewayStatusReport) {
-	_m.Called(_a0)
+func (_m *IDb) SaveGatewayStatusReport(_a0 models.GatewayStatusReport) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(models.GatewayStatusReport) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
 }
 
 // SaveMixStatusReport provides a mock function with given fields: _a0
-func (_m *IDb) SaveMixStatusReport(_a0 models.MixStatusReport) {
-	_m.Called(_a0)
+func (_m *IDb) SaveMixStatusReport(_a0 models.MixStatusReport) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(models.MixStatusReport) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SaveMixStatusReportIfUnchanged provides a mock function with given fields: _a0
+func (_m *IDb) SaveMixStatusReportIfUnchanged(_a0 models.MixStatusReport) bool {
+	ret := _m.Called(_a0)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(models.MixStatusReport) bool); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// TopMixReports provides a mock function with given fields: ctx, field, n
+func (_m *IDb) TopMixReports(ctx context.Context, field string, n int) models.BatchMixStatusReport {
+	ret := _m.Called(ctx, field, n)
+
+	var r0 models.BatchMixStatusReport
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) models.BatchMixStatusReport); ok {
+		r0 = rf(ctx, field, n)
+	} else {
+		r0 = ret.Get(0).(models.BatchMixStatusReport)
+	}
+
+	return r0
+}
+
+// Vacuum provides a mock function with given fields: ctx
+func (_m *IDb) Vacuum(ctx context.Context) (int64, error) {
+	ret := _m.Called(ctx)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context) int64); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
@@ -3,6 +3,10 @@
 package mocks
 
 import (
+	context "context"
+
+	time "time"
+
 	models "github.com/nymtech/node-status-api/models"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -12,8 +16,36 @@ type IService struct {
 	mock.Mock
 }
 
+// AggregateMixUptime provides a mock function with given fields: ctx, hours
+func (_m *IService) AggregateMixUptime(ctx context.Context, hours int) models.MixUptimeAggregate {
+	ret := _m.Called(ctx, hours)
+
+	var r0 models.MixUptimeAggregate
+	if rf, ok := ret.Get(0).(func(context.Context, int) models.MixUptimeAggregate); ok {
+		r0 = rf(ctx, hours)
+	} else {
+		r0 = ret.Get(0).(models.MixUptimeAggregate)
+	}
+
+	return r0
+}
+
+// BackfillMixReports provides a mock function with given fields: ctx
+func (_m *IService) BackfillMixReports(ctx context.Context) models.Backfill {
+	ret := _m.Called(ctx)
+
+	var r0 models.Backfill
+	if rf, ok := ret.Get(0).(func(context.Context) models.Backfill); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(models.Backfill)
+	}
+
+	return r0
+}
+
 // BatchCreateGatewayStatus provides a mock function with given fields: batchGatewayStatus
-func (_m *IService) BatchCreateGatewayStatus(batchGatewayStatus models.BatchGatewayStatus) []models.PersistedGatewayStatus {
+func (_m *IService) BatchCreateGatewayStatus(batchGatewayStatus models.BatchGatewayStatus) ([]models.PersistedGatewayStatus, error) {
 	ret := _m.Called(batchGatewayStatus)
 
 	var r0 []models.PersistedGatewayStatus
@@ -25,11 +57,18 @@ func (_m *IService) BatchCreateGatewayStatus(batchGatewayStatus models.BatchGate
 		}
 	}
 
-	return r0
+	var r1 error
+	if rf, ok := ret.Get(1).(func(models.BatchGatewayStatus) error); ok {
+		r1 = rf(batchGatewayStatus)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // BatchCreateMixStatus provides a mock function with given fields: batchMixStatus
-func (_m *IService) BatchCreateMixStatus(batchMixStatus models.BatchMixStatus) []models.PersistedMixStatus {
+func (_m *IService) BatchCreateMixStatus(batchMixStatus models.BatchMixStatus) ([]models.PersistedMixStatus, error) {
 	ret := _m.Called(batchMixStatus)
 
 	var r0 []models.PersistedMixStatus
@@ -41,16 +80,23 @@ func (_m *IService) BatchCreateMixStatus(batchMixStatus models.BatchMixStatus) [
 		}
 	}
 
-	return r0
+	var r1 error
+	if rf, ok := ret.Get(1).(func(models.BatchMixStatus) error); ok {
+		r1 = rf(batchMixStatus)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// BatchGetGatewayStatusReport provides a mock function with given fields:
-func (_m *IService) BatchGetGatewayStatusReport() models.BatchGatewayStatusReport {
-	ret := _m.Called()
+// BatchGetGatewayStatusReport provides a mock function with given fields: ctx
+func (_m *IService) BatchGetGatewayStatusReport(ctx context.Context) models.BatchGatewayStatusReport {
+	ret := _m.Called(ctx)
 
 	var r0 models.BatchGatewayStatusReport
-	if rf, ok := ret.Get(0).(func() models.BatchGatewayStatusReport); ok {
-		r0 = rf()
+	if rf, ok := ret.Get(0).(func(context.Context) models.BatchGatewayStatusReport); ok {
+		r0 = rf(ctx)
 	} else {
 		r0 = ret.Get(0).(models.BatchGatewayStatusReport)
 	}
@@ -58,13 +104,13 @@ func (_m *IService) BatchGetGatewayStatusReport() models.BatchGatewayStatusRepor
 	return r0
 }
 
-// BatchGetMixStatusReport provides a mock function with given fields:
-func (_m *IService) BatchGetMixStatusReport() models.BatchMixStatusReport {
-	ret := _m.Called()
+// BatchGetMixStatusReport provides a mock function with given fields: ctx
+func (_m *IService) BatchGetMixStatusReport(ctx context.Context) models.BatchMixStatusReport {
+	ret := _m.Called(ctx)
 
 	var r0 models.BatchMixStatusReport
-	if rf, ok := ret.Get(0).(func() models.BatchMixStatusReport); ok {
-		r0 = rf()
+	if rf, ok := ret.Get(0).(func(context.Context) models.BatchMixStatusReport); ok {
+		r0 = rf(ctx)
 	} else {
 		r0 = ret.Get(0).(models.BatchMixStatusReport)
 	}
@@ -72,8 +118,38 @@ func (_m *IService) BatchGetMixStatusReport() models.BatchMixStatusReport {
 	return r0
 }
 
+// CalculateMixUptimeAt provides a mock function with given fields: ctx, pubkey, ipVersion, at, window
+func (_m *IService) CalculateMixUptimeAt(ctx context.Context, pubkey string, ipVersion string, at int64, window time.Duration) int {
+	ret := _m.Called(ctx, pubkey, ipVersion, at, window)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64, time.Duration) int); ok {
+		r0 = rf(ctx, pubkey, ipVersion, at, window)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	return r0
+}
+
+// ChangedMixOwners provides a mock function with given fields: ctx, statuses
+func (_m *IService) ChangedMixOwners(ctx context.Context, statuses []models.MixStatus) []int {
+	ret := _m.Called(ctx, statuses)
+
+	var r0 []int
+	if rf, ok := ret.Get(0).(func(context.Context, []models.MixStatus) []int); ok {
+		r0 = rf(ctx, statuses)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]int)
+		}
+	}
+
+	return r0
+}
+
 // CreateGatewayStatus provides a mock function with given fields: gatewayStatus
-func (_m *IService) CreateGatewayStatus(gatewayStatus models.GatewayStatus) models.PersistedGatewayStatus {
+func (_m *IService) CreateGatewayStatus(gatewayStatus models.GatewayStatus) (models.PersistedGatewayStatus, error) {
 	ret := _m.Called(gatewayStatus)
 
 	var r0 models.PersistedGatewayStatus
@@ -83,11 +159,18 @@ func (_m *IService) CreateGatewayStatus(gatewayStatus models.GatewayStatus) mode
 		r0 = ret.Get(0).(models.PersistedGatewayStatus)
 	}
 
-	return r0
+	var r1 error
+	if rf, ok := ret.Get(1).(func(models.GatewayStatus) error); ok {
+		r1 = rf(gatewayStatus)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // CreateMixStatus provides a mock function with given fields: mixStatus
-func (_m *IService) CreateMixStatus(mixStatus models.MixStatus) models.PersistedMixStatus {
+func (_m *IService) CreateMixStatus(mixStatus models.MixStatus) (models.PersistedMixStatus, error) {
 	ret := _m.Called(mixStatus)
 
 	var r0 models.PersistedMixStatus
@@ -97,16 +180,81 @@ func (_m *IService) CreateMixStatus(mixStatus models.MixStatus) models.Persisted
 		r0 = ret.Get(0).(models.PersistedMixStatus)
 	}
 
+	var r1 error
+	if rf, ok := ret.Get(1).(func(models.MixStatus) error); ok {
+		r1 = rf(mixStatus)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DetectMixUptimeDrops provides a mock function with given fields: ctx, threshold
+func (_m *IService) DetectMixUptimeDrops(ctx context.Context, threshold int) []models.MixUptimeDrop {
+	ret := _m.Called(ctx, threshold)
+
+	var r0 []models.MixUptimeDrop
+	if rf, ok := ret.Get(0).(func(context.Context, int) []models.MixUptimeDrop); ok {
+		r0 = rf(ctx, threshold)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.MixUptimeDrop)
+		}
+	}
+
 	return r0
 }
 
-// GetGatewayStatusReport provides a mock function with given fields: pubkey
-func (_m *IService) GetGatewayStatusReport(pubkey string) models.GatewayStatusReport {
-	ret := _m.Called(pubkey)
+// ExportData provides a mock function with given fields: ctx, statuses, write
+func (_m *IService) ExportData(ctx context.Context, statuses bool, write func(models.ExportRecord) error) error {
+	ret := _m.Called(ctx, statuses, write)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, bool, func(models.ExportRecord) error) error); ok {
+		r0 = rf(ctx, statuses, write)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GatewayCount provides a mock function with given fields: ctx
+func (_m *IService) GatewayCount(ctx context.Context) int {
+	ret := _m.Called(ctx)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(context.Context) int); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	return r0
+}
+
+// GetGatewayLifetime provides a mock function with given fields: ctx, pubkey
+func (_m *IService) GetGatewayLifetime(ctx context.Context, pubkey string) models.NodeLifetime {
+	ret := _m.Called(ctx, pubkey)
+
+	var r0 models.NodeLifetime
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.NodeLifetime); ok {
+		r0 = rf(ctx, pubkey)
+	} else {
+		r0 = ret.Get(0).(models.NodeLifetime)
+	}
+
+	return r0
+}
+
+// GetGatewayStatusReport provides a mock function with given fields: ctx, pubkey
+func (_m *IService) GetGatewayStatusReport(ctx context.Context, pubkey string) models.GatewayStatusReport {
+	ret := _m.Called(ctx, pubkey)
 
 	var r0 models.GatewayStatusReport
-	if rf, ok := ret.Get(0).(func(string) models.GatewayStatusReport); ok {
-		r0 = rf(pubkey)
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.GatewayStatusReport); ok {
+		r0 = rf(ctx, pubkey)
 	} else {
 		r0 = ret.Get(0).(models.GatewayStatusReport)
 	}
@@ -114,27 +262,125 @@ func (_m *IService) GetGatewayStatusReport(pubkey string) models.GatewayStatusRe
 	return r0
 }
 
-// GetMixStatusReport provides a mock function with given fields: pubkey
-func (_m *IService) GetMixStatusReport(pubkey string) models.MixStatusReport {
-	ret := _m.Called(pubkey)
+// GetMixEpochReport provides a mock function with given fields: ctx, epoch
+func (_m *IService) GetMixEpochReport(ctx context.Context, epoch uint64) models.MixEpochReport {
+	ret := _m.Called(ctx, epoch)
+
+	var r0 models.MixEpochReport
+	if rf, ok := ret.Get(0).(func(context.Context, uint64) models.MixEpochReport); ok {
+		r0 = rf(ctx, epoch)
+	} else {
+		r0 = ret.Get(0).(models.MixEpochReport)
+	}
+
+	return r0
+}
+
+// GetMixLifetime provides a mock function with given fields: ctx, pubkey
+func (_m *IService) GetMixLifetime(ctx context.Context, pubkey string) models.NodeLifetime {
+	ret := _m.Called(ctx, pubkey)
+
+	var r0 models.NodeLifetime
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.NodeLifetime); ok {
+		r0 = rf(ctx, pubkey)
+	} else {
+		r0 = ret.Get(0).(models.NodeLifetime)
+	}
+
+	return r0
+}
+
+// GetMixNodeSummary provides a mock function with given fields: ctx, pubkey
+func (_m *IService) GetMixNodeSummary(ctx context.Context, pubkey string) models.MixNodeSummary {
+	ret := _m.Called(ctx, pubkey)
+
+	var r0 models.MixNodeSummary
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.MixNodeSummary); ok {
+		r0 = rf(ctx, pubkey)
+	} else {
+		r0 = ret.Get(0).(models.MixNodeSummary)
+	}
+
+	return r0
+}
+
+// GetMixRawUptime provides a mock function with given fields: ctx, pubkey
+func (_m *IService) GetMixRawUptime(ctx context.Context, pubkey string) models.MixRawUptime {
+	ret := _m.Called(ctx, pubkey)
+
+	var r0 models.MixRawUptime
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.MixRawUptime); ok {
+		r0 = rf(ctx, pubkey)
+	} else {
+		r0 = ret.Get(0).(models.MixRawUptime)
+	}
+
+	return r0
+}
+
+// GetMixStatusReport provides a mock function with given fields: ctx, pubkey
+func (_m *IService) GetMixStatusReport(ctx context.Context, pubkey string) (models.MixStatusReport, error) {
+	ret := _m.Called(ctx, pubkey)
 
 	var r0 models.MixStatusReport
-	if rf, ok := ret.Get(0).(func(string) models.MixStatusReport); ok {
-		r0 = rf(pubkey)
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.MixStatusReport); ok {
+		r0 = rf(ctx, pubkey)
 	} else {
 		r0 = ret.Get(0).(models.MixStatusReport)
 	}
 
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, pubkey)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetStats provides a mock function with given fields: ctx
+func (_m *IService) GetStats(ctx context.Context) models.StatusStats {
+	ret := _m.Called(ctx)
+
+	var r0 models.StatusStats
+	if rf, ok := ret.Get(0).(func(context.Context) models.StatusStats); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(models.StatusStats)
+	}
+
 	return r0
 }
 
-// ListGatewayStatus provides a mock function with given fields: pubkey
-func (_m *IService) ListGatewayStatus(pubkey string) []models.PersistedGatewayStatus {
-	ret := _m.Called(pubkey)
+// ImportData provides a mock function with given fields: ctx, next
+func (_m *IService) ImportData(ctx context.Context, next func() (models.ExportRecord, error)) (models.Import, error) {
+	ret := _m.Called(ctx, next)
+
+	var r0 models.Import
+	if rf, ok := ret.Get(0).(func(context.Context, func() (models.ExportRecord, error)) models.Import); ok {
+		r0 = rf(ctx, next)
+	} else {
+		r0 = ret.Get(0).(models.Import)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, func() (models.ExportRecord, error)) error); ok {
+		r1 = rf(ctx, next)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListGatewayStatus provides a mock function with given fields: ctx, pubkey
+func (_m *IService) ListGatewayStatus(ctx context.Context, pubkey string) []models.PersistedGatewayStatus {
+	ret := _m.Called(ctx, pubkey)
 
 	var r0 []models.PersistedGatewayStatus
-	if rf, ok := ret.Get(0).(func(string) []models.PersistedGatewayStatus); ok {
-		r0 = rf(pubkey)
+	if rf, ok := ret.Get(0).(func(context.Context, string) []models.PersistedGatewayStatus); ok {
+		r0 = rf(ctx, pubkey)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]models.PersistedGatewayStatus)
@@ -144,13 +390,13 @@ func (_m *IService) ListGatewayStatus(pubkey string) []models.PersistedGatewaySt
 	return r0
 }
 
-// ListMixStatus provides a mock function with given fields: pubkey
-func (_m *IService) ListMixStatus(pubkey string) []models.PersistedMixStatus {
-	ret := _m.Called(pubkey)
+// ListMixStatus provides a mock function with given fields: ctx, pubkey
+func (_m *IService) ListMixStatus(ctx context.Context, pubkey string) []models.PersistedMixStatus {
+	ret := _m.Called(ctx, pubkey)
 
 	var r0 []models.PersistedMixStatus
-	if rf, ok := ret.Get(0).(func(string) []models.PersistedMixStatus); ok {
-		r0 = rf(pubkey)
+	if rf, ok := ret.Get(0).(func(context.Context, string) []models.PersistedMixStatus); ok {
+		r0 = rf(ctx, pubkey)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]models.PersistedMixStatus)
@@ -160,13 +406,184 @@ func (_m *IService) ListMixStatus(pubkey string) []models.PersistedMixStatus {
 	return r0
 }
 
-// SaveBatchGatewayStatusReport provides a mock function with given fields: status
-func (_m *IService) SaveBatchGatewayStatusReport(status []models.PersistedGatewayStatus) models.BatchGatewayStatusReport {
-	ret := _m.Called(status)
+// ListMixStatusBulk provides a mock function with given fields: ctx, pubkeys, limit
+func (_m *IService) ListMixStatusBulk(ctx context.Context, pubkeys []string, limit int) map[string][]models.PersistedMixStatus {
+	ret := _m.Called(ctx, pubkeys, limit)
+
+	var r0 map[string][]models.PersistedMixStatus
+	if rf, ok := ret.Get(0).(func(context.Context, []string, int) map[string][]models.PersistedMixStatus); ok {
+		r0 = rf(ctx, pubkeys, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string][]models.PersistedMixStatus)
+		}
+	}
+
+	return r0
+}
+
+// ListMixStatusByState provides a mock function with given fields: ctx, pubkey, ipVersion, up, hours
+func (_m *IService) ListMixStatusByState(ctx context.Context, pubkey string, ipVersion string, up bool, hours int) []models.PersistedMixStatus {
+	ret := _m.Called(ctx, pubkey, ipVersion, up, hours)
+
+	var r0 []models.PersistedMixStatus
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, bool, int) []models.PersistedMixStatus); ok {
+		r0 = rf(ctx, pubkey, ipVersion, up, hours)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.PersistedMixStatus)
+		}
+	}
+
+	return r0
+}
+
+// ListNetworkUptimeHistory provides a mock function with given fields: ctx, hours
+func (_m *IService) ListNetworkUptimeHistory(ctx context.Context, hours int) []models.NetworkUptimeSample {
+	ret := _m.Called(ctx, hours)
+
+	var r0 []models.NetworkUptimeSample
+	if rf, ok := ret.Get(0).(func(context.Context, int) []models.NetworkUptimeSample); ok {
+		r0 = rf(ctx, hours)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.NetworkUptimeSample)
+		}
+	}
+
+	return r0
+}
+
+// ListOwners provides a mock function with given fields: ctx
+func (_m *IService) ListOwners(ctx context.Context) []string {
+	ret := _m.Called(ctx)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(context.Context) []string); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	return r0
+}
+
+// ListOwnershipChanges provides a mock function with given fields: ctx, pubkey
+func (_m *IService) ListOwnershipChanges(ctx context.Context, pubkey string) []models.OwnershipChange {
+	ret := _m.Called(ctx, pubkey)
+
+	var r0 []models.OwnershipChange
+	if rf, ok := ret.Get(0).(func(context.Context, string) []models.OwnershipChange); ok {
+		r0 = rf(ctx, pubkey)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.OwnershipChange)
+		}
+	}
+
+	return r0
+}
+
+// MixCount provides a mock function with given fields: ctx
+func (_m *IService) MixCount(ctx context.Context) int {
+	ret := _m.Called(ctx)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(context.Context) int); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	return r0
+}
+
+// Ping provides a mock function with given fields: ctx
+func (_m *IService) Ping(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RecomputeAllReports provides a mock function with given fields: ctx
+func (_m *IService) RecomputeAllReports(ctx context.Context) models.Recomputation {
+	ret := _m.Called(ctx)
+
+	var r0 models.Recomputation
+	if rf, ok := ret.Get(0).(func(context.Context) models.Recomputation); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(models.Recomputation)
+	}
+
+	return r0
+}
+
+// RecomputeMixReport provides a mock function with given fields: ctx, pubkey
+func (_m *IService) RecomputeMixReport(ctx context.Context, pubkey string) (models.MixStatusReport, error) {
+	ret := _m.Called(ctx, pubkey)
+
+	var r0 models.MixStatusReport
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.MixStatusReport); ok {
+		r0 = rf(ctx, pubkey)
+	} else {
+		r0 = ret.Get(0).(models.MixStatusReport)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, pubkey)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RetractGatewayStatus provides a mock function with given fields: ctx, pubkey, ipVersion, timestamp
+func (_m *IService) RetractGatewayStatus(ctx context.Context, pubkey string, ipVersion string, timestamp int64) bool {
+	ret := _m.Called(ctx, pubkey, ipVersion, timestamp)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64) bool); ok {
+		r0 = rf(ctx, pubkey, ipVersion, timestamp)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// RetractMixStatus provides a mock function with given fields: ctx, pubkey, ipVersion, timestamp
+func (_m *IService) RetractMixStatus(ctx context.Context, pubkey string, ipVersion string, timestamp int64) bool {
+	ret := _m.Called(ctx, pubkey, ipVersion, timestamp)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64) bool); ok {
+		r0 = rf(ctx, pubkey, ipVersion, timestamp)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// SaveBatchGatewayStatusReport provides a mock function with given fields: ctx, status
+func (_m *IService) SaveBatchGatewayStatusReport(ctx context.Context, status []models.PersistedGatewayStatus) models.BatchGatewayStatusReport {
+	ret := _m.Called(ctx, status)
 
 	var r0 models.BatchGatewayStatusReport
-	if rf, ok := ret.Get(0).(func([]models.PersistedGatewayStatus) models.BatchGatewayStatusReport); ok {
-		r0 = rf(status)
+	if rf, ok := ret.Get(0).(func(context.Context, []models.PersistedGatewayStatus) models.BatchGatewayStatusReport); ok {
+		r0 = rf(ctx, status)
 	} else {
 		r0 = ret.Get(0).(models.BatchGatewayStatusReport)
 	}
@@ -174,13 +591,13 @@ func (_m *IService) SaveBatchGatewayStatusReport(status []models.PersistedGatewa
 	return r0
 }
 
-// SaveBatchMixStatusReport provides a mock function with given fields: status
-func (_m *IService) SaveBatchMixStatusReport(status []models.PersistedMixStatus) models.BatchMixStatusReport {
-	ret := _m.Called(status)
+// SaveBatchMixStatusReport provides a mock function with given fields: ctx, status
+func (_m *IService) SaveBatchMixStatusReport(ctx context.Context, status []models.PersistedMixStatus) models.BatchMixStatusReport {
+	ret := _m.Called(ctx, status)
 
 	var r0 models.BatchMixStatusReport
-	if rf, ok := ret.Get(0).(func([]models.PersistedMixStatus) models.BatchMixStatusReport); ok {
-		r0 = rf(status)
+	if rf, ok := ret.Get(0).(func(context.Context, []models.PersistedMixStatus) models.BatchMixStatusReport); ok {
+		r0 = rf(ctx, status)
 	} else {
 		r0 = ret.Get(0).(models.BatchMixStatusReport)
 	}
@@ -188,13 +605,13 @@ func (_m *IService) SaveBatchMixStatusReport(status []models.PersistedMixStatus)
 	return r0
 }
 
-// SaveGatewayStatusReport provides a mock function with given fields: status
-func (_m *IService) SaveGatewayStatusReport(status models.PersistedGatewayStatus) models.GatewayStatusReport {
-	ret := _m.Called(status)
+// SaveGatewayStatusReport provides a mock function with given fields: ctx, status
+func (_m *IService) SaveGatewayStatusReport(ctx context.Context, status models.PersistedGatewayStatus) models.GatewayStatusReport {
+	ret := _m.Called(ctx, status)
 
 	var r0 models.GatewayStatusReport
-	if rf, ok := ret.Get(0).(func(models.PersistedGatewayStatus) models.GatewayStatusReport); ok {
-		r0 = rf(status)
+	if rf, ok := ret.Get(0).(func(context.Context, models.PersistedGatewayStatus) models.GatewayStatusReport); ok {
+		r0 = rf(ctx, status)
 	} else {
 		r0 = ret.Get(0).(models.GatewayStatusReport)
 	}
@@ -202,16 +619,55 @@ func (_m *IService) SaveGatewayStatusReport(status models.PersistedGatewayStatus
 	return r0
 }
 
-// SaveMixStatusReport provides a mock function with given fields: status
-func (_m *IService) SaveMixStatusReport(status models.PersistedMixStatus) models.MixStatusReport {
-	ret := _m.Called(status)
+// SaveMixStatusReport provides a mock function with given fields: ctx, status
+func (_m *IService) SaveMixStatusReport(ctx context.Context, status models.PersistedMixStatus) models.MixStatusReport {
+	ret := _m.Called(ctx, status)
 
 	var r0 models.MixStatusReport
-	if rf, ok := ret.Get(0).(func(models.PersistedMixStatus) models.MixStatusReport); ok {
-		r0 = rf(status)
+	if rf, ok := ret.Get(0).(func(context.Context, models.PersistedMixStatus) models.MixStatusReport); ok {
+		r0 = rf(ctx, status)
 	} else {
 		r0 = ret.Get(0).(models.MixStatusReport)
 	}
 
 	return r0
 }
+
+// SubscribeMixStatuses provides a mock function with given fields:
+func (_m *IService) SubscribeMixStatuses() (<-chan models.PersistedMixStatus, func()) {
+	ret := _m.Called()
+
+	var r0 <-chan models.PersistedMixStatus
+	if rf, ok := ret.Get(0).(func() <-chan models.PersistedMixStatus); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(<-chan models.PersistedMixStatus)
+		}
+	}
+
+	var r1 func()
+	if rf, ok := ret.Get(1).(func() func()); ok {
+		r1 = rf()
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(func())
+		}
+	}
+
+	return r0, r1
+}
+
+// TopMixReports provides a mock function with given fields: ctx, field, n
+func (_m *IService) TopMixReports(ctx context.Context, field string, n int) models.BatchMixStatusReport {
+	ret := _m.Called(ctx, field, n)
+
+	var r0 models.BatchMixStatusReport
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) models.BatchMixStatusReport); ok {
+		r0 = rf(ctx, field, n)
+	} else {
+		r0 = ret.Get(0).(models.BatchMixStatusReport)
+	}
+
+	return r0
+}
@@ -0,0 +1,130 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mixmining
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nymtech/node-status-api/models"
+)
+
+// DefaultReportCacheTTL is how long the full mix report is served from memory by default. Dashboards poll it every
+// few seconds, while the reports it's made of only change with new statuses.
+const DefaultReportCacheTTL = 10 * time.Second
+
+// reportCache keeps the full mix report in memory for a short while. It's kept behind a pointer, so that copies of
+// the service share it with the updater goroutine.
+type reportCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	report  models.BatchMixStatusReport
+	expires time.Time
+	// generation changes with every invalidation, so that a report loaded before one doesn't get cached after it
+	generation uint64
+
+	hits int64
+}
+
+func newReportCache(ttl time.Duration) *reportCache {
+	return &reportCache{ttl: ttl}
+}
+
+// get returns the cached report if it hasn't expired by now, otherwise the one returned by load, which gets cached
+// unless it's incomplete. The report returned is a copy, callers are free to modify it.
+func (cache *reportCache) get(now time.Time, load func() (models.BatchMixStatusReport, bool)) models.BatchMixStatusReport {
+	if cache.ttl <= 0 {
+		report, _ := load()
+		return report
+	}
+
+	cache.mu.Lock()
+	if now.Before(cache.expires) {
+		report := copyBatchMixStatusReport(cache.report)
+		cache.mu.Unlock()
+		atomic.AddInt64(&cache.hits, 1)
+		return report
+	}
+	generation := cache.generation
+	cache.mu.Unlock()
+
+	report, complete := load()
+	if !complete {
+		return report
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.generation == generation {
+		cache.report = report
+		cache.expires = now.Add(cache.ttl)
+	}
+	return copyBatchMixStatusReport(report)
+}
+
+// invalidate drops the cached report, the next one gets loaded from the database
+func (cache *reportCache) invalidate() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.report = models.BatchMixStatusReport{}
+	cache.expires = time.Time{}
+	cache.generation++
+}
+
+// hitCount returns how many times the report was served from the cache
+func (cache *reportCache) hitCount() int64 {
+	return atomic.LoadInt64(&cache.hits)
+}
+
+// copyBatchMixStatusReport deep copies the reports, so that modifying the copy doesn't reach the cached ones through
+// their uptime maps or round-trip time pointers
+func copyBatchMixStatusReport(report models.BatchMixStatusReport) models.BatchMixStatusReport {
+	if report.Report == nil {
+		return report
+	}
+	reports := make([]models.MixStatusReport, len(report.Report))
+	for i, mixReport := range report.Report {
+		reports[i] = copyMixStatusReport(mixReport)
+	}
+	return models.BatchMixStatusReport{Report: reports}
+}
+
+func copyMixStatusReport(report models.MixStatusReport) models.MixStatusReport {
+	report.UptimesIPV4 = copyUptimes(report.UptimesIPV4)
+	report.UptimesIPV6 = copyUptimes(report.UptimesIPV6)
+	for _, rtt := range []**int{
+		&report.Last5MinutesRTTIPV4, &report.LastHourRTTIPV4, &report.LastDayRTTIPV4,
+		&report.Last5MinutesRTTIPV6, &report.LastHourRTTIPV6, &report.LastDayRTTIPV6,
+	} {
+		if *rtt != nil {
+			value := **rtt
+			*rtt = &value
+		}
+	}
+	return report
+}
+
+func copyUptimes(uptimes models.Uptimes) models.Uptimes {
+	if uptimes == nil {
+		return nil
+	}
+	copied := make(models.Uptimes, len(uptimes))
+	for window, uptime := range uptimes {
+		copied[window] = uptime
+	}
+	return copied
+}
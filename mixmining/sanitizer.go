@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"strings"
 
 	"github.com/microcosm-cc/bluemonday"
 	"github.com/nymtech/node-status-api/models"
@@ -41,24 +42,58 @@ func NewGenericSanitizer(policy *bluemonday.Policy) GenericSanitizer {
 
 func (s genericSanitizer) sanitizeStruct(v reflect.Value) {
 	for i := 0; i < v.NumField(); i++ {
-		field := v.Field(i)
-		kind := field.Kind()
-
-		switch kind {
-		case reflect.String:
-			if !field.CanSet() {
-				fmt.Printf("wtf can't set %v (type: %v)", field, kind)
-				continue
-			}
-			field.SetString(s.policy.Sanitize(field.String()))
-		case reflect.Struct:
-			s.Sanitize(v.Field(i).Addr().Interface())
-		case reflect.Int64:
-		case reflect.Uint:
-			continue
-		default:
-			fmt.Fprintf(os.Stderr, "tried to sanitize unknown type %+v\n", kind)
+		s.sanitizeValue(v.Field(i))
+	}
+}
+
+// sanitizeValue sanitizes a struct field, or an element of a slice or a map, in place
+func (s genericSanitizer) sanitizeValue(field reflect.Value) {
+	kind := field.Kind()
+
+	switch kind {
+	case reflect.String:
+		if !field.CanSet() {
+			fmt.Printf("wtf can't set %v (type: %v)", field, kind)
+			return
 		}
+		field.SetString(s.policy.Sanitize(field.String()))
+	case reflect.Struct:
+		s.sanitizeStruct(field)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < field.Len(); i++ {
+			s.sanitizeValue(field.Index(i))
+		}
+	case reflect.Map:
+		s.sanitizeMap(field)
+	case reflect.Ptr:
+		// whatever the pointer points to is sanitized in place, the pointer itself stays the same
+		if !field.IsNil() {
+			s.sanitizeValue(field.Elem())
+		}
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		// there's no markup in numbers and booleans
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "tried to sanitize unknown type %+v\n", kind)
+	}
+}
+
+// sanitizeMap sanitizes the values of the map. They aren't addressable, so each of them gets sanitized in a copy
+// which then replaces the original one. The keys are left as they are.
+func (s genericSanitizer) sanitizeMap(m reflect.Value) {
+	if !m.CanInterface() {
+		fmt.Printf("wtf can't set %v (type: %v)", m, m.Kind())
+		return
+	}
+	iter := m.MapRange()
+	for iter.Next() {
+		value := reflect.New(m.Type().Elem()).Elem()
+		value.Set(iter.Value())
+		s.sanitizeValue(value)
+		m.SetMapIndex(iter.Key(), value)
 	}
 }
 
@@ -72,12 +107,30 @@ func (s genericSanitizer) Sanitize(input interface{}) {
 		v.SetString(s.policy.Sanitize(v.String()))
 	case reflect.Struct:
 		s.sanitizeStruct(v)
+	case reflect.Slice, reflect.Map:
+		s.sanitizeValue(v)
 	default:
 		fmt.Fprintf(os.Stderr, "tried to sanitize unknown type %+v\n", inputKind)
 	}
 
 }
 
+// NormalizeIdentifier trims the whitespace some monitors pad pubkeys and owners with, which would otherwise split the
+// data of a single node between two keys. The case is left alone, as it's significant in the encoded identifiers.
+// The normalization must be symmetric: whenever the identifiers written get normalized, the ones looked up must be
+// normalized the same way, or the writes can't be found.
+func NormalizeIdentifier(identifier string) string {
+	return strings.TrimSpace(identifier)
+}
+
+// normalizeIf normalizes the identifier if normalization is enabled
+func normalizeIf(enabled bool, identifier string) string {
+	if !enabled {
+		return identifier
+	}
+	return NormalizeIdentifier(identifier)
+}
+
 // BatchMixSanitizer sanitizes untrusted batch mixmining data. It should be used in
 // controllers to wipe out any questionable input at our application's front
 // door.
@@ -89,11 +142,13 @@ type batchMixSanitizer struct {
 	sanitizer mixStatusSanitizer
 }
 
-// NewBatchMixSanitizer returns a new input mixStatusSanitizer for metrics
-func NewBatchMixSanitizer(policy *bluemonday.Policy) BatchMixSanitizer {
+// NewBatchMixSanitizer returns a new input mixStatusSanitizer for metrics. See NewMixStatusSanitizer for the policies.
+func NewBatchMixSanitizer(policy *bluemonday.Policy, identifierPolicy *bluemonday.Policy, normalizeIdentifiers bool) BatchMixSanitizer {
 	return batchMixSanitizer{
 		sanitizer: mixStatusSanitizer{
-			policy: policy,
+			policy:               policy,
+			identifierPolicy:     identifierPolicy,
+			normalizeIdentifiers: normalizeIdentifiers,
 		},
 	}
 }
@@ -116,11 +171,13 @@ type batchGatewaySanitizer struct {
 	sanitizer gatewayStatusSanitizer
 }
 
-// NewBatchGatewaySanitizer returns a new input mixStatusSanitizer for metrics
-func NewBatchGatewaySanitizer(policy *bluemonday.Policy) BatchGatewaySanitizer {
+// NewBatchGatewaySanitizer returns a new input mixStatusSanitizer for metrics. See NewMixStatusSanitizer for the policies.
+func NewBatchGatewaySanitizer(policy *bluemonday.Policy, identifierPolicy *bluemonday.Policy, normalizeIdentifiers bool) BatchGatewaySanitizer {
 	return batchGatewaySanitizer{
 		sanitizer: gatewayStatusSanitizer{
-			policy: policy,
+			policy:               policy,
+			identifierPolicy:     identifierPolicy,
+			normalizeIdentifiers: normalizeIdentifiers,
 		},
 	}
 }
@@ -140,23 +197,33 @@ type MixStatusSanitizer interface {
 }
 
 type mixStatusSanitizer struct {
-	policy *bluemonday.Policy
+	policy               *bluemonday.Policy
+	identifierPolicy     *bluemonday.Policy
+	normalizeIdentifiers bool
 }
 
-// NewMixStatusSanitizer returns a new input mixStatusSanitizer for metrics
-func NewMixStatusSanitizer(policy *bluemonday.Policy) MixStatusSanitizer {
+// NewMixStatusSanitizer returns a new input mixStatusSanitizer for metrics. The identifier policy applies to the
+// pubkey and the ip version, which are opaque identifiers rather than markup, so it's meant to be a strict one
+// stripping all of the HTML. The general policy applies to everything else. With normalizeIdentifiers, the pubkey
+// and the owner also go through NormalizeIdentifier.
+func NewMixStatusSanitizer(policy *bluemonday.Policy, identifierPolicy *bluemonday.Policy, normalizeIdentifiers bool) MixStatusSanitizer {
 	return mixStatusSanitizer{
-		policy: policy,
+		policy:               policy,
+		identifierPolicy:     identifierPolicy,
+		normalizeIdentifiers: normalizeIdentifiers,
 	}
 }
 
 func (s mixStatusSanitizer) Sanitize(input models.MixStatus) models.MixStatus {
 	sanitized := newMixMeasurement()
 
-	sanitized.PubKey = s.policy.Sanitize(input.PubKey)
-	sanitized.Owner = s.policy.Sanitize(input.Owner)
-	sanitized.IPVersion = s.policy.Sanitize(input.IPVersion)
+	sanitized.PubKey = normalizeIf(s.normalizeIdentifiers, s.identifierPolicy.Sanitize(input.PubKey))
+	sanitized.Owner = normalizeIf(s.normalizeIdentifiers, s.policy.Sanitize(input.Owner))
+	sanitized.IPVersion = s.identifierPolicy.Sanitize(input.IPVersion)
 	sanitized.Up = input.Up
+	sanitized.RTTMillis = input.RTTMillis
+	sanitized.MeasuredAt = input.MeasuredAt
+	sanitized.Epoch = input.Epoch
 	return sanitized
 }
 
@@ -179,23 +246,29 @@ type GatewayStatusSanitizer interface {
 }
 
 type gatewayStatusSanitizer struct {
-	policy *bluemonday.Policy
+	policy               *bluemonday.Policy
+	identifierPolicy     *bluemonday.Policy
+	normalizeIdentifiers bool
 }
 
-// NewGatewayStatusSanitizer returns a new input mixStatusSanitizer for metrics
-func NewGatewayStatusSanitizer(policy *bluemonday.Policy) GatewayStatusSanitizer {
+// NewGatewayStatusSanitizer returns a new input mixStatusSanitizer for metrics. See NewMixStatusSanitizer for the policies.
+func NewGatewayStatusSanitizer(policy *bluemonday.Policy, identifierPolicy *bluemonday.Policy, normalizeIdentifiers bool) GatewayStatusSanitizer {
 	return gatewayStatusSanitizer{
-		policy: policy,
+		policy:               policy,
+		identifierPolicy:     identifierPolicy,
+		normalizeIdentifiers: normalizeIdentifiers,
 	}
 }
 
 func (s gatewayStatusSanitizer) Sanitize(input models.GatewayStatus) models.GatewayStatus {
 	sanitized := newGatewayMeasurement()
 
-	sanitized.PubKey = s.policy.Sanitize(input.PubKey)
-	sanitized.Owner = s.policy.Sanitize(input.Owner)
-	sanitized.IPVersion = s.policy.Sanitize(input.IPVersion)
+	sanitized.PubKey = normalizeIf(s.normalizeIdentifiers, s.identifierPolicy.Sanitize(input.PubKey))
+	sanitized.Owner = normalizeIf(s.normalizeIdentifiers, s.policy.Sanitize(input.Owner))
+	sanitized.IPVersion = s.identifierPolicy.Sanitize(input.IPVersion)
 	sanitized.Up = input.Up
+	sanitized.RTTMillis = input.RTTMillis
+	sanitized.ClientsHostUp = input.ClientsHostUp
 	return sanitized
 }
 
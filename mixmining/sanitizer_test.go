@@ -26,7 +26,7 @@ var _ = Describe("MixStatusSanitizer", func() {
 		Context("when XSS is present", func() {
 			It("sanitizes input", func() {
 				policy := bluemonday.UGCPolicy()
-				sanitizer := NewMixStatusSanitizer(policy)
+				sanitizer := NewMixStatusSanitizer(policy, policy, false)
 				result := sanitizer.Sanitize(xssStatus())
 				assert.Equal(GinkgoT(), goodMetric(), result)
 			})
@@ -34,11 +34,88 @@ var _ = Describe("MixStatusSanitizer", func() {
 		Context("when XSS is not present", func() {
 			It("doesn't change input", func() {
 				policy := bluemonday.UGCPolicy()
-				sanitizer := NewMixStatusSanitizer(policy)
+				sanitizer := NewMixStatusSanitizer(policy, policy, false)
 				result := sanitizer.Sanitize(goodMetric())
 				assert.Equal(GinkgoT(), goodMetric(), result)
 			})
 		})
+		Context("when the round-trip time is present", func() {
+			It("keeps it", func() {
+				rtt := 42
+				status := goodMetric()
+				status.RTTMillis = &rtt
+				policy := bluemonday.UGCPolicy()
+				sanitizer := NewMixStatusSanitizer(policy, policy, false)
+				result := sanitizer.Sanitize(status)
+				assert.Equal(GinkgoT(), status, result)
+			})
+		})
+		Context("when the measurement time is present", func() {
+			It("keeps it", func() {
+				measuredAt := int64(1234)
+				status := goodMetric()
+				status.MeasuredAt = &measuredAt
+				policy := bluemonday.UGCPolicy()
+				sanitizer := NewMixStatusSanitizer(policy, policy, false)
+				result := sanitizer.Sanitize(status)
+				assert.Equal(GinkgoT(), status, result)
+			})
+		})
+		Context("when the epoch is present", func() {
+			It("keeps it", func() {
+				epoch := uint64(42)
+				status := goodMetric()
+				status.Epoch = &epoch
+				policy := bluemonday.UGCPolicy()
+				sanitizer := NewMixStatusSanitizer(policy, policy, false)
+				result := sanitizer.Sanitize(status)
+				assert.Equal(GinkgoT(), status, result)
+			})
+		})
+		Context("with identifier normalization", func() {
+			It("trims the whitespace around the pubkey and the owner but keeps their case", func() {
+				status := goodMetric()
+				status.PubKey = " AbCd\t"
+				status.Owner = "\nOwner "
+				policy := bluemonday.UGCPolicy()
+				result := NewMixStatusSanitizer(policy, policy, true).Sanitize(status)
+				assert.Equal(GinkgoT(), "AbCd", result.PubKey)
+				assert.Equal(GinkgoT(), "Owner", result.Owner)
+			})
+			It("does the same for gateways", func() {
+				up := true
+				policy := bluemonday.UGCPolicy()
+				result := NewGatewayStatusSanitizer(policy, policy, true).Sanitize(models.GatewayStatus{PubKey: " AbCd ", Owner: " Owner", IPVersion: "4", Up: &up})
+				assert.Equal(GinkgoT(), "AbCd", result.PubKey)
+				assert.Equal(GinkgoT(), "Owner", result.Owner)
+			})
+			It("leaves the identifiers alone when disabled", func() {
+				status := goodMetric()
+				status.PubKey = " AbCd "
+				policy := bluemonday.UGCPolicy()
+				assert.Equal(GinkgoT(), " AbCd ", NewMixStatusSanitizer(policy, policy, false).Sanitize(status).PubKey)
+			})
+		})
+		Context("with a strict policy for the identifiers", func() {
+			It("strips all of the markup from them", func() {
+				status := goodMetric()
+				status.PubKey = "ab<b>cd</b>"
+				status.IPVersion = "<i>4</i>"
+				status.Owner = "<b>owner</b>"
+				sanitizer := NewMixStatusSanitizer(bluemonday.UGCPolicy(), bluemonday.StrictPolicy(), false)
+				result := sanitizer.Sanitize(status)
+				assert.Equal(GinkgoT(), "abcd", result.PubKey)
+				assert.Equal(GinkgoT(), "4", result.IPVersion)
+				assert.Equal(GinkgoT(), "<b>owner</b>", result.Owner)
+			})
+			It("does the same for gateways", func() {
+				up := true
+				sanitizer := NewGatewayStatusSanitizer(bluemonday.UGCPolicy(), bluemonday.StrictPolicy(), false)
+				result := sanitizer.Sanitize(models.GatewayStatus{PubKey: "ab<b>cd</b>", IPVersion: "6", Up: &up, ClientsHostUp: &up})
+				assert.Equal(GinkgoT(), "abcd", result.PubKey)
+				assert.Equal(GinkgoT(), &up, result.ClientsHostUp)
+			})
+		})
 	})
 })
 
@@ -101,6 +178,81 @@ var _ = Describe("GenericSanitizer", func() {
 				sanitizer.Sanitize(&xssInput)
 				assert.Equal(GinkgoT(), goodInput, xssInput)
 			})
+			It("sanitizes input for collections in a struct", func() {
+				type foomp struct {
+					Foomper string
+				}
+				type bar struct {
+					Tags    []string
+					Labels  map[string]string
+					Foomps  []foomp
+					ByName  map[string]foomp
+					Nothing []string
+				}
+
+				xssInput := bar{
+					Tags:   []string{xssString(), "fine"},
+					Labels: map[string]string{"label": xssString()},
+					Foomps: []foomp{{xssString()}},
+					ByName: map[string]foomp{"name": {xssString()}},
+				}
+				goodInput := bar{
+					Tags:   []string{goodString(), "fine"},
+					Labels: map[string]string{"label": goodString()},
+					Foomps: []foomp{{goodString()}},
+					ByName: map[string]foomp{"name": {goodString()}},
+				}
+
+				policy := bluemonday.UGCPolicy()
+				sanitizer := NewGenericSanitizer(policy)
+				sanitizer.Sanitize(&xssInput)
+				assert.Equal(GinkgoT(), goodInput, xssInput)
+			})
+			It("leaves numbers and booleans alone", func() {
+				type foomp struct {
+					Foomper string
+					Int     int
+					Int32   int32
+					Float   float64
+					Bool    bool
+					Pointer *string
+				}
+				pointee := "foomp"
+				xssInput := foomp{xssString(), -1, 32, 4.2, true, &pointee}
+				goodInput := foomp{goodString(), -1, 32, 4.2, true, &pointee}
+
+				policy := bluemonday.UGCPolicy()
+				sanitizer := NewGenericSanitizer(policy)
+				sanitizer.Sanitize(&xssInput)
+				assert.Equal(GinkgoT(), goodInput, xssInput)
+			})
+			It("sanitizes input behind pointers", func() {
+				type foomp struct {
+					Foomper string
+				}
+				type bar struct {
+					Baz   *string
+					Foomp *foomp
+					Nil   *string
+				}
+				baz := xssString()
+				xssInput := bar{Baz: &baz, Foomp: &foomp{xssString()}}
+
+				policy := bluemonday.UGCPolicy()
+				sanitizer := NewGenericSanitizer(policy)
+				sanitizer.Sanitize(&xssInput)
+				assert.Equal(GinkgoT(), goodString(), baz)
+				assert.Equal(GinkgoT(), &baz, xssInput.Baz)
+				assert.Equal(GinkgoT(), goodString(), xssInput.Foomp.Foomper)
+				assert.Nil(GinkgoT(), xssInput.Nil)
+			})
+			It("sanitizes input for slice", func() {
+				input := []string{xssString()}
+				policy := bluemonday.UGCPolicy()
+				sanitizer := NewGenericSanitizer(policy)
+				sanitizer.Sanitize(&input)
+				assert.Equal(GinkgoT(), []string{goodString()}, input)
+			})
 		})
 	})
 })
@@ -15,50 +15,186 @@
 package mixmining
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
 	"time"
 
-	"github.com/BorisBorshevsky/timemock"
 	"github.com/nymtech/node-status-api/models"
+	"github.com/sirupsen/logrus"
 )
 
+// StatusRetention is how long individual statuses are kept around before they get purged
+const StatusRetention = time.Hour * 24 * 7
+
+// DefaultStaleAfter is how long after its most recent status a node is considered stale by default
+const DefaultStaleAfter = time.Hour * 24
+
+// DefaultNetworkHistoryHorizon is how long the network uptime samples are kept around by default
+const DefaultNetworkHistoryHorizon = time.Hour * 24 * 30
+
+// InsufficientData is the uptime of a node that didn't report enough statuses during the window to tell.
+// The percentage out of one or two statuses would be meaningless.
+const InsufficientData = -1
+
+// MaxHistoryLimit is the maximum number of statuses listed for a single node
+const MaxHistoryLimit = 1000
+
+// MaxBulkHistoryKeys is the maximum number of nodes whose statuses can be listed at once
+const MaxBulkHistoryKeys = 100
+
+const lastDayReportsUpdateInterval = time.Minute * 10
+const oldDataPurgeInterval = time.Hour * 2
+
 // Service struct
 type Service struct {
-	db     IDb
+	db         IDb
+	broker     *Broker
+	clock      Clock
+	staleAfter time.Duration
+	// minMeasurements is the number of statuses needed in a window for its uptime to be calculated
+	minMeasurements int
+
+	// all uptime windows, then split into the ones recalculated with each status and by the periodic reports updater
+	windows          []UptimeWindow
+	perStatusWindows []UptimeWindow
+	periodicWindows  []UptimeWindow
+
+	reportsUpdaterBackoff backoff
+	dataPurgerBackoff     backoff
+	reportsFreshness      *reportsFreshness
+	reportCache           *reportCache
+
+	// networkHistoryHorizon is how long the network uptime samples are kept around
+	networkHistoryHorizon time.Duration
+
+	// vacuumInterval is the least time between two vacuums of the database following a purge, 0 to never vacuum it.
+	// lastVacuum is only ever touched by the purger.
+	vacuumInterval time.Duration
+	lastVacuum     time.Time
+}
+
+// reportsFreshness records when the reports were last updated by the periodic updater. It's kept behind a pointer,
+// so that copies of the service share it with the updater goroutine.
+type reportsFreshness struct {
+	mu         sync.Mutex
+	lastUpdate int64
 }
 
 // IService defines the REST service interface for mixmining.
 type IService interface {
-	CreateMixStatus(mixStatus models.MixStatus) models.PersistedMixStatus
-	ListMixStatus(pubkey string) []models.PersistedMixStatus
-	SaveMixStatusReport(status models.PersistedMixStatus) models.MixStatusReport
-	GetMixStatusReport(pubkey string) models.MixStatusReport
-
-	SaveBatchMixStatusReport(status []models.PersistedMixStatus) models.BatchMixStatusReport
-	BatchCreateMixStatus(batchMixStatus models.BatchMixStatus) []models.PersistedMixStatus
-	BatchGetMixStatusReport() models.BatchMixStatusReport
-
-
-	CreateGatewayStatus(gatewayStatus models.GatewayStatus) models.PersistedGatewayStatus
-	ListGatewayStatus(pubkey string) []models.PersistedGatewayStatus
-	SaveGatewayStatusReport(status models.PersistedGatewayStatus) models.GatewayStatusReport
-	GetGatewayStatusReport(pubkey string) models.GatewayStatusReport
+	CreateMixStatus(mixStatus models.MixStatus) (models.PersistedMixStatus, error)
+	ListMixStatus(ctx context.Context, pubkey string) []models.PersistedMixStatus
+	ListMixStatusByState(ctx context.Context, pubkey string, ipVersion string, up bool, hours int) []models.PersistedMixStatus
+	ListMixStatusBulk(ctx context.Context, pubkeys []string, limit int) map[string][]models.PersistedMixStatus
+	GetMixEpochReport(ctx context.Context, epoch uint64) models.MixEpochReport
+	GetMixRawUptime(ctx context.Context, pubkey string) models.MixRawUptime
+	SaveMixStatusReport(ctx context.Context, status models.PersistedMixStatus) models.MixStatusReport
+	GetMixStatusReport(ctx context.Context, pubkey string) (models.MixStatusReport, error)
+
+	SaveBatchMixStatusReport(ctx context.Context, status []models.PersistedMixStatus) models.BatchMixStatusReport
+	BatchCreateMixStatus(batchMixStatus models.BatchMixStatus) ([]models.PersistedMixStatus, error)
+	BatchGetMixStatusReport(ctx context.Context) models.BatchMixStatusReport
+	AggregateMixUptime(ctx context.Context, hours int) models.MixUptimeAggregate
+	DetectMixUptimeDrops(ctx context.Context, threshold int) []models.MixUptimeDrop
+	ListNetworkUptimeHistory(ctx context.Context, hours int) []models.NetworkUptimeSample
+	CalculateMixUptimeAt(ctx context.Context, pubkey string, ipVersion string, at int64, window time.Duration) int
+	GetMixNodeSummary(ctx context.Context, pubkey string) models.MixNodeSummary
+	RecomputeMixReport(ctx context.Context, pubkey string) (models.MixStatusReport, error)
+	RecomputeAllReports(ctx context.Context) models.Recomputation
+	BackfillMixReports(ctx context.Context) models.Backfill
+	TopMixReports(ctx context.Context, field string, n int) models.BatchMixStatusReport
+	RetractMixStatus(ctx context.Context, pubkey string, ipVersion string, timestamp int64) bool
+	ListOwnershipChanges(ctx context.Context, pubkey string) []models.OwnershipChange
+	ChangedMixOwners(ctx context.Context, statuses []models.MixStatus) []int
+	SubscribeMixStatuses() (<-chan models.PersistedMixStatus, func())
+
+	CreateGatewayStatus(gatewayStatus models.GatewayStatus) (models.PersistedGatewayStatus, error)
+	ListGatewayStatus(ctx context.Context, pubkey string) []models.PersistedGatewayStatus
+	SaveGatewayStatusReport(ctx context.Context, status models.PersistedGatewayStatus) models.GatewayStatusReport
+	GetGatewayStatusReport(ctx context.Context, pubkey string) models.GatewayStatusReport
+
+	SaveBatchGatewayStatusReport(ctx context.Context, status []models.PersistedGatewayStatus) models.BatchGatewayStatusReport
+	BatchCreateGatewayStatus(batchGatewayStatus models.BatchGatewayStatus) ([]models.PersistedGatewayStatus, error)
+	BatchGetGatewayStatusReport(ctx context.Context) models.BatchGatewayStatusReport
+	RetractGatewayStatus(ctx context.Context, pubkey string, ipVersion string, timestamp int64) bool
+
+	GetStats(ctx context.Context) models.StatusStats
+	ListOwners(ctx context.Context) []string
+	GetMixLifetime(ctx context.Context, pubkey string) models.NodeLifetime
+	GetGatewayLifetime(ctx context.Context, pubkey string) models.NodeLifetime
+	ExportData(ctx context.Context, statuses bool, write func(models.ExportRecord) error) error
+	ImportData(ctx context.Context, next func() (models.ExportRecord, error)) (models.Import, error)
+	MixCount(ctx context.Context) int
+	GatewayCount(ctx context.Context) int
+	Ping(ctx context.Context) error
+}
 
-	SaveBatchGatewayStatusReport(status []models.PersistedGatewayStatus) models.BatchGatewayStatusReport
-	BatchCreateGatewayStatus(batchGatewayStatus models.BatchGatewayStatus) []models.PersistedGatewayStatus
-	BatchGetGatewayStatusReport() models.BatchGatewayStatusReport
+// ServiceConfig for the service. The zero value of each field picks its default.
+type ServiceConfig struct {
+	StaleAfter            time.Duration  // nodes without a status for longer are left out of the full reports, 0 means DefaultStaleAfter
+	UptimeWindows         []UptimeWindow // windows uptime is calculated over, none means DefaultUptimeWindows
+	MinMeasurements       int            // windows with fewer statuses get the InsufficientData uptime, same as the ones without any
+	NetworkHistoryHorizon time.Duration  // how long the network uptime samples are kept, 0 means DefaultNetworkHistoryHorizon
+	VacuumInterval        time.Duration  // the database is vacuumed after the purges at most this often, 0 means never
+	ReportCacheTTL        time.Duration  // how long the full mix report is served from memory once loaded, 0 means it's loaded every time
+	Clock                 Clock          // tells the time and paces the background jobs, nil means SystemClock
+	// IsTest keeps the service from purging, backfilling and starting its background jobs, so that tests control
+	// what runs
+	IsTest bool
 }
 
 // NewService constructor
-func NewService(db IDb, isTest bool) *Service {
+func NewService(db IDb, config ServiceConfig) *Service {
+	staleAfter := config.StaleAfter
+	if staleAfter <= 0 {
+		staleAfter = DefaultStaleAfter
+	}
+	networkHistoryHorizon := config.NetworkHistoryHorizon
+	if networkHistoryHorizon <= 0 {
+		networkHistoryHorizon = DefaultNetworkHistoryHorizon
+	}
+	windows := config.UptimeWindows
+	if len(windows) == 0 {
+		windows = DefaultUptimeWindows
+	}
+	clock := config.Clock
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	perStatusWindows, periodicWindows := splitUptimeWindows(windows)
 	service := &Service{
-		db: db,
+		db:         db,
+		broker:     NewBroker(DefaultSubscriberBufferSize),
+		clock:      clock,
+		staleAfter: staleAfter,
+
+		minMeasurements:  config.MinMeasurements,
+		windows:          windows,
+		perStatusWindows: perStatusWindows,
+		periodicWindows:  periodicWindows,
+
+		reportsUpdaterBackoff: newBackoff(lastDayReportsUpdateInterval),
+		dataPurgerBackoff:     newBackoff(oldDataPurgeInterval),
+		reportsFreshness:      &reportsFreshness{},
+		reportCache:           newReportCache(config.ReportCacheTTL),
+
+		networkHistoryHorizon: networkHistoryHorizon,
+		vacuumInterval:        config.VacuumInterval,
 	}
 
-	if !isTest {
+	if !config.IsTest {
+		// get rid of long gone nodes before serving anything
+		service.StartupPurge()
+		// and make up for any reports that went missing while it was down
+		service.BackfillMixReports(context.Background())
 		// same with 'last day' report updater (every 10min)
 		go lastDayReportsUpdater(service)
-		// and old statuses remover (every 1h)
+		// and old statuses remover (every 2h)
 		go oldDataPurger(service)
 	}
 
@@ -66,113 +202,395 @@ func NewService(db IDb, isTest bool) *Service {
 }
 
 func lastDayReportsUpdater(service *Service) {
-	ticker := time.NewTicker(time.Minute * 10)
+	delay := service.reportsUpdaterBackoff.next(nil)
+	for {
+		<-service.clock.After(delay)
+		delay = service.reportsUpdaterBackoff.next(service.updateLastDayReports(context.Background()))
+	}
+}
 
+func oldDataPurger(service *Service) {
+	// the first purge already happened on startup
+	delay := service.dataPurgerBackoff.next(nil)
 	for {
-		<-ticker.C
-		fmt.Println("Updating last day reports")
-		service.updateLastDayMixReports()
-		service.updateLastDayGatewayReports()
+		<-service.clock.After(delay)
+		delay = service.dataPurgerBackoff.next(service.purgeOldData(context.Background()))
 	}
+}
 
+// StartupPurge removes the reports of the nodes without any status within the report retention and the statuses past
+// the retention period right away, so that a restarted service doesn't serve long gone nodes until the purger runs. Failures are logged and otherwise ignored,
+// the purger will try again later.
+func (service *Service) StartupPurge() {
+	_ = service.purgeOldData(context.Background())
 }
 
-func oldDataPurger(service *Service) {
-	ticker := time.NewTicker(time.Hour * 2)
+// updateLastDayReports updates the 'last day' uptimes of all active nodes. It fails if the database is unreachable,
+// so that the next attempt gets delayed.
+func (service *Service) updateLastDayReports(ctx context.Context) error {
+	if err := service.db.Ping(ctx); err != nil {
+		logrus.WithError(err).Error("failed to update last day reports")
+		return err
+	}
 
-	for {
-		now := timemock.Now()
-		allNodesReport := service.db.BatchLoadAllMixReports()
-
-		// if the node didn't get ANY reports in last 24h it means it's stale
-		// and we don't need to hold its report data anymore
-		lastDay := now.Add(-time.Hour * 24).UnixNano()
-
-		var reportsToPurge []string
-		for _, report := range allNodesReport.Report {
-			// we should have an equal number of ipv4 and ipv6 statuses,
-			// so it's enough to query just for one type
-			v4Statuses := service.db.ListMixStatusSince(report.PubKey, "4", lastDay)
-			if len(v4Statuses) == 0 {
-				reportsToPurge = append(reportsToPurge, report.PubKey)
-			}
+	fmt.Println("Updating last day reports")
+	mixReports := service.updateLastDayMixReports(ctx)
+	service.updateLastDayGatewayReports(ctx)
+	service.db.AddNetworkUptimeSample(sampleNetworkUptime(mixReports, service.clock.Now().UnixNano()))
+
+	service.reportsFreshness.mu.Lock()
+	service.reportsFreshness.lastUpdate = service.clock.Now().UnixNano()
+	service.reportsFreshness.mu.Unlock()
+	return nil
+}
+
+// LastReportUpdate returns when the periodic updater last finished updating the reports, or 0 if it hasn't yet.
+// If it's much older than the update interval, the updater got stuck and the reports are going stale.
+func (service *Service) LastReportUpdate() int64 {
+	service.reportsFreshness.mu.Lock()
+	defer service.reportsFreshness.mu.Unlock()
+	return service.reportsFreshness.lastUpdate
+}
+
+// purgeOldData removes the reports of the nodes without any status within the report retention and the statuses past
+// the retention period. It fails if the database is
+// unreachable, so that the next attempt gets delayed.
+func (service *Service) purgeOldData(ctx context.Context) error {
+	if err := service.db.Ping(ctx); err != nil {
+		logrus.WithError(err).Error("failed to purge old data")
+		return err
+	}
+
+	now := service.clock.Now()
+	allNodesReport := service.db.BatchLoadAllMixReports(ctx)
+
+	// a node without any status, whichever the ip version, within the report retention has nothing left to back its
+	// report, so there's no need to hold its report data anymore
+	active := make(map[string]bool)
+	for _, pubkey := range service.db.GetActiveMixes(ctx, "", now.Add(-service.reportRetention()).UnixNano()) {
+		active[pubkey] = true
+	}
+
+	var reportsToPurge []string
+	for _, report := range allNodesReport.Report {
+		if !active[report.PubKey] {
+			reportsToPurge = append(reportsToPurge, report.PubKey)
 		}
-		service.db.RemoveMixReports(reportsToPurge)
+	}
+	service.db.RemoveMixReports(reportsToPurge)
+	service.reportCache.invalidate()
+
+	lastWeek := now.Add(-StatusRetention).UnixNano()
+	service.db.RemoveOldMixStatuses(lastWeek)
+	service.db.RemoveOldGatewayStatuses(lastWeek)
+	service.db.RemoveOldNetworkUptimeSamples(now.Add(-service.networkHistoryHorizon).UnixNano())
+	service.vacuumIfDue(ctx, now)
+	return nil
+}
 
-		lastWeek := now.Add(-(time.Hour * 24 * 7)).UnixNano()
-		service.db.RemoveOldMixStatuses(lastWeek)
-		service.db.RemoveOldGatewayStatuses(lastWeek)
-		<-ticker.C
+// reportRetention is how long the report of a node is kept after its most recent status. That's the status retention
+// period, past which there are no statuses left to back the report, unless the staleness window is longer, so that the
+// full report never loses a node it still serves.
+func (service *Service) reportRetention() time.Duration {
+	if service.staleAfter > StatusRetention {
+		return service.staleAfter
 	}
+	return StatusRetention
 }
 
-func (service *Service) updateLastDayMixReports() models.BatchMixStatusReport {
-	dayAgo := timemock.Now().Add(-time.Hour * 24).UnixNano()
-	allActive := service.db.GetActiveMixes(dayAgo)
+// vacuumIfDue vacuums the database if it's enabled and the last vacuum is at least vacuumInterval ago. Deleting the
+// old statuses doesn't shrink the sqlite file on its own. A failure is logged and retried after the next purge.
+func (service *Service) vacuumIfDue(ctx context.Context, now time.Time) {
+	if service.vacuumInterval <= 0 || now.Sub(service.lastVacuum) < service.vacuumInterval {
+		return
+	}
+	reclaimed, err := service.db.Vacuum(ctx)
+	if err != nil {
+		logrus.WithError(err).Error("failed to vacuum the database")
+		return
+	}
+	service.lastVacuum = now
+	logrus.WithField("reclaimedBytes", reclaimed).Info("vacuumed the database")
+}
 
-	batchReport := service.db.BatchLoadMixReports(allActive)
+// sampleNetworkUptime takes the mean last hour uptime of the mixnodes that reported any status during the last hour.
+// The uptime of each of them is the mean of its ip versions with data.
+func sampleNetworkUptime(reports models.BatchMixStatusReport, timestamp int64) models.NetworkUptimeSample {
+	sample := models.NetworkUptimeSample{Timestamp: timestamp}
+	total := 0.0
+	for _, report := range reports.Report {
+		var uptimes []int
+		for _, uptime := range []int{report.LastHourIPV4, report.LastHourIPV6} {
+			if uptime >= 0 {
+				uptimes = append(uptimes, uptime)
+			}
+		}
+		if len(uptimes) == 0 {
+			continue
+		}
+		sum := 0
+		for _, uptime := range uptimes {
+			sum += uptime
+		}
+		total += float64(sum) / float64(len(uptimes))
+		sample.ActiveCount++
+	}
+	if sample.ActiveCount > 0 {
+		sample.MeanUptime = total / float64(sample.ActiveCount)
+	}
+	return sample
+}
+
+// ListNetworkUptimeHistory lists the network uptime samples taken during the last `hours` hours, oldest first
+func (service *Service) ListNetworkUptimeHistory(ctx context.Context, hours int) []models.NetworkUptimeSample {
+	since := service.clock.Now().Add(-time.Duration(hours) * time.Hour).UnixNano()
+	return service.db.ListNetworkUptimeSamples(ctx, since)
+}
+
+func (service *Service) updateLastDayMixReports(ctx context.Context) models.BatchMixStatusReport {
+	dayAgo := service.clock.Now().Add(-time.Hour * 24).UnixNano()
+	// a node that only reports ipv4 statuses has nothing to calculate its ipv6 uptime from and vice versa
+	activeIn := map[string]map[string]bool{"4": {}, "6": {}}
+	allActive := []string{}
+	for _, ipVersion := range []string{"4", "6"} {
+		for _, pubkey := range service.db.GetActiveMixes(ctx, ipVersion, dayAgo) {
+			if !activeIn["4"][pubkey] && !activeIn["6"][pubkey] {
+				allActive = append(allActive, pubkey)
+			}
+			activeIn[ipVersion][pubkey] = true
+		}
+	}
+
+	update := func(report *models.MixStatusReport) {
+		for _, ipVersion := range []string{"4", "6"} {
+			if activeIn[ipVersion][report.PubKey] {
+				service.updateMixWindows(ctx, report, ipVersion, service.periodicWindows)
+			} else {
+				service.updateInactiveMixWindows(ctx, report, ipVersion, service.periodicWindows)
+			}
+		}
+	}
 
+	batchReport := service.db.BatchLoadMixReports(ctx, allActive)
 	for i := range batchReport.Report {
-		batchReport.Report[i].LastDayIPV4 = service.CalculateMixUptime(batchReport.Report[i].PubKey, "4", dayAgo)
-		batchReport.Report[i].LastDayIPV6 = service.CalculateMixUptime(batchReport.Report[i].PubKey, "6", dayAgo)
+		update(&batchReport.Report[i])
 	}
 
-	service.db.SaveBatchMixStatusReport(batchReport)
+	// the single status updates saved in the meantime are kept, the reports they saved get updated instead
+	batchReport = service.saveBatchMixReports(ctx, batchReport, update)
+	service.reportCache.invalidate()
 	return batchReport
 }
 
-// CreateMixStatus adds a new PersistedMixStatus in the orm.
-func (service *Service) CreateMixStatus(mixStatus models.MixStatus) models.PersistedMixStatus {
-	persistedMixStatus := models.PersistedMixStatus{
-		MixStatus: mixStatus,
-		Timestamp: timemock.Now().UnixNano(),
+// CreateMixStatus adds a new PersistedMixStatus in the orm. The status is only published to the subscribers
+// once it's been stored.
+func (service *Service) CreateMixStatus(mixStatus models.MixStatus) (models.PersistedMixStatus, error) {
+	persistedMixStatus := models.NewPersistedMixStatus(mixStatus, service.clock.Now().UnixNano())
+	if err := service.db.AddMixStatus(persistedMixStatus); err != nil {
+		return persistedMixStatus, err
 	}
-	service.db.AddMixStatus(persistedMixStatus)
+	service.broker.Publish(persistedMixStatus)
 
-	return persistedMixStatus
+	return persistedMixStatus, nil
 }
 
 // List lists the given number mix metrics
-func (service *Service) ListMixStatus(pubkey string) []models.PersistedMixStatus {
-	return service.db.ListMixStatus(pubkey, 1000)
+func (service *Service) ListMixStatus(ctx context.Context, pubkey string) []models.PersistedMixStatus {
+	return service.db.ListMixStatus(ctx, pubkey, MaxHistoryLimit)
+}
+
+// ListMixStatusByState lists the statuses of the node for the ip version during the last `hours` hours that were
+// either up or down, from the most recent one
+func (service *Service) ListMixStatusByState(ctx context.Context, pubkey string, ipVersion string, up bool, hours int) []models.PersistedMixStatus {
+	since := service.clock.Now().Add(-time.Duration(hours) * time.Hour).UnixNano()
+	return service.db.ListMixStatusByState(ctx, pubkey, ipVersion, up, since)
+}
+
+// ListMixStatusBulk lists the most recent statuses of each of the nodes, at most `limit` of them per node. Every one
+// of the nodes is in the result, even if it has no statuses.
+func (service *Service) ListMixStatusBulk(ctx context.Context, pubkeys []string, limit int) map[string][]models.PersistedMixStatus {
+	history := make(map[string][]models.PersistedMixStatus, len(pubkeys))
+	for _, pubkey := range pubkeys {
+		history[pubkey] = []models.PersistedMixStatus{}
+	}
+	for _, status := range service.db.ListMixStatusBulk(ctx, pubkeys, limit) {
+		history[status.PubKey] = append(history[status.PubKey], status)
+	}
+	return history
 }
 
-// GetStatusReport gets a single MixStatusReport by node public key
-func (service *Service) GetMixStatusReport(pubkey string) models.MixStatusReport {
-	return service.db.LoadMixReport(pubkey)
+// GetStatusReport gets a single MixStatusReport by node public key. The report is empty if the node never reported,
+// an error is only returned if it couldn't be loaded.
+func (service *Service) GetMixStatusReport(ctx context.Context, pubkey string) (models.MixStatusReport, error) {
+	return service.db.LoadMixReport(ctx, pubkey)
 }
 
 // BatchCreateMixStatus batch adds new multiple PersistedMixStatus in the orm.
-func (service *Service) BatchCreateMixStatus(batchMixStatus models.BatchMixStatus) []models.PersistedMixStatus {
-	statusList := make([]models.PersistedMixStatus, len(batchMixStatus.Status))
-	for i, mixStatus := range batchMixStatus.Status {
-		persistedMixStatus := models.PersistedMixStatus{
-			MixStatus: mixStatus,
-			Timestamp: timemock.Now().UnixNano(),
+// If the batch contains multiple statuses for the same node and ip version, only the last one is kept. Statuses
+// that don't tell their epoch get the one of the batch, if any.
+func (service *Service) BatchCreateMixStatus(batchMixStatus models.BatchMixStatus) ([]models.PersistedMixStatus, error) {
+	statuses := dedupeMixStatuses(batchMixStatus.Status)
+	if dropped := len(batchMixStatus.Status) - len(statuses); dropped > 0 {
+		logrus.WithField("dropped", dropped).Warn("dropped duplicate mix statuses from the batch")
+	}
+
+	statusList := make([]models.PersistedMixStatus, len(statuses))
+	for i, mixStatus := range statuses {
+		if mixStatus.Epoch == nil {
+			mixStatus.Epoch = batchMixStatus.Epoch
 		}
-		statusList[i] = persistedMixStatus
+		statusList[i] = models.NewPersistedMixStatus(mixStatus, service.clock.Now().UnixNano())
 	}
 
-	service.db.BatchAddMixStatus(statusList)
+	if err := service.db.BatchAddMixStatus(statusList); err != nil {
+		return statusList, err
+	}
+	for _, status := range statusList {
+		service.broker.Publish(status)
+	}
+
+	return statusList, nil
+}
+
+// GetMixEpochReport gathers the statuses of all nodes attributed to the epoch and the uptime of each node and ip version
+// over them, so that the incentives can be worked out from exactly what was measured during it.
+func (service *Service) GetMixEpochReport(ctx context.Context, epoch uint64) models.MixEpochReport {
+	statuses := service.db.ListMixStatusByEpoch(ctx, epoch)
 
-	return statusList
+	var keys []string
+	grouped := make(map[string][]models.PersistedMixStatus)
+	for _, status := range statuses {
+		key := status.PubKey + "/" + status.IPVersion
+		if _, ok := grouped[key]; !ok {
+			keys = append(keys, key)
+		}
+		grouped[key] = append(grouped[key], status)
+	}
+	sort.Strings(keys)
+
+	uptimes := make([]models.MixEpochUptime, len(keys))
+	for i, key := range keys {
+		group := grouped[key]
+		uptimes[i] = models.MixEpochUptime{
+			PubKey:       group[0].PubKey,
+			Owner:        group[len(group)-1].Owner,
+			IPVersion:    group[0].IPVersion,
+			Uptime:       service.mixUptime(group),
+			Measurements: len(group),
+		}
+	}
+	return models.MixEpochReport{Epoch: epoch, Uptimes: uptimes, Statuses: statuses}
+}
+
+// SubscribeMixStatuses subscribes to newly created mix statuses. The returned function must be called
+// once the subscriber is no longer interested in them.
+func (service *Service) SubscribeMixStatuses() (<-chan models.PersistedMixStatus, func()) {
+	return service.broker.Subscribe()
 }
 
 // BatchGetMixStatusReport gets BatchMixStatusReport which contain multiple MixStatusReport.
-func (service *Service) BatchGetMixStatusReport() models.BatchMixStatusReport {
-	return service.db.LoadNonStaleMixReports()
+// Only non-stale mixnodes are included, that is the ones that reported any status recently, regardless of their uptime.
+// It's served from the report cache while that's fresh, and reports cut short by the context aren't cached.
+func (service *Service) BatchGetMixStatusReport(ctx context.Context) models.BatchMixStatusReport {
+	return service.reportCache.get(service.clock.Now(), func() (models.BatchMixStatusReport, bool) {
+		since := service.clock.Now().Add(-service.staleAfter).UnixNano()
+		report := service.db.BatchLoadMixReports(ctx, service.db.GetActiveMixes(ctx, "", since))
+		return report, ctx.Err() == nil
+	})
+}
+
+// AggregateMixUptime calculates uptime of every non-stale mixnode over the last `hours` hours and summarises
+// the results, so that clients don't need to download every single report to get an overview of the network.
+func (service *Service) AggregateMixUptime(ctx context.Context, hours int) models.MixUptimeAggregate {
+	since := service.clock.Now().Add(-time.Duration(hours) * time.Hour).UnixNano()
+	reports := service.BatchGetMixStatusReport(ctx)
+
+	var v4Uptimes, v6Uptimes []int
+	for _, report := range reports.Report {
+		if uptime := service.CalculateMixUptime(ctx, report.PubKey, "4", since); uptime >= 0 {
+			v4Uptimes = append(v4Uptimes, uptime)
+		}
+		if uptime := service.CalculateMixUptime(ctx, report.PubKey, "6", since); uptime >= 0 {
+			v6Uptimes = append(v6Uptimes, uptime)
+		}
+	}
+
+	return models.MixUptimeAggregate{
+		Hours: hours,
+		Nodes: len(reports.Report),
+		IPV4:  summariseUptimes(v4Uptimes),
+		IPV6:  summariseUptimes(v6Uptimes),
+	}
+}
+
+// DetectMixUptimeDrops finds the non-stale mixnodes whose uptime during the last 5 minutes is more than `threshold`
+// percentage points below their uptime during the last hour, for either ip version. It only looks at the stored
+// reports, so it's cheap enough to be polled by alerting. Windows without enough data are never flagged. The biggest
+// drops come first.
+func (service *Service) DetectMixUptimeDrops(ctx context.Context, threshold int) []models.MixUptimeDrop {
+	drops := []models.MixUptimeDrop{}
+	for _, report := range service.BatchGetMixStatusReport(ctx).Report {
+		if drop, ok := uptimeDrop(report, "4", report.LastHourIPV4, report.Last5MinutesIPV4, threshold); ok {
+			drops = append(drops, drop)
+		}
+		if drop, ok := uptimeDrop(report, "6", report.LastHourIPV6, report.Last5MinutesIPV6, threshold); ok {
+			drops = append(drops, drop)
+		}
+	}
+
+	sort.SliceStable(drops, func(i, j int) bool {
+		if drops[i].Drop != drops[j].Drop {
+			return drops[i].Drop > drops[j].Drop
+		}
+		return drops[i].PubKey < drops[j].PubKey
+	})
+	return drops
+}
+
+// uptimeDrop tells whether the uptime fell by more than the threshold between the last hour and the last 5 minutes
+func uptimeDrop(report models.MixStatusReport, ipVersion string, lastHour int, last5Minutes int, threshold int) (models.MixUptimeDrop, bool) {
+	if lastHour < 0 || last5Minutes < 0 || lastHour-last5Minutes <= threshold {
+		return models.MixUptimeDrop{}, false
+	}
+	return models.MixUptimeDrop{
+		PubKey:       report.PubKey,
+		Owner:        report.Owner,
+		IPVersion:    ipVersion,
+		LastHour:     lastHour,
+		Last5Minutes: last5Minutes,
+		Drop:         lastHour - last5Minutes,
+	}, true
+}
+
+// GetMixNodeSummary combines the status report of a mixnode with its uptime trends and the time of its
+// most recent status. An empty summary is returned if the node is unknown, or if its report couldn't be loaded.
+func (service *Service) GetMixNodeSummary(ctx context.Context, pubkey string) models.MixNodeSummary {
+	report, err := service.db.LoadMixReport(ctx, pubkey)
+	if err != nil || report.PubKey == "" {
+		return models.MixNodeSummary{}
+	}
+
+	summary := models.MixNodeSummary{
+		Report:    report,
+		TrendIPV4: uptimeTrend(report.LastHourIPV4, report.LastDayIPV4),
+		TrendIPV6: uptimeTrend(report.LastHourIPV6, report.LastDayIPV6),
+	}
+	if latest := service.db.ListMixStatus(ctx, pubkey, 1); len(latest) > 0 {
+		summary.MostRecentStatusTime = latest[0].Timestamp
+	}
+	return summary
 }
 
 // SaveBatchStatusReport builds and saves a status report for multiple mixnodes simultaneously.
 // Those reports can be updated once whenever we receive a new status,
 // and the saved results can then be queried. This keeps us from having to build the report dynamically
 // on every request at runtime.
-func (service *Service) SaveBatchMixStatusReport(status []models.PersistedMixStatus) models.BatchMixStatusReport {
+func (service *Service) SaveBatchMixStatusReport(ctx context.Context, status []models.PersistedMixStatus) models.BatchMixStatusReport {
 	pubkeys := make([]string, len(status))
 	for i := range status {
 		pubkeys[i] = status[i].PubKey
 	}
-	batchReport := service.db.BatchLoadMixReports(pubkeys)
+	batchReport := service.db.BatchLoadMixReports(ctx, pubkeys)
 
 	// that's super crude but I don't think db results are guaranteed to come in order, plus some entries might
 	// not exist
@@ -181,75 +599,415 @@ func (service *Service) SaveBatchMixStatusReport(status []models.PersistedMixSta
 		reportMap[report.PubKey] = i
 	}
 
+	// the statuses of each node are applied in order, to the report as it was loaded and again to a fresh copy of it
+	// if it got saved by someone else in the meantime, so the ownership changes are only known once it's saved
+	statusesOf := make(map[string][]models.PersistedMixStatus)
 	for _, mixStatus := range status {
-		if reportIdx, ok := reportMap[mixStatus.PubKey]; ok {
-			service.updateMixReportUpToLastHour(&batchReport.Report[reportIdx], &mixStatus)
-		} else {
-			var freshReport models.MixStatusReport
-			service.updateMixReportUpToLastHour(&freshReport, &mixStatus)
-			batchReport.Report = append(batchReport.Report, freshReport)
-			reportMap[freshReport.PubKey] = len(batchReport.Report) - 1
+		if _, ok := statusesOf[mixStatus.PubKey]; !ok {
+			if _, ok := reportMap[mixStatus.PubKey]; !ok {
+				batchReport.Report = append(batchReport.Report, models.MixStatusReport{})
+				reportMap[mixStatus.PubKey] = len(batchReport.Report) - 1
+			}
 		}
+		statusesOf[mixStatus.PubKey] = append(statusesOf[mixStatus.PubKey], mixStatus)
+	}
+	changes := make(map[string][]models.OwnershipChange)
+	update := func(report *models.MixStatusReport) {
+		changes[report.PubKey] = nil
+		for _, mixStatus := range statusesOf[report.PubKey] {
+			if change, changed := ownershipChange(*report, mixStatus); changed {
+				changes[report.PubKey] = append(changes[report.PubKey], change)
+			}
+			service.updateMixReportUpToLastHour(ctx, report, &mixStatus)
+		}
+	}
+	for pubkey, i := range reportMap {
+		// fresh reports don't have their pubkey until the first status is applied
+		batchReport.Report[i].PubKey = pubkey
+		update(&batchReport.Report[i])
 	}
 
-	service.db.SaveBatchMixStatusReport(batchReport)
+	batchReport = service.saveBatchMixReports(ctx, batchReport, update)
+	service.reportCache.invalidate()
+	var recorded []models.OwnershipChange
+	for _, report := range batchReport.Report {
+		recorded = append(recorded, changes[report.PubKey]...)
+	}
+	service.recordOwnershipChanges(recorded)
 
 	return batchReport
 }
 
+// saveBatchMixReports saves the reports unless someone else saved them since they were loaded. Those get reloaded
+// and updated again, up to maxReportSaveAttempts times, the same as the single status updates. It returns the
+// reports as they got saved.
+func (service *Service) saveBatchMixReports(ctx context.Context, batchReport models.BatchMixStatusReport, update func(report *models.MixStatusReport)) models.BatchMixStatusReport {
+	reportMap := make(map[string]int, len(batchReport.Report))
+	for i, report := range batchReport.Report {
+		reportMap[report.PubKey] = i
+	}
+
+	pending := batchReport
+	for attempt := 1; ; attempt++ {
+		changed := service.db.SaveBatchMixStatusReportIfUnchanged(pending)
+		for _, report := range pending.Report {
+			batchReport.Report[reportMap[report.PubKey]] = report
+		}
+		if len(changed) == 0 {
+			return batchReport
+		}
+		if attempt == maxReportSaveAttempts {
+			logrus.WithField("pubKeys", changed).Warn("gave up saving the mix status reports as they kept getting saved concurrently")
+			return batchReport
+		}
+		// reports removed in the meantime aren't reloaded, and stay as they were in the batch
+		pending = service.db.BatchLoadMixReports(ctx, changed)
+		for i := range pending.Report {
+			update(&pending.Report[i])
+		}
+	}
+}
+
+// maxReportSaveAttempts is how many times a report gets rebuilt when other writers keep saving it first
+const maxReportSaveAttempts = 5
+
 // SaveStatusReport builds and saves a status report for a mixnode. The report can be updated once
 // whenever we receive a new status, and the saved result can then be queried. This keeps us from
-// having to build the report dynamically on every request at runtime.
-func (service *Service) SaveMixStatusReport(status models.PersistedMixStatus) models.MixStatusReport {
-	report := service.db.LoadMixReport(status.PubKey)
+// having to build the report dynamically on every request at runtime. The ipv4 and ipv6 statuses of a node
+// can arrive at the same time, so if the report got saved in between loading and saving it, it's rebuilt
+// from the fresh copy rather than overwriting the other update. If the report can't be loaded it's left alone, as
+// rebuilding it from scratch would wipe out its last day uptimes until the next periodic update.
+func (service *Service) SaveMixStatusReport(ctx context.Context, status models.PersistedMixStatus) models.MixStatusReport {
+	var report models.MixStatusReport
+	for attempt := 0; attempt < maxReportSaveAttempts; attempt++ {
+		var err error
+		if report, err = service.db.LoadMixReport(ctx, status.PubKey); err != nil {
+			return models.MixStatusReport{}
+		}
 
-	service.updateMixReportUpToLastHour(&report, &status)
-	service.db.SaveMixStatusReport(report)
+		change, changed := ownershipChange(report, status)
+		service.updateMixReportUpToLastHour(ctx, &report, &status)
+		if service.db.SaveMixStatusReportIfUnchanged(report) {
+			service.reportCache.invalidate()
+			if changed {
+				service.recordOwnershipChanges([]models.OwnershipChange{change})
+			}
+			return report
+		}
+	}
 
+	logrus.WithField("pubKey", status.PubKey).Warn("gave up saving the mix status report as it kept getting saved concurrently")
 	return report
 }
 
-func (service *Service) updateMixReportUpToLastHour(report *models.MixStatusReport, status *models.PersistedMixStatus) {
+// ownershipChange tells whether the status changes the owner of the mixnode, which is the owner of its report. Fresh
+// reports have no owner yet, so there's nothing to change.
+func ownershipChange(report models.MixStatusReport, status models.PersistedMixStatus) (models.OwnershipChange, bool) {
+	if report.PubKey == "" || report.Owner == "" || report.Owner == status.Owner {
+		return models.OwnershipChange{}, false
+	}
+	return models.OwnershipChange{PubKey: status.PubKey, PreviousOwner: report.Owner, Owner: status.Owner, Timestamp: status.Timestamp}, true
+}
+
+// recordOwnershipChanges logs and saves the ownership changes of mixnodes whose reports got saved with the new owner
+func (service *Service) recordOwnershipChanges(changes []models.OwnershipChange) {
+	if len(changes) == 0 {
+		return
+	}
+	for _, change := range changes {
+		logrus.WithFields(logrus.Fields{
+			"pubKey":        change.PubKey,
+			"previousOwner": change.PreviousOwner,
+			"owner":         change.Owner,
+		}).Info("mixnode changed owner")
+	}
+	service.db.AddOwnershipChanges(changes)
+}
+
+// ListOwnershipChanges lists the recorded ownership changes of the mixnode, oldest first
+func (service *Service) ListOwnershipChanges(ctx context.Context, pubkey string) []models.OwnershipChange {
+	return service.db.ListOwnershipChanges(ctx, pubkey)
+}
+
+// ChangedMixOwners returns the indices of the statuses that would change the owner of their mixnode, that is the ones
+// under another owner than the one of the report of the node. Nodes without a report have no owner to change.
+func (service *Service) ChangedMixOwners(ctx context.Context, statuses []models.MixStatus) []int {
+	pubkeys := make([]string, len(statuses))
+	for i := range statuses {
+		pubkeys[i] = statuses[i].PubKey
+	}
+	owners := service.db.MixReportOwners(ctx, pubkeys)
+
+	changed := []int{}
+	for i, status := range statuses {
+		if owner, ok := owners[status.PubKey]; ok && owner != "" && owner != status.Owner {
+			changed = append(changed, i)
+		}
+	}
+	return changed
+}
+
+func (service *Service) updateMixReportUpToLastHour(ctx context.Context, report *models.MixStatusReport, status *models.PersistedMixStatus) {
 	report.PubKey = status.PubKey // crude, we do this in case it's a fresh struct returned from the db
 	report.Owner = status.Owner
 
 	if status.IPVersion == "4" {
-		report.MostRecentIPV4 = *status.Up
-		report.Last5MinutesIPV4 = service.CalculateMixUptime(status.PubKey, "4", minutesAgo(5))
-		report.LastHourIPV4 = service.CalculateMixUptime(status.PubKey, "4", minutesAgo(60))
+		report.MostRecentIPV4 = status.Up
+		report.MostRecentIPV4Timestamp = status.Timestamp
 	} else if status.IPVersion == "6" {
-		report.MostRecentIPV6 = *status.Up
-		report.Last5MinutesIPV6 = service.CalculateMixUptime(status.PubKey, "6", minutesAgo(5))
-		report.LastHourIPV6 = service.CalculateMixUptime(status.PubKey, "6", minutesAgo(60))
+		report.MostRecentIPV6 = status.Up
+		report.MostRecentIPV6Timestamp = status.Timestamp
+		report.HasIPV6Data = true
+	}
+	service.updateMixWindows(ctx, report, status.IPVersion, service.perStatusWindows)
+}
+
+// updateMixWindows recalculates the uptime of the node during each of the windows
+func (service *Service) updateMixWindows(ctx context.Context, report *models.MixStatusReport, ipVersion string, windows []UptimeWindow) {
+	for _, window := range windows {
+		uptime, rtt, count := service.calculateMixUptimeAndRTT(ctx, report.PubKey, ipVersion, window.since(service.clock.Now()))
+		setMixUptime(report, ipVersion, window.Name, uptime, rtt, count)
+	}
+}
+
+// updateInactiveMixWindows recalculates the uptime during each of the windows of a node that didn't report any status
+// for the ip version during the last day. Windows no longer than that can't hold any, so they're set to
+// InsufficientData right away, only the longer ones are calculated.
+func (service *Service) updateInactiveMixWindows(ctx context.Context, report *models.MixStatusReport, ipVersion string, windows []UptimeWindow) {
+	var longer []UptimeWindow
+	for _, window := range windows {
+		if window.Duration > time.Hour*24 {
+			longer = append(longer, window)
+			continue
+		}
+		setMixUptime(report, ipVersion, window.Name, InsufficientData, nil, 0)
+	}
+	service.updateMixWindows(ctx, report, ipVersion, longer)
+}
+
+func (service *Service) CalculateMixUptime(ctx context.Context, pubkey string, ipVersion string, since int64) int {
+	uptime, _, _ := service.calculateMixUptimeAndRTT(ctx, pubkey, ipVersion, since)
+	return uptime
+}
+
+// CalculateMixUptimeCounts counts the statuses of the node since the given timestamp and how many of them were up,
+// which CalculateMixUptime rounds to a percentage
+func (service *Service) CalculateMixUptimeCounts(ctx context.Context, pubkey string, ipVersion string, since int64) models.UptimeCount {
+	statuses := service.db.ListMixStatusSince(ctx, pubkey, ipVersion, since)
+	return models.UptimeCount{Up: countMixUp(statuses), Total: len(statuses)}
+}
+
+// GetMixRawUptime counts the statuses of the node during each of the windows and how many of them were up
+func (service *Service) GetMixRawUptime(ctx context.Context, pubkey string) models.MixRawUptime {
+	counts := func(ipVersion string) map[string]models.UptimeCount {
+		windows := make(map[string]models.UptimeCount, len(service.windows))
+		for _, window := range service.windows {
+			windows[window.Name] = service.CalculateMixUptimeCounts(ctx, pubkey, ipVersion, window.since(service.clock.Now()))
+		}
+		return windows
 	}
+	return models.MixRawUptime{PubKey: pubkey, IPV4: counts("4"), IPV6: counts("6")}
 }
 
-func (service *Service) CalculateMixUptime(pubkey string, ipVersion string, since int64) int {
-	statuses := service.db.ListMixStatusSince(pubkey, ipVersion, since)
+// CalculateMixUptimeAt calculates the uptime of the node over the window ending at the given timestamp, which lets
+// us tell what the node's uptime was back then rather than now.
+func (service *Service) CalculateMixUptimeAt(ctx context.Context, pubkey string, ipVersion string, at int64, window time.Duration) int {
+	statuses := service.db.ListMixStatusDateRange(ctx, pubkey, ipVersion, at-window.Nanoseconds(), at)
+	return service.mixUptime(statuses)
+}
+
+// calculateMixUptimeAndRTT calculates the uptime and the average round-trip time out of a single query, along with
+// the number of statuses they were calculated from.
+func (service *Service) calculateMixUptimeAndRTT(ctx context.Context, pubkey string, ipVersion string, since int64) (int, *int, int) {
+	statuses := service.db.ListMixStatusSince(ctx, pubkey, ipVersion, since)
+	return service.mixUptime(statuses), averageMixRTT(statuses), len(statuses)
+}
+
+func (service *Service) mixUptime(statuses []models.PersistedMixStatus) int {
 	numStatuses := len(statuses)
-	if numStatuses == 0 {
-		return 0
+	if numStatuses == 0 || numStatuses < service.minMeasurements {
+		return InsufficientData
 	}
+
+	return service.calculatePercent(countMixUp(statuses), numStatuses)
+}
+
+// countMixUp counts the statuses the node was up in
+func countMixUp(statuses []models.PersistedMixStatus) int {
 	up := 0
 	for _, status := range statuses {
-		if *status.Up {
+		if status.Up {
 			up = up + 1
 		}
 	}
+	return up
+}
 
-	return service.calculatePercent(up, numStatuses)
+// averageMixRTT averages the round-trip times of the statuses that carry one. It returns nil if none of them does.
+func averageMixRTT(statuses []models.PersistedMixStatus) *int {
+	total, measured := 0, 0
+	for _, status := range statuses {
+		if status.RTTMillis != nil {
+			total += *status.RTTMillis
+			measured++
+		}
+	}
+	if measured == 0 {
+		return nil
+	}
+	average := total / measured
+	return &average
+}
+
+// mixUptimeAndRTTSince calculates the uptime and the average round-trip time out of the statuses not older than since,
+// along with their number
+func (service *Service) mixUptimeAndRTTSince(statuses []models.PersistedMixStatus, since int64) (int, *int, int) {
+	var recent []models.PersistedMixStatus
+	for _, status := range statuses {
+		if status.Timestamp >= since {
+			recent = append(recent, status)
+		}
+	}
+	return service.mixUptime(recent), averageMixRTT(recent), len(recent)
+}
+
+// RecomputeMixReport rebuilds the report of the node from all of its retained statuses and saves it, replacing
+// whatever was stored before. If the node has no retained statuses, nothing is saved and an empty report is returned.
+// It returns an error if the report couldn't be saved.
+func (service *Service) RecomputeMixReport(ctx context.Context, pubkey string) (models.MixStatusReport, error) {
+	retained := service.clock.Now().Add(-StatusRetention).UnixNano()
+	v4Statuses := service.db.ListMixStatusSince(ctx, pubkey, "4", retained)
+	v6Statuses := service.db.ListMixStatusSince(ctx, pubkey, "6", retained)
+	if len(v4Statuses) == 0 && len(v6Statuses) == 0 {
+		return models.MixStatusReport{}, nil
+	}
+
+	report := models.MixStatusReport{PubKey: pubkey}
+	// the statuses are ordered from the most recent one, so the owner is taken from the newest of them
+	if len(v4Statuses) > 0 {
+		report.Owner = v4Statuses[0].Owner
+		report.MostRecentIPV4 = v4Statuses[0].Up
+		report.MostRecentIPV4Timestamp = v4Statuses[0].Timestamp
+	}
+	if len(v6Statuses) > 0 {
+		if len(v4Statuses) == 0 || v6Statuses[0].Timestamp > v4Statuses[0].Timestamp {
+			report.Owner = v6Statuses[0].Owner
+		}
+		report.MostRecentIPV6 = v6Statuses[0].Up
+		report.MostRecentIPV6Timestamp = v6Statuses[0].Timestamp
+		report.HasIPV6Data = true
+	}
+
+	for _, window := range service.windows {
+		uptime, rtt, count := service.mixUptimeAndRTTSince(v4Statuses, window.since(service.clock.Now()))
+		setMixUptime(&report, "4", window.Name, uptime, rtt, count)
+		uptime, rtt, count = service.mixUptimeAndRTTSince(v6Statuses, window.since(service.clock.Now()))
+		setMixUptime(&report, "6", window.Name, uptime, rtt, count)
+	}
+
+	if err := service.db.SaveMixStatusReport(report); err != nil {
+		return models.MixStatusReport{}, err
+	}
+	service.reportCache.invalidate()
+	return report, nil
+}
+
+// RetractMixStatus retracts a status the node reported and recomputes its report without it. It returns false if
+// there's no such status.
+func (service *Service) RetractMixStatus(ctx context.Context, pubkey string, ipVersion string, timestamp int64) bool {
+	if !service.db.RetractMixStatus(ctx, pubkey, ipVersion, timestamp) {
+		return false
+	}
+	if _, err := service.RecomputeMixReport(ctx, pubkey); err != nil {
+		logrus.WithError(err).WithField("pubkey", pubkey).Error("failed to recompute the report after a retraction")
+	}
+	return true
 }
 
+// TopMixReports returns the reports of the n mixnodes with the highest value of the given uptime field.
+func (service *Service) TopMixReports(ctx context.Context, field string, n int) models.BatchMixStatusReport {
+	return service.db.TopMixReports(ctx, field, n)
+}
+
+// BackfillMixReports recomputes the reports of all mixnodes that have retained statuses but no report, so that they
+// don't go without one until their next status comes in.
+func (service *Service) BackfillMixReports(ctx context.Context) models.Backfill {
+	retained := service.clock.Now().Add(-StatusRetention).UnixNano()
+	missing := service.db.GetMixesWithoutReport(ctx, retained)
+	for _, pubkey := range missing {
+		if _, err := service.RecomputeMixReport(ctx, pubkey); err != nil {
+			logrus.WithError(err).WithField("pubkey", pubkey).Error("failed to backfill the report")
+		}
+	}
+	if len(missing) > 0 {
+		logrus.WithField("reports", len(missing)).Info("backfilled missing mixnode reports")
+	}
+	return models.Backfill{PubKeys: missing}
+}
+
+// recomputeWorkers is the number of reports RecomputeAllReports rebuilds at the same time. Sqlite serializes the
+// writes anyway, so more of them would only pile up on the lock.
+const recomputeWorkers = 4
+
+// RecomputeAllReports rebuilds the reports of all mixnodes and gateways that have retained statuses, e.g. after
+// the way uptime gets calculated changed, so that none of them keeps showing the old numbers.
+func (service *Service) RecomputeAllReports(ctx context.Context) models.Recomputation {
+	retained := service.clock.Now().Add(-StatusRetention).UnixNano()
+	mixes := service.db.GetActiveMixes(ctx, "", retained)
+	gateways := service.db.GetActiveGateways(ctx, retained)
+
+	failed := recomputeConcurrently(mixes, func(pubkey string) bool {
+		report, err := service.RecomputeMixReport(ctx, pubkey)
+		return err == nil && report.PubKey != ""
+	})
+	failed = append(failed, recomputeConcurrently(gateways, func(pubkey string) bool {
+		report, err := service.recomputeGatewayReport(ctx, pubkey)
+		return err == nil && report.PubKey != ""
+	})...)
+
+	logrus.WithFields(logrus.Fields{"mixnodes": len(mixes), "gateways": len(gateways), "failed": len(failed)}).Info("recomputed all reports")
+	return models.Recomputation{Mixnodes: len(mixes), Gateways: len(gateways), Failed: failed}
+}
+
+// recomputeConcurrently calls recompute for each of the pubkeys, at most recomputeWorkers of them at a time. It returns
+// the sorted pubkeys recompute failed for.
+func recomputeConcurrently(pubkeys []string, recompute func(pubkey string) bool) []string {
+	jobs := make(chan string)
+	failed := []string{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < recomputeWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pubkey := range jobs {
+				if !recompute(pubkey) {
+					mu.Lock()
+					failed = append(failed, pubkey)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, pubkey := range pubkeys {
+		jobs <- pubkey
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.Strings(failed)
+	return failed
+}
 
-func (service *Service) updateLastDayGatewayReports() models.BatchGatewayStatusReport {
-	dayAgo := timemock.Now().Add(-time.Hour * 24).UnixNano()
-	allActive := service.db.GetActiveGateways(dayAgo)
+func (service *Service) updateLastDayGatewayReports(ctx context.Context) models.BatchGatewayStatusReport {
+	dayAgo := service.clock.Now().Add(-time.Hour * 24).UnixNano()
+	allActive := service.db.GetActiveGateways(ctx, dayAgo)
 
-	batchReport := service.db.BatchLoadGatewayReports(allActive)
+	batchReport := service.db.BatchLoadGatewayReports(ctx, allActive)
 
 	for i := range batchReport.Report {
-		batchReport.Report[i].LastDayIPV4 = service.CalculateGatewayUptime(batchReport.Report[i].PubKey, "4", dayAgo)
-		batchReport.Report[i].LastDayIPV6 = service.CalculateGatewayUptime(batchReport.Report[i].PubKey, "6", dayAgo)
+		service.updateGatewayWindows(ctx, &batchReport.Report[i], "4", service.periodicWindows)
+		service.updateGatewayWindows(ctx, &batchReport.Report[i], "6", service.periodicWindows)
 	}
 
 	service.db.SaveBatchGatewayStatusReport(batchReport)
@@ -257,57 +1015,58 @@ func (service *Service) updateLastDayGatewayReports() models.BatchGatewayStatusR
 }
 
 // CreateGatewayStatus adds a new PersistedGatewayStatus in the orm.
-func (service *Service) CreateGatewayStatus(gatewayStatus models.GatewayStatus) models.PersistedGatewayStatus {
-	persistedGatewayStatus := models.PersistedGatewayStatus{
-		GatewayStatus: gatewayStatus,
-		Timestamp: timemock.Now().UnixNano(),
-	}
-	service.db.AddGatewayStatus(persistedGatewayStatus)
+func (service *Service) CreateGatewayStatus(gatewayStatus models.GatewayStatus) (models.PersistedGatewayStatus, error) {
+	persistedGatewayStatus := models.NewPersistedGatewayStatus(gatewayStatus, service.clock.Now().UnixNano())
+	err := service.db.AddGatewayStatus(persistedGatewayStatus)
 
-	return persistedGatewayStatus
+	return persistedGatewayStatus, err
 }
 
 // List lists the given number gateway metrics
-func (service *Service) ListGatewayStatus(pubkey string) []models.PersistedGatewayStatus {
-	return service.db.ListGatewayStatus(pubkey, 1000)
+func (service *Service) ListGatewayStatus(ctx context.Context, pubkey string) []models.PersistedGatewayStatus {
+	return service.db.ListGatewayStatus(ctx, pubkey, MaxHistoryLimit)
 }
 
 // GetStatusReport gets a single GatewayStatusReport by node public key
-func (service *Service) GetGatewayStatusReport(pubkey string) models.GatewayStatusReport {
-	return service.db.LoadGatewayReport(pubkey)
+func (service *Service) GetGatewayStatusReport(ctx context.Context, pubkey string) models.GatewayStatusReport {
+	return service.db.LoadGatewayReport(ctx, pubkey)
 }
 
 // BatchCreateGatewayStatus batch adds new multiple PersistedGatewayStatus in the orm.
-func (service *Service) BatchCreateGatewayStatus(batchGatewayStatus models.BatchGatewayStatus) []models.PersistedGatewayStatus {
-	statusList := make([]models.PersistedGatewayStatus, len(batchGatewayStatus.Status))
-	for i, gatewayStatus := range batchGatewayStatus.Status {
-		persistedGatewayStatus := models.PersistedGatewayStatus{
-			GatewayStatus: gatewayStatus,
-			Timestamp: timemock.Now().UnixNano(),
-		}
-		statusList[i] = persistedGatewayStatus
+// If the batch contains multiple statuses for the same node and ip version, only the last one is kept.
+func (service *Service) BatchCreateGatewayStatus(batchGatewayStatus models.BatchGatewayStatus) ([]models.PersistedGatewayStatus, error) {
+	statuses := dedupeGatewayStatuses(batchGatewayStatus.Status)
+	if dropped := len(batchGatewayStatus.Status) - len(statuses); dropped > 0 {
+		logrus.WithField("dropped", dropped).Warn("dropped duplicate gateway statuses from the batch")
+	}
+
+	statusList := make([]models.PersistedGatewayStatus, len(statuses))
+	for i, gatewayStatus := range statuses {
+		statusList[i] = models.NewPersistedGatewayStatus(gatewayStatus, service.clock.Now().UnixNano())
 	}
 
-	service.db.BatchAddGatewayStatus(statusList)
+	err := service.db.BatchAddGatewayStatus(statusList)
 
-	return statusList
+	return statusList, err
 }
 
 // BatchGetGatewayStatusReport gets BatchGatewayStatusReport which contain multiple GatewayStatusReport.
-func (service *Service) BatchGetGatewayStatusReport() models.BatchGatewayStatusReport {
-	return service.db.LoadNonStaleGatewayReports()
+// Only non-stale gateways are included, that is the ones that reported any status recently, regardless of their uptime.
+func (service *Service) BatchGetGatewayStatusReport(ctx context.Context) models.BatchGatewayStatusReport {
+	since := service.clock.Now().Add(-service.staleAfter).UnixNano()
+	return service.db.BatchLoadGatewayReports(ctx, service.db.GetActiveGateways(ctx, since))
 }
 
 // SaveBatchStatusReport builds and saves a status report for multiple gateways simultaneously.
 // Those reports can be updated once whenever we receive a new status,
 // and the saved results can then be queried. This keeps us from having to build the report dynamically
 // on every request at runtime.
-func (service *Service) SaveBatchGatewayStatusReport(status []models.PersistedGatewayStatus) models.BatchGatewayStatusReport {
+func (service *Service) SaveBatchGatewayStatusReport(ctx context.Context, status []models.PersistedGatewayStatus) models.BatchGatewayStatusReport {
 	pubkeys := make([]string, len(status))
 	for i := range status {
 		pubkeys[i] = status[i].PubKey
 	}
-	batchReport := service.db.BatchLoadGatewayReports(pubkeys)
+	batchReport := service.db.BatchLoadGatewayReports(ctx, pubkeys)
 
 	// that's super crude but I don't think db results are guaranteed to come in order, plus some entries might
 	// not exist
@@ -318,10 +1077,10 @@ func (service *Service) SaveBatchGatewayStatusReport(status []models.PersistedGa
 
 	for _, gatewayStatus := range status {
 		if reportIdx, ok := reportMap[gatewayStatus.PubKey]; ok {
-			service.updateGatewayReportUpToLastHour(&batchReport.Report[reportIdx], &gatewayStatus)
+			service.updateGatewayReportUpToLastHour(ctx, &batchReport.Report[reportIdx], &gatewayStatus)
 		} else {
 			var freshReport models.GatewayStatusReport
-			service.updateGatewayReportUpToLastHour(&freshReport, &gatewayStatus)
+			service.updateGatewayReportUpToLastHour(ctx, &freshReport, &gatewayStatus)
 			batchReport.Report = append(batchReport.Report, freshReport)
 			reportMap[freshReport.PubKey] = len(batchReport.Report) - 1
 		}
@@ -335,39 +1094,121 @@ func (service *Service) SaveBatchGatewayStatusReport(status []models.PersistedGa
 // SaveStatusReport builds and saves a status report for a gatewa. The report can be updated once
 // whenever we receive a new status, and the saved result can then be queried. This keeps us from
 // having to build the report dynamically on every request at runtime.
-func (service *Service) SaveGatewayStatusReport(status models.PersistedGatewayStatus) models.GatewayStatusReport {
-	report := service.db.LoadGatewayReport(status.PubKey)
+func (service *Service) SaveGatewayStatusReport(ctx context.Context, status models.PersistedGatewayStatus) models.GatewayStatusReport {
+	report := service.db.LoadGatewayReport(ctx, status.PubKey)
 
-	service.updateGatewayReportUpToLastHour(&report, &status)
+	service.updateGatewayReportUpToLastHour(ctx, &report, &status)
 	service.db.SaveGatewayStatusReport(report)
 
 	return report
 }
 
-func (service *Service) updateGatewayReportUpToLastHour(report *models.GatewayStatusReport, status *models.PersistedGatewayStatus) {
+func (service *Service) updateGatewayReportUpToLastHour(ctx context.Context, report *models.GatewayStatusReport, status *models.PersistedGatewayStatus) {
 	report.PubKey = status.PubKey // crude, we do this in case it's a fresh struct returned from the db
 	report.Owner = status.Owner
 
 	if status.IPVersion == "4" {
-		report.MostRecentIPV4 = *status.Up
-		report.Last5MinutesIPV4 = service.CalculateGatewayUptime(status.PubKey, "4", minutesAgo(5))
-		report.LastHourIPV4 = service.CalculateGatewayUptime(status.PubKey, "4", minutesAgo(60))
+		report.MostRecentIPV4 = status.Up
 	} else if status.IPVersion == "6" {
-		report.MostRecentIPV6 = *status.Up
-		report.Last5MinutesIPV6 = service.CalculateGatewayUptime(status.PubKey, "6", minutesAgo(5))
-		report.LastHourIPV6 = service.CalculateGatewayUptime(status.PubKey, "6", minutesAgo(60))
+		report.MostRecentIPV6 = status.Up
+	}
+	service.updateGatewayWindows(ctx, report, status.IPVersion, service.perStatusWindows)
+}
+
+// updateGatewayWindows recalculates the uptime of the gateway, and of its clients host, during each of the windows
+func (service *Service) updateGatewayWindows(ctx context.Context, report *models.GatewayStatusReport, ipVersion string, windows []UptimeWindow) {
+	for _, window := range windows {
+		statuses := service.db.ListGatewayStatusSince(ctx, report.PubKey, ipVersion, window.since(service.clock.Now()))
+		setGatewayUptime(report, ipVersion, window.Name, service.gatewayUptime(statuses), averageGatewayRTT(statuses))
+		setGatewayClientsUptime(report, ipVersion, window.Name, service.gatewayClientsUptime(statuses))
+	}
+}
+
+// RetractGatewayStatus retracts a status the gateway reported and recomputes its report without it. It returns false
+// if there's no such status.
+func (service *Service) RetractGatewayStatus(ctx context.Context, pubkey string, ipVersion string, timestamp int64) bool {
+	if !service.db.RetractGatewayStatus(ctx, pubkey, ipVersion, timestamp) {
+		return false
+	}
+	if _, err := service.recomputeGatewayReport(ctx, pubkey); err != nil {
+		logrus.WithError(err).WithField("pubkey", pubkey).Error("failed to recompute the report after a retraction")
+	}
+	return true
+}
+
+// recomputeGatewayReport is RecomputeMixReport for gateways
+func (service *Service) recomputeGatewayReport(ctx context.Context, pubkey string) (models.GatewayStatusReport, error) {
+	retained := service.clock.Now().Add(-StatusRetention).UnixNano()
+	v4Statuses := service.db.ListGatewayStatusSince(ctx, pubkey, "4", retained)
+	v6Statuses := service.db.ListGatewayStatusSince(ctx, pubkey, "6", retained)
+	if len(v4Statuses) == 0 && len(v6Statuses) == 0 {
+		return models.GatewayStatusReport{}, nil
+	}
+
+	report := models.GatewayStatusReport{PubKey: pubkey}
+	// the statuses are ordered from the most recent one, so the owner is taken from the newest of them
+	if len(v4Statuses) > 0 {
+		report.Owner = v4Statuses[0].Owner
+		report.MostRecentIPV4 = v4Statuses[0].Up
 	}
+	if len(v6Statuses) > 0 {
+		if len(v4Statuses) == 0 || v6Statuses[0].Timestamp > v4Statuses[0].Timestamp {
+			report.Owner = v6Statuses[0].Owner
+		}
+		report.MostRecentIPV6 = v6Statuses[0].Up
+	}
+
+	for _, window := range service.windows {
+		uptime, rtt := service.gatewayUptimeAndRTTSince(v4Statuses, window.since(service.clock.Now()))
+		setGatewayUptime(&report, "4", window.Name, uptime, rtt)
+		setGatewayClientsUptime(&report, "4", window.Name, service.gatewayClientsUptime(gatewayStatusesSince(v4Statuses, window.since(service.clock.Now()))))
+		uptime, rtt = service.gatewayUptimeAndRTTSince(v6Statuses, window.since(service.clock.Now()))
+		setGatewayUptime(&report, "6", window.Name, uptime, rtt)
+		setGatewayClientsUptime(&report, "6", window.Name, service.gatewayClientsUptime(gatewayStatusesSince(v6Statuses, window.since(service.clock.Now()))))
+	}
+
+	if err := service.db.SaveGatewayStatusReport(report); err != nil {
+		return models.GatewayStatusReport{}, err
+	}
+	return report, nil
+}
+
+// gatewayUptimeAndRTTSince calculates the uptime and the average round-trip time out of the statuses not older than since
+func (service *Service) gatewayUptimeAndRTTSince(statuses []models.PersistedGatewayStatus, since int64) (int, *int) {
+	recent := gatewayStatusesSince(statuses, since)
+	return service.gatewayUptime(recent), averageGatewayRTT(recent)
+}
+
+// gatewayStatusesSince picks the statuses not older than since
+func gatewayStatusesSince(statuses []models.PersistedGatewayStatus, since int64) []models.PersistedGatewayStatus {
+	var recent []models.PersistedGatewayStatus
+	for _, status := range statuses {
+		if status.Timestamp >= since {
+			recent = append(recent, status)
+		}
+	}
+	return recent
+}
+
+func (service *Service) CalculateGatewayUptime(ctx context.Context, pubkey string, ipVersion string, since int64) int {
+	uptime, _ := service.calculateGatewayUptimeAndRTT(ctx, pubkey, ipVersion, since)
+	return uptime
 }
 
-func (service *Service) CalculateGatewayUptime(pubkey string, ipVersion string, since int64) int {
-	statuses := service.db.ListGatewayStatusSince(pubkey, ipVersion, since)
+// calculateGatewayUptimeAndRTT calculates both the uptime and the average round-trip time out of a single query.
+func (service *Service) calculateGatewayUptimeAndRTT(ctx context.Context, pubkey string, ipVersion string, since int64) (int, *int) {
+	statuses := service.db.ListGatewayStatusSince(ctx, pubkey, ipVersion, since)
+	return service.gatewayUptime(statuses), averageGatewayRTT(statuses)
+}
+
+func (service *Service) gatewayUptime(statuses []models.PersistedGatewayStatus) int {
 	numStatuses := len(statuses)
-	if numStatuses == 0 {
-		return 0
+	if numStatuses == 0 || numStatuses < service.minMeasurements {
+		return InsufficientData
 	}
 	up := 0
 	for _, status := range statuses {
-		if *status.Up {
+		if status.Up {
 			up = up + 1
 		}
 	}
@@ -375,11 +1216,295 @@ func (service *Service) CalculateGatewayUptime(pubkey string, ipVersion string,
 	return service.calculatePercent(up, numStatuses)
 }
 
-func (service *Service) calculatePercent(num int, outOf int) int {
-	return int(float32(num) / float32(outOf) * 100)
+// gatewayClientsUptime calculates the uptime of the clients host out of the statuses that say whether it was up.
+// Same as with the uptime of the gateway itself, there must be at least minMeasurements of them.
+func (service *Service) gatewayClientsUptime(statuses []models.PersistedGatewayStatus) int {
+	checked, up := 0, 0
+	for _, status := range statuses {
+		if status.ClientsHostUp != nil {
+			checked++
+			if *status.ClientsHostUp {
+				up++
+			}
+		}
+	}
+	if checked == 0 || checked < service.minMeasurements {
+		return InsufficientData
+	}
+	return service.calculatePercent(up, checked)
+}
+
+// averageGatewayRTT averages the round-trip times of the statuses that carry one. It returns nil if none of them does.
+func averageGatewayRTT(statuses []models.PersistedGatewayStatus) *int {
+	total, measured := 0, 0
+	for _, status := range statuses {
+		if status.RTTMillis != nil {
+			total += *status.RTTMillis
+			measured++
+		}
+	}
+	if measured == 0 {
+		return nil
+	}
+	average := total / measured
+	return &average
+}
+
+// GetMixLifetime tells when the mixnode was first and last seen out of its retained statuses
+func (service *Service) GetMixLifetime(ctx context.Context, pubkey string) models.NodeLifetime {
+	return service.db.MixLifetime(ctx, pubkey)
+}
+
+// GetGatewayLifetime tells when the gateway was first and last seen out of its retained statuses
+func (service *Service) GetGatewayLifetime(ctx context.Context, pubkey string) models.NodeLifetime {
+	return service.db.GatewayLifetime(ctx, pubkey)
+}
+
+// MixCount returns the number of non-stale mixnodes, the ones that reported at least a single status within the
+// staleness window.
+func (service *Service) MixCount(ctx context.Context) int {
+	since := service.clock.Now().Add(-service.staleAfter).UnixNano()
+	return len(service.db.GetActiveMixes(ctx, "", since))
+}
+
+// GatewayCount returns the number of non-stale gateways, the ones that reported at least a single status within the
+// staleness window.
+func (service *Service) GatewayCount(ctx context.Context) int {
+	since := service.clock.Now().Add(-service.staleAfter).UnixNano()
+	return len(service.db.GetActiveGateways(ctx, since))
+}
+
+// ingestionLagWindow is how far back the statuses making up the ingestion lag in the stats go
+const ingestionLagWindow = time.Hour
+
+// GetStats counts the statuses currently stored along with the active nodes, and tells how far behind the monitor
+// has recently been.
+func (service *Service) GetStats(ctx context.Context) models.StatusStats {
+	return models.StatusStats{
+		MixStatuses:           service.db.CountMixStatuses(ctx),
+		GatewayStatuses:       service.db.CountGatewayStatuses(ctx),
+		ActiveMixnodes:        service.MixCount(ctx),
+		ActiveGateways:        service.GatewayCount(ctx),
+		OldestStatusTimestamp: service.db.OldestStatusTimestamp(ctx),
+		LastReportUpdate:      service.LastReportUpdate(),
+		IngestionLag:          service.db.MixIngestionLag(ctx, service.clock.Now().Add(-ingestionLagWindow).UnixNano()),
+		QueryTimeouts:         service.db.QueryTimeouts(),
+		ReportCacheHits:       service.reportCache.hitCount(),
+	}
+}
+
+// errDatabaseNotEmpty is returned when importing into a database that already holds statuses, every one of them
+// would end up counted twice
+var errDatabaseNotEmpty = errors.New("the database already holds statuses")
+
+// errInvalidRecord is returned when importing a record that isn't one of a known kind, or lacks its content
+var errInvalidRecord = errors.New("invalid record")
+
+// ExportData passes every report to write, then every status unless statuses is false, so that the whole dataset
+// can be backed up while the service keeps running. Retracted statuses are left out.
+func (service *Service) ExportData(ctx context.Context, statuses bool, write func(models.ExportRecord) error) error {
+	err := service.db.EachMixReport(ctx, func(report models.MixStatusReport) error {
+		return write(models.ExportRecord{Kind: models.ExportMixReport, MixReport: &report})
+	})
+	if err == nil {
+		err = service.db.EachGatewayReport(ctx, func(report models.GatewayStatusReport) error {
+			return write(models.ExportRecord{Kind: models.ExportGatewayReport, GatewayReport: &report})
+		})
+	}
+	if err != nil || !statuses {
+		return err
+	}
+
+	err = service.db.EachMixStatus(ctx, func(status models.PersistedMixStatus) error {
+		return write(models.ExportRecord{Kind: models.ExportMixStatus, MixStatus: &status})
+	})
+	if err != nil {
+		return err
+	}
+	return service.db.EachGatewayStatus(ctx, func(status models.PersistedGatewayStatus) error {
+		return write(models.ExportRecord{Kind: models.ExportGatewayStatus, GatewayStatus: &status})
+	})
+}
+
+// ImportData stores the records returned by next until it returns io.EOF. They're stored in chunks, through the same
+// batch inserts as the statuses sent by the monitor. It's meant to fill a fresh database, so it refuses to import
+// into one that already holds statuses. Whatever got stored before a failure stays stored.
+func (service *Service) ImportData(ctx context.Context, next func() (models.ExportRecord, error)) (models.Import, error) {
+	var imported models.Import
+	if service.db.CountMixStatuses(ctx)+service.db.CountGatewayStatuses(ctx) > 0 {
+		return imported, errDatabaseNotEmpty
+	}
+
+	var mixReports []models.MixStatusReport
+	var gatewayReports []models.GatewayStatusReport
+	var mixStatuses []models.PersistedMixStatus
+	var gatewayStatuses []models.PersistedGatewayStatus
+	flush := func() error {
+		if len(mixReports) > 0 {
+			service.db.SaveBatchMixStatusReport(models.BatchMixStatusReport{Report: mixReports})
+			service.reportCache.invalidate()
+			imported.MixReports += len(mixReports)
+			mixReports = nil
+		}
+		if len(gatewayReports) > 0 {
+			service.db.SaveBatchGatewayStatusReport(models.BatchGatewayStatusReport{Report: gatewayReports})
+			imported.GatewayReports += len(gatewayReports)
+			gatewayReports = nil
+		}
+		if len(mixStatuses) > 0 {
+			if err := service.db.BatchAddMixStatus(mixStatuses); err != nil {
+				return err
+			}
+			imported.MixStatuses += len(mixStatuses)
+			mixStatuses = nil
+		}
+		if len(gatewayStatuses) > 0 {
+			if err := service.db.BatchAddGatewayStatus(gatewayStatuses); err != nil {
+				return err
+			}
+			imported.GatewayStatuses += len(gatewayStatuses)
+			gatewayStatuses = nil
+		}
+		return nil
+	}
+
+	for {
+		record, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return imported, err
+		}
+
+		switch {
+		case record.Kind == models.ExportMixReport && record.MixReport != nil:
+			mixReports = append(mixReports, *record.MixReport)
+		case record.Kind == models.ExportGatewayReport && record.GatewayReport != nil:
+			gatewayReports = append(gatewayReports, *record.GatewayReport)
+		case record.Kind == models.ExportMixStatus && record.MixStatus != nil:
+			mixStatuses = append(mixStatuses, *record.MixStatus)
+		case record.Kind == models.ExportGatewayStatus && record.GatewayStatus != nil:
+			gatewayStatuses = append(gatewayStatuses, *record.GatewayStatus)
+		default:
+			return imported, fmt.Errorf("%w of kind %q", errInvalidRecord, record.Kind)
+		}
+
+		if len(mixReports)+len(gatewayReports) >= MaxReportSize || len(mixStatuses)+len(gatewayStatuses) >= MaxStatusesPerInsertion {
+			if err := flush(); err != nil {
+				return imported, err
+			}
+		}
+	}
+	return imported, flush()
+}
+
+// ListOwners returns the sorted union of mix and gateway owners, each one listed only once.
+func (service *Service) ListOwners(ctx context.Context) []string {
+	seen := make(map[string]struct{})
+	owners := []string{}
+	for _, owner := range append(service.db.DistinctMixOwners(ctx), service.db.DistinctGatewayOwners(ctx)...) {
+		if _, ok := seen[owner]; ok || owner == "" {
+			continue
+		}
+		seen[owner] = struct{}{}
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+	return owners
+}
+
+// Ping checks whether the underlying database is still reachable.
+func (service *Service) Ping(ctx context.Context) error {
+	return service.db.Ping(ctx)
+}
+
+// uptimeTrend tells whether the last hour uptime is better or worse than the last day one. It's steady if either of
+// them is InsufficientData, as there's nothing to compare then.
+func uptimeTrend(lastHour int, lastDay int) string {
+	switch {
+	case lastHour == InsufficientData || lastDay == InsufficientData:
+		return models.TrendSteady
+	case lastHour > lastDay:
+		return models.TrendImproving
+	case lastHour < lastDay:
+		return models.TrendDeclining
+	default:
+		return models.TrendSteady
+	}
+}
+
+// summariseUptimes calculates mean, median, min and max of the provided uptime percentages.
+func summariseUptimes(uptimes []int) models.UptimeStatistics {
+	if len(uptimes) == 0 {
+		return models.UptimeStatistics{}
+	}
+
+	sorted := make([]int, len(uptimes))
+	copy(sorted, uptimes)
+	sort.Ints(sorted)
+
+	sum := 0
+	for _, uptime := range sorted {
+		sum += uptime
+	}
+
+	middle := len(sorted) / 2
+	median := float64(sorted[middle])
+	if len(sorted)%2 == 0 {
+		median = float64(sorted[middle-1]+sorted[middle]) / 2
+	}
+
+	return models.UptimeStatistics{
+		Mean:   float64(sum) / float64(len(sorted)),
+		Median: median,
+		Min:    sorted[0],
+		Max:    sorted[len(sorted)-1],
+	}
+}
+
+// dedupeMixStatuses keeps only the last status sent for each pubkey and ip version pair, so that the
+// 'most recent' report fields don't depend on the order in which duplicates happen to get processed.
+// The relative order of the kept statuses is preserved.
+func dedupeMixStatuses(statuses []models.MixStatus) []models.MixStatus {
+	seen := make(map[string]bool, len(statuses))
+	deduped := make([]models.MixStatus, 0, len(statuses))
+	for i := len(statuses) - 1; i >= 0; i-- {
+		key := statuses[i].PubKey + "/" + statuses[i].IPVersion
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		deduped = append(deduped, statuses[i])
+	}
+
+	for i, j := 0, len(deduped)-1; i < j; i, j = i+1, j-1 {
+		deduped[i], deduped[j] = deduped[j], deduped[i]
+	}
+	return deduped
+}
+
+// dedupeGatewayStatuses is the gateway equivalent of dedupeMixStatuses
+func dedupeGatewayStatuses(statuses []models.GatewayStatus) []models.GatewayStatus {
+	seen := make(map[string]bool, len(statuses))
+	deduped := make([]models.GatewayStatus, 0, len(statuses))
+	for i := len(statuses) - 1; i >= 0; i-- {
+		key := statuses[i].PubKey + "/" + statuses[i].IPVersion
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		deduped = append(deduped, statuses[i])
+	}
+
+	for i, j := 0, len(deduped)-1; i < j; i, j = i+1, j-1 {
+		deduped[i], deduped[j] = deduped[j], deduped[i]
+	}
+	return deduped
 }
 
-func minutesAgo(minutes int) int64 {
-	now := timemock.Now()
-	return now.Add(time.Duration(-minutes) * time.Minute).UnixNano()
-}
\ No newline at end of file
+// calculatePercent rounds to the nearest whole percent rather than truncating, so 2 out of 3 gives 67, not 66.
+// Truncating consistently under-reported uptime, which matters for nodes sitting right below a threshold.
+func (service *Service) calculatePercent(num int, outOf int) int {
+	return int(math.Round(float64(num) / float64(outOf) * 100))
+}
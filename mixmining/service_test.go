@@ -15,24 +15,43 @@
 package mixmining
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/BorisBorshevsky/timemock"
+	"github.com/nymtech/node-status-api/mixmining/fixtures"
 	"github.com/nymtech/node-status-api/mixmining/mocks"
 	"github.com/nymtech/node-status-api/models"
 	. "github.com/onsi/ginkgo"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
+var ctx = context.Background()
+
 func now() int64 {
 	return timemock.Now().UnixNano()
 }
 
+// newTestService creates a service with the default configuration that doesn't start any background job
+func newTestService(db IDb) *Service {
+	return NewService(db, ServiceConfig{IsTest: true})
+}
+
 func daysAgo(days int) int64 {
 	now := timemock.Now()
 	return now.Add(time.Duration(-days) * time.Hour * 24).UnixNano()
 }
 
+func minutesAgo(minutes int) int64 {
+	now := timemock.Now()
+	return now.Add(time.Duration(-minutes) * time.Minute).UnixNano()
+}
+
 // Some fixtures data to dry up tests a bit
 
 // A slice of IPv4 mix statuses with 2 ups and 1 down during the past day
@@ -40,10 +59,9 @@ func twoUpOneDown() []models.PersistedMixStatus {
 	db := []models.PersistedMixStatus{}
 	var status = persistedStatus()
 
-	booltrue := true
 	status.PubKey = "key1"
 	status.IPVersion = "4"
-	status.Up = &booltrue
+	status.Up = true
 
 	status.Timestamp = minutesAgo(5)
 	db = append(db, status)
@@ -51,9 +69,8 @@ func twoUpOneDown() []models.PersistedMixStatus {
 	status.Timestamp = minutesAgo(10)
 	db = append(db, status)
 
-	boolfalse := false
 	status.Timestamp = minutesAgo(15)
-	status.Up = &boolfalse
+	status.Up = false
 	db = append(db, status)
 
 	return db
@@ -61,19 +78,13 @@ func twoUpOneDown() []models.PersistedMixStatus {
 
 func persistedStatus() models.PersistedMixStatus {
 	mixStatus := status()
-	persisted := models.PersistedMixStatus{
-		MixStatus: mixStatus,
-		Timestamp: Now(),
-	}
+	persisted := models.NewPersistedMixStatus(mixStatus, Now())
 	return persisted
 }
 
 func persistedStatusDown(key string, ipversion string) models.PersistedMixStatus {
 	mixStatus := statusDown(key, ipversion)
-	persisted := models.PersistedMixStatus{
-		MixStatus: mixStatus,
-		Timestamp: Now(),
-	}
+	persisted := models.NewPersistedMixStatus(mixStatus, Now())
 	return persisted
 }
 
@@ -105,10 +116,7 @@ func statusDown(key string, ipversion string) models.MixStatus {
 }
 
 func persistedStatusFrom(mixStatus models.MixStatus) models.PersistedMixStatus {
-	persisted := models.PersistedMixStatus{
-		MixStatus: mixStatus,
-		Timestamp: Now(),
-	}
+	persisted := models.NewPersistedMixStatus(mixStatus, Now())
 	return persisted
 }
 
@@ -120,6 +128,13 @@ func Now() int64 {
 	return nanos
 }
 
+// recomputeMixReport recomputes the report of the node, which must get saved
+func recomputeMixReport(serv *Service, pubkey string) models.MixStatusReport {
+	report, err := serv.RecomputeMixReport(ctx, pubkey)
+	assert.Nil(GinkgoT(), err)
+	return report
+}
+
 var _ = Describe("mixmining.Service", func() {
 	var mockDb mocks.IDb
 	var status1 models.MixStatus
@@ -138,10 +153,7 @@ var _ = Describe("mixmining.Service", func() {
 		Up:        &boolfalse,
 	}
 
-	persisted1 = models.PersistedMixStatus{
-		MixStatus: status1,
-		Timestamp: Now(),
-	}
+	persisted1 = models.NewPersistedMixStatus(status1, Now())
 
 	status2 = models.MixStatus{
 		PubKey:    "key2",
@@ -149,96 +161,186 @@ var _ = Describe("mixmining.Service", func() {
 		Up:        &booltrue,
 	}
 
-	persisted2 = models.PersistedMixStatus{
-		MixStatus: status2,
-		Timestamp: Now(),
-	}
+	persisted2 = models.NewPersistedMixStatus(status2, Now())
 
 	downer := persisted1
-	downer.MixStatus.Up = &boolfalse
+	downer.Up = false
 
 	upper := persisted1
-	upper.MixStatus.Up = &booltrue
+	upper.Up = true
 
 	persistedList := []models.PersistedMixStatus{persisted1, persisted2}
 	emptyList := []models.PersistedMixStatus{}
 
 	BeforeEach(func() {
 		mockDb = *new(mocks.IDb)
-		serv = *NewService(&mockDb, true)
+		serv = *newTestService(&mockDb)
 	})
 
 	Describe("Adding a mix status and creating a new summary report for a node", func() {
 		Context("when no statuses have yet been saved", func() {
 			It("should add a PersistedMixStatus to the db and save the new report", func() {
 
-				mockDb.On("AddMixStatus", persisted1)
+				mockDb.On("AddMixStatus", persisted1).Return(nil)
 
 				serv.CreateMixStatus(status1)
 				mockDb.AssertCalled(GinkgoT(), "AddMixStatus", persisted1)
 			})
+			It("should publish it to the stream subscribers", func() {
+				mockDb.On("AddMixStatus", persisted1).Return(nil)
+				statuses, unsubscribe := serv.SubscribeMixStatuses()
+				defer unsubscribe()
+
+				serv.CreateMixStatus(status1)
+				assert.Equal(GinkgoT(), persisted1, <-statuses)
+			})
+			It("should return the error and not publish it if it couldn't be stored", func() {
+				mockDb.On("AddMixStatus", persisted1).Return(errors.New("database is locked"))
+				statuses, unsubscribe := serv.SubscribeMixStatuses()
+				defer unsubscribe()
+
+				_, err := serv.CreateMixStatus(status1)
+				assert.NotNil(GinkgoT(), err)
+				assert.Empty(GinkgoT(), statuses)
+			})
 		})
 	})
 	Describe("Listing mix statuses", func() {
 		Context("when receiving a list request", func() {
 			It("should call to the Db", func() {
-				mockDb.On("ListMixStatus", persisted1.PubKey, 1000).Return(persistedList)
+				mockDb.On("ListMixStatus", ctx, persisted1.PubKey, 1000).Return(persistedList)
 
-				result := serv.ListMixStatus(persisted1.PubKey)
+				result := serv.ListMixStatus(ctx, persisted1.PubKey)
 
-				mockDb.AssertCalled(GinkgoT(), "ListMixStatus", persisted1.PubKey, 1000)
-				assert.Equal(GinkgoT(), persistedList[0].MixStatus.PubKey, result[0].MixStatus.PubKey)
-				assert.Equal(GinkgoT(), persistedList[1].MixStatus.PubKey, result[1].MixStatus.PubKey)
+				mockDb.AssertCalled(GinkgoT(), "ListMixStatus", ctx, persisted1.PubKey, 1000)
+				assert.Equal(GinkgoT(), persistedList[0].PubKey, result[0].PubKey)
+				assert.Equal(GinkgoT(), persistedList[1].PubKey, result[1].PubKey)
 			})
 		})
 	})
 
+	Describe("Listing mix statuses by state", func() {
+		It("should ask the Db for the statuses of the window", func() {
+			Now()
+			down := []models.PersistedMixStatus{persistedStatusDown("key1", "4")}
+			mockDb.On("ListMixStatusByState", ctx, "key1", "4", false, daysAgo(1)).Return(down)
+
+			assert.Equal(GinkgoT(), down, serv.ListMixStatusByState(ctx, "key1", "4", false, 24))
+		})
+	})
+
+	Describe("Getting the lifetime of a node", func() {
+		It("should ask the Db for it", func() {
+			lifetime := models.NodeLifetime{FirstSeen: 100, LastSeen: 300, TotalMeasurements: 3}
+			mockDb.On("MixLifetime", ctx, "key1").Return(lifetime)
+			mockDb.On("GatewayLifetime", ctx, "key2").Return(lifetime)
+
+			assert.Equal(GinkgoT(), lifetime, serv.GetMixLifetime(ctx, "key1"))
+			assert.Equal(GinkgoT(), lifetime, serv.GetGatewayLifetime(ctx, "key2"))
+		})
+	})
+
+	Describe("Listing the statuses of multiple nodes", func() {
+		It("should group them by node out of a single query", func() {
+			pubkeys := []string{"key1", "key2", "key3"}
+			mockDb.On("ListMixStatusBulk", ctx, pubkeys, 10).Return(persistedList)
+
+			history := serv.ListMixStatusBulk(ctx, pubkeys, 10)
+
+			mockDb.AssertNumberOfCalls(GinkgoT(), "ListMixStatusBulk", 1)
+			mockDb.AssertNotCalled(GinkgoT(), "ListMixStatus", mock.Anything, mock.Anything, mock.Anything)
+			assert.Equal(GinkgoT(), map[string][]models.PersistedMixStatus{
+				"key1": {persisted1},
+				"key2": {persisted2},
+				"key3": {},
+			}, history)
+		})
+	})
+
 	Describe("Calculating uptime", func() {
 		Context("when no statuses exist yet", func() {
 			It("should return 0", func() {
-				mockDb.On("ListMixStatusSince", "key1", "4", daysAgo(30)).Return(emptyList)
+				mockDb.On("ListMixStatusSince", ctx, "key1", "4", daysAgo(30)).Return(emptyList)
 
-				uptime := serv.CalculateMixUptime(persisted1.PubKey, persisted1.IPVersion, daysAgo(30))
+				uptime := serv.CalculateMixUptime(ctx, persisted1.PubKey, persisted1.IPVersion, daysAgo(30))
 				assert.Equal(GinkgoT(), -1, uptime)
 			})
 
 		})
 		Context("when 2 ups and 1 down exist in the given time period", func() {
-			It("should return 66", func() {
-				mockDb.On("ListMixStatusSince", "key1", "4", daysAgo(1)).Return(twoUpOneDown())
+			It("should return 67", func() {
+				mockDb.On("ListMixStatusSince", ctx, "key1", "4", daysAgo(1)).Return(twoUpOneDown())
 
-				uptime := serv.CalculateMixUptime("key1", "4", daysAgo(1))
-				expected := 66 // percent
+				uptime := serv.CalculateMixUptime(ctx, "key1", "4", daysAgo(1))
+				expected := 67 // percent
 				assert.Equal(GinkgoT(), expected, uptime)
 			})
 		})
 	})
 
 	Describe("Saving a mix status report", func() {
+		Context("when some of the statuses carry a round-trip time", func() {
+			It("should average the round-trip times of only those that do", func() {
+				withRTT := func(status models.PersistedMixStatus, rtt int) models.PersistedMixStatus {
+					status.RTTMillis = &rtt
+					return status
+				}
+				last5Minutes := []models.PersistedMixStatus{withRTT(upper, 20), upper, withRTT(downer, 40)}
+				lastHour := append(last5Minutes, withRTT(upper, 90))
+				mockDb.On("LoadMixReport", ctx, upper.PubKey).Return(models.MixStatusReport{}, nil)
+				mockDb.On("ListMixStatusSince", ctx, upper.PubKey, upper.IPVersion, minutesAgo(5)).Return(last5Minutes)
+				mockDb.On("ListMixStatusSince", ctx, upper.PubKey, upper.IPVersion, minutesAgo(60)).Return(lastHour)
+				mockDb.On("SaveMixStatusReportIfUnchanged", mock.Anything).Return(true)
+
+				result := serv.SaveMixStatusReport(ctx, upper)
+
+				assert.Equal(GinkgoT(), 67, result.Last5MinutesIPV4)
+				assert.Equal(GinkgoT(), 30, *result.Last5MinutesRTTIPV4)
+				assert.Equal(GinkgoT(), 50, *result.LastHourRTTIPV4)
+				assert.Nil(GinkgoT(), result.Last5MinutesRTTIPV6)
+			})
+		})
+		Context("when none of the statuses carry a round-trip time", func() {
+			It("should leave the round-trip times empty", func() {
+				mockDb.On("LoadMixReport", ctx, upper.PubKey).Return(models.MixStatusReport{}, nil)
+				mockDb.On("ListMixStatusSince", ctx, upper.PubKey, upper.IPVersion, mock.Anything).Return([]models.PersistedMixStatus{upper, downer})
+				mockDb.On("SaveMixStatusReportIfUnchanged", mock.Anything).Return(true)
+
+				result := serv.SaveMixStatusReport(ctx, upper)
+
+				assert.Equal(GinkgoT(), 50, result.Last5MinutesIPV4)
+				assert.Nil(GinkgoT(), result.Last5MinutesRTTIPV4)
+				assert.Nil(GinkgoT(), result.LastHourRTTIPV4)
+			})
+		})
 		Context("when 1 down status exists", func() {
 			BeforeEach(func() {
 				oneDown := []models.PersistedMixStatus{downer}
-				mockDb.On("ListMixStatusSince", downer.PubKey, downer.IPVersion, minutesAgo(5)).Return(oneDown)
-				mockDb.On("ListMixStatusSince", downer.PubKey, downer.IPVersion, minutesAgo(60)).Return(oneDown)
+				mockDb.On("ListMixStatusSince", ctx, downer.PubKey, downer.IPVersion, minutesAgo(5)).Return(oneDown)
+				mockDb.On("ListMixStatusSince", ctx, downer.PubKey, downer.IPVersion, minutesAgo(60)).Return(oneDown)
 			})
 			Context("this one *must be* a downer, so calculate using it", func() {
 				BeforeEach(func() {
-					mockDb.On("LoadMixReport", downer.PubKey).Return(models.MixStatusReport{}) // TODO: Mockery isn't happy returning an untyped nil, so I've had to sub in a blank `models.MixStatusReport{}`. It will actually return a nil.
+					mockDb.On("LoadMixReport", ctx, downer.PubKey).Return(models.MixStatusReport{}, nil) // TODO: Mockery isn't happy returning an untyped nil, so I've had to sub in a blank `models.MixStatusReport{}`. It will actually return a nil.
 					expectedSave := models.MixStatusReport{
-						PubKey:           downer.PubKey,
-						MostRecentIPV4:   false,
-						Last5MinutesIPV4: 0,
-						LastHourIPV4:     0,
-						LastDayIPV4:      0,
-						MostRecentIPV6:   false,
-						Last5MinutesIPV6: 0,
-						LastHourIPV6:     0,
-						LastDayIPV6:      0,
+						PubKey:                  downer.PubKey,
+						MostRecentIPV4:          false,
+						Last5MinutesIPV4:        0,
+						LastHourIPV4:            0,
+						LastDayIPV4:             0,
+						MostRecentIPV6:          false,
+						Last5MinutesIPV6:        0,
+						LastHourIPV6:            0,
+						LastDayIPV6:             0,
+						Last5MinutesIPV4Count:   1,
+						LastHourIPV4Count:       1,
+						MostRecentIPV4Timestamp: downer.Timestamp,
+						UptimesIPV4:             models.Uptimes{Last5MinutesWindow: 0, LastHourWindow: 0},
 					}
-					mockDb.On("SaveMixStatusReport", expectedSave)
+					mockDb.On("SaveMixStatusReportIfUnchanged", expectedSave).Return(true)
 				})
 				It("should save the initial report, all statuses will be set to down. Node will also be moved to removed set", func() {
-					result := serv.SaveMixStatusReport(downer)
+					result := serv.SaveMixStatusReport(ctx, downer)
 					assert.Equal(GinkgoT(), 0, result.Last5MinutesIPV4)
 					assert.Equal(GinkgoT(), 0, result.LastHourIPV4)
 					assert.Equal(GinkgoT(), 0, result.LastDayIPV4)
@@ -250,30 +352,34 @@ var _ = Describe("mixmining.Service", func() {
 		Context("when 1 up status exists", func() {
 			BeforeEach(func() {
 				oneUp := []models.PersistedMixStatus{upper}
-				mockDb.On("ListMixStatusSince", downer.PubKey, downer.IPVersion, minutesAgo(5)).Return(oneUp)
-				mockDb.On("ListMixStatusSince", downer.PubKey, downer.IPVersion, minutesAgo(60)).Return(oneUp)
+				mockDb.On("ListMixStatusSince", ctx, downer.PubKey, downer.IPVersion, minutesAgo(5)).Return(oneUp)
+				mockDb.On("ListMixStatusSince", ctx, downer.PubKey, downer.IPVersion, minutesAgo(60)).Return(oneUp)
 			})
 			Context("this one *must be* an upper, so calculate using it", func() {
 				BeforeEach(func() {
 					oneDown := []models.PersistedMixStatus{downer}
 					mockDb.On("GetNMostRecentMixStatuses", upper.PubKey, upper.IPVersion, now()).Return(oneDown)
 					mockDb.On("GetNMostRecentMixStatuses", upper.PubKey, upper.IPVersion, now()).Return(oneDown)
-					mockDb.On("LoadMixReport", upper.PubKey).Return(models.MixStatusReport{}) // TODO: Mockery isn't happy returning an untyped nil, so I've had to sub in a blank `models.MixStatusReport{}`. It will actually return a nil.
+					mockDb.On("LoadMixReport", ctx, upper.PubKey).Return(models.MixStatusReport{}, nil) // TODO: Mockery isn't happy returning an untyped nil, so I've had to sub in a blank `models.MixStatusReport{}`. It will actually return a nil.
 					expectedSave := models.MixStatusReport{
-						PubKey:           upper.PubKey,
-						MostRecentIPV4:   true,
-						Last5MinutesIPV4: 100,
-						LastHourIPV4:     100,
-						LastDayIPV4:      0,
-						MostRecentIPV6:   false,
-						Last5MinutesIPV6: 0,
-						LastHourIPV6:     0,
-						LastDayIPV6:      0,
+						PubKey:                  upper.PubKey,
+						MostRecentIPV4:          true,
+						Last5MinutesIPV4:        100,
+						LastHourIPV4:            100,
+						LastDayIPV4:             0,
+						MostRecentIPV6:          false,
+						Last5MinutesIPV6:        0,
+						LastHourIPV6:            0,
+						LastDayIPV6:             0,
+						Last5MinutesIPV4Count:   1,
+						LastHourIPV4Count:       1,
+						MostRecentIPV4Timestamp: upper.Timestamp,
+						UptimesIPV4:             models.Uptimes{Last5MinutesWindow: 100, LastHourWindow: 100},
 					}
-					mockDb.On("SaveMixStatusReport", expectedSave)
+					mockDb.On("SaveMixStatusReportIfUnchanged", expectedSave).Return(true)
 				})
 				It("should save the initial report, all statuses will be set to up", func() {
-					result := serv.SaveMixStatusReport(upper)
+					result := serv.SaveMixStatusReport(ctx, upper)
 					assert.Equal(GinkgoT(), true, result.MostRecentIPV4)
 					assert.Equal(GinkgoT(), 100, result.Last5MinutesIPV4)
 					assert.Equal(GinkgoT(), 100, result.LastHourIPV4)
@@ -282,10 +388,71 @@ var _ = Describe("mixmining.Service", func() {
 			})
 		})
 
+		Context("when the windows hold different numbers of statuses", func() {
+			It("should tell how many statuses each uptime was calculated from", func() {
+				recent := twoUpOneDown()
+				older := append(twoUpOneDown(), persistedStatusDown("key1", "4"), persistedStatusDown("key1", "4"))
+				mockDb.On("ListMixStatusSince", ctx, "key1", "4", minutesAgo(5)).Return(recent)
+				mockDb.On("ListMixStatusSince", ctx, "key1", "4", minutesAgo(60)).Return(older)
+				mockDb.On("LoadMixReport", ctx, "key1").Return(models.MixStatusReport{}, nil)
+				mockDb.On("SaveMixStatusReportIfUnchanged", mock.Anything).Return(true)
+
+				report := serv.SaveMixStatusReport(ctx, persistedStatusDown("key1", "4"))
+				assert.Equal(GinkgoT(), len(recent), report.Last5MinutesIPV4Count)
+				assert.Equal(GinkgoT(), len(older), report.LastHourIPV4Count)
+				assert.Equal(GinkgoT(), 0, report.LastHourIPV6Count)
+			})
+			It("should still tell the number of statuses when there are too few of them for an uptime", func() {
+				serv := *NewService(&mockDb, ServiceConfig{MinMeasurements: 5, IsTest: true})
+				mockDb.On("ListMixStatusSince", ctx, "key1", "4", mock.Anything).Return(twoUpOneDown())
+				mockDb.On("LoadMixReport", ctx, "key1").Return(models.MixStatusReport{}, nil)
+				mockDb.On("SaveMixStatusReportIfUnchanged", mock.Anything).Return(true)
+
+				report := serv.SaveMixStatusReport(ctx, persistedStatusDown("key1", "4"))
+				assert.Equal(GinkgoT(), InsufficientData, report.LastHourIPV4)
+				assert.Equal(GinkgoT(), 3, report.LastHourIPV4Count)
+			})
+		})
+		Context("when the monitor only probes ipv4", func() {
+			It("should tell there's no ipv6 data rather than a 0% ipv6 uptime", func() {
+				statuses := []models.PersistedMixStatus{persistedStatusFrom(statusUp("key1", "4")), persistedStatusFrom(statusUp("key1", "4"))}
+				mockDb.On("ListMixStatusSince", ctx, "key1", "4", minutesAgo(5)).Return(statuses)
+				mockDb.On("ListMixStatusSince", ctx, "key1", "4", minutesAgo(60)).Return(statuses)
+				mockDb.On("LoadMixReport", ctx, "key1").Return(models.MixStatusReport{}, nil)
+				mockDb.On("SaveMixStatusReportIfUnchanged", mock.Anything).Return(true)
+
+				report := serv.SaveMixStatusReport(ctx, statuses[1])
+				assert.Equal(GinkgoT(), 100, report.LastHourIPV4)
+				assert.False(GinkgoT(), report.HasIPV6Data)
+				mockDb.AssertNotCalled(GinkgoT(), "ListMixStatusSince", ctx, "key1", "6", mock.Anything)
+			})
+		})
+		Context("when the node reported an ipv6 status", func() {
+			It("should tell there's ipv6 data even if it was down", func() {
+				down := persistedStatusDown("key1", "6")
+				mockDb.On("ListMixStatusSince", ctx, "key1", "6", mock.Anything).Return([]models.PersistedMixStatus{down})
+				mockDb.On("LoadMixReport", ctx, "key1").Return(models.MixStatusReport{}, nil)
+				mockDb.On("SaveMixStatusReportIfUnchanged", mock.Anything).Return(true)
+
+				report := serv.SaveMixStatusReport(ctx, down)
+				assert.Equal(GinkgoT(), 0, report.LastHourIPV6)
+				assert.True(GinkgoT(), report.HasIPV6Data)
+			})
+		})
+		Context("when the report can't be loaded", func() {
+			It("should leave it alone rather than saving a fresh one over it", func() {
+				mockDb.On("LoadMixReport", ctx, "key1").Return(models.MixStatusReport{}, errors.New("disk I/O error"))
+
+				report := serv.SaveMixStatusReport(ctx, persistedStatusDown("key1", "4"))
+				assert.Equal(GinkgoT(), models.MixStatusReport{}, report)
+				mockDb.AssertNotCalled(GinkgoT(), "SaveMixStatusReportIfUnchanged", mock.Anything)
+			})
+		})
+
 		Context("when 2 up statuses exist for the last 5 minutes already and we just added a down", func() {
 			BeforeEach(func() {
-				mockDb.On("ListMixStatusSince", downer.PubKey, downer.IPVersion, minutesAgo(5)).Return(twoUpOneDown())
-				mockDb.On("ListMixStatusSince", downer.PubKey, downer.IPVersion, minutesAgo(60)).Return(twoUpOneDown())
+				mockDb.On("ListMixStatusSince", ctx, downer.PubKey, downer.IPVersion, minutesAgo(5)).Return(twoUpOneDown())
+				mockDb.On("ListMixStatusSince", ctx, downer.PubKey, downer.IPVersion, minutesAgo(60)).Return(twoUpOneDown())
 			})
 			It("should save the report", func() {
 				initialState := models.MixStatusReport{
@@ -301,20 +468,24 @@ var _ = Describe("mixmining.Service", func() {
 				}
 
 				expectedAfterUpdate := models.MixStatusReport{
-					PubKey:           downer.PubKey,
-					MostRecentIPV4:   false,
-					Last5MinutesIPV4: 66,
-					LastHourIPV4:     66,
-					LastDayIPV4:      100, // last day will not change, it's updated in separate routine
-					MostRecentIPV6:   false,
-					Last5MinutesIPV6: 0,
-					LastHourIPV6:     0,
-					LastDayIPV6:      0,
+					PubKey:                  downer.PubKey,
+					MostRecentIPV4:          false,
+					Last5MinutesIPV4:        67,
+					LastHourIPV4:            67,
+					LastDayIPV4:             100, // last day will not change, it's updated in separate routine
+					MostRecentIPV6:          false,
+					Last5MinutesIPV6:        0,
+					LastHourIPV6:            0,
+					LastDayIPV6:             0,
+					Last5MinutesIPV4Count:   3,
+					LastHourIPV4Count:       3,
+					MostRecentIPV4Timestamp: downer.Timestamp,
+					UptimesIPV4:             models.Uptimes{Last5MinutesWindow: 67, LastHourWindow: 67},
 				}
-				mockDb.On("LoadMixReport", downer.PubKey).Return(initialState)
-				mockDb.On("SaveMixStatusReport", expectedAfterUpdate)
+				mockDb.On("LoadMixReport", ctx, downer.PubKey).Return(initialState, nil)
+				mockDb.On("SaveMixStatusReportIfUnchanged", expectedAfterUpdate).Return(true)
 
-				updatedStatus := serv.SaveMixStatusReport(downer)
+				updatedStatus := serv.SaveMixStatusReport(ctx, downer)
 				assert.Equal(GinkgoT(), expectedAfterUpdate, updatedStatus)
 
 				mockDb.AssertExpectations(GinkgoT())
@@ -322,6 +493,31 @@ var _ = Describe("mixmining.Service", func() {
 		})
 	})
 
+	Describe("Batch creating mix statuses", func() {
+		Context("when the batch contains the same node and ip version twice", func() {
+			It("should only persist the last of the duplicates", func() {
+				first := statusUp("key1", "4")
+				other := statusUp("key2", "4")
+				last := statusDown("key1", "4")
+				v6 := statusUp("key1", "6")
+				batch := models.BatchMixStatus{Status: []models.MixStatus{first, other, last, v6}}
+
+				timestamp := Now()
+				expected := []models.PersistedMixStatus{
+					models.NewPersistedMixStatus(other, timestamp),
+					models.NewPersistedMixStatus(last, timestamp),
+					models.NewPersistedMixStatus(v6, timestamp),
+				}
+				mockDb.On("BatchAddMixStatus", expected).Return(nil)
+
+				persisted, err := serv.BatchCreateMixStatus(batch)
+				assert.Nil(GinkgoT(), err)
+				assert.Equal(GinkgoT(), expected, persisted)
+				mockDb.AssertExpectations(GinkgoT())
+			})
+		})
+	})
+
 	Describe("Saving batch status report", func() {
 		Context("if it contains v4 and v6 up status for same node", func() {
 			It("should combine them into single entry", func() {
@@ -331,41 +527,269 @@ var _ = Describe("mixmining.Service", func() {
 
 				expected := models.BatchMixStatusReport{
 					Report: []models.MixStatusReport{{
-						PubKey:           "key1",
-						MostRecentIPV4:   false,
-						Last5MinutesIPV4: 0,
-						LastHourIPV4:     0,
-						LastDayIPV4:      0,
-						MostRecentIPV6:   false,
-						Last5MinutesIPV6: 0,
-						LastHourIPV6:     0,
-						LastDayIPV6:      0,
+						PubKey:                  "key1",
+						MostRecentIPV4:          false,
+						Last5MinutesIPV4:        0,
+						LastHourIPV4:            0,
+						LastDayIPV4:             0,
+						MostRecentIPV6:          false,
+						Last5MinutesIPV6:        0,
+						LastHourIPV6:            0,
+						LastDayIPV6:             0,
+						Last5MinutesIPV4Count:   1,
+						LastHourIPV4Count:       1,
+						Last5MinutesIPV6Count:   1,
+						LastHourIPV6Count:       1,
+						MostRecentIPV4Timestamp: upv4.Timestamp,
+						MostRecentIPV6Timestamp: upv6.Timestamp,
+						HasIPV6Data:             true,
+						UptimesIPV4:             models.Uptimes{Last5MinutesWindow: 0, LastHourWindow: 0},
+						UptimesIPV6:             models.Uptimes{Last5MinutesWindow: 0, LastHourWindow: 0},
 					}},
 				}
 
-				mockDb.On("ListMixStatusSince", "key1", "4", minutesAgo(5)).Return([]models.PersistedMixStatus{persistedStatusDown("key1", "4")})
-				mockDb.On("ListMixStatusSince", "key1", "4", minutesAgo(60)).Return([]models.PersistedMixStatus{persistedStatusDown("key1", "4")})
-				mockDb.On("ListMixStatusSince", "key1", "6", minutesAgo(5)).Return([]models.PersistedMixStatus{persistedStatusDown("key1", "6")})
-				mockDb.On("ListMixStatusSince", "key1", "6", minutesAgo(60)).Return([]models.PersistedMixStatus{persistedStatusDown("key1", "6")})
+				mockDb.On("ListMixStatusSince", ctx, "key1", "4", minutesAgo(5)).Return([]models.PersistedMixStatus{persistedStatusDown("key1", "4")})
+				mockDb.On("ListMixStatusSince", ctx, "key1", "4", minutesAgo(60)).Return([]models.PersistedMixStatus{persistedStatusDown("key1", "4")})
+				mockDb.On("ListMixStatusSince", ctx, "key1", "6", minutesAgo(5)).Return([]models.PersistedMixStatus{persistedStatusDown("key1", "6")})
+				mockDb.On("ListMixStatusSince", ctx, "key1", "6", minutesAgo(60)).Return([]models.PersistedMixStatus{persistedStatusDown("key1", "6")})
 
-				mockDb.On("BatchLoadMixReports", []string{"key1", "key1"}).Return(models.BatchMixStatusReport{Report: make([]models.MixStatusReport, 0)})
-				mockDb.On("SaveBatchMixStatusReport", expected)
-				updatedStatus := serv.SaveBatchMixStatusReport(batchReport)
+				mockDb.On("BatchLoadMixReports", ctx, []string{"key1", "key1"}).Return(models.BatchMixStatusReport{Report: make([]models.MixStatusReport, 0)})
+				mockDb.On("SaveBatchMixStatusReportIfUnchanged", expected).Return([]string{})
+				updatedStatus := serv.SaveBatchMixStatusReport(ctx, batchReport)
 				assert.Equal(GinkgoT(), 1, len(updatedStatus.Report))
 			})
 		})
 	})
 
+	Describe("Saving batch status report for brand new nodes", func() {
+		It("should keep a separate report for each of them", func() {
+			first := persistedStatusFrom(statusUp("new1", "4"))
+			second := persistedStatusFrom(statusUp("new2", "4"))
+			secondAgain := persistedStatusFrom(statusDown("new2", "4"))
+			mockDb.On("ListMixStatusSince", ctx, mock.Anything, "4", mock.Anything).Return([]models.PersistedMixStatus{})
+			mockDb.On("BatchLoadMixReports", ctx, []string{"new1", "new2", "new2"}).Return(models.BatchMixStatusReport{Report: make([]models.MixStatusReport, 0)})
+			mockDb.On("SaveBatchMixStatusReportIfUnchanged", mock.Anything).Return([]string{})
+
+			saved := serv.SaveBatchMixStatusReport(ctx, []models.PersistedMixStatus{first, second, secondAgain})
+
+			assert.Len(GinkgoT(), saved.Report, 2)
+			assert.Equal(GinkgoT(), "new1", saved.Report[0].PubKey)
+			assert.True(GinkgoT(), saved.Report[0].MostRecentIPV4)
+			assert.Equal(GinkgoT(), "new2", saved.Report[1].PubKey)
+			assert.False(GinkgoT(), saved.Report[1].MostRecentIPV4, "the second status of a new node updates the report the first one created")
+			mockDb.AssertCalled(GinkgoT(), "SaveBatchMixStatusReportIfUnchanged", saved)
+		})
+	})
+
+	Describe("Calculating uptime over custom windows", func() {
+		windows := []UptimeWindow{
+			{Name: "last15Minutes", Duration: time.Minute * 15},
+			{Name: LastDayWindow, Duration: time.Hour * 24},
+			{Name: "lastWeek", Duration: time.Hour * 24 * 7},
+		}
+
+		It("should recalculate windows up to an hour with each status", func() {
+			Now()
+			serv := NewService(&mockDb, ServiceConfig{UptimeWindows: windows, IsTest: true})
+			mockDb.On("LoadMixReport", ctx, "key1").Return(models.MixStatusReport{}, nil)
+			mockDb.On("ListMixStatusSince", ctx, "key1", "4", minutesAgo(15)).Return(twoUpOneDown())
+			mockDb.On("SaveMixStatusReportIfUnchanged", mock.Anything).Return(true)
+
+			report := serv.SaveMixStatusReport(ctx, persisted1)
+			assert.Equal(GinkgoT(), models.Uptimes{"last15Minutes": 67}, report.UptimesIPV4)
+			assert.Equal(GinkgoT(), 0, report.Last5MinutesIPV4)
+			assert.Equal(GinkgoT(), 0, report.LastHourIPV4)
+		})
+
+		It("should leave the longer windows to the periodic updater, filling in the named fields", func() {
+			Now()
+			serv := NewService(&mockDb, ServiceConfig{UptimeWindows: windows, IsTest: true})
+			mockDb.On("GetActiveMixes", ctx, "4", daysAgo(1)).Return([]string{"key1"})
+			mockDb.On("GetActiveMixes", ctx, "6", daysAgo(1)).Return([]string{})
+			mockDb.On("BatchLoadMixReports", ctx, []string{"key1"}).Return(models.BatchMixStatusReport{Report: []models.MixStatusReport{{PubKey: "key1"}}})
+			mockDb.On("ListMixStatusSince", ctx, "key1", "4", daysAgo(1)).Return(twoUpOneDown())
+			mockDb.On("ListMixStatusSince", ctx, "key1", "4", daysAgo(7)).Return(append(twoUpOneDown(), persistedStatusDown("key1", "4")))
+			mockDb.On("ListMixStatusSince", ctx, "key1", "6", mock.Anything).Return(emptyList)
+			mockDb.On("SaveBatchMixStatusReportIfUnchanged", mock.Anything).Return([]string{})
+
+			report := serv.updateLastDayMixReports(ctx).Report[0]
+			assert.Equal(GinkgoT(), models.Uptimes{LastDayWindow: 67, "lastWeek": 50}, report.UptimesIPV4)
+			assert.Equal(GinkgoT(), 67, report.LastDayIPV4)
+			mockDb.AssertNotCalled(GinkgoT(), "ListMixStatusSince", ctx, "key1", "4", minutesAgo(15))
+			// it didn't report any ipv6 status during the last day, so only the longer window needed calculating
+			assert.Equal(GinkgoT(), InsufficientData, report.LastDayIPV6)
+			mockDb.AssertNotCalled(GinkgoT(), "ListMixStatusSince", ctx, "key1", "6", daysAgo(1))
+			mockDb.AssertCalled(GinkgoT(), "ListMixStatusSince", ctx, "key1", "6", daysAgo(7))
+		})
+	})
+
+	Describe("Calculating uptime over aligned windows", func() {
+		It("should give the same uptime throughout the same minute", func() {
+			start := timemock.Now()
+			defer timemock.Freeze(start)
+			minute := start.Truncate(time.Minute)
+			db := NewDb(true)
+			serv := NewService(db, ServiceConfig{UptimeWindows: AlignUptimeWindows(DefaultUptimeWindows, time.Minute), IsTest: true})
+
+			// the down status is just inside the last 5 minutes at the start of the minute, but not in its second half
+			timemock.Freeze(minute.Add(10 * time.Second))
+			db.BatchAddMixStatus([]models.PersistedMixStatus{
+				{PubKey: "aligned", Owner: "owner", IPVersion: "4", Up: true, Timestamp: minute.UnixNano()},
+				{PubKey: "aligned", Owner: "owner", IPVersion: "4", Up: false, Timestamp: minute.Add(-5*time.Minute + 20*time.Second).UnixNano()},
+			})
+			early := recomputeMixReport(serv, "aligned").Last5MinutesIPV4
+
+			timemock.Freeze(minute.Add(50 * time.Second))
+			assert.Equal(GinkgoT(), 50, early)
+			assert.Equal(GinkgoT(), early, recomputeMixReport(serv, "aligned").Last5MinutesIPV4)
+		})
+	})
+
+	Describe("Calculating uptime with a minimum number of measurements", func() {
+		It("should calculate it once there are exactly as many statuses as required", func() {
+			serv := NewService(&mockDb, ServiceConfig{MinMeasurements: 3, IsTest: true})
+			mockDb.On("ListMixStatusSince", ctx, "key1", "4", daysAgo(1)).Return(twoUpOneDown())
+
+			assert.Equal(GinkgoT(), 67, serv.CalculateMixUptime(ctx, "key1", "4", daysAgo(1)))
+		})
+		It("should tell there isn't enough data with a single status fewer", func() {
+			serv := NewService(&mockDb, ServiceConfig{MinMeasurements: 4, IsTest: true})
+			mockDb.On("ListMixStatusSince", ctx, "key1", "4", daysAgo(1)).Return(twoUpOneDown())
+
+			assert.Equal(GinkgoT(), InsufficientData, serv.CalculateMixUptime(ctx, "key1", "4", daysAgo(1)))
+		})
+		It("should surface the lack of data in the report", func() {
+			Now()
+			serv := NewService(&mockDb, ServiceConfig{MinMeasurements: 2, IsTest: true})
+			mockDb.On("LoadMixReport", ctx, "key1").Return(models.MixStatusReport{}, nil)
+			mockDb.On("ListMixStatusSince", ctx, "key1", "4", minutesAgo(5)).Return([]models.PersistedMixStatus{persisted1})
+			mockDb.On("ListMixStatusSince", ctx, "key1", "4", minutesAgo(60)).Return([]models.PersistedMixStatus{persisted1, persisted2})
+			mockDb.On("SaveMixStatusReportIfUnchanged", mock.Anything).Return(true)
+
+			report := serv.SaveMixStatusReport(ctx, persisted1)
+			assert.Equal(GinkgoT(), InsufficientData, report.Last5MinutesIPV4)
+			assert.Equal(GinkgoT(), 50, report.LastHourIPV4)
+		})
+		It("should apply to gateways too", func() {
+			serv := NewService(&mockDb, ServiceConfig{MinMeasurements: 4, IsTest: true})
+			mockDb.On("ListGatewayStatusSince", ctx, "key1", "4", daysAgo(1)).Return(twoUpOneDownGateway())
+
+			assert.Equal(GinkgoT(), InsufficientData, serv.CalculateGatewayUptime(ctx, "key1", "4", daysAgo(1)))
+		})
+	})
+
+	Describe("Getting the full mix status report", func() {
+		Context("when a node reported statuses recently but has zero last day uptime", func() {
+			It("should still include it in the report", func() {
+				Now()
+				freshNode := models.MixStatusReport{PubKey: "key2", MostRecentIPV4: false, LastDayIPV4: 0}
+				mockDb.On("GetActiveMixes", ctx, "", daysAgo(1)).Return([]string{"key2"})
+				mockDb.On("BatchLoadMixReports", ctx, []string{"key2"}).Return(models.BatchMixStatusReport{Report: []models.MixStatusReport{freshNode}})
+
+				report := serv.BatchGetMixStatusReport(ctx)
+				assert.Equal(GinkgoT(), []models.MixStatusReport{freshNode}, report.Report)
+			})
+		})
+		Context("when the staleness window is configured", func() {
+			It("should only include nodes that reported within it", func() {
+				Now()
+				serv := NewService(&mockDb, ServiceConfig{StaleAfter: time.Hour * 6, IsTest: true})
+				upNode := models.MixStatusReport{PubKey: "key1", LastDayIPV4: 100}
+				since := timemock.Now().Add(-time.Hour * 6).UnixNano()
+				mockDb.On("GetActiveMixes", ctx, "", since).Return([]string{"key1"})
+				mockDb.On("BatchLoadMixReports", ctx, []string{"key1"}).Return(models.BatchMixStatusReport{Report: []models.MixStatusReport{upNode}})
+
+				report := serv.BatchGetMixStatusReport(ctx)
+				assert.Equal(GinkgoT(), []models.MixStatusReport{upNode}, report.Report)
+				mockDb.AssertNotCalled(GinkgoT(), "GetActiveMixes", ctx, "", daysAgo(1))
+			})
+		})
+	})
+
+	Describe("Aggregating mix uptime", func() {
+		It("should summarise uptime of all non-stale nodes, skipping ip versions without data", func() {
+			Now()
+			since := timemock.Now().Add(-time.Hour * 12).UnixNano()
+			reports := models.BatchMixStatusReport{Report: []models.MixStatusReport{{PubKey: "key1"}, {PubKey: "key2"}}}
+			mockDb.On("GetActiveMixes", ctx, "", daysAgo(1)).Return([]string{"key1", "key2"})
+			mockDb.On("BatchLoadMixReports", ctx, []string{"key1", "key2"}).Return(reports)
+			mockDb.On("ListMixStatusSince", ctx, "key1", "4", since).Return(twoUpOneDown())
+			mockDb.On("ListMixStatusSince", ctx, "key1", "6", since).Return(emptyList)
+			mockDb.On("ListMixStatusSince", ctx, "key2", "4", since).Return([]models.PersistedMixStatus{persistedStatusFrom(statusUp("key2", "4"))})
+			mockDb.On("ListMixStatusSince", ctx, "key2", "6", since).Return([]models.PersistedMixStatus{persistedStatusFrom(statusDown("key2", "6"))})
+
+			aggregate := serv.AggregateMixUptime(ctx, 12)
+			assert.Equal(GinkgoT(), 12, aggregate.Hours)
+			assert.Equal(GinkgoT(), 2, aggregate.Nodes)
+			assert.Equal(GinkgoT(), models.UptimeStatistics{Mean: 83.5, Median: 83.5, Min: 67, Max: 100}, aggregate.IPV4)
+			assert.Equal(GinkgoT(), models.UptimeStatistics{Mean: 0, Median: 0, Min: 0, Max: 0}, aggregate.IPV6)
+		})
+	})
+
+	Describe("Detecting mix uptime drops", func() {
+		It("should flag the nodes whose last 5 minutes fell well below their last hour", func() {
+			reports := models.BatchMixStatusReport{Report: []models.MixStatusReport{
+				{PubKey: "collapsed", Owner: "alice", LastHourIPV4: 95, Last5MinutesIPV4: 10, LastHourIPV6: 90, Last5MinutesIPV6: 80},
+				{PubKey: "steady", Owner: "bob", LastHourIPV4: 100, Last5MinutesIPV4: 100, LastHourIPV6: 100, Last5MinutesIPV6: 60},
+				{PubKey: "silent", Owner: "carol", LastHourIPV4: 100, Last5MinutesIPV4: InsufficientData, LastHourIPV6: InsufficientData, Last5MinutesIPV6: 0},
+				{PubKey: "v6", Owner: "dave", LastHourIPV4: 50, Last5MinutesIPV4: 50, LastHourIPV6: 100, Last5MinutesIPV6: 50},
+			}}
+			mockDb.On("GetActiveMixes", ctx, "", daysAgo(1)).Return([]string{"collapsed", "steady", "silent", "v6"})
+			mockDb.On("BatchLoadMixReports", ctx, []string{"collapsed", "steady", "silent", "v6"}).Return(reports)
+
+			expected := []models.MixUptimeDrop{
+				{PubKey: "collapsed", Owner: "alice", IPVersion: "4", LastHour: 95, Last5Minutes: 10, Drop: 85},
+				{PubKey: "v6", Owner: "dave", IPVersion: "6", LastHour: 100, Last5Minutes: 50, Drop: 50},
+			}
+			assert.Equal(GinkgoT(), expected, serv.DetectMixUptimeDrops(ctx, 40))
+		})
+		It("should return an empty list rather than nil when nothing dropped", func() {
+			mockDb.On("GetActiveMixes", ctx, "", daysAgo(1)).Return([]string{})
+			mockDb.On("BatchLoadMixReports", ctx, []string{}).Return(models.BatchMixStatusReport{Report: []models.MixStatusReport{}})
+
+			assert.Equal(GinkgoT(), []models.MixUptimeDrop{}, serv.DetectMixUptimeDrops(ctx, 40))
+		})
+	})
+
+	Describe("Summarising uptimes", func() {
+		Context("for an odd number of nodes", func() {
+			It("should use the middle value as the median", func() {
+				summary := summariseUptimes([]int{100, 10, 40})
+				assert.Equal(GinkgoT(), models.UptimeStatistics{Mean: 50, Median: 40, Min: 10, Max: 100}, summary)
+			})
+		})
+		Context("for an even number of nodes", func() {
+			It("should average the two middle values for the median", func() {
+				summary := summariseUptimes([]int{100, 10, 40, 50})
+				assert.Equal(GinkgoT(), models.UptimeStatistics{Mean: 50, Median: 45, Min: 10, Max: 100}, summary)
+			})
+		})
+		Context("without any data", func() {
+			It("should return zeroed statistics", func() {
+				assert.Equal(GinkgoT(), models.UptimeStatistics{}, summariseUptimes(nil))
+			})
+		})
+	})
+
 	Describe("Getting a mix status report", func() {
 		Context("When no saved report exists for a pubkey", func() {
 			It("should return an empty report", func() {
 				blank := models.MixStatusReport{}
-				mockDb.On("LoadMixReport", "superkey").Return(blank)
+				mockDb.On("LoadMixReport", ctx, "superkey").Return(blank, nil)
 
-				report := serv.GetMixStatusReport("superkey")
+				report, err := serv.GetMixStatusReport(ctx, "superkey")
+				assert.Nil(GinkgoT(), err)
 				assert.Equal(GinkgoT(), blank, report)
 			})
 		})
+		Context("When the report can't be loaded", func() {
+			It("should return the error", func() {
+				mockDb.On("LoadMixReport", ctx, "superkey").Return(models.MixStatusReport{}, errors.New("disk I/O error"))
+
+				_, err := serv.GetMixStatusReport(ctx, "superkey")
+				assert.NotNil(GinkgoT(), err)
+			})
+		})
 		Context("When a saved report exists for a pubkey", func() {
 			It("should return the report", func() {
 				perfect := models.MixStatusReport{
@@ -379,11 +803,1208 @@ var _ = Describe("mixmining.Service", func() {
 					LastHourIPV6:     100,
 					LastDayIPV6:      100,
 				}
-				mockDb.On("LoadMixReport", "superkey").Return(perfect)
+				mockDb.On("LoadMixReport", ctx, "superkey").Return(perfect, nil)
 
-				report := serv.GetMixStatusReport("superkey")
+				report, err := serv.GetMixStatusReport(ctx, "superkey")
+				assert.Nil(GinkgoT(), err)
 				assert.Equal(GinkgoT(), perfect, report)
 			})
 		})
 	})
+
+	Describe("Counting the statuses behind the uptimes of a mixnode", func() {
+		It("should count the statuses and the ones that were up during each window", func() {
+			mockDb.On("ListMixStatusSince", ctx, "key1", "4", minutesAgo(5)).Return(twoUpOneDown()[:1])
+			mockDb.On("ListMixStatusSince", ctx, "key1", "4", minutesAgo(60)).Return(twoUpOneDown())
+			mockDb.On("ListMixStatusSince", ctx, "key1", "4", minutesAgo(1440)).Return(append(twoUpOneDown(), persistedStatusDown("key1", "4")))
+			mockDb.On("ListMixStatusSince", ctx, "key1", "6", mock.Anything).Return([]models.PersistedMixStatus{})
+
+			raw := serv.GetMixRawUptime(ctx, "key1")
+			assert.Equal(GinkgoT(), models.MixRawUptime{
+				PubKey: "key1",
+				IPV4: map[string]models.UptimeCount{
+					Last5MinutesWindow: {Up: 1, Total: 1},
+					LastHourWindow:     {Up: 2, Total: 3},
+					LastDayWindow:      {Up: 2, Total: 4},
+				},
+				IPV6: map[string]models.UptimeCount{
+					Last5MinutesWindow: {Up: 0, Total: 0},
+					LastHourWindow:     {Up: 0, Total: 0},
+					LastDayWindow:      {Up: 0, Total: 0},
+				},
+			}, raw)
+		})
+		It("should match the rounded uptime", func() {
+			mockDb.On("ListMixStatusSince", ctx, "key1", "4", minutesAgo(60)).Return(twoUpOneDown())
+
+			counts := serv.CalculateMixUptimeCounts(ctx, "key1", "4", minutesAgo(60))
+			assert.Equal(GinkgoT(), serv.CalculateMixUptime(ctx, "key1", "4", minutesAgo(60)), serv.calculatePercent(counts.Up, counts.Total))
+		})
+	})
+
+	Describe("Getting a mixnode summary", func() {
+		Context("When no saved report exists for a pubkey", func() {
+			It("should return an empty summary", func() {
+				mockDb.On("LoadMixReport", ctx, "superkey").Return(models.MixStatusReport{}, nil)
+
+				summary := serv.GetMixNodeSummary(ctx, "superkey")
+				assert.Equal(GinkgoT(), models.MixNodeSummary{}, summary)
+				mockDb.AssertNotCalled(GinkgoT(), "ListMixStatus", mock.Anything, mock.Anything, mock.Anything)
+			})
+		})
+		Context("When a saved report exists for a pubkey", func() {
+			It("should add the trends and the time of the most recent status", func() {
+				report := models.MixStatusReport{
+					PubKey:       "superkey",
+					LastHourIPV4: 100,
+					LastDayIPV4:  90,
+					LastHourIPV6: 50,
+					LastDayIPV6:  80,
+				}
+				latest := persistedStatusFrom(statusUp("superkey", "6"))
+				mockDb.On("LoadMixReport", ctx, "superkey").Return(report, nil)
+				mockDb.On("ListMixStatus", ctx, "superkey", 1).Return([]models.PersistedMixStatus{latest})
+
+				summary := serv.GetMixNodeSummary(ctx, "superkey")
+				expected := models.MixNodeSummary{
+					Report:               report,
+					TrendIPV4:            models.TrendImproving,
+					TrendIPV6:            models.TrendDeclining,
+					MostRecentStatusTime: latest.Timestamp,
+				}
+				assert.Equal(GinkgoT(), expected, summary)
+			})
+		})
+	})
+
+	Describe("Working out uptime trends", func() {
+		It("should be steady when the last hour matches the last day", func() {
+			assert.Equal(GinkgoT(), models.TrendSteady, uptimeTrend(100, 100))
+		})
+		It("should be steady when either window has insufficient data", func() {
+			assert.Equal(GinkgoT(), models.TrendSteady, uptimeTrend(InsufficientData, 80))
+			assert.Equal(GinkgoT(), models.TrendSteady, uptimeTrend(80, InsufficientData))
+		})
+	})
+
+	Describe("Getting stats", func() {
+		It("should combine the status counts with the active node counts", func() {
+			mockDb.On("CountMixStatuses", ctx).Return(int64(3000))
+			mockDb.On("CountGatewayStatuses", ctx).Return(int64(200))
+			mockDb.On("OldestStatusTimestamp", ctx).Return(int64(1234))
+			mockDb.On("GetActiveMixes", ctx, "", daysAgo(1)).Return([]string{"key1", "key2"})
+			mockDb.On("GetActiveGateways", ctx, daysAgo(1)).Return([]string{"key3"})
+			lag := models.IngestionLag{Statuses: 10, AverageMillis: 1500, MaxMillis: 4000}
+			hourAgo := timemock.Now().Add(-time.Hour).UnixNano()
+			mockDb.On("MixIngestionLag", ctx, hourAgo).Return(lag)
+			mockDb.On("QueryTimeouts").Return(int64(2))
+
+			expected := models.StatusStats{
+				MixStatuses:           3000,
+				GatewayStatuses:       200,
+				ActiveMixnodes:        2,
+				ActiveGateways:        1,
+				OldestStatusTimestamp: 1234,
+				IngestionLag:          lag,
+				QueryTimeouts:         2,
+			}
+			assert.Equal(GinkgoT(), expected, serv.GetStats(ctx))
+		})
+	})
+
+	Describe("Listing owners", func() {
+		It("should return the sorted union of mix and gateway owners without duplicates", func() {
+			mockDb.On("DistinctMixOwners", ctx).Return([]string{"bob", "alice"})
+			mockDb.On("DistinctGatewayOwners", ctx).Return([]string{"alice", "", "carol"})
+
+			assert.Equal(GinkgoT(), []string{"alice", "bob", "carol"}, serv.ListOwners(ctx))
+		})
+	})
+})
+
+// A slice of IPv4 gateway statuses with 2 ups and 1 down during the past day
+func twoUpOneDownGateway() []models.PersistedGatewayStatus {
+	booltrue := true
+	status := models.NewPersistedGatewayStatus(models.GatewayStatus{PubKey: "key1", IPVersion: "4", Up: &booltrue}, Now())
+	db := []models.PersistedGatewayStatus{}
+
+	status.Timestamp = minutesAgo(5)
+	db = append(db, status)
+
+	status.Timestamp = minutesAgo(10)
+	db = append(db, status)
+
+	status.Timestamp = minutesAgo(15)
+	status.Up = false
+	db = append(db, status)
+
+	return db
+}
+
+func gatewayStatusUp(key string, ipversion string) models.GatewayStatus {
+	booltrue := true
+	return models.GatewayStatus{
+		PubKey:    key,
+		IPVersion: ipversion,
+		Up:        &booltrue,
+	}
+}
+
+func gatewayStatusDown(key string, ipversion string) models.GatewayStatus {
+	boolfalse := false
+	return models.GatewayStatus{
+		PubKey:    key,
+		IPVersion: ipversion,
+		Up:        &boolfalse,
+	}
+}
+
+var _ = Describe("mixmining.Service for gateways", func() {
+	var mockDb mocks.IDb
+	var serv Service
+
+	status1 := gatewayStatusDown("key1", "4")
+	persisted1 := models.NewPersistedGatewayStatus(status1, Now())
+	persisted2 := models.NewPersistedGatewayStatus(gatewayStatusUp("key2", "6"), Now())
+
+	BeforeEach(func() {
+		mockDb = *new(mocks.IDb)
+		serv = *newTestService(&mockDb)
+	})
+
+	Describe("Adding a gateway status", func() {
+		It("should add a PersistedGatewayStatus to the db", func() {
+			mockDb.On("AddGatewayStatus", persisted1).Return(nil)
+
+			serv.CreateGatewayStatus(status1)
+			mockDb.AssertCalled(GinkgoT(), "AddGatewayStatus", persisted1)
+		})
+	})
+
+	Describe("Listing gateway statuses", func() {
+		It("should call to the Db", func() {
+			persistedList := []models.PersistedGatewayStatus{persisted1, persisted2}
+			mockDb.On("ListGatewayStatus", ctx, "key1", 1000).Return(persistedList)
+
+			result := serv.ListGatewayStatus(ctx, "key1")
+			assert.Equal(GinkgoT(), persistedList, result)
+		})
+	})
+
+	Describe("Calculating uptime", func() {
+		Context("when no statuses exist yet", func() {
+			It("should return -1", func() {
+				mockDb.On("ListGatewayStatusSince", ctx, "key1", "4", daysAgo(30)).Return([]models.PersistedGatewayStatus{})
+
+				assert.Equal(GinkgoT(), -1, serv.CalculateGatewayUptime(ctx, "key1", "4", daysAgo(30)))
+			})
+		})
+		Context("when 2 ups and 1 down exist in the given time period", func() {
+			It("should return 67", func() {
+				mockDb.On("ListGatewayStatusSince", ctx, "key1", "4", daysAgo(1)).Return(twoUpOneDownGateway())
+
+				assert.Equal(GinkgoT(), 67, serv.CalculateGatewayUptime(ctx, "key1", "4", daysAgo(1)))
+			})
+		})
+	})
+
+	Describe("Saving a gateway status report", func() {
+		Context("when 2 up statuses exist for the last 5 minutes already and we just added a down", func() {
+			It("should save the report", func() {
+				mockDb.On("ListGatewayStatusSince", ctx, "key1", "4", minutesAgo(5)).Return(twoUpOneDownGateway())
+				mockDb.On("ListGatewayStatusSince", ctx, "key1", "4", minutesAgo(60)).Return(twoUpOneDownGateway())
+				initialState := models.GatewayStatusReport{
+					PubKey:           "key1",
+					MostRecentIPV4:   true,
+					Last5MinutesIPV4: 100,
+					LastHourIPV4:     100,
+					LastDayIPV4:      100,
+				}
+				expectedAfterUpdate := models.GatewayStatusReport{
+					PubKey:           "key1",
+					MostRecentIPV4:   false,
+					Last5MinutesIPV4: 67,
+					LastHourIPV4:     67,
+					LastDayIPV4:      100, // last day will not change, it's updated in separate routine
+					UptimesIPV4:      models.Uptimes{Last5MinutesWindow: 67, LastHourWindow: 67},
+
+					ClientsLast5MinutesIPV4: InsufficientData,
+					ClientsLastHourIPV4:     InsufficientData,
+				}
+				mockDb.On("LoadGatewayReport", ctx, "key1").Return(initialState)
+				mockDb.On("SaveGatewayStatusReport", expectedAfterUpdate).Return(nil)
+
+				updatedStatus := serv.SaveGatewayStatusReport(ctx, persisted1)
+				assert.Equal(GinkgoT(), expectedAfterUpdate, updatedStatus)
+
+				mockDb.AssertExpectations(GinkgoT())
+			})
+		})
+	})
+
+	Describe("Batch creating gateway statuses", func() {
+		Context("when the batch contains the same node and ip version twice", func() {
+			It("should only persist the last of the duplicates", func() {
+				first := gatewayStatusUp("key1", "4")
+				other := gatewayStatusUp("key2", "4")
+				last := gatewayStatusDown("key1", "4")
+				batch := models.BatchGatewayStatus{Status: []models.GatewayStatus{first, other, last}}
+
+				timestamp := Now()
+				expected := []models.PersistedGatewayStatus{
+					models.NewPersistedGatewayStatus(other, timestamp),
+					models.NewPersistedGatewayStatus(last, timestamp),
+				}
+				mockDb.On("BatchAddGatewayStatus", expected).Return(nil)
+
+				persisted, err := serv.BatchCreateGatewayStatus(batch)
+				assert.Nil(GinkgoT(), err)
+				assert.Equal(GinkgoT(), expected, persisted)
+				mockDb.AssertExpectations(GinkgoT())
+			})
+		})
+	})
+
+	Describe("Saving batch status report", func() {
+		Context("if it contains v4 and v6 down status for same node", func() {
+			It("should combine them into single entry", func() {
+				downv4 := models.NewPersistedGatewayStatus(gatewayStatusDown("key1", "4"), Now())
+				downv6 := models.NewPersistedGatewayStatus(gatewayStatusDown("key1", "6"), Now())
+
+				expected := models.BatchGatewayStatusReport{
+					Report: []models.GatewayStatusReport{{
+						PubKey:      "key1",
+						UptimesIPV4: models.Uptimes{Last5MinutesWindow: 0, LastHourWindow: 0},
+						UptimesIPV6: models.Uptimes{Last5MinutesWindow: 0, LastHourWindow: 0},
+
+						ClientsLast5MinutesIPV4: InsufficientData,
+						ClientsLastHourIPV4:     InsufficientData,
+						ClientsLast5MinutesIPV6: InsufficientData,
+						ClientsLastHourIPV6:     InsufficientData,
+					}},
+				}
+
+				mockDb.On("ListGatewayStatusSince", ctx, "key1", "4", minutesAgo(5)).Return([]models.PersistedGatewayStatus{downv4})
+				mockDb.On("ListGatewayStatusSince", ctx, "key1", "4", minutesAgo(60)).Return([]models.PersistedGatewayStatus{downv4})
+				mockDb.On("ListGatewayStatusSince", ctx, "key1", "6", minutesAgo(5)).Return([]models.PersistedGatewayStatus{downv6})
+				mockDb.On("ListGatewayStatusSince", ctx, "key1", "6", minutesAgo(60)).Return([]models.PersistedGatewayStatus{downv6})
+				mockDb.On("BatchLoadGatewayReports", ctx, []string{"key1", "key1"}).Return(models.BatchGatewayStatusReport{Report: make([]models.GatewayStatusReport, 0)})
+				mockDb.On("SaveBatchGatewayStatusReport", expected)
+
+				updatedStatus := serv.SaveBatchGatewayStatusReport(ctx, []models.PersistedGatewayStatus{downv4, downv6})
+				assert.Equal(GinkgoT(), expected, updatedStatus)
+			})
+		})
+	})
+
+	Describe("Calculating the uptime of the clients host", func() {
+		It("should tell it apart from the uptime of the gateway", func() {
+			booltrue, boolfalse := true, false
+			status := persisted1
+			status.Up = true
+			status.ClientsHostUp = &boolfalse
+			unchecked := status
+			unchecked.ClientsHostUp = nil
+			clientsUp := status
+			clientsUp.ClientsHostUp = &booltrue
+			statuses := []models.PersistedGatewayStatus{status, status, clientsUp, unchecked}
+
+			mockDb.On("ListGatewayStatusSince", ctx, "key1", "4", minutesAgo(5)).Return(statuses)
+			mockDb.On("ListGatewayStatusSince", ctx, "key1", "4", minutesAgo(60)).Return(statuses)
+			mockDb.On("LoadGatewayReport", ctx, "key1").Return(models.GatewayStatusReport{})
+			mockDb.On("SaveGatewayStatusReport", mock.Anything).Return(nil)
+
+			report := serv.SaveGatewayStatusReport(ctx, status)
+			assert.Equal(GinkgoT(), 100, report.Last5MinutesIPV4)
+			assert.Equal(GinkgoT(), 100, report.LastHourIPV4)
+			// only the statuses that checked the clients host count towards its uptime
+			assert.Equal(GinkgoT(), 33, report.ClientsLast5MinutesIPV4)
+			assert.Equal(GinkgoT(), 33, report.ClientsLastHourIPV4)
+		})
+		It("should tell when no monitor checked it", func() {
+			assert.Equal(GinkgoT(), InsufficientData, serv.gatewayClientsUptime(twoUpOneDownGateway()))
+		})
+	})
+
+	Describe("Getting the full gateway status report", func() {
+		Context("when a gateway reported statuses recently but has zero last day uptime", func() {
+			It("should still include it in the report", func() {
+				Now()
+				upNode := models.GatewayStatusReport{PubKey: "key1", LastDayIPV4: 100}
+				freshNode := models.GatewayStatusReport{PubKey: "key2", MostRecentIPV4: true, LastHourIPV4: 100}
+				mockDb.On("GetActiveGateways", ctx, daysAgo(1)).Return([]string{"key1", "key2"})
+				mockDb.On("BatchLoadGatewayReports", ctx, []string{"key1", "key2"}).Return(models.BatchGatewayStatusReport{Report: []models.GatewayStatusReport{upNode, freshNode}})
+
+				report := serv.BatchGetGatewayStatusReport(ctx)
+				assert.Equal(GinkgoT(), []models.GatewayStatusReport{upNode, freshNode}, report.Report)
+			})
+		})
+	})
+
+	Describe("Getting a gateway status report", func() {
+		It("should return the saved report", func() {
+			mockDb.On("LoadGatewayReport", ctx, "key1").Return(fixtures.GatewayStatusReport())
+
+			assert.Equal(GinkgoT(), fixtures.GatewayStatusReport(), serv.GetGatewayStatusReport(ctx, "key1"))
+		})
+	})
+})
+
+var _ = Describe("mixmining.Service background jobs", func() {
+	var mockDb mocks.IDb
+	var serv Service
+
+	BeforeEach(func() {
+		mockDb = *new(mocks.IDb)
+		serv = *newTestService(&mockDb)
+	})
+
+	Describe("updating the last day reports", func() {
+		Context("when the database is unreachable", func() {
+			It("should delay the next run exponentially", func() {
+				mockDb.On("Ping", ctx).Return(errors.New("database is locked"))
+
+				firstDelay := serv.reportsUpdaterBackoff.next(serv.updateLastDayReports(ctx))
+				secondDelay := serv.reportsUpdaterBackoff.next(serv.updateLastDayReports(ctx))
+
+				assert.GreaterOrEqual(GinkgoT(), int64(firstDelay), int64(2*lastDayReportsUpdateInterval))
+				assert.GreaterOrEqual(GinkgoT(), int64(secondDelay), int64(4*lastDayReportsUpdateInterval))
+				mockDb.AssertNotCalled(GinkgoT(), "GetActiveMixes", mock.Anything, mock.Anything, mock.Anything)
+			})
+		})
+
+		Context("when the database is back after a failure", func() {
+			It("should go back to the regular interval", func() {
+				mockDb.On("Ping", ctx).Return(errors.New("database is locked")).Once()
+				mockDb.On("Ping", ctx).Return(nil)
+				mockDb.On("GetActiveMixes", ctx, mock.Anything, mock.Anything).Return([]string{})
+				mockDb.On("BatchLoadMixReports", ctx, []string{}).Return(models.BatchMixStatusReport{})
+				mockDb.On("SaveBatchMixStatusReportIfUnchanged", models.BatchMixStatusReport{}).Return([]string{})
+				mockDb.On("GetActiveGateways", ctx, mock.Anything).Return([]string{})
+				mockDb.On("BatchLoadGatewayReports", ctx, []string{}).Return(models.BatchGatewayStatusReport{})
+				mockDb.On("SaveBatchGatewayStatusReport", models.BatchGatewayStatusReport{})
+				mockDb.On("AddNetworkUptimeSample", mock.Anything)
+
+				serv.reportsUpdaterBackoff.next(serv.updateLastDayReports(ctx))
+				delay := serv.reportsUpdaterBackoff.next(serv.updateLastDayReports(ctx))
+
+				assert.GreaterOrEqual(GinkgoT(), int64(delay), int64(lastDayReportsUpdateInterval))
+				assert.Less(GinkgoT(), int64(delay), int64(2*lastDayReportsUpdateInterval))
+				mockDb.AssertExpectations(GinkgoT())
+			})
+		})
+
+		Context("when the reports get updated", func() {
+			It("should record when that last happened", func() {
+				mockDb.On("Ping", ctx).Return(errors.New("database is locked")).Once()
+				mockDb.On("Ping", ctx).Return(nil)
+				mockDb.On("GetActiveMixes", ctx, mock.Anything, mock.Anything).Return([]string{})
+				mockDb.On("BatchLoadMixReports", ctx, []string{}).Return(models.BatchMixStatusReport{})
+				mockDb.On("SaveBatchMixStatusReportIfUnchanged", models.BatchMixStatusReport{}).Return([]string{})
+				mockDb.On("GetActiveGateways", ctx, mock.Anything).Return([]string{})
+				mockDb.On("BatchLoadGatewayReports", ctx, []string{}).Return(models.BatchGatewayStatusReport{})
+				mockDb.On("SaveBatchGatewayStatusReport", models.BatchGatewayStatusReport{})
+				mockDb.On("AddNetworkUptimeSample", mock.Anything)
+
+				start := timemock.Now()
+				timemock.Freeze(start)
+				// other specs rely on the time they froze, so go back to it
+				defer timemock.Freeze(start)
+				serv.updateLastDayReports(ctx)
+				assert.Equal(GinkgoT(), int64(0), serv.LastReportUpdate(), "a failed run isn't an update")
+
+				serv.updateLastDayReports(ctx)
+				assert.Equal(GinkgoT(), start.UnixNano(), serv.LastReportUpdate())
+
+				timemock.Freeze(start.Add(lastDayReportsUpdateInterval))
+				serv.updateLastDayReports(ctx)
+				assert.Equal(GinkgoT(), start.Add(lastDayReportsUpdateInterval).UnixNano(), serv.LastReportUpdate())
+			})
+		})
+
+		Context("when mixnodes reported statuses during the last hour", func() {
+			It("should sample the mean last hour uptime of the network", func() {
+				Now()
+				reports := models.BatchMixStatusReport{Report: []models.MixStatusReport{
+					{PubKey: "key1", LastHourIPV4: 100, LastHourIPV6: 80},
+					{PubKey: "key2", LastHourIPV4: 60, LastHourIPV6: InsufficientData},
+					{PubKey: "key3", LastHourIPV4: InsufficientData, LastHourIPV6: InsufficientData},
+				}}
+				mockDb.On("Ping", ctx).Return(nil)
+				mockDb.On("GetActiveMixes", ctx, mock.Anything, mock.Anything).Return([]string{"key1", "key2", "key3"})
+				mockDb.On("BatchLoadMixReports", ctx, []string{"key1", "key2", "key3"}).Return(reports)
+				mockDb.On("ListMixStatusSince", ctx, mock.Anything, mock.Anything, mock.Anything).Return([]models.PersistedMixStatus{})
+				mockDb.On("SaveBatchMixStatusReportIfUnchanged", mock.Anything).Return([]string{})
+				mockDb.On("GetActiveGateways", ctx, mock.Anything).Return([]string{})
+				mockDb.On("BatchLoadGatewayReports", ctx, []string{}).Return(models.BatchGatewayStatusReport{})
+				mockDb.On("SaveBatchGatewayStatusReport", models.BatchGatewayStatusReport{})
+				mockDb.On("AddNetworkUptimeSample", mock.Anything)
+
+				serv.updateLastDayReports(ctx)
+
+				// key1 is up 90% of the time across its ip versions, key2 60%, key3 didn't report enough to count
+				mockDb.AssertCalled(GinkgoT(), "AddNetworkUptimeSample", models.NetworkUptimeSample{
+					Timestamp:   timemock.Now().UnixNano(),
+					MeanUptime:  75,
+					ActiveCount: 2,
+				})
+			})
+		})
+
+		Context("when no mixnode reported enough statuses", func() {
+			It("should sample an empty network", func() {
+				sample := sampleNetworkUptime(models.BatchMixStatusReport{Report: []models.MixStatusReport{
+					{PubKey: "key1", LastHourIPV4: InsufficientData, LastHourIPV6: InsufficientData},
+				}}, 42)
+				assert.Equal(GinkgoT(), models.NetworkUptimeSample{Timestamp: 42}, sample)
+			})
+		})
+	})
+
+	Describe("listing the network uptime history", func() {
+		It("should list the samples of the requested window", func() {
+			Now()
+			samples := []models.NetworkUptimeSample{{Timestamp: 1, MeanUptime: 75, ActiveCount: 2}}
+			mockDb.On("ListNetworkUptimeSamples", ctx, timemock.Now().Add(-3*time.Hour).UnixNano()).Return(samples)
+
+			assert.Equal(GinkgoT(), samples, serv.ListNetworkUptimeHistory(ctx, 3))
+		})
+	})
+
+	Describe("purging old data", func() {
+		Context("when the database is unreachable", func() {
+			It("should delay the next run", func() {
+				mockDb.On("Ping", ctx).Return(errors.New("database is locked"))
+
+				delay := serv.dataPurgerBackoff.next(serv.purgeOldData(ctx))
+
+				assert.GreaterOrEqual(GinkgoT(), int64(delay), int64(2*oldDataPurgeInterval))
+				mockDb.AssertNotCalled(GinkgoT(), "BatchLoadAllMixReports", mock.Anything)
+			})
+		})
+	})
+
+	Describe("purging on startup", func() {
+		It("should remove reports of nodes without statuses within the retention period and the statuses past it", func() {
+			Now()
+			weekAgo := timemock.Now().Add(-StatusRetention).UnixNano()
+			reports := models.BatchMixStatusReport{Report: []models.MixStatusReport{{PubKey: "gone"}, {PubKey: "alive"}}}
+			mockDb.On("Ping", ctx).Return(nil)
+			mockDb.On("BatchLoadAllMixReports", ctx).Return(reports)
+			mockDb.On("GetActiveMixes", ctx, "", weekAgo).Return([]string{"alive"})
+			mockDb.On("RemoveMixReports", []string{"gone"})
+			mockDb.On("RemoveOldMixStatuses", weekAgo)
+			mockDb.On("RemoveOldGatewayStatuses", weekAgo)
+			mockDb.On("RemoveOldNetworkUptimeSamples", timemock.Now().Add(-DefaultNetworkHistoryHorizon).UnixNano())
+
+			serv.StartupPurge()
+			mockDb.AssertExpectations(GinkgoT())
+		})
+	})
+
+	Describe("vacuuming after purges", func() {
+		It("should vacuum at most once every interval", func() {
+			Now()
+			now := timemock.Now()
+			mockDb.On("Vacuum", ctx).Return(int64(4096), nil)
+			serv.vacuumInterval = time.Hour
+
+			serv.vacuumIfDue(ctx, now)
+			serv.vacuumIfDue(ctx, now.Add(30*time.Minute))
+			mockDb.AssertNumberOfCalls(GinkgoT(), "Vacuum", 1)
+
+			serv.vacuumIfDue(ctx, now.Add(time.Hour))
+			mockDb.AssertNumberOfCalls(GinkgoT(), "Vacuum", 2)
+		})
+		It("should try again after the next purge if it failed", func() {
+			Now()
+			now := timemock.Now()
+			mockDb.On("Vacuum", ctx).Return(int64(0), errors.New("database is locked"))
+			serv.vacuumInterval = time.Hour
+
+			serv.vacuumIfDue(ctx, now)
+			serv.vacuumIfDue(ctx, now.Add(time.Minute))
+			mockDb.AssertNumberOfCalls(GinkgoT(), "Vacuum", 2)
+		})
+		It("should never vacuum unless enabled", func() {
+			serv.vacuumIfDue(ctx, timemock.Now())
+			mockDb.AssertNotCalled(GinkgoT(), "Vacuum", mock.Anything)
+		})
+	})
+
+	Describe("computing the delay", func() {
+		It("should cap the backoff", func() {
+			b := newBackoff(time.Minute)
+			for i := 0; i < 10; i++ {
+				b.next(errors.New("failure"))
+			}
+
+			assert.LessOrEqual(GinkgoT(), int64(b.next(errors.New("failure"))), int64(time.Minute<<maxBackoffShift*11/10))
+		})
+	})
+})
+
+var _ = Describe("mixmining.Service recomputing reports", func() {
+	Context("when the stored report drifted from the statuses", func() {
+		It("should replace it with one computed from the statuses", func() {
+			db := NewDb(true)
+			serv := newTestService(db)
+
+			now := Now()
+			statusAt := func(status models.MixStatus, minutesAgo int64) models.PersistedMixStatus {
+				status.Owner = "owner"
+				return models.NewPersistedMixStatus(status, now-minutesAgo*int64(time.Minute))
+			}
+			db.BatchAddMixStatus([]models.PersistedMixStatus{
+				statusAt(statusUp("drifted", "4"), 1),
+				statusAt(statusDown("drifted", "4"), 30),
+				statusAt(statusUp("drifted", "4"), 120),
+				statusAt(statusDown("drifted", "4"), 3*24*60),
+				statusAt(statusUp("drifted", "6"), 1),
+			})
+			db.SaveMixStatusReport(models.MixStatusReport{PubKey: "drifted", Owner: "wrong", LastDayIPV4: 3})
+
+			expected := models.MixStatusReport{
+				PubKey:           "drifted",
+				Owner:            "owner",
+				MostRecentIPV4:   true,
+				Last5MinutesIPV4: 100,
+				LastHourIPV4:     50,
+				LastDayIPV4:      67,
+				MostRecentIPV6:   true,
+				Last5MinutesIPV6: 100,
+				LastHourIPV6:     100,
+				LastDayIPV6:      100,
+
+				Last5MinutesIPV4Count:   1,
+				LastHourIPV4Count:       2,
+				Last5MinutesIPV6Count:   1,
+				LastHourIPV6Count:       1,
+				MostRecentIPV4Timestamp: now - int64(time.Minute),
+				MostRecentIPV6Timestamp: now - int64(time.Minute),
+				HasIPV6Data:             true,
+				UptimesIPV4:             models.Uptimes{Last5MinutesWindow: 100, LastHourWindow: 50, LastDayWindow: 67},
+				UptimesIPV6:             models.Uptimes{Last5MinutesWindow: 100, LastHourWindow: 100, LastDayWindow: 100},
+			}
+			assert.Equal(GinkgoT(), expected, recomputeMixReport(serv, "drifted"))
+			// the version got bumped past the one of the drifted report, so that updates that loaded it retry
+			expected.Version = 2
+			assert.Equal(GinkgoT(), expected, loadMixReport(db, "drifted"))
+		})
+	})
+
+	Context("when the node has no statuses", func() {
+		It("should return an empty report without saving it", func() {
+			mockDb := new(mocks.IDb)
+			mockDb.On("ListMixStatusSince", ctx, "unknown", mock.Anything, mock.Anything).Return([]models.PersistedMixStatus{})
+			serv := newTestService(mockDb)
+
+			assert.Equal(GinkgoT(), models.MixStatusReport{}, recomputeMixReport(serv, "unknown"))
+			mockDb.AssertNotCalled(GinkgoT(), "SaveMixStatusReport", mock.Anything)
+		})
+	})
+})
+
+var _ = Describe("mixmining.Service calculating uptime at a past instant", func() {
+	var db *Db
+	var serv *Service
+	var now int64
+
+	BeforeEach(func() {
+		db = NewDb(true)
+		serv = newTestService(db)
+
+		now = Now()
+		statusAt := func(up bool, minutesAgo int64) models.PersistedMixStatus {
+			return models.PersistedMixStatus{PubKey: "timeline", Owner: "owner", IPVersion: "4", Up: up, Timestamp: now - minutesAgo*int64(time.Minute)}
+		}
+		db.orm.Exec("DELETE FROM persisted_mix_statuses WHERE pub_key = ?", "timeline")
+		db.BatchAddMixStatus([]models.PersistedMixStatus{
+			statusAt(true, 10),
+			statusAt(false, 70),
+			statusAt(false, 80),
+			statusAt(true, 100),
+			statusAt(true, 180),
+		})
+	})
+
+	It("should only count the statuses within the window up to that instant", func() {
+		anHourAgo := now - int64(time.Hour)
+		assert.Equal(GinkgoT(), 100, serv.CalculateMixUptimeAt(ctx, "timeline", "4", now, time.Hour))
+		assert.Equal(GinkgoT(), 33, serv.CalculateMixUptimeAt(ctx, "timeline", "4", anHourAgo, time.Hour))
+		assert.Equal(GinkgoT(), 0, serv.CalculateMixUptimeAt(ctx, "timeline", "4", anHourAgo, 10*time.Minute))
+		assert.Equal(GinkgoT(), 60, serv.CalculateMixUptimeAt(ctx, "timeline", "4", now, 3*time.Hour))
+	})
+	It("should tell when there were no statuses during the window", func() {
+		assert.Equal(GinkgoT(), InsufficientData, serv.CalculateMixUptimeAt(ctx, "timeline", "4", now-int64(5*time.Hour), time.Hour))
+		assert.Equal(GinkgoT(), InsufficientData, serv.CalculateMixUptimeAt(ctx, "timeline", "6", now, time.Hour))
+	})
+})
+
+var _ = Describe("mixmining.Service retracting statuses", func() {
+	It("should stop a retracted down status from dragging the uptime down", func() {
+		db := NewDb(true)
+		serv := newTestService(db)
+
+		now := Now()
+		mismeasured := models.PersistedMixStatus{PubKey: "node", Owner: "owner", IPVersion: "4", Up: false, Timestamp: now - 2*int64(time.Minute)}
+		db.BatchAddMixStatus([]models.PersistedMixStatus{
+			{PubKey: "node", Owner: "owner", IPVersion: "4", Up: true, Timestamp: now - int64(time.Minute)},
+			mismeasured,
+		})
+		assert.Equal(GinkgoT(), 50, recomputeMixReport(serv, "node").LastHourIPV4)
+
+		assert.True(GinkgoT(), serv.RetractMixStatus(ctx, "node", "4", mismeasured.Timestamp))
+		report := loadMixReport(db, "node")
+		assert.Equal(GinkgoT(), 100, report.Last5MinutesIPV4)
+		assert.Equal(GinkgoT(), 100, report.LastHourIPV4)
+		assert.Equal(GinkgoT(), 100, serv.CalculateMixUptime(ctx, "node", "4", minutesAgo(60)))
+	})
+	It("should do the same for gateways", func() {
+		db := NewDb(true)
+		serv := newTestService(db)
+
+		now := Now()
+		mismeasured := models.PersistedGatewayStatus{PubKey: "gateway", Owner: "owner", IPVersion: "6", Up: false, Timestamp: now - 2*int64(time.Minute)}
+		db.BatchAddGatewayStatus([]models.PersistedGatewayStatus{
+			{PubKey: "gateway", Owner: "owner", IPVersion: "6", Up: true, Timestamp: now - int64(time.Minute)},
+			mismeasured,
+		})
+
+		assert.True(GinkgoT(), serv.RetractGatewayStatus(ctx, "gateway", "6", mismeasured.Timestamp))
+		report := db.LoadGatewayReport(ctx, "gateway")
+		assert.Equal(GinkgoT(), "owner", report.Owner)
+		assert.True(GinkgoT(), report.MostRecentIPV6)
+		assert.Equal(GinkgoT(), 100, report.LastDayIPV6)
+	})
+	It("should leave the report alone if there's no such status", func() {
+		mockDb := new(mocks.IDb)
+		mockDb.On("RetractMixStatus", ctx, "node", "4", int64(1)).Return(false)
+		serv := newTestService(mockDb)
+
+		assert.False(GinkgoT(), serv.RetractMixStatus(ctx, "node", "4", 1))
+		mockDb.AssertNotCalled(GinkgoT(), "SaveMixStatusReport", mock.Anything)
+	})
+})
+
+var _ = Describe("mixmining.Service backfilling reports", func() {
+	It("should create the reports of nodes that have statuses but no report", func() {
+		db := NewDb(true)
+		serv := newTestService(db)
+
+		now := Now()
+		db.BatchAddMixStatus([]models.PersistedMixStatus{
+			{PubKey: "unreported", Owner: "owner", IPVersion: "4", Up: true, Timestamp: now - int64(time.Minute)},
+			{PubKey: "reported", Owner: "owner", IPVersion: "4", Up: true, Timestamp: now - int64(time.Minute)},
+		})
+		db.SaveMixStatusReport(models.MixStatusReport{PubKey: "reported", Owner: "owner", LastDayIPV4: 3})
+
+		assert.Equal(GinkgoT(), models.Backfill{PubKeys: []string{"unreported"}}, serv.BackfillMixReports(ctx))
+
+		report := loadMixReport(db, "unreported")
+		assert.Equal(GinkgoT(), "owner", report.Owner)
+		assert.Equal(GinkgoT(), 100, report.LastDayIPV4)
+		// the existing report is left alone
+		assert.Equal(GinkgoT(), 3, loadMixReport(db, "reported").LastDayIPV4)
+		// and there's nothing left to backfill
+		assert.Empty(GinkgoT(), serv.BackfillMixReports(ctx).PubKeys)
+	})
+})
+
+var _ = Describe("mixmining.Service saving a mix status report concurrently", func() {
+	It("should rebuild the report from a fresh copy when it got saved in the meantime", func() {
+		mockDb := new(mocks.IDb)
+		serv := newTestService(mockDb)
+		status := models.PersistedMixStatus{PubKey: "key", Owner: "owner", IPVersion: "4", Up: true, Timestamp: Now()}
+		mockDb.On("LoadMixReport", ctx, "key").Return(models.MixStatusReport{PubKey: "key"}, nil).Once()
+		mockDb.On("LoadMixReport", ctx, "key").Return(models.MixStatusReport{PubKey: "key", LastHourIPV6: 100, Version: 1}, nil).Once()
+		mockDb.On("ListMixStatusSince", ctx, "key", "4", mock.Anything).Return([]models.PersistedMixStatus{status})
+		mockDb.On("SaveMixStatusReportIfUnchanged", mock.Anything).Return(false).Once()
+		mockDb.On("SaveMixStatusReportIfUnchanged", mock.Anything).Return(true).Once()
+
+		report := serv.SaveMixStatusReport(ctx, status)
+
+		assert.Equal(GinkgoT(), 100, report.LastHourIPV6)
+		assert.Equal(GinkgoT(), 100, report.LastHourIPV4)
+		mockDb.AssertNumberOfCalls(GinkgoT(), "SaveMixStatusReportIfUnchanged", 2)
+	})
+	It("should keep both the ipv4 and the ipv6 update of a node", func() {
+		db := &interleavingDb{Db: newFileDb()}
+		db.loaded.Add(2)
+		serv := newTestService(db)
+
+		now := Now()
+		v4 := models.PersistedMixStatus{PubKey: "key", Owner: "owner", IPVersion: "4", Up: true, Timestamp: now}
+		v6 := models.PersistedMixStatus{PubKey: "key", Owner: "owner", IPVersion: "6", Up: true, Timestamp: now}
+		db.BatchAddMixStatus([]models.PersistedMixStatus{v4, v6})
+
+		var wg sync.WaitGroup
+		for _, status := range []models.PersistedMixStatus{v4, v6} {
+			wg.Add(1)
+			go func(status models.PersistedMixStatus) {
+				defer wg.Done()
+				serv.SaveMixStatusReport(ctx, status)
+			}(status)
+		}
+		wg.Wait()
+
+		report := loadMixReport(db, "key")
+		assert.Equal(GinkgoT(), now, report.MostRecentIPV4Timestamp)
+		assert.Equal(GinkgoT(), now, report.MostRecentIPV6Timestamp)
+		assert.Equal(GinkgoT(), 100, report.LastHourIPV4)
+		assert.Equal(GinkgoT(), 100, report.LastHourIPV6)
+	})
+})
+
+var _ = Describe("mixmining.Service saving a batch of mix status reports concurrently", func() {
+	It("should keep the single status updates saved since the batch loaded the reports", func() {
+		now := Now()
+		v4 := models.PersistedMixStatus{PubKey: "key", Owner: "owner", IPVersion: "4", Up: true, Timestamp: now}
+		v6 := models.PersistedMixStatus{PubKey: "key", Owner: "owner", IPVersion: "6", Up: true, Timestamp: now}
+		db := &racingBatchDb{Db: newFileDb()}
+		serv := newTestService(db)
+		db.BatchAddMixStatus([]models.PersistedMixStatus{v4, v6})
+		db.SaveMixStatusReport(models.MixStatusReport{PubKey: "key", Owner: "owner"})
+		db.race = func() {
+			serv.SaveMixStatusReport(ctx, v6)
+		}
+
+		serv.SaveBatchMixStatusReport(ctx, []models.PersistedMixStatus{v4})
+
+		report := loadMixReport(db, "key")
+		assert.Equal(GinkgoT(), now, report.MostRecentIPV4Timestamp)
+		assert.Equal(GinkgoT(), now, report.MostRecentIPV6Timestamp)
+		assert.Equal(GinkgoT(), 100, report.LastHourIPV4)
+		assert.Equal(GinkgoT(), 100, report.LastHourIPV6)
+	})
+})
+
+// racingBatchDb runs race right after the first batch of reports got loaded, so that it saves them before the batch
+// does
+type racingBatchDb struct {
+	*Db
+	race func()
+}
+
+func (db *racingBatchDb) BatchLoadMixReports(ctx context.Context, pubkeys []string) models.BatchMixStatusReport {
+	reports := db.Db.BatchLoadMixReports(ctx, pubkeys)
+	if race := db.race; race != nil {
+		db.race = nil
+		race()
+	}
+	return reports
+}
+
+// failingSaveDb fails to save the reports of the node with the given pubkey
+type failingSaveDb struct {
+	*Db
+	pubkey string
+}
+
+func (db *failingSaveDb) SaveMixStatusReport(report models.MixStatusReport) error {
+	if report.PubKey == db.pubkey {
+		return errors.New("disk I/O error")
+	}
+	return db.Db.SaveMixStatusReport(report)
+}
+
+func (db *failingSaveDb) SaveGatewayStatusReport(report models.GatewayStatusReport) error {
+	if report.PubKey == db.pubkey {
+		return errors.New("disk I/O error")
+	}
+	return db.Db.SaveGatewayStatusReport(report)
+}
+
+// interleavingDb holds the first two report loads until both of them are done, so that two concurrent updates of
+// a report are sure to both load it before either of them saves it
+type interleavingDb struct {
+	*Db
+	loads  int32
+	loaded sync.WaitGroup
+}
+
+func (db *interleavingDb) LoadMixReport(ctx context.Context, pubkey string) (models.MixStatusReport, error) {
+	report, err := db.Db.LoadMixReport(ctx, pubkey)
+	if atomic.AddInt32(&db.loads, 1) <= 2 {
+		db.loaded.Done()
+		db.loaded.Wait()
+	}
+	return report, err
+}
+
+var _ = Describe("mixmining.Service recomputing all reports", func() {
+	It("should rebuild the report of every node with statuses", func() {
+		db := NewDb(true)
+		serv := newTestService(db)
+
+		now := Now()
+		var statuses []models.PersistedMixStatus
+		for i := 0; i < 2*recomputeWorkers+1; i++ {
+			pubkey := fmt.Sprintf("mix%d", i)
+			statuses = append(statuses, models.PersistedMixStatus{PubKey: pubkey, Owner: "owner", IPVersion: "4", Up: true, Timestamp: now - int64(time.Minute)})
+			db.SaveMixStatusReport(models.MixStatusReport{PubKey: pubkey, LastDayIPV4: 3})
+		}
+		db.BatchAddMixStatus(statuses)
+		db.BatchAddGatewayStatus([]models.PersistedGatewayStatus{
+			{PubKey: "gateway", Owner: "owner", IPVersion: "6", Up: true, Timestamp: now - int64(time.Minute)},
+		})
+		db.SaveGatewayStatusReport(models.GatewayStatusReport{PubKey: "gateway", LastDayIPV6: 3})
+
+		assert.Equal(GinkgoT(), models.Recomputation{Mixnodes: len(statuses), Gateways: 1, Failed: []string{}}, serv.RecomputeAllReports(ctx))
+		for _, status := range statuses {
+			assert.Equal(GinkgoT(), 100, loadMixReport(db, status.PubKey).LastDayIPV4)
+		}
+		assert.Equal(GinkgoT(), 100, db.LoadGatewayReport(ctx, "gateway").LastDayIPV6)
+	})
+	It("should report the nodes whose rebuilt report couldn't be saved", func() {
+		db := &failingSaveDb{Db: NewDb(true), pubkey: "broken"}
+		serv := newTestService(db)
+
+		now := Now()
+		db.BatchAddMixStatus([]models.PersistedMixStatus{
+			{PubKey: "broken", Owner: "owner", IPVersion: "4", Up: true, Timestamp: now - int64(time.Minute)},
+			{PubKey: "fine", Owner: "owner", IPVersion: "4", Up: true, Timestamp: now - int64(time.Minute)},
+		})
+		db.BatchAddGatewayStatus([]models.PersistedGatewayStatus{
+			{PubKey: "broken", Owner: "owner", IPVersion: "4", Up: true, Timestamp: now - int64(time.Minute)},
+		})
+
+		assert.Equal(GinkgoT(), models.Recomputation{Mixnodes: 2, Gateways: 1, Failed: []string{"broken", "broken"}}, serv.RecomputeAllReports(ctx))
+		assert.Equal(GinkgoT(), 100, loadMixReport(db, "fine").LastDayIPV4)
+	})
+	It("should report the nodes whose report couldn't be rebuilt", func() {
+		failed := recomputeConcurrently([]string{"c", "a", "b"}, func(pubkey string) bool {
+			return pubkey == "b"
+		})
+		assert.Equal(GinkgoT(), []string{"a", "c"}, failed)
+	})
+})
+
+var _ = Describe("mixmining.Service gateway clients host", func() {
+	It("should keep what the monitor reported about it", func() {
+		db := NewDb(true)
+		serv := newTestService(db)
+
+		booltrue, boolfalse := true, false
+		serv.BatchCreateGatewayStatus(models.BatchGatewayStatus{Status: []models.GatewayStatus{
+			{PubKey: "unreachable", Owner: "owner", IPVersion: "4", Up: &booltrue, ClientsHostUp: &boolfalse},
+		}})
+
+		report, err := serv.recomputeGatewayReport(ctx, "unreachable")
+		assert.Nil(GinkgoT(), err)
+		assert.Equal(GinkgoT(), 100, report.LastDayIPV4)
+		assert.Equal(GinkgoT(), 0, report.ClientsLastDayIPV4)
+		assert.Equal(GinkgoT(), InsufficientData, report.ClientsLastDayIPV6)
+	})
+})
+
+var _ = Describe("mixmining.Service updating the last day reports of ipv4 only nodes", func() {
+	It("should leave their ipv6 uptime without data", func() {
+		Now()
+		db := NewDb(true)
+		serv := newTestService(db)
+
+		persisted, err := serv.BatchCreateMixStatus(models.BatchMixStatus{Status: []models.MixStatus{
+			statusUp("v4only", "4"),
+			statusUp("both", "4"),
+			statusDown("both", "6"),
+		}})
+		assert.Nil(GinkgoT(), err)
+		serv.SaveBatchMixStatusReport(ctx, persisted)
+
+		serv.updateLastDayMixReports(ctx)
+
+		v4only := loadMixReport(db, "v4only")
+		assert.Equal(GinkgoT(), 100, v4only.LastDayIPV4)
+		assert.Equal(GinkgoT(), InsufficientData, v4only.LastDayIPV6)
+		assert.Nil(GinkgoT(), v4only.LastDayRTTIPV6)
+		both := loadMixReport(db, "both")
+		assert.Equal(GinkgoT(), 100, both.LastDayIPV4)
+		assert.Equal(GinkgoT(), 0, both.LastDayIPV6)
+	})
+})
+
+var _ = Describe("mixmining.Service reporting on an epoch", func() {
+	It("should attribute the statuses to their own epoch or the one of their batch", func() {
+		Now()
+		db := NewDb(true)
+		serv := newTestService(db)
+		epoch, previous := uint64(12), uint64(11)
+		late := statusUp("late", "4")
+		late.Epoch = &previous
+
+		_, err := serv.BatchCreateMixStatus(models.BatchMixStatus{Epoch: &epoch, Status: []models.MixStatus{
+			statusUp("node", "4"),
+			statusDown("node", "6"),
+			late,
+		}})
+		assert.Nil(GinkgoT(), err)
+		_, err = serv.BatchCreateMixStatus(models.BatchMixStatus{Epoch: &epoch, Status: []models.MixStatus{statusUp("node", "4")}})
+		assert.Nil(GinkgoT(), err)
+		_, err = serv.BatchCreateMixStatus(models.BatchMixStatus{Status: []models.MixStatus{statusDown("node", "4")}})
+		assert.Nil(GinkgoT(), err)
+
+		report := serv.GetMixEpochReport(ctx, epoch)
+		assert.Equal(GinkgoT(), epoch, report.Epoch)
+		assert.Len(GinkgoT(), report.Statuses, 3)
+		assert.Equal(GinkgoT(), []models.MixEpochUptime{
+			{PubKey: "node", IPVersion: "4", Uptime: 100, Measurements: 2},
+			{PubKey: "node", IPVersion: "6", Uptime: 0, Measurements: 1},
+		}, report.Uptimes)
+
+		assert.Equal(GinkgoT(), []models.MixEpochUptime{
+			{PubKey: "late", IPVersion: "4", Uptime: 100, Measurements: 1},
+		}, serv.GetMixEpochReport(ctx, previous).Uptimes)
+	})
+})
+
+// countingDb counts the full report loads, so that the ones served from the report cache can be told apart
+type countingDb struct {
+	*Db
+	batchLoads int32
+}
+
+func (db *countingDb) BatchLoadMixReports(ctx context.Context, pubkeys []string) models.BatchMixStatusReport {
+	atomic.AddInt32(&db.batchLoads, 1)
+	return db.Db.BatchLoadMixReports(ctx, pubkeys)
+}
+
+var _ = Describe("mixmining.Service caching the full mix report", func() {
+	var db *countingDb
+	var serv *Service
+	BeforeEach(func() {
+		db = &countingDb{Db: NewDb(true)}
+		serv = NewService(db, ServiceConfig{ReportCacheTTL: time.Minute, IsTest: true})
+		persisted, err := serv.BatchCreateMixStatus(models.BatchMixStatus{Status: []models.MixStatus{statusUp("node", "4")}})
+		assert.Nil(GinkgoT(), err)
+		serv.SaveBatchMixStatusReport(ctx, persisted)
+		// saving the reports loaded them too
+		atomic.StoreInt32(&db.batchLoads, 0)
+	})
+
+	It("should only load it from the database once within the ttl", func() {
+		start := timemock.Now()
+		defer timemock.Freeze(start)
+		timemock.Freeze(start)
+
+		first := serv.BatchGetMixStatusReport(ctx)
+		timemock.Freeze(start.Add(30 * time.Second))
+		second := serv.BatchGetMixStatusReport(ctx)
+
+		assert.Len(GinkgoT(), first.Report, 1)
+		assert.Equal(GinkgoT(), first, second)
+		assert.Equal(GinkgoT(), int32(1), atomic.LoadInt32(&db.batchLoads))
+		assert.Equal(GinkgoT(), int64(1), serv.GetStats(ctx).ReportCacheHits)
+
+		timemock.Freeze(start.Add(2 * time.Minute))
+		serv.BatchGetMixStatusReport(ctx)
+		assert.Equal(GinkgoT(), int32(2), atomic.LoadInt32(&db.batchLoads))
+	})
+
+	It("should load it again once reports got saved", func() {
+		Now()
+		assert.Len(GinkgoT(), serv.BatchGetMixStatusReport(ctx).Report, 1)
+
+		persisted, err := serv.BatchCreateMixStatus(models.BatchMixStatus{Status: []models.MixStatus{statusUp("other", "4")}})
+		assert.Nil(GinkgoT(), err)
+		serv.SaveBatchMixStatusReport(ctx, persisted)
+
+		atomic.StoreInt32(&db.batchLoads, 0)
+
+		assert.Len(GinkgoT(), serv.BatchGetMixStatusReport(ctx).Report, 2)
+		assert.Equal(GinkgoT(), int32(1), atomic.LoadInt32(&db.batchLoads))
+	})
+
+	It("should not let callers modify the cached report", func() {
+		Now()
+		serv.BatchGetMixStatusReport(ctx).Report[0].Status = models.NodeDown
+
+		assert.Equal(GinkgoT(), "", serv.BatchGetMixStatusReport(ctx).Report[0].Status)
+	})
+
+	It("should not let callers modify the uptimes or round-trip times of the cached report", func() {
+		Now()
+		rtt := 40
+		status := statusUp("timed", "4")
+		status.RTTMillis = &rtt
+		persisted, err := serv.BatchCreateMixStatus(models.BatchMixStatus{Status: []models.MixStatus{status}})
+		assert.Nil(GinkgoT(), err)
+		serv.SaveBatchMixStatusReport(ctx, persisted)
+
+		report := serv.BatchGetMixStatusReport(ctx).Report[1]
+		report.UptimesIPV4[LastHourWindow] = 0
+		*report.LastHourRTTIPV4 = 0
+
+		cached := serv.BatchGetMixStatusReport(ctx).Report[1]
+		assert.Equal(GinkgoT(), "timed", cached.PubKey)
+		assert.Equal(GinkgoT(), 100, cached.UptimesIPV4[LastHourWindow])
+		assert.Equal(GinkgoT(), 40, *cached.LastHourRTTIPV4)
+	})
+
+	It("should not cache a report cut short by the context", func() {
+		Now()
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		serv.BatchGetMixStatusReport(cancelled)
+		serv.BatchGetMixStatusReport(ctx)
+		serv.BatchGetMixStatusReport(ctx)
+
+		assert.Equal(GinkgoT(), int32(2), atomic.LoadInt32(&db.batchLoads))
+	})
+})
+
+var _ = Describe("mixmining.Service tracking the owners of mixnodes", func() {
+	var db *Db
+	var serv *Service
+	owned := func(owner string, timestamp int64) models.PersistedMixStatus {
+		return models.PersistedMixStatus{PubKey: "node", Owner: owner, IPVersion: "4", Up: true, Timestamp: timestamp}
+	}
+	BeforeEach(func() {
+		db = NewDb(true)
+		serv = newTestService(db)
+	})
+
+	It("should record the change when a node reports under another owner", func() {
+		now := Now()
+		first, second := owned("alice", now-time.Minute.Nanoseconds()), owned("bob", now)
+		db.BatchAddMixStatus([]models.PersistedMixStatus{first, second})
+
+		serv.SaveMixStatusReport(ctx, first)
+		assert.Empty(GinkgoT(), serv.ListOwnershipChanges(ctx, "node"), "a new node has no owner to change")
+		serv.SaveMixStatusReport(ctx, first)
+		assert.Empty(GinkgoT(), serv.ListOwnershipChanges(ctx, "node"))
+
+		report := serv.SaveMixStatusReport(ctx, second)
+
+		assert.Equal(GinkgoT(), "bob", report.Owner)
+		assert.Equal(GinkgoT(), []models.OwnershipChange{
+			{PubKey: "node", PreviousOwner: "alice", Owner: "bob", Timestamp: now},
+		}, serv.ListOwnershipChanges(ctx, "node"))
+	})
+
+	It("should record the changes within a batch", func() {
+		now := Now()
+		serv.SaveBatchMixStatusReport(ctx, []models.PersistedMixStatus{owned("alice", now-time.Minute.Nanoseconds())})
+
+		serv.SaveBatchMixStatusReport(ctx, []models.PersistedMixStatus{owned("bob", now)})
+
+		assert.Equal(GinkgoT(), []models.OwnershipChange{
+			{PubKey: "node", PreviousOwner: "alice", Owner: "bob", Timestamp: now},
+		}, serv.ListOwnershipChanges(ctx, "node"))
+	})
+
+	It("should tell which statuses would change the owner of their node", func() {
+		serv.SaveBatchMixStatusReport(ctx, []models.PersistedMixStatus{owned("alice", Now())})
+
+		changed := serv.ChangedMixOwners(ctx, []models.MixStatus{
+			{PubKey: "node", Owner: "alice"},
+			{PubKey: "node", Owner: "bob"},
+			{PubKey: "fresh", Owner: "carol"},
+		})
+
+		assert.Equal(GinkgoT(), []int{1}, changed)
+	})
+})
+
+// fixedClock is stuck at the same time, and never makes the periodic jobs wait
+type fixedClock struct {
+	now time.Time
+}
+
+func (clock fixedClock) Now() time.Time {
+	return clock.now
+}
+
+func (clock fixedClock) After(time.Duration) <-chan time.Time {
+	ready := make(chan time.Time, 1)
+	ready <- clock.now
+	return ready
+}
+
+var _ = Describe("mixmining.Service purging with a staleness window longer than a day", func() {
+	It("should keep the reports of the nodes that reported within it", func() {
+		db := NewDb(true)
+		serv := NewService(db, ServiceConfig{StaleAfter: 48 * time.Hour, IsTest: true})
+		Now()
+		db.BatchAddMixStatus([]models.PersistedMixStatus{
+			{PubKey: "recent", Owner: "owner", IPVersion: "4", Up: true, Timestamp: minutesAgo(36 * 60)},
+			{PubKey: "gone", Owner: "owner", IPVersion: "4", Up: true, Timestamp: daysAgo(8)},
+		})
+		db.SaveBatchMixStatusReport(models.BatchMixStatusReport{Report: []models.MixStatusReport{
+			{PubKey: "recent", Owner: "owner"},
+			{PubKey: "gone", Owner: "owner"},
+		}})
+
+		assert.Nil(GinkgoT(), serv.purgeOldData(ctx))
+
+		reports := db.BatchLoadAllMixReports(ctx).Report
+		assert.Len(GinkgoT(), reports, 1)
+		assert.Equal(GinkgoT(), "recent", reports[0].PubKey)
+		assert.Equal(GinkgoT(), 1, serv.MixCount(ctx))
+		assert.Len(GinkgoT(), serv.BatchGetMixStatusReport(ctx).Report, 1)
+	})
+})
+
+var _ = Describe("mixmining.Service purging on startup", func() {
+	It("should keep the reports of the nodes only reporting ipv6 statuses", func() {
+		db := NewDb(true)
+		serv := newTestService(db)
+		Now()
+		db.BatchAddMixStatus([]models.PersistedMixStatus{
+			{PubKey: "v6only", Owner: "owner", IPVersion: "6", Up: true, Timestamp: minutesAgo(10)},
+			{PubKey: "gone", Owner: "owner", IPVersion: "4", Up: true, Timestamp: daysAgo(8)},
+			{PubKey: "gone", Owner: "owner", IPVersion: "6", Up: true, Timestamp: daysAgo(8)},
+		})
+		db.SaveBatchMixStatusReport(models.BatchMixStatusReport{Report: []models.MixStatusReport{
+			{PubKey: "v6only", Owner: "owner"},
+			{PubKey: "gone", Owner: "owner"},
+		}})
+
+		serv.StartupPurge()
+
+		reports := db.BatchLoadAllMixReports(ctx).Report
+		assert.Len(GinkgoT(), reports, 1)
+		assert.Equal(GinkgoT(), "v6only", reports[0].PubKey)
+	})
+	It("should keep the reports of the nodes that reported within the retention period", func() {
+		db := NewDb(true)
+		serv := newTestService(db)
+		Now()
+		db.AddMixStatus(models.PersistedMixStatus{PubKey: "quiet", Owner: "owner", IPVersion: "4", Up: true, Timestamp: daysAgo(3)})
+		db.SaveMixStatusReport(models.MixStatusReport{PubKey: "quiet", Owner: "owner"})
+
+		serv.StartupPurge()
+
+		assert.Len(GinkgoT(), db.BatchLoadAllMixReports(ctx).Report, 1)
+		assert.Empty(GinkgoT(), serv.BatchGetMixStatusReport(ctx).Report)
+	})
+})
+
+var _ = Describe("mixmining.Service using another clock", func() {
+	var db *Db
+	var serv *Service
+	var clock fixedClock
+	BeforeEach(func() {
+		db = NewDb(true)
+		// long enough ago for every status to be out of retention if the service went by the real time
+		clock = fixedClock{now: time.Now().Add(-365 * 24 * time.Hour)}
+		serv = NewService(db, ServiceConfig{Clock: clock, IsTest: true})
+	})
+
+	It("should timestamp the statuses it creates with the time of the clock", func() {
+		status, err := serv.CreateMixStatus(statusUp("node", "4"))
+		assert.Nil(GinkgoT(), err)
+		assert.Equal(GinkgoT(), clock.now.UnixNano(), status.Timestamp)
+	})
+
+	It("should build the uptime windows back from the time of the clock", func() {
+		db.BatchAddMixStatus([]models.PersistedMixStatus{
+			{PubKey: "node", Owner: "owner", IPVersion: "4", Up: true, Timestamp: clock.now.Add(-time.Minute).UnixNano()},
+			{PubKey: "node", Owner: "owner", IPVersion: "4", Up: false, Timestamp: clock.now.Add(-2 * time.Hour).UnixNano()},
+		})
+
+		report := recomputeMixReport(serv, "node")
+		assert.Equal(GinkgoT(), 100, report.LastHourIPV4)
+		assert.Equal(GinkgoT(), 50, report.LastDayIPV4)
+	})
 })
@@ -0,0 +1,68 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mixmining
+
+import (
+	"time"
+
+	"github.com/nymtech/node-status-api/models"
+)
+
+// DefaultStatusStaleAfter is how long after its most recent status a node is stale by default. Monitors report
+// every few minutes, so a node they didn't hear from in an hour is one nobody knows the state of.
+const DefaultStatusStaleAfter = time.Hour
+
+// DefaultDegradedUptime is the last hour uptime under which a node that's up is degraded by default
+const DefaultDegradedUptime = 90
+
+// mixNodeStatus derives the overall state of the mixnode from its report at the given time, in unix nanoseconds.
+// Only the ip versions the node has last hour data for are judged, ipv6 only if it ever reported any. A node is down
+// if none of them was up most recently, and degraded if only some were or any last hour uptime is under
+// degradedUptime.
+func mixNodeStatus(report models.MixStatusReport, now int64, staleAfter time.Duration, degradedUptime int) string {
+	// reports saved before the timestamps were recorded have no known age, so they're never stale
+	if mostRecent := report.MostRecentTimestamp(); mostRecent != 0 && mostRecent < now-staleAfter.Nanoseconds() {
+		return models.NodeStale
+	}
+
+	judged, up, degraded := 0, 0, false
+	judge := func(lastHour int, mostRecent bool) {
+		if lastHour == InsufficientData {
+			return
+		}
+		judged++
+		if mostRecent {
+			up++
+		}
+		if lastHour < degradedUptime {
+			degraded = true
+		}
+	}
+	judge(report.LastHourIPV4, report.MostRecentIPV4)
+	if report.HasIPV6Data {
+		judge(report.LastHourIPV6, report.MostRecentIPV6)
+	}
+
+	switch {
+	case judged == 0:
+		return models.NodeNoData
+	case up == 0:
+		return models.NodeDown
+	case up < judged || degraded:
+		return models.NodeDegraded
+	default:
+		return models.NodeUp
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mixmining
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nymtech/node-status-api/models"
+)
+
+var _ = Describe("Mixnode status", func() {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC).UnixNano()
+	recent := now - time.Minute.Nanoseconds()
+	report := func(mostRecentIPV4 bool, lastHourIPV4 int, mostRecentIPV6 bool, lastHourIPV6 int) models.MixStatusReport {
+		return models.MixStatusReport{
+			PubKey:                  "key1",
+			MostRecentIPV4:          mostRecentIPV4,
+			LastHourIPV4:            lastHourIPV4,
+			MostRecentIPV4Timestamp: recent,
+			MostRecentIPV6:          mostRecentIPV6,
+			LastHourIPV6:            lastHourIPV6,
+			MostRecentIPV6Timestamp: recent,
+			HasIPV6Data:             true,
+		}
+	}
+	status := func(report models.MixStatusReport) string {
+		return mixNodeStatus(report, now, DefaultStatusStaleAfter, DefaultDegradedUptime)
+	}
+
+	It("should be up if both ip versions are up and their uptime is high", func() {
+		assert.Equal(GinkgoT(), models.NodeUp, status(report(true, 100, true, 95)))
+	})
+	It("should be degraded if only one of the ip versions is up", func() {
+		assert.Equal(GinkgoT(), models.NodeDegraded, status(report(true, 100, false, 100)))
+	})
+	It("should be degraded if an uptime is under the threshold", func() {
+		assert.Equal(GinkgoT(), models.NodeDegraded, status(report(true, 100, true, 89)))
+		assert.Equal(GinkgoT(), models.NodeUp, mixNodeStatus(report(true, 100, true, 89), now, DefaultStatusStaleAfter, 80))
+	})
+	It("should be down if no ip version is up", func() {
+		assert.Equal(GinkgoT(), models.NodeDown, status(report(false, 100, false, 100)))
+	})
+	It("should be stale once the most recent status is older than the threshold", func() {
+		stale := report(true, 100, true, 100)
+		stale.MostRecentIPV4Timestamp = now - 2*time.Hour.Nanoseconds()
+		stale.MostRecentIPV6Timestamp = now - 2*time.Hour.Nanoseconds()
+		assert.Equal(GinkgoT(), models.NodeStale, status(stale))
+		assert.Equal(GinkgoT(), models.NodeUp, mixNodeStatus(stale, now, 3*time.Hour, DefaultDegradedUptime))
+	})
+	It("should not be stale if its age is unknown", func() {
+		unknown := report(true, 100, true, 100)
+		unknown.MostRecentIPV4Timestamp = 0
+		unknown.MostRecentIPV6Timestamp = 0
+		assert.Equal(GinkgoT(), models.NodeUp, status(unknown))
+	})
+	It("should have no data if no ip version has enough statuses during the last hour", func() {
+		assert.Equal(GinkgoT(), models.NodeNoData, status(report(true, InsufficientData, true, InsufficientData)))
+		assert.Equal(GinkgoT(), models.NodeNoData, status(models.MixStatusReport{PubKey: "key1", LastHourIPV4: InsufficientData}))
+	})
+	It("should only judge the ip versions with data", func() {
+		assert.Equal(GinkgoT(), models.NodeUp, status(report(true, 100, false, InsufficientData)))
+
+		ipv4Only := report(true, 100, false, 0)
+		ipv4Only.HasIPV6Data = false
+		assert.Equal(GinkgoT(), models.NodeUp, status(ipv4Only))
+	})
+})
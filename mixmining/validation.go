@@ -0,0 +1,113 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mixmining
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/nymtech/node-status-api/models"
+)
+
+// invalidBatchMessage is the message of the responses rejecting a batch because some of its statuses are invalid
+const invalidBatchMessage = "batch contains invalid statuses"
+
+// respondWithBindingError responds with the invalid fields if binding the request body into a value of the given
+// type failed validation, and with the plain error if it failed for any other reason, such as malformed json.
+func respondWithBindingError(c *gin.Context, err error, body interface{}) {
+	fields := fieldErrors(err, reflect.TypeOf(body), "")
+	if len(fields) == 0 {
+		respondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondWithValidationError(c, err.Error(), fields)
+}
+
+func respondWithValidationError(c *gin.Context, message string, fields []models.FieldError) {
+	c.JSON(http.StatusBadRequest, models.ValidationError{Code: http.StatusBadRequest, Message: message, Fields: fields})
+}
+
+// validateBatchEntries validates every status of a batch, which the binding doesn't do as it doesn't dive into
+// slices, and lists the invalid fields of all of them.
+func validateBatchEntries[T any](statuses []T) []models.FieldError {
+	fields := []models.FieldError{}
+	for i, status := range statuses {
+		fields = append(fields, entryFieldErrors(binding.Validator.ValidateStruct(status), status, i)...)
+	}
+	return fields
+}
+
+// entryFieldErrors lists the invalid fields of the status at the given index of a batch
+func entryFieldErrors[T any](err error, status T, index int) []models.FieldError {
+	return fieldErrors(err, reflect.TypeOf(status), fmt.Sprintf("status[%d]", index))
+}
+
+// fieldErrors lists the fields that failed validation, named by their json path below the prefix. It returns nothing
+// for errors that aren't about validation.
+func fieldErrors(err error, root reflect.Type, prefix string) []models.FieldError {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return nil
+	}
+
+	fields := make([]models.FieldError, len(validationErrors))
+	for i, fieldErr := range validationErrors {
+		reason := fieldErr.Tag()
+		if fieldErr.Param() != "" {
+			reason += "=" + fieldErr.Param()
+		}
+		fields[i] = models.FieldError{Field: jsonPath(root, fieldErr.StructNamespace(), prefix), Reason: reason}
+	}
+	return fields
+}
+
+// jsonPath turns a validator namespace, such as BatchMixStatus.Status[2].Up, into the path of the field in the json
+// body, such as status[2].up, by looking up the json names of the fields of the root type.
+func jsonPath(root reflect.Type, namespace string, prefix string) string {
+	path := prefix
+	t := root
+	// the first segment is the name of the root type itself
+	for _, segment := range strings.Split(namespace, ".")[1:] {
+		name, index := segment, ""
+		if i := strings.Index(segment, "["); i >= 0 {
+			name, index = segment[:i], segment[i:]
+		}
+		for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+			t = t.Elem()
+		}
+
+		jsonName := name
+		if t.Kind() == reflect.Struct {
+			if field, ok := t.FieldByName(name); ok {
+				if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag != "" && tag != "-" {
+					jsonName = tag
+				}
+				t = field.Type
+			}
+		}
+
+		if path != "" {
+			path += "."
+		}
+		path += jsonName + index
+	}
+	return path
+}
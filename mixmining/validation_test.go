@@ -0,0 +1,62 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mixmining
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/nymtech/node-status-api/models"
+	. "github.com/onsi/ginkgo"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ = Describe("Validation errors", func() {
+	Describe("naming a field by its json path", func() {
+		It("should use the json names along with the indexes", func() {
+			path := jsonPath(reflect.TypeOf(models.BatchMixStatus{}), "BatchMixStatus.Status[2].RTTMillis", "")
+			assert.Equal(GinkgoT(), "status[2].rttMillis", path)
+		})
+		It("should go below the prefix", func() {
+			path := jsonPath(reflect.TypeOf(models.MixStatus{}), "MixStatus.IPVersion", "status[0]")
+			assert.Equal(GinkgoT(), "status[0].ipVersion", path)
+		})
+	})
+
+	Describe("validating the statuses of a batch", func() {
+		It("should list the invalid fields of every status", func() {
+			booltrue := true
+			statuses := []models.MixStatus{
+				{PubKey: "key", IPVersion: "4", Up: &booltrue},
+				{IPVersion: "4"},
+			}
+
+			expected := []models.FieldError{
+				{Field: "status[1].pubKey", Reason: "required"},
+				{Field: "status[1].up", Reason: "required"},
+			}
+			assert.Equal(GinkgoT(), expected, validateBatchEntries(statuses))
+		})
+		It("should return an empty list when they're all valid", func() {
+			booltrue := true
+			statuses := []models.MixStatus{{PubKey: "key", IPVersion: "4", Up: &booltrue}}
+			assert.Empty(GinkgoT(), validateBatchEntries(statuses))
+		})
+	})
+
+	It("should list nothing for errors that aren't about validation", func() {
+		assert.Nil(GinkgoT(), fieldErrors(errors.New("unexpected EOF"), reflect.TypeOf(models.MixStatus{}), ""))
+	})
+})
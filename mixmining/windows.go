@@ -0,0 +1,223 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mixmining
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nymtech/node-status-api/models"
+)
+
+// Names of the default uptime windows, which back the named uptime fields of the reports
+const (
+	Last5MinutesWindow = "last5Minutes"
+	LastHourWindow     = "lastHour"
+	LastDayWindow      = "lastDay"
+)
+
+// maxPerStatusWindow is the longest window recalculated whenever a status comes in. Longer windows cover too many
+// statuses for that, so they're recalculated by the periodic reports updater instead.
+const maxPerStatusWindow = time.Hour
+
+// UptimeWindow is a period, ending now, over which the uptime of nodes is calculated. If Alignment is set, the start
+// of the window is floored to a multiple of it. Otherwise the window slides with every calculation, so a status right
+// at its start can drop in and out of it between two reports built moments apart, making the uptime flicker.
+type UptimeWindow struct {
+	Name      string
+	Duration  time.Duration
+	Alignment time.Duration
+}
+
+// DefaultUptimeWindows are used unless others are configured
+var DefaultUptimeWindows = []UptimeWindow{
+	{Name: Last5MinutesWindow, Duration: time.Minute * 5},
+	{Name: LastHourWindow, Duration: time.Hour},
+	{Name: LastDayWindow, Duration: time.Hour * 24},
+}
+
+// since returns the timestamp the window ending at now starts at
+func (window UptimeWindow) since(now time.Time) int64 {
+	return now.Truncate(window.Alignment).Add(-window.Duration).UnixNano()
+}
+
+// AlignUptimeWindows returns copies of the windows aligned to the given boundary, such as a minute
+func AlignUptimeWindows(windows []UptimeWindow, alignment time.Duration) []UptimeWindow {
+	aligned := make([]UptimeWindow, len(windows))
+	for i, window := range windows {
+		window.Alignment = alignment
+		aligned[i] = window
+	}
+	return aligned
+}
+
+// ParseUptimeWindows reads a comma separated list of name=duration pairs, such as "last15Minutes=15m,lastDay=24h".
+// Windows can't be longer than the status retention, as there would be nothing to calculate them from.
+func ParseUptimeWindows(value string) ([]UptimeWindow, error) {
+	var windows []UptimeWindow
+	seen := make(map[string]bool)
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid uptime window %q, expected name=duration", pair)
+		}
+		if seen[parts[0]] {
+			return nil, fmt.Errorf("uptime window %q is defined more than once", parts[0])
+		}
+		duration, err := time.ParseDuration(parts[1])
+		if err != nil || duration <= 0 || duration > StatusRetention {
+			return nil, fmt.Errorf("invalid duration of uptime window %q, expected a positive duration up to %s", parts[0], StatusRetention)
+		}
+		seen[parts[0]] = true
+		windows = append(windows, UptimeWindow{Name: parts[0], Duration: duration})
+	}
+	return windows, nil
+}
+
+// splitUptimeWindows separates the windows recalculated with each status from the ones left to the periodic updater
+func splitUptimeWindows(windows []UptimeWindow) (perStatus []UptimeWindow, periodic []UptimeWindow) {
+	for _, window := range windows {
+		if window.Duration <= maxPerStatusWindow {
+			perStatus = append(perStatus, window)
+		} else {
+			periodic = append(periodic, window)
+		}
+	}
+	return perStatus, periodic
+}
+
+// setMixUptime records the uptime, the average round-trip time and the number of statuses during the window,
+// filling in the named fields if it's one of the default windows.
+func setMixUptime(report *models.MixStatusReport, ipVersion string, window string, uptime int, rtt *int, count int) {
+	if ipVersion == "4" {
+		if report.UptimesIPV4 == nil {
+			report.UptimesIPV4 = models.Uptimes{}
+		}
+		report.UptimesIPV4[window] = uptime
+		switch window {
+		case Last5MinutesWindow:
+			report.Last5MinutesIPV4, report.Last5MinutesRTTIPV4, report.Last5MinutesIPV4Count = uptime, rtt, count
+		case LastHourWindow:
+			report.LastHourIPV4, report.LastHourRTTIPV4, report.LastHourIPV4Count = uptime, rtt, count
+		case LastDayWindow:
+			report.LastDayIPV4, report.LastDayRTTIPV4 = uptime, rtt
+		}
+	} else if ipVersion == "6" {
+		if report.UptimesIPV6 == nil {
+			report.UptimesIPV6 = models.Uptimes{}
+		}
+		report.UptimesIPV6[window] = uptime
+		switch window {
+		case Last5MinutesWindow:
+			report.Last5MinutesIPV6, report.Last5MinutesRTTIPV6, report.Last5MinutesIPV6Count = uptime, rtt, count
+		case LastHourWindow:
+			report.LastHourIPV6, report.LastHourRTTIPV6, report.LastHourIPV6Count = uptime, rtt, count
+		case LastDayWindow:
+			report.LastDayIPV6, report.LastDayRTTIPV6 = uptime, rtt
+		}
+	}
+}
+
+// setGatewayUptime is setMixUptime for gateways
+func setGatewayUptime(report *models.GatewayStatusReport, ipVersion string, window string, uptime int, rtt *int) {
+	if ipVersion == "4" {
+		if report.UptimesIPV4 == nil {
+			report.UptimesIPV4 = models.Uptimes{}
+		}
+		report.UptimesIPV4[window] = uptime
+		switch window {
+		case Last5MinutesWindow:
+			report.Last5MinutesIPV4, report.Last5MinutesRTTIPV4 = uptime, rtt
+		case LastHourWindow:
+			report.LastHourIPV4, report.LastHourRTTIPV4 = uptime, rtt
+		case LastDayWindow:
+			report.LastDayIPV4, report.LastDayRTTIPV4 = uptime, rtt
+		}
+	} else if ipVersion == "6" {
+		if report.UptimesIPV6 == nil {
+			report.UptimesIPV6 = models.Uptimes{}
+		}
+		report.UptimesIPV6[window] = uptime
+		switch window {
+		case Last5MinutesWindow:
+			report.Last5MinutesIPV6, report.Last5MinutesRTTIPV6 = uptime, rtt
+		case LastHourWindow:
+			report.LastHourIPV6, report.LastHourRTTIPV6 = uptime, rtt
+		case LastDayWindow:
+			report.LastDayIPV6, report.LastDayRTTIPV6 = uptime, rtt
+		}
+	}
+}
+
+// setGatewayClientsUptime records the uptime of the host clients connect to during the window. It's only kept for the
+// default windows.
+func setGatewayClientsUptime(report *models.GatewayStatusReport, ipVersion string, window string, uptime int) {
+	if ipVersion == "4" {
+		switch window {
+		case Last5MinutesWindow:
+			report.ClientsLast5MinutesIPV4 = uptime
+		case LastHourWindow:
+			report.ClientsLastHourIPV4 = uptime
+		case LastDayWindow:
+			report.ClientsLastDayIPV4 = uptime
+		}
+	} else if ipVersion == "6" {
+		switch window {
+		case Last5MinutesWindow:
+			report.ClientsLast5MinutesIPV6 = uptime
+		case LastHourWindow:
+			report.ClientsLastHourIPV6 = uptime
+		case LastDayWindow:
+			report.ClientsLastDayIPV6 = uptime
+		}
+	}
+}
+
+// mixUptimes returns the uptimes of the report for the ip version by window. Reports saved before they were kept by
+// window only have the default ones.
+func mixUptimes(report models.MixStatusReport, ipVersion string) models.Uptimes {
+	uptimes := report.UptimesIPV4
+	if ipVersion == "6" {
+		uptimes = report.UptimesIPV6
+	}
+	if uptimes != nil {
+		return uptimes
+	}
+	if ipVersion == "6" {
+		return models.Uptimes{Last5MinutesWindow: report.Last5MinutesIPV6, LastHourWindow: report.LastHourIPV6, LastDayWindow: report.LastDayIPV6}
+	}
+	return models.Uptimes{Last5MinutesWindow: report.Last5MinutesIPV4, LastHourWindow: report.LastHourIPV4, LastDayWindow: report.LastDayIPV4}
+}
+
+// compareMixReports puts the reports side by side along with the differences between their uptimes
+func compareMixReports(a models.MixStatusReport, b models.MixStatusReport) models.MixComparison {
+	deltas := func(ipVersion string) models.Uptimes {
+		deltas := models.Uptimes{}
+		uptimesB := mixUptimes(b, ipVersion)
+		for window, uptimeA := range mixUptimes(a, ipVersion) {
+			if uptimeB, ok := uptimesB[window]; ok && uptimeA != InsufficientData && uptimeB != InsufficientData {
+				deltas[window] = uptimeB - uptimeA
+			}
+		}
+		return deltas
+	}
+
+	comparison := models.MixComparison{A: a, B: b, DeltasIPV4: deltas("4"), DeltasIPV6: models.Uptimes{}}
+	if a.HasIPV6Data && b.HasIPV6Data {
+		comparison.DeltasIPV6 = deltas("6")
+	}
+	return comparison
+}
@@ -0,0 +1,66 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mixmining
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ = Describe("Uptime windows", func() {
+	Describe("Parsing", func() {
+		It("should read name=duration pairs in order", func() {
+			windows, err := ParseUptimeWindows("last15Minutes=15m, lastWeek=168h")
+			assert.Nil(GinkgoT(), err)
+			assert.Equal(GinkgoT(), []UptimeWindow{
+				{Name: "last15Minutes", Duration: time.Minute * 15},
+				{Name: "lastWeek", Duration: time.Hour * 168},
+			}, windows)
+		})
+		It("should reject malformed, duplicated, non-positive and too long windows", func() {
+			for _, value := range []string{"", "last15Minutes", "=15m", "a=1h,a=2h", "a=0s", "a=-1h", "a=abc", "a=169h"} {
+				_, err := ParseUptimeWindows(value)
+				assert.NotNil(GinkgoT(), err, value)
+			}
+		})
+	})
+
+	Describe("Aligning", func() {
+		It("should keep the start of the window within the same minute", func() {
+			minute := time.Now().Truncate(time.Minute)
+			windows := AlignUptimeWindows(DefaultUptimeWindows, time.Minute)
+
+			early := windows[0].since(minute.Add(10 * time.Second))
+			assert.Equal(GinkgoT(), early, windows[0].since(minute.Add(50*time.Second)))
+			assert.Equal(GinkgoT(), minute.Add(-5*time.Minute).UnixNano(), early)
+			assert.NotEqual(GinkgoT(), early, DefaultUptimeWindows[0].since(minute.Add(10*time.Second)))
+		})
+		It("should leave the windows it was given alone", func() {
+			windows := AlignUptimeWindows(DefaultUptimeWindows, time.Minute)
+			assert.Equal(GinkgoT(), time.Minute, windows[0].Alignment)
+			assert.Equal(GinkgoT(), time.Duration(0), DefaultUptimeWindows[0].Alignment)
+		})
+	})
+
+	Describe("Splitting", func() {
+		It("should recalculate windows up to an hour with every status", func() {
+			perStatus, periodic := splitUptimeWindows(DefaultUptimeWindows)
+			assert.Equal(GinkgoT(), DefaultUptimeWindows[:2], perStatus)
+			assert.Equal(GinkgoT(), DefaultUptimeWindows[2:], periodic)
+		})
+	})
+})
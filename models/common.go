@@ -14,7 +14,50 @@
 
 package models
 
-// Error ...
+// Error is the body of every failed response. The message is kept under the `error` key, which is where it
+// always was, so that existing clients keep working.
 type Error struct {
-	Error string `json:"error"`
+	Code    int    `json:"code"`
+	Message string `json:"error"`
+}
+
+// ValidationError is the body of responses rejecting a request body that failed validation. On top of the message,
+// it lists every invalid field by its path in the body, such as status[2].up, along with the rule it broke.
+type ValidationError struct {
+	Code    int          `json:"code"`
+	Message string       `json:"error"`
+	Fields  []FieldError `json:"fields"`
+}
+
+// FieldError names an invalid field and the validation rule it broke, such as required or min=0
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// OK is the body of successful responses that have nothing else to return
+type OK struct {
+	OK bool `json:"ok"`
+}
+
+// Envelope wraps a list along with metadata about it, for clients that ask for it. GeneratedAt is when the response
+// was generated, as unix nanoseconds like every other timestamp.
+type Envelope struct {
+	Data        interface{} `json:"data"`
+	Count       int         `json:"count"`
+	GeneratedAt int64       `json:"generatedAt"`
+}
+
+// Readiness is the body of a successful readiness check
+type Readiness struct {
+	OK             bool `json:"ok"`
+	ActiveMixnodes int  `json:"activeMixnodes"`
+	ActiveGateways int  `json:"activeGateways"`
+}
+
+// Version tells which build of the application is running
+type Version struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"gitCommit"`
+	BuildTime string `json:"buildTime"`
 }
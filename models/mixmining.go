@@ -15,7 +15,12 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
 	_ "github.com/jinzhu/gorm"
+	"gorm.io/gorm"
 )
 
 // MixStatus indicates whether a given node is up or down, as reported by a Nym monitor node.
@@ -23,115 +28,471 @@ import (
 // so making it a pointer works. This necessitates crapification of the Up-related code, as you can't
 // do `*true` or `&true`, you need a variable to point to or dereference. This is why you'll see e.g.
 // things like `booltrue := true`, `&booltrue` in the codebase. Maybe there's a more elegant way to
-// achieve that which a bigger gopher could clean up.
+// achieve that which a bigger gopher could clean up. The pointer only lives on the inbound structs,
+// once the status gets persisted it's converted into a plain bool (see PersistedMixStatus).
+// RTTMillis is the packet round-trip time measured by the monitor. It's optional, as not all monitors measure it.
 type MixStatus struct {
 	PubKey    string `json:"pubKey" binding:"required" gorm:"index:mix_status_index"`
-	Owner     string `json:"owner" binding:"required" gorm:"index:mix_status_index"`
+	// Owner is required unless the API is configured to accept statuses of nodes nobody claimed yet, so it's
+	// checked by the controller rather than by the binding
+	Owner     string `json:"owner" gorm:"index:mix_status_index"`
 	IPVersion string `json:"ipVersion" binding:"required" gorm:"index:mix_status_index"`
 	Up        *bool  `json:"up" binding:"required"`
+	RTTMillis *int   `json:"rttMillis,omitempty" binding:"omitempty,min=0"`
+	// MeasuredAt is when the monitor took the measurement, as unix nanoseconds. It's optional, the time the status
+	// was received at is used for statuses without it.
+	MeasuredAt *int64 `json:"measuredAt,omitempty" binding:"omitempty,min=0"`
+	// Epoch is the incentive epoch the monitor took the measurement in. It's optional, statuses without it aren't
+	// attributed to any epoch.
+	Epoch *uint64 `json:"epoch,omitempty"`
 }
 
 type GatewayStatus struct {
 	PubKey    string `json:"pubKey" binding:"required" gorm:"index:gateway_status_index"`
-	Owner     string `json:"owner" binding:"required" gorm:"index:gateway_status_index"`
+	// Owner is required unless owners are configured to be optional, same as for mixnodes
+	Owner     string `json:"owner" gorm:"index:gateway_status_index"`
 	IPVersion string `json:"ipVersion" binding:"required" gorm:"index:gateway_status_index"`
 	Up        *bool  `json:"up" binding:"required"`
+	RTTMillis *int   `json:"rttMillis,omitempty" binding:"omitempty,min=0"`
+	// ClientsHostUp tells whether clients could connect to the gateway, which can be down even if the gateway is up
+	// for mixnet traffic. It's optional, as not all monitors check it.
+	ClientsHostUp *bool `json:"clientsHostUp,omitempty"`
 }
 
 // PersistedMixStatus is a saved MixStatus with a timestamp recording when it
 // was seen by the directory server. It can be used to build visualizations of
-// mixnode uptime.
+// mixnode uptime. Up is stored as a plain bool, so there's no nil to worry about
+// anywhere past the binding boundary.
 type PersistedMixStatus struct {
-	MixStatus
-	Timestamp int64 `json:"timestamp" binding:"required" gorm:"index:mix_status_index,sort:desc"`
+	PubKey    string `json:"pubKey" binding:"required" gorm:"index:mix_status_index"`
+	Owner     string `json:"owner" binding:"required" gorm:"index:mix_status_index"`
+	IPVersion string `json:"ipVersion" binding:"required" gorm:"index:mix_status_index"`
+	Up        bool   `json:"up"`
+	RTTMillis *int   `json:"rttMillis,omitempty"`
+	Timestamp int64  `json:"timestamp" binding:"required" gorm:"index:mix_status_index,sort:desc"`
+	// DeletedAt is set once the status gets retracted, e.g. because the monitor realised it mis-measured.
+	// Retracted statuses are left out of every query, and so out of the uptime calculations.
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	// MeasuredAt is when the monitor took the measurement, Timestamp is when the status was received. The gap
+	// between the two is the ingestion lag.
+	MeasuredAt int64 `json:"measuredAt"`
+	// Epoch is the incentive epoch the status was measured in, as told by the monitor, nil if it didn't tell
+	Epoch *uint64 `json:"epoch,omitempty" gorm:"index"`
 }
 
+// NewPersistedMixStatus converts an inbound MixStatus into its persisted form, seen at the given timestamp.
+// A missing Up value is treated as the node being down, a missing MeasuredAt as the status having been measured
+// at the time it was seen.
+func NewPersistedMixStatus(status MixStatus, timestamp int64) PersistedMixStatus {
+	measuredAt := timestamp
+	if status.MeasuredAt != nil {
+		measuredAt = *status.MeasuredAt
+	}
+	return PersistedMixStatus{
+		PubKey:     status.PubKey,
+		Owner:      status.Owner,
+		IPVersion:  status.IPVersion,
+		Up:         status.Up != nil && *status.Up,
+		RTTMillis:  status.RTTMillis,
+		Timestamp:  timestamp,
+		MeasuredAt: measuredAt,
+		Epoch:      status.Epoch,
+	}
+}
+
+// PersistedGatewayStatus is a saved GatewayStatus with a timestamp recording when it
+// was seen by the directory server. Same as with PersistedMixStatus, Up is a plain bool.
 type PersistedGatewayStatus struct {
-	GatewayStatus
-	Timestamp int64 `json:"timestamp" binding:"required" gorm:"index:gateway_status_index,sort:desc"`
+	PubKey    string `json:"pubKey" binding:"required" gorm:"index:gateway_status_index"`
+	Owner     string `json:"owner" binding:"required" gorm:"index:gateway_status_index"`
+	IPVersion string `json:"ipVersion" binding:"required" gorm:"index:gateway_status_index"`
+	Up        bool   `json:"up"`
+	RTTMillis *int   `json:"rttMillis,omitempty"`
+	Timestamp int64  `json:"timestamp" binding:"required" gorm:"index:gateway_status_index,sort:desc"`
+	// DeletedAt is set once the status gets retracted, same as for mixnodes
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	// ClientsHostUp stays a pointer, as unlike Up it's nil whenever the monitor didn't check it
+	ClientsHostUp *bool `json:"clientsHostUp,omitempty"`
+}
+
+// NewPersistedGatewayStatus converts an inbound GatewayStatus into its persisted form, seen at the given timestamp.
+// A missing Up value is treated as the node being down.
+func NewPersistedGatewayStatus(status GatewayStatus, timestamp int64) PersistedGatewayStatus {
+	return PersistedGatewayStatus{
+		PubKey:    status.PubKey,
+		Owner:     status.Owner,
+		IPVersion: status.IPVersion,
+		Up:        status.Up != nil && *status.Up,
+		RTTMillis: status.RTTMillis,
+		Timestamp: timestamp,
+
+		ClientsHostUp: status.ClientsHostUp,
+	}
 }
 
-// MixStatusReport gives a quick view of mixnode uptime performance
+// MixStatusReport gives a quick view of mixnode uptime performance. The uptimes are percentages, or -1 if the node
+// didn't report any status during the window, so that a node nobody heard from isn't mistaken for one that was down
+// the whole time. The RTT fields hold the average round-trip time in milliseconds during each window, they're null if
+// no status in the window carried one.
 type MixStatusReport struct {
-	PubKey           string `json:"pubKey" binding:"required" gorm:"primaryKey;unique"`
-	Owner            string `json:"owner" binding:"required" binding:"required"`
-	MostRecentIPV4   bool   `json:"mostRecentIPV4" binding:"required"`
-	Last5MinutesIPV4 int    `json:"last5MinutesIPV4" binding:"required"`
-	LastHourIPV4     int    `json:"lastHourIPV4" binding:"required"`
-	LastDayIPV4      int    `json:"lastDayIPV4" binding:"required"`
-	MostRecentIPV6   bool   `json:"mostRecentIPV6" binding:"required"`
-	Last5MinutesIPV6 int    `json:"last5MinutesIPV6" binding:"required"`
-	LastHourIPV6     int    `json:"lastHourIPV6" binding:"required"`
-	LastDayIPV6      int    `json:"lastDayIPV6" binding:"required"`
+	PubKey              string `json:"pubKey" binding:"required" gorm:"primaryKey;unique"`
+	Owner               string `json:"owner" binding:"required" binding:"required"`
+	MostRecentIPV4      bool   `json:"mostRecentIPV4" binding:"required"`
+	Last5MinutesIPV4    int    `json:"last5MinutesIPV4" binding:"required"`
+	LastHourIPV4        int    `json:"lastHourIPV4" binding:"required"`
+	LastDayIPV4         int    `json:"lastDayIPV4" binding:"required"`
+	MostRecentIPV6      bool   `json:"mostRecentIPV6" binding:"required"`
+	Last5MinutesIPV6    int    `json:"last5MinutesIPV6" binding:"required"`
+	LastHourIPV6        int    `json:"lastHourIPV6" binding:"required"`
+	LastDayIPV6         int    `json:"lastDayIPV6" binding:"required"`
+	Last5MinutesRTTIPV4 *int   `json:"last5MinutesRTTIPV4"`
+	LastHourRTTIPV4     *int   `json:"lastHourRTTIPV4"`
+	LastDayRTTIPV4      *int   `json:"lastDayRTTIPV4"`
+	Last5MinutesRTTIPV6 *int   `json:"last5MinutesRTTIPV6"`
+	LastHourRTTIPV6     *int   `json:"lastHourRTTIPV6"`
+	LastDayRTTIPV6      *int   `json:"lastDayRTTIPV6"`
+	// *Count are the numbers of statuses the uptimes of the windows were calculated from, so that clients can tell
+	// how confident they can be in them
+	Last5MinutesIPV4Count int `json:"last5MinutesIPV4Count"`
+	LastHourIPV4Count     int `json:"lastHourIPV4Count"`
+	Last5MinutesIPV6Count int `json:"last5MinutesIPV6Count"`
+	LastHourIPV6Count     int `json:"lastHourIPV6Count"`
+	// MostRecent*Timestamp are the timestamps of the most recent statuses, 0 for reports saved before they were added
+	MostRecentIPV4Timestamp int64 `json:"mostRecentIPV4Timestamp"`
+	MostRecentIPV6Timestamp int64 `json:"mostRecentIPV6Timestamp"`
+	// HasIPV6Data tells whether the node ever reported an ipv6 status. Some monitors only probe ipv4, without it
+	// the zero ipv6 uptimes of their nodes would be indistinguishable from a 0% ipv6 uptime
+	HasIPV6Data bool `json:"hasIPV6Data"`
+	// Uptimes* hold the uptime during every configured window, the named fields above are filled in from the
+	// default windows
+	UptimesIPV4 Uptimes `json:"uptimesIPV4,omitempty"`
+	UptimesIPV6 Uptimes `json:"uptimesIPV6,omitempty"`
+	// Version gets bumped on every save of a report updated from a single status, so that two such updates racing
+	// each other don't silently overwrite one another
+	Version int64 `json:"-" gorm:"not null;default:0"`
+	// Status is the overall state of the node, derived from the fields above whenever the report is served. It's
+	// not stored, as a node goes stale without any of them changing.
+	Status string `json:"status" gorm:"-" enums:"up,degraded,down,stale,no-data"`
+}
+
+// Overall states of a mixnode, telling clients what to make of its report without deriving it themselves
+const (
+	NodeUp       = "up"
+	NodeDegraded = "degraded"
+	NodeDown     = "down"
+	NodeStale    = "stale"
+	NodeNoData   = "no-data"
+)
+
+// MostRecentTimestamp returns the timestamp of the most recent status of either ip version
+func (report MixStatusReport) MostRecentTimestamp() int64 {
+	if report.MostRecentIPV4Timestamp > report.MostRecentIPV6Timestamp {
+		return report.MostRecentIPV4Timestamp
+	}
+	return report.MostRecentIPV6Timestamp
 }
 
+// GatewayStatusReport gives a quick view of gateway uptime performance. Same as for mixnodes, the uptimes are -1 if
+// the gateway didn't report any status during the window.
 type GatewayStatusReport struct {
-	PubKey           string `json:"pubKey" binding:"required" gorm:"primaryKey;unique"`
-	Owner            string `json:"owner" binding:"required" binding:"required"`
-	MostRecentIPV4   bool   `json:"mostRecentIPV4" binding:"required"`
-	Last5MinutesIPV4 int    `json:"last5MinutesIPV4" binding:"required"`
-	LastHourIPV4     int    `json:"lastHourIPV4" binding:"required"`
-	LastDayIPV4      int    `json:"lastDayIPV4" binding:"required"`
-	MostRecentIPV6   bool   `json:"mostRecentIPV6" binding:"required"`
-	Last5MinutesIPV6 int    `json:"last5MinutesIPV6" binding:"required"`
-	LastHourIPV6     int    `json:"lastHourIPV6" binding:"required"`
-	LastDayIPV6      int    `json:"lastDayIPV6" binding:"required"`
+	PubKey              string `json:"pubKey" binding:"required" gorm:"primaryKey;unique"`
+	Owner               string `json:"owner" binding:"required" binding:"required"`
+	MostRecentIPV4      bool   `json:"mostRecentIPV4" binding:"required"`
+	Last5MinutesIPV4    int    `json:"last5MinutesIPV4" binding:"required"`
+	LastHourIPV4        int    `json:"lastHourIPV4" binding:"required"`
+	LastDayIPV4         int    `json:"lastDayIPV4" binding:"required"`
+	MostRecentIPV6      bool   `json:"mostRecentIPV6" binding:"required"`
+	Last5MinutesIPV6    int    `json:"last5MinutesIPV6" binding:"required"`
+	LastHourIPV6        int    `json:"lastHourIPV6" binding:"required"`
+	LastDayIPV6         int    `json:"lastDayIPV6" binding:"required"`
+	Last5MinutesRTTIPV4 *int   `json:"last5MinutesRTTIPV4"`
+	LastHourRTTIPV4     *int   `json:"lastHourRTTIPV4"`
+	LastDayRTTIPV4      *int   `json:"lastDayRTTIPV4"`
+	Last5MinutesRTTIPV6 *int   `json:"last5MinutesRTTIPV6"`
+	LastHourRTTIPV6     *int   `json:"lastHourRTTIPV6"`
+	LastDayRTTIPV6      *int   `json:"lastDayRTTIPV6"`
+	// Uptimes* hold the uptime during every configured window, same as for mixnodes
+	UptimesIPV4 Uptimes `json:"uptimesIPV4,omitempty"`
+	UptimesIPV6 Uptimes `json:"uptimesIPV6,omitempty"`
+	// Clients* hold the uptime of the host clients connect to during the default windows. They're -1 if no
+	// monitor checked it during the window.
+	ClientsLast5MinutesIPV4 int `json:"clientsLast5MinutesIPV4"`
+	ClientsLastHourIPV4     int `json:"clientsLastHourIPV4"`
+	ClientsLastDayIPV4      int `json:"clientsLastDayIPV4"`
+	ClientsLast5MinutesIPV6 int `json:"clientsLast5MinutesIPV6"`
+	ClientsLastHourIPV6     int `json:"clientsLastHourIPV6"`
+	ClientsLastDayIPV6      int `json:"clientsLastDayIPV6"`
 }
 
-// BatchMixStatus allows to indicate whether given set of nodes is up or down, as reported by a Nym monitor node.
-type BatchMixStatus struct {
-	Status []MixStatus `json:"status" binding:"required"`
+// Uptimes maps the names of uptime windows to the uptime percentage during each of them, -1 for the windows without
+// any status. It's stored as JSON text.
+type Uptimes map[string]int
+
+// GormDataType tells gorm which column type to use
+func (Uptimes) GormDataType() string {
+	return "text"
 }
 
-type BatchGatewayStatus struct {
-	Status []GatewayStatus `json:"status" binding:"required"`
+// Value implements driver.Valuer
+func (uptimes Uptimes) Value() (driver.Value, error) {
+	if uptimes == nil {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(uptimes)
+	return string(encoded), err
 }
 
-// BatchMixStatusReport gives a quick view of network uptime performance
-type BatchMixStatusReport struct {
-	Report []MixStatusReport `json:"report" binding:"required"`
+// Scan implements sql.Scanner
+func (uptimes *Uptimes) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*uptimes = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), uptimes)
+	case []byte:
+		return json.Unmarshal(v, uptimes)
+	default:
+		return fmt.Errorf("can't scan %T into Uptimes", value)
+	}
 }
 
-type BatchGatewayStatusReport struct {
-	Report []GatewayStatusReport `json:"report" binding:"required"`
+// UptimeStatistics summarises uptime percentages of multiple nodes
+type UptimeStatistics struct {
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	Min    int     `json:"min"`
+	Max    int     `json:"max"`
 }
 
-func (report *BatchMixStatusReport) SplitToChunks(chunkSize int) []BatchMixStatusReport {
-	reportDataCopy := make([]MixStatusReport, len(report.Report))
-	copy(reportDataCopy, report.Report)
+// MixUptimeAggregate gives a quick view of the uptime of all active mixnodes over the last `Hours` hours.
+// Nodes without any measurements for the given ip version during that time are not included in its statistics.
+type MixUptimeAggregate struct {
+	Hours int              `json:"hours"`
+	Nodes int              `json:"nodes"`
+	IPV4  UptimeStatistics `json:"ipv4"`
+	IPV6  UptimeStatistics `json:"ipv6"`
+}
 
-	var chunks [][]MixStatusReport
-	for chunkSize < len(reportDataCopy) {
-		reportDataCopy, chunks = reportDataCopy[chunkSize:], append(chunks, reportDataCopy[0:chunkSize:chunkSize])
-	}
+// MixUptimeDrop flags a mixnode whose uptime during the last 5 minutes fell well below its uptime during the last
+// hour for the given ip version. Drop is the difference between the two, in percentage points.
+type MixUptimeDrop struct {
+	PubKey       string `json:"pubKey"`
+	Owner        string `json:"owner"`
+	IPVersion    string `json:"ipVersion"`
+	LastHour     int    `json:"lastHour"`
+	Last5Minutes int    `json:"last5Minutes"`
+	Drop         int    `json:"drop"`
+}
 
-	chunks = append(chunks, reportDataCopy)
+// Uptime trends, telling whether the last hour went better or worse than the last day
+const (
+	TrendImproving = "improving"
+	TrendSteady    = "steady"
+	TrendDeclining = "declining"
+)
 
+// UptimeCount is the exact fraction behind an uptime: the node was up in Up of the Total statuses of the window
+type UptimeCount struct {
+	Up    int `json:"up"`
+	Total int `json:"total"`
+}
 
-	newReports := make([]BatchMixStatusReport, len(chunks))
-	for i, chunk := range chunks {
-		newReports[i] = BatchMixStatusReport{Report: chunk}
-	}
+// MixRawUptime holds the counts behind the uptimes of a mixnode during every configured window, keyed by the names
+// of the windows, so that clients can work out the uptimes with whatever precision they need. The counts are there
+// even for the windows with too few statuses for an uptime.
+type MixRawUptime struct {
+	PubKey string                 `json:"pubKey"`
+	IPV4   map[string]UptimeCount `json:"ipv4"`
+	IPV6   map[string]UptimeCount `json:"ipv6"`
+}
 
-	return newReports
+// MixEpochReport holds what was measured during an incentive epoch: the statuses the monitors attributed to it, in the
+// order they came in, and the uptime of every node and ip version over them.
+type MixEpochReport struct {
+	Epoch    uint64               `json:"epoch"`
+	Uptimes  []MixEpochUptime     `json:"uptimes"`
+	Statuses []PersistedMixStatus `json:"statuses"`
 }
 
-func (report *BatchGatewayStatusReport) SplitToChunks(chunkSize int) []BatchGatewayStatusReport {
-	reportDataCopy := make([]GatewayStatusReport, len(report.Report))
-	copy(reportDataCopy, report.Report)
+// MixEpochUptime is the uptime of a mixnode over its statuses of an epoch for the given ip version. As with the
+// reports, an uptime of -1 means there weren't enough statuses to tell.
+type MixEpochUptime struct {
+	PubKey       string `json:"pubKey"`
+	Owner        string `json:"owner"`
+	IPVersion    string `json:"ipVersion"`
+	Uptime       int    `json:"uptime"`
+	Measurements int    `json:"measurements"`
+}
 
-	var chunks [][]GatewayStatusReport
-	for chunkSize < len(reportDataCopy) {
-		reportDataCopy, chunks = reportDataCopy[chunkSize:], append(chunks, reportDataCopy[0:chunkSize:chunkSize])
-	}
+// MixNodeSummary combines the status report of a mixnode with what's needed to show its details at a glance.
+// Trends compare the last hour uptime to the last day uptime. They're steady when either of them is -1, meaning there
+// were no statuses to tell.
+type MixNodeSummary struct {
+	Report               MixStatusReport `json:"report"`
+	TrendIPV4            string          `json:"trendIPV4" enums:"improving,steady,declining"`
+	TrendIPV6            string          `json:"trendIPV6" enums:"improving,steady,declining"`
+	MostRecentStatusTime int64           `json:"mostRecentStatusTime"`
+}
 
-	chunks = append(chunks, reportDataCopy)
+// MixComparison puts the reports of two mixnodes side by side. The deltas are the uptimes of B minus the ones of A,
+// keyed by the names of the windows, for the windows both of them have enough statuses during. The ipv6 ones are only
+// there if both of them ever reported ipv6 statuses.
+type MixComparison struct {
+	A          MixStatusReport `json:"a"`
+	B          MixStatusReport `json:"b"`
+	DeltasIPV4 Uptimes         `json:"deltasIPV4"`
+	DeltasIPV6 Uptimes         `json:"deltasIPV6"`
+}
 
+// MixUptimeAt tells what the uptime of a mixnode was during the `WindowMinutes` minutes up to `Timestamp`.
+// As with the reports, an uptime of -1 means there weren't enough statuses during the window to tell.
+type MixUptimeAt struct {
+	PubKey        string `json:"pubKey"`
+	IPVersion     string `json:"ipVersion"`
+	Timestamp     int64  `json:"timestamp"`
+	WindowMinutes int    `json:"windowMinutes"`
+	Uptime        int    `json:"uptime"`
+}
 
-	newReports := make([]BatchGatewayStatusReport, len(chunks))
-	for i, chunk := range chunks {
-		newReports[i] = BatchGatewayStatusReport{Report: chunk}
-	}
+// NetworkUptimeSample records the mean last hour uptime of the mixnodes at the time of a periodic reports update.
+// ActiveCount is the number of mixnodes that reported any status during that hour, the mean is taken over them.
+// Together the samples make up the uptime history of the network as a whole.
+type NetworkUptimeSample struct {
+	Timestamp   int64   `json:"timestamp" gorm:"index"`
+	MeanUptime  float64 `json:"meanUptime"`
+	ActiveCount int     `json:"activeCount"`
+}
+
+// OwnershipChange records a mixnode reporting a status under another owner than the one of its report, e.g. after it
+// got transferred. Timestamp is the one of that status.
+type OwnershipChange struct {
+	PubKey        string `json:"pubKey" gorm:"index"`
+	PreviousOwner string `json:"previousOwner"`
+	Owner         string `json:"owner"`
+	Timestamp     int64  `json:"timestamp"`
+}
+
+// StatusStats tells how much data is being kept around
+type StatusStats struct {
+	MixStatuses           int64 `json:"mixStatuses"`
+	GatewayStatuses       int64 `json:"gatewayStatuses"`
+	ActiveMixnodes        int   `json:"activeMixnodes"`
+	ActiveGateways        int   `json:"activeGateways"`
+	OldestStatusTimestamp int64 `json:"oldestStatusTimestamp"`
+	// LastReportUpdate is when the reports were last updated, 0 if they haven't been since startup. They get
+	// updated every 10 minutes, so anything much older means the updater is stuck.
+	LastReportUpdate int64 `json:"lastReportUpdate"`
+	// IngestionLag tells how long the recent mix statuses took to get from the monitor to the server
+	IngestionLag IngestionLag `json:"ingestionLag"`
+	// QueryTimeouts counts the database queries that ran out of time since startup
+	QueryTimeouts int64 `json:"queryTimeouts"`
+	// ReportCacheHits counts the full mix reports served from memory since startup
+	ReportCacheHits int64 `json:"reportCacheHits"`
+}
+
+// NodeLifetime tells when a node reported its first and its most recent retained status, and how many it reported
+// in between. Statuses past the retention period are gone, so FirstSeen is at most that far back.
+type NodeLifetime struct {
+	FirstSeen         int64 `json:"firstSeen"`
+	LastSeen          int64 `json:"lastSeen"`
+	TotalMeasurements int64 `json:"totalMeasurements"`
+}
+
+// IngestionLag summarises the gap between when the mix statuses were measured and when they were received. Statuses
+// without a MeasuredAt count as received right away, so a lagging monitor shows up here only if it sets it.
+type IngestionLag struct {
+	Statuses      int64 `json:"statuses"`
+	AverageMillis int64 `json:"averageMillis"`
+	MaxMillis     int64 `json:"maxMillis"`
+}
+
+// Backfill lists the mixnodes whose missing reports got recomputed from their statuses
+type Backfill struct {
+	PubKeys []string `json:"pubKeys"`
+}
+
+// Recomputation tells how many reports got rebuilt from the statuses. Failed lists the nodes whose statuses were
+// purged before their report could be rebuilt, or whose rebuilt report couldn't be saved.
+type Recomputation struct {
+	Mixnodes int      `json:"mixnodes"`
+	Gateways int      `json:"gateways"`
+	Failed   []string `json:"failed"`
+}
+
+// Kinds of the records of an export
+const (
+	ExportMixReport     = "mixReport"
+	ExportGatewayReport = "gatewayReport"
+	ExportMixStatus     = "mixStatus"
+	ExportGatewayStatus = "gatewayStatus"
+)
+
+// ExportRecord is a single line of an export of the whole dataset. Only the field matching its kind is set.
+type ExportRecord struct {
+	Kind          string                  `json:"kind"`
+	MixReport     *MixStatusReport        `json:"mixReport,omitempty"`
+	GatewayReport *GatewayStatusReport    `json:"gatewayReport,omitempty"`
+	MixStatus     *PersistedMixStatus     `json:"mixStatus,omitempty"`
+	GatewayStatus *PersistedGatewayStatus `json:"gatewayStatus,omitempty"`
+}
+
+// Import tells how many records of each kind got imported
+type Import struct {
+	MixReports      int `json:"mixReports"`
+	GatewayReports  int `json:"gatewayReports"`
+	MixStatuses     int `json:"mixStatuses"`
+	GatewayStatuses int `json:"gatewayStatuses"`
+}
+
+// BulkHistoryRequest asks for the most recent statuses of multiple mixnodes at once, at most Limit of them per node
+type BulkHistoryRequest struct {
+	PubKeys []string `json:"pubKeys" binding:"required,min=1"`
+	Limit   int      `json:"limit" binding:"omitempty,min=1"`
+}
+
+// BatchMixStatus allows to indicate whether given set of nodes is up or down, as reported by a Nym monitor node.
+type BatchMixStatus struct {
+	Status []MixStatus `json:"status" binding:"required"`
+	// Epoch is the incentive epoch of the statuses of the batch that don't tell their own
+	Epoch *uint64 `json:"epoch,omitempty"`
+}
+
+type BatchGatewayStatus struct {
+	Status []GatewayStatus `json:"status" binding:"required"`
+}
+
+// ValidatedMixStatus is a sanitized MixStatus along with whether it would pass validation
+type ValidatedMixStatus struct {
+	Status MixStatus `json:"status"`
+	Valid  bool      `json:"valid"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// BatchMixStatusValidation tells what would be stored for a BatchMixStatus
+type BatchMixStatusValidation struct {
+	Status []ValidatedMixStatus `json:"status"`
+}
 
-	return newReports
+// BatchResult tells which statuses of a batch accepted in part got stored and why the other ones didn't. Statuses
+// are identified by their index in the batch.
+type BatchResult struct {
+	Stored []int          `json:"stored"`
+	Failed []FailedStatus `json:"failed"`
+}
+
+// FailedStatus is a status of a batch that was left out as it's invalid
+type FailedStatus struct {
+	Index  int          `json:"index"`
+	Error  string       `json:"error"`
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// BatchMixStatusReport gives a quick view of network uptime performance
+type BatchMixStatusReport struct {
+	Report []MixStatusReport `json:"report" binding:"required"`
+}
+
+type BatchGatewayStatusReport struct {
+	Report []GatewayStatusReport `json:"report" binding:"required"`
 }
\ No newline at end of file
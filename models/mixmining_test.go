@@ -0,0 +1,76 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	. "github.com/onsi/ginkgo"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ = Describe("Persisting a mix status", func() {
+	Context("when the node was up", func() {
+		It("should keep all the fields and store Up as plain true", func() {
+			booltrue := true
+			status := MixStatus{PubKey: "key", Owner: "owner", IPVersion: "4", Up: &booltrue}
+
+			persisted := NewPersistedMixStatus(status, 1234)
+			expected := PersistedMixStatus{PubKey: "key", Owner: "owner", IPVersion: "4", Up: true, Timestamp: 1234, MeasuredAt: 1234}
+			assert.Equal(GinkgoT(), expected, persisted)
+		})
+	})
+	Context("when the monitor said when it measured the status", func() {
+		It("should keep both that time and the time it was seen", func() {
+			booltrue := true
+			measuredAt := int64(1000)
+			status := MixStatus{PubKey: "key", Owner: "owner", IPVersion: "4", Up: &booltrue, MeasuredAt: &measuredAt}
+
+			persisted := NewPersistedMixStatus(status, 1234)
+			assert.Equal(GinkgoT(), int64(1000), persisted.MeasuredAt)
+			assert.Equal(GinkgoT(), int64(1234), persisted.Timestamp)
+		})
+	})
+	Context("when the round-trip time was measured", func() {
+		It("should keep it", func() {
+			booltrue := true
+			rtt := 42
+			status := MixStatus{PubKey: "key", Owner: "owner", IPVersion: "4", Up: &booltrue, RTTMillis: &rtt}
+			assert.Equal(GinkgoT(), 42, *NewPersistedMixStatus(status, 1234).RTTMillis)
+		})
+	})
+	Context("when the node was down", func() {
+		It("should store Up as plain false", func() {
+			boolfalse := false
+			status := MixStatus{PubKey: "key", Owner: "owner", IPVersion: "4", Up: &boolfalse}
+			assert.False(GinkgoT(), NewPersistedMixStatus(status, 1234).Up)
+		})
+	})
+	Context("when Up is missing", func() {
+		It("should treat the node as down rather than dereferencing nil", func() {
+			status := MixStatus{PubKey: "key", Owner: "owner", IPVersion: "4"}
+			assert.False(GinkgoT(), NewPersistedMixStatus(status, 1234).Up)
+		})
+	})
+})
+
+var _ = Describe("Persisting a gateway status", func() {
+	Context("when Up is missing", func() {
+		It("should treat the node as down rather than dereferencing nil", func() {
+			status := GatewayStatus{PubKey: "key", Owner: "owner", IPVersion: "6"}
+			persisted := NewPersistedGatewayStatus(status, 1234)
+			expected := PersistedGatewayStatus{PubKey: "key", Owner: "owner", IPVersion: "6", Up: false, Timestamp: 1234}
+			assert.Equal(GinkgoT(), expected, persisted)
+		})
+	})
+})
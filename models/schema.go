@@ -0,0 +1,113 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// JSONSchemaDraft is the JSON Schema version the generated schemas follow
+const JSONSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// JSONSchema is the subset of JSON Schema needed to describe the payloads accepted by the API
+type JSONSchema struct {
+	Schema      string                 `json:"$schema,omitempty"`
+	Type        string                 `json:"type,omitempty"`
+	Properties  map[string]*JSONSchema `json:"properties,omitempty"`
+	Required    []string               `json:"required,omitempty"`
+	Items       *JSONSchema            `json:"items,omitempty"`
+	Minimum     *int                   `json:"minimum,omitempty"`
+	Definitions map[string]*JSONSchema `json:"definitions,omitempty"`
+}
+
+// PayloadSchema describes every status payload the API accepts. It's generated from the struct tags, so it can't
+// drift from what the handlers actually bind.
+func PayloadSchema() JSONSchema {
+	return JSONSchema{
+		Schema: JSONSchemaDraft,
+		Definitions: map[string]*JSONSchema{
+			"MixStatus":          SchemaFor(MixStatus{}),
+			"GatewayStatus":      SchemaFor(GatewayStatus{}),
+			"BatchMixStatus":     SchemaFor(BatchMixStatus{}),
+			"BatchGatewayStatus": SchemaFor(BatchGatewayStatus{}),
+		},
+	}
+}
+
+// SchemaFor builds the schema of the given value from its type. Property names come from the `json` tags,
+// `binding:"required"` marks a property as required and `binding:"min=N"` sets its minimum.
+func SchemaFor(v interface{}) *JSONSchema {
+	return schemaForType(reflect.TypeOf(v))
+}
+
+func schemaForType(t reflect.Type) *JSONSchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return schemaForStruct(t)
+	case reflect.Slice, reflect.Array:
+		return &JSONSchema{Type: "array", Items: schemaForType(t.Elem())}
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &JSONSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}
+	case reflect.String:
+		return &JSONSchema{Type: "string"}
+	default:
+		return &JSONSchema{}
+	}
+}
+
+func schemaForStruct(t reflect.Type) *JSONSchema {
+	schema := &JSONSchema{Type: "object", Properties: make(map[string]*JSONSchema)}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := schemaForType(field.Type)
+		for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+			switch {
+			case rule == "required":
+				schema.Required = append(schema.Required, name)
+			case strings.HasPrefix(rule, "min="):
+				if min, err := strconv.Atoi(strings.TrimPrefix(rule, "min=")); err == nil {
+					property.Minimum = &min
+				}
+			}
+		}
+		schema.Properties[name] = property
+	}
+
+	return schema
+}
@@ -0,0 +1,55 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	. "github.com/onsi/ginkgo"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ = Describe("Generating the payload schema", func() {
+	Context("for a mix status", func() {
+		schema := SchemaFor(MixStatus{})
+
+		It("should mark the fields required by the binding as required", func() {
+			assert.ElementsMatch(GinkgoT(), []string{"pubKey", "ipVersion", "up"}, schema.Required)
+		})
+		It("should use the json names and types", func() {
+			assert.Equal(GinkgoT(), "object", schema.Type)
+			assert.Equal(GinkgoT(), "string", schema.Properties["pubKey"].Type)
+			assert.Equal(GinkgoT(), "boolean", schema.Properties["up"].Type)
+			assert.Equal(GinkgoT(), "integer", schema.Properties["rttMillis"].Type)
+		})
+		It("should carry the minimum of the round-trip time", func() {
+			assert.Equal(GinkgoT(), 0, *schema.Properties["rttMillis"].Minimum)
+		})
+	})
+	Context("for a batch", func() {
+		It("should describe the statuses as an array of status objects", func() {
+			schema := SchemaFor(BatchGatewayStatus{})
+			assert.Equal(GinkgoT(), []string{"status"}, schema.Required)
+			assert.Equal(GinkgoT(), "array", schema.Properties["status"].Type)
+			assert.Equal(GinkgoT(), SchemaFor(GatewayStatus{}), schema.Properties["status"].Items)
+		})
+	})
+	Context("for all payloads", func() {
+		It("should define each of them", func() {
+			schema := PayloadSchema()
+			assert.Equal(GinkgoT(), JSONSchemaDraft, schema.Schema)
+			assert.Len(GinkgoT(), schema.Definitions, 4)
+			assert.Contains(GinkgoT(), schema.Definitions, "BatchMixStatus")
+		})
+	})
+})
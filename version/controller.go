@@ -0,0 +1,65 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nymtech/node-status-api/models"
+)
+
+// Build information. They're meant to be set at build time, e.g.
+// go build -ldflags "-X github.com/nymtech/node-status-api/version.GitCommit=$(git rev-parse HEAD)"
+var (
+	Version   = "0.10.0"
+	GitCommit = "unknown"
+	BuildTime = "unknown"
+)
+
+// controller is the version controller
+type controller struct{}
+
+// Controller ...
+type Controller interface {
+	GetVersion(c *gin.Context)
+	RegisterRoutes(router *gin.Engine)
+}
+
+// New returns a new version.Controller
+func New() Controller {
+	return &controller{}
+}
+
+func (controller *controller) RegisterRoutes(router *gin.Engine) {
+	router.GET("/api/version", controller.GetVersion)
+}
+
+// GetVersion ...
+// @Summary Lets you know which build is deployed
+// @Description Returns the version of the application along with the git commit and the time it was built from. The latter two are unknown unless they were set at build time.
+// @ID getVersion
+// @Accept  json
+// @Produce  json
+// @Tags version
+// @Success 200 {object} models.Version
+// @Router /api/version [get]
+func (controller *controller) GetVersion(c *gin.Context) {
+	c.JSON(http.StatusOK, models.Version{
+		Version:   Version,
+		GitCommit: GitCommit,
+		BuildTime: BuildTime,
+	})
+}
@@ -0,0 +1,45 @@
+// Copyright 2020 Nym Technologies SA
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nymtech/node-status-api/models"
+	. "github.com/onsi/ginkgo"
+	"github.com/stretchr/testify/assert"
+)
+
+var _ = Describe("Controller", func() {
+	Describe("retrieving the version", func() {
+		It("should return the build information", func() {
+			gin.SetMode(gin.TestMode)
+			router := gin.Default()
+			New().RegisterRoutes(router)
+
+			req := httptest.NewRequest("GET", "/api/version", nil)
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			var response models.Version
+			json.Unmarshal([]byte(resp.Body.String()), &response)
+			assert.Equal(GinkgoT(), 200, resp.Code)
+			assert.Equal(GinkgoT(), models.Version{Version: Version, GitCommit: "unknown", BuildTime: "unknown"}, response)
+			assert.NotEmpty(GinkgoT(), response.Version)
+		})
+	})
+})